@@ -0,0 +1,254 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	cfg "github.com/mmq88/quickcerts/configs"
+	"github.com/mmq88/quickcerts/data"
+	"github.com/mmq88/quickcerts/model"
+	"github.com/mmq88/quickcerts/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// The issuer shown by the authenticator apps for the TOTP secrets.
+const totpIssuer = "QuickCertS"
+
+// Get all admin tokens, only requests with valid tokens are allowed.
+//
+// @Summary Get all admin tokens
+// @Description Get all admin tokens including the revoked and expired ones, the newest first. The tokens themselves are never returned.
+// @Tags Tokens
+// @Accept json
+// @Produce json
+// @Param X-RunTime-Code header string false "Security code for admin access. Check path_to_qcs/configs/server.toml for more information."
+// @Param X-Access-Token header string false "Security token for admin access. The tokens are managed by /tokens and bootstrapped from path_to_qcs/configs/allowlist.toml."
+// @Success 200 {object} model.GetAllAdminTokensResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /tokens [get]
+func GetAllAdminTokens(ctx *gin.Context) {
+	tokens, err := adminTokenStore.GetAllAdminTokens(ctx.Request.Context())
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, model.ErrorResponse{Code: errorCode(err), Error: err.Error()})
+		utils.Record(logrus.ErrorLevel, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, model.GetAllAdminTokensResponse{Data: tokens})
+}
+
+// Create a new admin token, only requests with valid tokens are allowed.
+//
+// @Summary Create a new admin token
+// @Description Create a new admin token with the given role, a new TOTP secret and a new signing secret. The token and the secrets are only shown in this response. The signed requests carry the ID of the token in X-QCS-Token-ID instead of the token, see REQUIRE_REQUEST_SIGNATURE in server.toml.
+// @Tags Tokens
+// @Accept json
+// @Produce json
+// @Param X-RunTime-Code header string false "Security code for admin access. Check path_to_qcs/configs/server.toml for more information."
+// @Param X-Access-Token header string false "Security token for admin access. The tokens are managed by /tokens and bootstrapped from path_to_qcs/configs/allowlist.toml."
+// @Param adminTokenInfo body model.AdminTokenInfo true "Admin name, role and expiration time"
+// @Success 200 {object} model.CreateAdminTokenResponse
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /tokens [post]
+func CreateAdminToken(ctx *gin.Context) {
+	tokenInfo := model.AdminTokenInfo{}
+	err := ctx.ShouldBindJSON(&tokenInfo)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Code: model.ErrCodeInvalidRequest, Error: "Invalid data format."})
+		utils.Record(logrus.ErrorLevel, err.Error())
+		return
+	}
+
+	if !cfg.IsValidRole(tokenInfo.Role) {
+		errMsg := fmt.Sprintf("The role [%s] is not valid (Require: viewer, support, issuer, owner).", tokenInfo.Role)
+		ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Code: model.ErrCodeInvalidRequest, Error: errMsg})
+		utils.Record(logrus.WarnLevel, errMsg)
+		return
+	}
+
+	if tokenInfo.ExpiresAt != nil && !tokenInfo.ExpiresAt.After(time.Now()) {
+		ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Code: model.ErrCodeInvalidRequest, Error: "The expiration time must be in the future."})
+		utils.Record(logrus.WarnLevel, fmt.Sprintf("Invalid expiration time [%s].", tokenInfo.ExpiresAt.Format(time.RFC3339)))
+		return
+	}
+
+	admin := ctx.GetString("admin")
+	record, token, signingSecret, err := adminTokenStore.CreateAdminToken(
+		ctx.Request.Context(), tokenInfo.Name, tokenInfo.Role, admin, tokenInfo.ExpiresAt,
+	)
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, model.ErrorResponse{Code: errorCode(err), Error: err.Error()})
+		utils.Record(logrus.ErrorLevel, err.Error())
+		return
+	}
+
+	ctx.JSON(
+		http.StatusOK,
+		model.CreateAdminTokenResponse{
+			Msg:           "Successfully created the admin token, it will not be shown again.",
+			Token:         token,
+			SigningSecret: signingSecret,
+			TOTPSecret:    record.TOTPSecret,
+			TOTPURI:       utils.TOTPURI(totpIssuer, record.Name, record.TOTPSecret),
+			Data:          record,
+		},
+	)
+	utils.Record(
+		logrus.InfoLevel,
+		fmt.Sprintf("Admin [%s] created the admin token [%s] for [%s] with role [%s].",
+			admin, record.ID, record.Name, record.Role),
+	)
+}
+
+// Revoke an admin token, only requests with valid tokens are allowed.
+//
+// @Summary Revoke an admin token
+// @Description Revoke an admin token, a revoked token can no longer be used.
+// @Tags Tokens
+// @Accept json
+// @Produce json
+// @Param X-RunTime-Code header string false "Security code for admin access. Check path_to_qcs/configs/server.toml for more information."
+// @Param X-Access-Token header string false "Security token for admin access. The tokens are managed by /tokens and bootstrapped from path_to_qcs/configs/allowlist.toml."
+// @Param id path string true "Admin token ID"
+// @Success 200 {object} model.UpdateAdminTokenResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /tokens/{id}/revoke [post]
+func RevokeAdminToken(ctx *gin.Context) {
+	tokenID := ctx.Param("id")
+	record, err := adminTokenStore.RevokeAdminToken(ctx.Request.Context(), tokenID)
+
+	if err != nil {
+		respondAdminTokenError(ctx, tokenID, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, model.UpdateAdminTokenResponse{Msg: "Successfully revoked the admin token.", Data: record})
+	utils.Record(
+		logrus.InfoLevel,
+		fmt.Sprintf("Admin [%s] revoked the admin token [%s].", ctx.GetString("admin"), tokenID),
+	)
+}
+
+// Set the expiration time of an admin token, only requests with valid tokens are allowed.
+//
+// @Summary Expire an admin token
+// @Description Set the expiration time of an admin token, the token expires immediately if no time is given.
+// @Tags Tokens
+// @Accept json
+// @Produce json
+// @Param X-RunTime-Code header string false "Security code for admin access. Check path_to_qcs/configs/server.toml for more information."
+// @Param X-Access-Token header string false "Security token for admin access. The tokens are managed by /tokens and bootstrapped from path_to_qcs/configs/allowlist.toml."
+// @Param id path string true "Admin token ID"
+// @Param adminTokenExpiration body model.AdminTokenExpiration false "Expiration time"
+// @Success 200 {object} model.UpdateAdminTokenResponse
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /tokens/{id}/expire [post]
+func ExpireAdminToken(ctx *gin.Context) {
+	tokenID := ctx.Param("id")
+	expiration := model.AdminTokenExpiration{}
+
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&expiration); err != nil {
+			ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Code: model.ErrCodeInvalidRequest, Error: "Invalid data format."})
+			utils.Record(logrus.ErrorLevel, err.Error())
+			return
+		}
+	}
+
+	expiresAt := time.Now()
+	if expiration.ExpiresAt != nil {
+		expiresAt = *expiration.ExpiresAt
+	}
+
+	record, err := adminTokenStore.ExpireAdminToken(ctx.Request.Context(), tokenID, expiresAt)
+
+	if err != nil {
+		respondAdminTokenError(ctx, tokenID, err)
+		return
+	}
+
+	ctx.JSON(
+		http.StatusOK,
+		model.UpdateAdminTokenResponse{Msg: "Successfully updated the expiration time of the admin token.", Data: record},
+	)
+	utils.Record(
+		logrus.InfoLevel,
+		fmt.Sprintf("Admin [%s] set the admin token [%s] to expire at [%s].",
+			ctx.GetString("admin"), tokenID, expiresAt.Format(time.RFC3339)),
+	)
+}
+
+// Reset the TOTP secret of an admin token, only requests with valid tokens are allowed.
+//
+// @Summary Reset the TOTP secret of an admin token
+// @Description Replace the TOTP secret of an admin token with a new one, which is required as the runtime code when RUNTIME_CODE_TYPE is "totp". The secret is only shown in this response.
+// @Tags Tokens
+// @Accept json
+// @Produce json
+// @Param X-RunTime-Code header string false "Security code for admin access. Check path_to_qcs/configs/server.toml for more information."
+// @Param X-Access-Token header string false "Security token for admin access. The tokens are managed by /tokens and bootstrapped from path_to_qcs/configs/allowlist.toml."
+// @Param id path string true "Admin token ID"
+// @Success 200 {object} model.ResetAdminTokenTOTPResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /tokens/{id}/totp [post]
+func ResetAdminTokenTOTP(ctx *gin.Context) {
+	tokenID := ctx.Param("id")
+	record, totpSecret, err := adminTokenStore.ResetAdminTokenTOTP(ctx.Request.Context(), tokenID)
+
+	if err != nil {
+		respondAdminTokenError(ctx, tokenID, err)
+		return
+	}
+
+	ctx.JSON(
+		http.StatusOK,
+		model.ResetAdminTokenTOTPResponse{
+			Msg:        "Successfully reset the TOTP secret of the admin token, it will not be shown again.",
+			TOTPSecret: totpSecret,
+			TOTPURI:    utils.TOTPURI(totpIssuer, record.Name, totpSecret),
+			Data:       record,
+		},
+	)
+	utils.Record(
+		logrus.InfoLevel,
+		fmt.Sprintf("Admin [%s] reset the TOTP secret of the admin token [%s].", ctx.GetString("admin"), tokenID),
+	)
+}
+
+func respondAdminTokenError(ctx *gin.Context, tokenID string, err error) {
+	if errors.Is(err, data.ErrAdminTokenNotFound) {
+		errMsg := fmt.Sprintf("The admin token [%s] does not exist.", tokenID)
+		ctx.JSON(http.StatusNotFound, model.ErrorResponse{Code: model.ErrCodeAdminTokenNotFound, Error: errMsg})
+		utils.Record(logrus.WarnLevel, errMsg)
+	} else {
+		ctx.JSON(http.StatusInternalServerError, model.ErrorResponse{Code: errorCode(err), Error: err.Error()})
+		utils.Record(logrus.ErrorLevel, err.Error())
+	}
+}
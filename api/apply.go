@@ -1,10 +1,11 @@
 package api
 
 import (
+	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"net/http"
-	"strings"
 
 	"github.com/mmq88/quickcerts/data"
 	"github.com/mmq88/quickcerts/model"
@@ -21,15 +22,17 @@ import (
 // Check the device info structure in model/device_info.go.
 //
 // @Summary Provide the client with a certificate(unique key and signature) for app.
-// @Description Provide the client with a certificate(unique key and signature) for app.
+// @Description Provide the client with a certificate(unique key and signature) for app. The S/N is bound to the device in one transaction, the status is "bound" for a new binding or "rebound" if the device has applied before.
 // @Tags Apply
 // @Accept json
 // @Produce json
-// @Param X-Access-Token header string false "Authorized token for client access. This value is set in path_to_qcs/configs/server.toml."
+// @Param X-Access-Token header string false "Client key for client access. The keys are managed by /client-keys and bootstrapped from CLIENT_AUTH_TOKEN in path_to_qcs/configs/server.toml."
 // @Param applyInfo body model.ApplyCertInfo true "Apply certificate information"
 // @Success 200 {object} model.ApplyCertResponse
 // @Failure 400 {object} model.ErrorResponse
 // @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
 // @Failure 500 {object} model.ErrorResponse
 // @Router /apply/cert [post]
 func ApplyCertificate(ctx *gin.Context) {
@@ -37,91 +40,84 @@ func ApplyCertificate(ctx *gin.Context) {
 	err := ctx.ShouldBindJSON(&applyInfo)
 
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid data format."})
+		ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Code: model.ErrCodeInvalidRequest, Error: "Invalid data format."})
 		utils.Record(logrus.ErrorLevel, err.Error())
 		return
 	}
 
-	// Check if the SN exists in the database(It's a legal S/N).
-	sn_is_exist, err := data.IsSNExist(applyInfo.SerialNumber)
+	// Generate a key for the device, the same device always gets the same key.
+	base := fmt.Sprintf("%s&%s&%s&%s&",
+		applyInfo.SerialNumber, applyInfo.BoardProducer, applyInfo.BoardName, applyInfo.MACAddress)
+	key, err := deviceKey(ctx.Request.Context(), base)
 
-	if err != nil && err.Error() != "the s/n does not exist" {
-		ctx.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, model.ErrorResponse{Code: model.ErrCodeInternal, Error: "Internal server error."})
+		utils.Record(logrus.ErrorLevel, err.Error())
 		return
 	}
 
-	if !sn_is_exist {
-		ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "The S/N does not exist."})
-		utils.Record(logrus.ErrorLevel, fmt.Sprintf("The S/N [%s] does not exist.", applyInfo.SerialNumber))
-		return
+	// Bind the S/N to the key in one transaction. Client keys may only apply the S/N(s) of their products.
+	activation := model.CertActivation{SerialNumber: applyInfo.SerialNumber, Key: key, IP: ctx.GetString("client_ip")}
+	var allowsProduct func(product string) bool
+
+	if clientKey, ok := ctx.Get("client_key"); ok {
+		activation.ClientKeyID = clientKey.(model.ClientKey).ID
+		allowsProduct = clientKey.(model.ClientKey).AllowsProduct
 	}
 
-	// S/N exists, generate a key and a sinature for the device and update it in the database.
-	base := fmt.Sprintf("%s&%s&%s&%s&",
-		applyInfo.SerialNumber, applyInfo.BoardProducer, applyInfo.BoardName, applyInfo.MACAddress)
-	key, err := data.GetDeviceKeyCache(base)
+	status, err := certStore.BindCertificate(ctx.Request.Context(), activation, allowsProduct)
 
-	// The key not exist in the cache.
 	if err != nil {
-		if err.Error() == "currently not connecting the redis database" {
-			ctx.JSON(
-				http.StatusInternalServerError,
-				model.ErrorResponse{Error: "Currently not connecting the redis database."})
-			utils.Record(logrus.ErrorLevel, "Currently not connecting the redis database.")
-			return
-		} else {
-			// The key not exist in the cache, generate a new key.
-			key, err = utils.GenerateKey(base)
+		ctx.JSON(http.StatusInternalServerError, model.ErrorResponse{Code: errorCode(err), Error: err.Error()})
+		utils.Record(logrus.ErrorLevel, err.Error())
+		return
+	}
 
-			if err != nil {
-				ctx.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Internal server error."})
-				utils.Record(logrus.ErrorLevel, err.Error())
-				return
-			}
-			data.SetDeviceKeyCache(base, key)
-		}
+	switch status {
+	case model.BindStatusNotFound:
+		ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Code: model.ErrCodeSNNotFound, Error: "The S/N does not exist."})
+		utils.Record(logrus.WarnLevel, fmt.Sprintf("The S/N [%s] does not exist.", applyInfo.SerialNumber))
+		return
+	case model.BindStatusNotAllowed:
+		ctx.JSON(http.StatusForbidden, model.ErrorResponse{Code: model.ErrCodeSNNotAllowed, Error: "The S/N is not available for this client key."})
+		utils.Record(
+			logrus.WarnLevel,
+			fmt.Sprintf("Client key [%s] is not allowed to apply the S/N [%s].", activation.ClientKeyID, applyInfo.SerialNumber),
+		)
+		return
+	case model.BindStatusTaken, model.BindStatusRevoked:
+		ctx.JSON(
+			http.StatusBadRequest,
+			model.ErrorResponse{Code: model.ErrCodeSNUnavailable, Error: "The S/N does not exist or has already been used."},
+		)
+		utils.Record(
+			logrus.WarnLevel,
+			fmt.Sprintf("The S/N [%s] is not available (%s).", applyInfo.SerialNumber, status),
+		)
+		return
 	}
 
+	// The S/N is bound to the key, resend both the key and signature if the device applied again.
 	signature, err := utils.SignMessage([]byte(key))
 
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Internal server error."})
+		ctx.JSON(http.StatusInternalServerError, model.ErrorResponse{Code: model.ErrCodeInternal, Error: "Internal server error."})
 		utils.Record(logrus.ErrorLevel, err.Error())
 		return
 	}
 
-	// Update the key corresponding to the SN in the database.
-	// If the verification confirms that the key is the same, resend both the key and signature.
-
-	if err := data.BindSNWithKey(applyInfo.SerialNumber, key); err != nil {
-		if err.Error() == "the s/n does not exist or has already been used" {
-			ctx.JSON(
-				http.StatusBadRequest,
-				model.ErrorResponse{Error: "The S/N does not exist or has already been used."},
-			)
-			utils.Record(
-				logrus.WarnLevel,
-				fmt.Sprintf("The S/N [%s] does not exist or has already been used.", applyInfo.SerialNumber),
-			)
-		} else {
-			ctx.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
-			utils.Record(logrus.ErrorLevel, err.Error())
-		}
-		return
-
-	}
-
 	signatureBase64 := base64.StdEncoding.EncodeToString(signature)
 
 	// Sent the certificate to the client.
 	ctx.JSON(
 		http.StatusOK,
 		model.ApplyCertResponse{
+			Status:    string(status),
 			Key:       key,
 			Signature: signatureBase64,
 		},
 	)
-	utils.Record(logrus.InfoLevel, fmt.Sprintf("Successfully updated and sent the key [%s].", key))
+	utils.Record(logrus.InfoLevel, fmt.Sprintf("Successfully %s and sent the key [%s].", status, key))
 }
 
 // Allow users to apply for temporary use permits on devices.
@@ -131,11 +127,13 @@ func ApplyCertificate(ctx *gin.Context) {
 // @Tags Apply
 // @Accept json
 // @Produce json
-// @Param X-Access-Token header string false "Authorized token for client access. This value is set in path_to_qcs/configs/server.toml."
+// @Param X-Access-Token header string false "Client key for client access. The keys are managed by /client-keys and bootstrapped from CLIENT_AUTH_TOKEN in path_to_qcs/configs/server.toml."
 // @Param applyInfo body model.ApplyTempPermitInfo true "Apply temporary permit information"
 // @Success 200 {object} model.ApplyTempPermitResponse
 // @Failure 400 {object} model.ErrorResponse
 // @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
 // @Failure 500 {object} model.ErrorResponse
 // @Router /apply/temp-permit [post]
 func ApplyTemporaryPermit(ctx *gin.Context) {
@@ -143,7 +141,7 @@ func ApplyTemporaryPermit(ctx *gin.Context) {
 	err := ctx.ShouldBindJSON(&applyInfo)
 
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid data format."})
+		ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Code: model.ErrCodeInvalidRequest, Error: "Invalid data format."})
 		utils.Record(logrus.ErrorLevel, err.Error())
 		return
 	}
@@ -152,40 +150,25 @@ func ApplyTemporaryPermit(ctx *gin.Context) {
 	base := fmt.Sprintf("%s&%s&%s&%s&",
 		"_", applyInfo.BoardProducer, applyInfo.BoardName, applyInfo.MACAddress)
 
-	key, err := data.GetDeviceKeyCache(base)
+	key, err := deviceKey(ctx.Request.Context(), base)
 
-	// The key not exist in the cache.
 	if err != nil {
-		if err.Error() == "currently not connecting the redis database" {
-			ctx.JSON(
-				http.StatusInternalServerError,
-				model.ErrorResponse{Error: "Currently not connecting the redis database."})
-			utils.Record(logrus.ErrorLevel, "Currently not connecting the redis database.")
-			return
-		} else {
-			// The key not exist in the cache, generate a new key.
-			key, err = utils.GenerateKey(base)
-
-			if err != nil {
-				ctx.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Internal server error."})
-				utils.Record(logrus.ErrorLevel, err.Error())
-				return
-			}
-			data.SetDeviceKeyCache(base, key)
-		}
+		ctx.JSON(http.StatusInternalServerError, model.ErrorResponse{Code: model.ErrCodeInternal, Error: "Internal server error."})
+		utils.Record(logrus.ErrorLevel, err.Error())
+		return
 	}
 
-	remainingTime, err := data.GetTemporaryPermitExpiredTime(key)
+	remainingTime, err := permitStore.GetTemporaryPermitExpiredTime(ctx.Request.Context(), key)
 
 	// The given key has not been used yet, or there is an internal server error.
 	if err != nil {
-		if strings.Contains(err.Error(), "allowed new key") {
+		if errors.Is(err, data.ErrPermitNotFound) {
 			// Add new key to temporary permit table.
 			utils.Record(logrus.InfoLevel, err.Error()) // Allowed new key: xxx
-			remainingTime, err = data.AddTemporaryPermit(key)
+			remainingTime, err = permitStore.AddTemporaryPermit(ctx.Request.Context(), key)
 
 			if err != nil {
-				ctx.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
+				ctx.JSON(http.StatusInternalServerError, model.ErrorResponse{Code: errorCode(err), Error: err.Error()})
 				utils.Record(logrus.ErrorLevel, err.Error())
 			} else {
 				ctx.JSON(http.StatusOK, gin.H{
@@ -200,7 +183,7 @@ func ApplyTemporaryPermit(ctx *gin.Context) {
 			}
 
 		} else {
-			ctx.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
+			ctx.JSON(http.StatusInternalServerError, model.ErrorResponse{Code: errorCode(err), Error: err.Error()})
 			utils.Record(logrus.ErrorLevel, err.Error())
 		}
 		return
@@ -221,7 +204,7 @@ func ApplyTemporaryPermit(ctx *gin.Context) {
 			fmt.Sprintf("Authorized [%s] temporary use of the product remaining [%d s].", key, remainingTime),
 		)
 	} else {
-		ctx.JSON(http.StatusOK, model.ErrorResponse{Error: "The authorization has expired."})
+		ctx.JSON(http.StatusOK, model.ErrorResponse{Code: model.ErrCodeTempPermitExpired, Error: "The authorization has expired."})
 		utils.Record(
 			logrus.InfoLevel,
 			fmt.Sprintf("The authorization for [%s] to use the product has expired.", key),
@@ -229,3 +212,32 @@ func ApplyTemporaryPermit(ctx *gin.Context) {
 	}
 
 }
+
+// Get the key of the device from the key cache, or generate it if it is not cached.
+//
+// The key cache only saves the time of generating the key, so the key is generated without it while it is
+// unavailable, e.g. the redis database is unreachable.
+func deviceKey(ctx context.Context, base string) (string, error) {
+	key, err := keyCache.GetDeviceKey(ctx, base)
+	if err == nil {
+		return key, nil
+	}
+
+	available := errors.Is(err, data.ErrCacheKeyNotFound)
+	if !available {
+		utils.Record(logrus.WarnLevel, "Skipped the key cache. Due to: "+err.Error())
+	}
+
+	key, err = utils.GenerateKey(base)
+	if err != nil {
+		return "", err
+	}
+
+	if available {
+		if err := keyCache.SetDeviceKey(ctx, base, key); err != nil {
+			utils.Record(logrus.WarnLevel, "Failed to cache the key. Due to: "+err.Error())
+		}
+	}
+
+	return key, nil
+}
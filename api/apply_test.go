@@ -2,10 +2,12 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
 	"time"
 
@@ -21,6 +23,8 @@ import (
 )
 
 func TestApplyCertificate(t *testing.T) {
+	ctx := context.Background()
+
 	backupDBHost := cfg.DB_CONFIG.HOST
 	backupDBPort := cfg.DB_CONFIG.PORT
 	backupRDBHost := cfg.CACHE_CONFIG.HOST
@@ -57,7 +61,7 @@ func TestApplyCertificate(t *testing.T) {
 	router.POST("/api/v1/apply/cert", ApplyCertificate)
 
 	testSN := "testSN"
-	err = data.AddNewSN(testSN)
+	err = data.AddNewSN(ctx, testSN, "")
 	assert.Nil(t, err)
 
 	applyInfo := model.ApplyCertInfo{
@@ -82,7 +86,8 @@ func TestApplyCertificate(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 	expectedKey := "5578c9d3cd718345af4319f3021157999b993f2e991481524234746f38b84c03"
 	assert.Equal(t, expectedKey, applyCertResponse.Key)
-	assert.Equal(t, fmt.Sprintf("Successfully updated and sent the key [%s].", expectedKey), utils.TestBuffer)
+	assert.Equal(t, "bound", applyCertResponse.Status)
+	assert.Equal(t, fmt.Sprintf("Successfully bound and sent the key [%s].", expectedKey), utils.TestBuffer)
 
 	// Test invalid case (Required fields are empty or not exist)
 	w = httptest.NewRecorder()
@@ -143,9 +148,10 @@ func TestApplyCertificate(t *testing.T) {
 	err = json.Unmarshal([]byte(res), &errorResponse)
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 	assert.Equal(t, "The S/N does not exist or has already been used.", errorResponse.Error)
-	assert.Equal(t, "The S/N [testSN] does not exist or has already been used.", utils.TestBuffer)
+	assert.Equal(t, model.ErrCodeSNUnavailable, errorResponse.Code)
+	assert.Equal(t, "The S/N [testSN] is not available (taken).", utils.TestBuffer)
 
-	// Test invalid case (Disconnect the redis database)
+	// Test valid case (Disconnect the redis database, the key is generated without the cache)
 	w = httptest.NewRecorder()
 	err = data.DisconnectRDB()
 	assert.Nil(t, err)
@@ -162,23 +168,20 @@ func TestApplyCertificate(t *testing.T) {
 	router.ServeHTTP(w, req)
 
 	res = w.Body.String()
-	err = json.Unmarshal([]byte(res), &errorResponse)
-	assert.Equal(t, http.StatusInternalServerError, w.Code)
-	assert.Equal(t, "Currently not connecting the redis database.", errorResponse.Error)
-	assert.Equal(t, "Currently not connecting the redis database.", utils.TestBuffer)
-	err = data.ConnectRDB()
+	err = json.Unmarshal([]byte(res), &applyCertResponse)
 	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, expectedKey, applyCertResponse.Key)
+	assert.Equal(t, "rebound", applyCertResponse.Status)
+	assert.Equal(t, fmt.Sprintf("Successfully rebound and sent the key [%s].", expectedKey), utils.TestBuffer)
 
-	// Test invalid case (Disconnect the redis database)
+	// Test invalid case (Use the same S/N with different device while the redis database is disconnected)
 	w = httptest.NewRecorder()
-	err = data.DisconnectRDB()
-	assert.Nil(t, err)
-
 	applyInfo = model.ApplyCertInfo{
 		SerialNumber:  testSN,
 		BoardProducer: "testBP",
 		BoardName:     "testBN",
-		MACAddress:    "testMAC",
+		MACAddress:    "testInvalidMAC",
 	}
 	jsonValue, _ = json.Marshal(applyInfo)
 	req, _ = http.NewRequest("POST", "/api/v1/apply/cert", bytes.NewBuffer(jsonValue))
@@ -187,20 +190,263 @@ func TestApplyCertificate(t *testing.T) {
 
 	res = w.Body.String()
 	err = json.Unmarshal([]byte(res), &errorResponse)
-	assert.Equal(t, http.StatusInternalServerError, w.Code)
-	assert.Equal(t, "Currently not connecting the redis database.", errorResponse.Error)
-	assert.Equal(t, "Currently not connecting the redis database.", utils.TestBuffer)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, model.ErrCodeSNUnavailable, errorResponse.Code)
 	err = data.ConnectRDB()
 	assert.Nil(t, err)
 
 	// Delete the testing data
-	err = data.DeleteTestingData("DELETE FROM certs WHERE sn = $1", testSN)
+	err = data.DeleteTestingData(ctx, "DELETE FROM certs WHERE sn = $1", testSN)
+	assert.Nil(t, err)
+	err = data.DeleteTestingData(ctx, "DELETE FROM cert_activations WHERE sn = $1", testSN)
 	assert.Nil(t, err)
-	err = data.DeleteTestingCache(testSN)
+	err = data.DeleteTestingCache(ctx, testSN)
 	assert.Nil(t, err)
 }
 
+func TestApplyCertificateRace(t *testing.T) {
+	ctx := context.Background()
+
+	backupDBHost := cfg.DB_CONFIG.HOST
+	backupDBPort := cfg.DB_CONFIG.PORT
+	backupRDBHost := cfg.CACHE_CONFIG.HOST
+	backupRDBPort := cfg.CACHE_CONFIG.PORT
+
+	defer func() {
+		cfg.DB_CONFIG.HOST = backupDBHost
+		cfg.DB_CONFIG.PORT = backupDBPort
+		cfg.CACHE_CONFIG.HOST = backupRDBHost
+		cfg.CACHE_CONFIG.PORT = backupRDBPort
+	}()
+
+	// Uses docker-compose config
+	cfg.DB_CONFIG.HOST = "localhost"
+	cfg.DB_CONFIG.PORT = 33332
+	err := data.ConnectDB()
+	assert.Nil(t, err)
+	cfg.CACHE_CONFIG.HOST = "localhost"
+	cfg.CACHE_CONFIG.PORT = 33334
+	err = data.ConnectRDB()
+	assert.Nil(t, err)
+
+	defer func() {
+		err = data.DisconnectDB()
+		assert.Nil(t, err)
+		err = data.DisconnectRDB()
+		assert.Nil(t, err)
+		utils.TestBuffer = ""
+	}()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/api/v1/apply/cert", ApplyCertificate)
+
+	testSN := "testRaceSN"
+	err = data.AddNewSN(ctx, testSN, "")
+	assert.Nil(t, err)
+
+	// Two devices race for one S/N, exactly one of them gets the certificate.
+	devices := []string{"testMAC1", "testMAC2"}
+	codes := make([]int, len(devices))
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+
+	for i, mac := range devices {
+		wg.Add(1)
+		go func(i int, mac string) {
+			defer wg.Done()
+
+			jsonValue, _ := json.Marshal(model.ApplyCertInfo{
+				SerialNumber:  testSN,
+				BoardProducer: "testBP",
+				BoardName:     "testBN",
+				MACAddress:    mac,
+			})
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api/v1/apply/cert", bytes.NewBuffer(jsonValue))
+			req.Header.Set("Content-Type", "application/json")
+
+			<-start
+			router.ServeHTTP(w, req)
+			codes[i] = w.Code
+		}(i, mac)
+	}
+
+	close(start)
+	wg.Wait()
+
+	assert.ElementsMatch(t, []int{http.StatusOK, http.StatusBadRequest}, codes)
+
+	activations, err := data.GetCertActivations(ctx, testSN)
+	assert.Nil(t, err)
+	assert.Len(t, activations, 2)
+
+	// Delete the testing data
+	err = data.DeleteTestingData(ctx, "DELETE FROM certs WHERE sn = $1", testSN)
+	assert.Nil(t, err)
+	err = data.DeleteTestingData(ctx, "DELETE FROM cert_activations WHERE sn = $1", testSN)
+	assert.Nil(t, err)
+}
+
+// Replace the stores of the handlers with the in-memory ones until the test ends.
+func useMemoryStores(t *testing.T) *data.MemoryStore {
+	store := data.NewMemoryStore()
+	UseStores(store, store, data.NewMemoryKeyCache())
+	UseAdminStores(store, store, store)
+	t.Cleanup(func() {
+		UseStores(data.PostgresStore{}, data.PostgresStore{}, data.RedisKeyCache{})
+		UseAdminStores(data.PostgresStore{}, data.PostgresStore{}, data.PostgresStore{})
+		utils.TestBuffer = ""
+	})
+
+	return store
+}
+
+func TestApplyCertificateWithMemoryStore(t *testing.T) {
+	ctx := context.Background()
+
+	store := useMemoryStores(t)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/api/v1/apply/cert", ApplyCertificate)
+
+	apply := func(sn string, mac string) (int, model.ApplyCertResponse, model.ErrorResponse) {
+		jsonValue, _ := json.Marshal(model.ApplyCertInfo{
+			SerialNumber:  sn,
+			BoardProducer: "testBP",
+			BoardName:     "testBN",
+			MACAddress:    mac,
+		})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/v1/apply/cert", bytes.NewBuffer(jsonValue))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+
+		var applyCertResponse model.ApplyCertResponse
+		var errorResponse model.ErrorResponse
+		json.Unmarshal(w.Body.Bytes(), &applyCertResponse)
+		json.Unmarshal(w.Body.Bytes(), &errorResponse)
+
+		return w.Code, applyCertResponse, errorResponse
+	}
+
+	testSN := "testSN"
+	err := store.AddNewSN(ctx, testSN, "")
+	assert.Nil(t, err)
+
+	// Test valid case
+	code, applyCertResponse, _ := apply(testSN, "testMAC")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "bound", applyCertResponse.Status)
+	assert.Equal(t, "5578c9d3cd718345af4319f3021157999b993f2e991481524234746f38b84c03", applyCertResponse.Key)
+
+	// Apply again with the same device should be ok
+	code, applyCertResponse, _ = apply(testSN, "testMAC")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "rebound", applyCertResponse.Status)
+
+	// Test invalid case (Use the same S/N with different device)
+	code, _, errorResponse := apply(testSN, "testInvalidMAC")
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, model.ErrCodeSNUnavailable, errorResponse.Code)
+
+	// Test invalid case (The S/N does not exist)
+	code, _, errorResponse = apply("none", "testMAC")
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, model.ErrCodeSNNotFound, errorResponse.Code)
+
+	// Two devices race for one S/N, exactly one of them gets the certificate.
+	raceSN := "testRaceSN"
+	err = store.AddNewSN(ctx, raceSN, "")
+	assert.Nil(t, err)
+
+	codes := make([]int, 2)
+	var wg sync.WaitGroup
+
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			codes[i], _, _ = apply(raceSN, fmt.Sprintf("testMAC%d", i))
+		}(i)
+	}
+
+	wg.Wait()
+	assert.ElementsMatch(t, []int{http.StatusOK, http.StatusBadRequest}, codes)
+
+	activations, err := store.GetCertActivations(ctx, testSN)
+	assert.Nil(t, err)
+	assert.Len(t, activations, 3)
+}
+
+func TestApplyCertificateWithoutKeyCache(t *testing.T) {
+	ctx := context.Background()
+
+	// The redis database is not connected, the key is generated without the cache.
+	store := useMemoryStores(t)
+	UseStores(store, store, data.RedisKeyCache{})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/api/v1/apply/cert", ApplyCertificate)
+
+	testSN := "testSN"
+	err := store.AddNewSN(ctx, testSN, "")
+	assert.Nil(t, err)
+
+	jsonValue, _ := json.Marshal(model.ApplyCertInfo{
+		SerialNumber:  testSN,
+		BoardProducer: "testBP",
+		BoardName:     "testBN",
+		MACAddress:    "testMAC",
+	})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/apply/cert", bytes.NewBuffer(jsonValue))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	var applyCertResponse model.ApplyCertResponse
+	err = json.Unmarshal(w.Body.Bytes(), &applyCertResponse)
+
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "bound", applyCertResponse.Status)
+	assert.Equal(t, "5578c9d3cd718345af4319f3021157999b993f2e991481524234746f38b84c03", applyCertResponse.Key)
+}
+
+func TestApplyTemporaryPermitWithMemoryStore(t *testing.T) {
+	useMemoryStores(t)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/api/v1/apply/temp-permit", ApplyTemporaryPermit)
+
+	jsonValue, _ := json.Marshal(model.ApplyTempPermitInfo{
+		BoardProducer: "testBP",
+		BoardName:     "testBN",
+		MACAddress:    "testMAC",
+	})
+
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/v1/apply/temp-permit", bytes.NewBuffer(jsonValue))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+
+		var applyTempPermitResponse model.ApplyTempPermitResponse
+		err := json.Unmarshal(w.Body.Bytes(), &applyTempPermitResponse)
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "activated", applyTempPermitResponse.Status)
+		assert.Greater(t, applyTempPermitResponse.RemainingTime, int64(0))
+	}
+}
+
 func TestApplyTemporaryPermit(t *testing.T) {
+	ctx := context.Background()
+
 	backupDBHost := cfg.DB_CONFIG.HOST
 	backupDBPort := cfg.DB_CONFIG.PORT
 	backupRDBHost := cfg.CACHE_CONFIG.HOST
@@ -333,7 +579,7 @@ func TestApplyTemporaryPermit(t *testing.T) {
 		utils.TestBuffer,
 	)
 
-	// Test invalid case (Disconnect the redis database)
+	// Test valid case (Disconnect the redis database, the key is generated without the cache)
 	w = httptest.NewRecorder()
 	err = data.DisconnectRDB()
 	assert.Nil(t, err)
@@ -350,16 +596,16 @@ func TestApplyTemporaryPermit(t *testing.T) {
 	router.ServeHTTP(w, req)
 
 	res = w.Body.String()
-	err = json.Unmarshal([]byte(res), &errorResponse)
-	assert.Equal(t, http.StatusInternalServerError, w.Code)
-	assert.Equal(t, "Currently not connecting the redis database.", errorResponse.Error)
-	assert.Equal(t, "Currently not connecting the redis database.", utils.TestBuffer)
+	err = json.Unmarshal([]byte(res), &applyTempPermitResponse)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "activated", applyTempPermitResponse.Status)
 	err = data.ConnectRDB()
 	assert.Nil(t, err)
 
 	// Delete the testing data
-	err = data.DeleteTestingData("DELETE FROM temporary_permits WHERE key = $1", expectedKey)
+	err = data.DeleteTestingData(ctx, "DELETE FROM temporary_permits WHERE key = $1", expectedKey)
 	assert.Nil(t, err)
-	err = data.DeleteTestingCache(expectedKey)
+	err = data.DeleteTestingCache(ctx, expectedKey)
 	assert.Nil(t, err)
 }
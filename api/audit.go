@@ -0,0 +1,113 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/mmq88/quickcerts/model"
+	"github.com/mmq88/quickcerts/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultAuditLogLimit = 100
+	maxAuditLogLimit     = 1000
+)
+
+// Query the audit logs of admin requests, only requests with valid tokens are allowed.
+//
+// @Summary Query the audit logs
+// @Description Query the audit logs of admin requests with optional filters, the newest first.
+// @Tags Audit
+// @Accept json
+// @Produce json
+// @Param X-RunTime-Code header string false "Security code for admin access. Check path_to_qcs/configs/server.toml for more information."
+// @Param X-Access-Token header string false "Security token for admin access. The tokens are managed by /tokens and bootstrapped from path_to_qcs/configs/allowlist.toml."
+// @Param admin query string false "Admin name"
+// @Param role query string false "Admin role"
+// @Param ip query string false "Client IP address"
+// @Param route query string false "Route pattern, e.g. /api/v1/batch/:id/revoke"
+// @Param status query int false "HTTP status code"
+// @Param from query string false "Start time (inclusive) in RFC3339, e.g. 2024-01-01T00:00:00+08:00"
+// @Param to query string false "End time (exclusive) in RFC3339, e.g. 2024-02-01T00:00:00+08:00"
+// @Param limit query int false "Max number of records, 100 by default and 1000 at most"
+// @Param offset query int false "Number of records to skip"
+// @Success 200 {object} model.GetAuditLogsResponse
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /audit [get]
+func GetAuditLogs(ctx *gin.Context) {
+	filter := model.AuditFilter{}
+
+	if err := ctx.ShouldBindQuery(&filter); err != nil {
+		ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Code: model.ErrCodeInvalidRequest, Error: "Invalid data format."})
+		utils.Record(logrus.ErrorLevel, err.Error())
+		return
+	}
+
+	if filter.Limit < 0 || filter.Limit > maxAuditLogLimit || filter.Offset < 0 {
+		errMsg := fmt.Sprintf("The limit must be between 1 and %d, and the offset must not be negative.", maxAuditLogLimit)
+		ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Code: model.ErrCodeInvalidRequest, Error: errMsg})
+		utils.Record(logrus.WarnLevel, fmt.Sprintf("Invalid limit/offset [%d/%d].", filter.Limit, filter.Offset))
+		return
+	}
+
+	if filter.Limit == 0 {
+		filter.Limit = defaultAuditLogLimit
+	}
+
+	logs, err := auditStore.GetAuditLogs(ctx.Request.Context(), filter)
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, model.ErrorResponse{Code: errorCode(err), Error: err.Error()})
+		utils.Record(logrus.ErrorLevel, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, model.GetAuditLogsResponse{Data: logs})
+}
+
+// Verify the hash chain of the audit logs, only requests with valid tokens are allowed.
+//
+// @Summary Verify the audit logs
+// @Description Verify the hash chain of the audit logs to detect modified, inserted or deleted records.
+// @Tags Audit
+// @Accept json
+// @Produce json
+// @Param X-RunTime-Code header string false "Security code for admin access. Check path_to_qcs/configs/server.toml for more information."
+// @Param X-Access-Token header string false "Security token for admin access. The tokens are managed by /tokens and bootstrapped from path_to_qcs/configs/allowlist.toml."
+// @Success 200 {object} model.VerifyAuditLogsResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /audit/verify [get]
+func VerifyAuditLogs(ctx *gin.Context) {
+	checked, brokenID, err := auditStore.VerifyAuditLogs(ctx.Request.Context())
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, model.ErrorResponse{Code: errorCode(err), Error: err.Error()})
+		utils.Record(logrus.ErrorLevel, err.Error())
+		return
+	}
+
+	if brokenID != 0 {
+		msg := fmt.Sprintf("The audit log chain is broken at the record [%d].", brokenID)
+		ctx.JSON(
+			http.StatusOK,
+			model.VerifyAuditLogsResponse{Msg: msg, Valid: false, Checked: checked, BrokenID: brokenID},
+		)
+		utils.Record(logrus.ErrorLevel, msg)
+		return
+	}
+
+	ctx.JSON(
+		http.StatusOK,
+		model.VerifyAuditLogsResponse{Msg: "The audit logs are intact.", Valid: true, Checked: checked},
+	)
+}
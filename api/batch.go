@@ -0,0 +1,157 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/mmq88/quickcerts/data"
+	"github.com/mmq88/quickcerts/model"
+	"github.com/mmq88/quickcerts/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// Get all S/N batches from the database.
+//
+// @Summary Get all S/N batches from the database
+// @Description Get all S/N batches with their reseller/order information and S/N statistics, the newest first.
+// @Tags Batch
+// @Accept json
+// @Produce json
+// @Param X-RunTime-Code header string false "Security code for admin access. Check path_to_qcs/configs/server.toml for more information."
+// @Param X-Access-Token header string false "Security token for admin access. The tokens are managed by /tokens and bootstrapped from path_to_qcs/configs/allowlist.toml."
+// @Success 200 {object} model.GetAllBatchesResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /batch/get-all [get]
+func GetAllBatches(ctx *gin.Context) {
+	batches, err := certStore.GetAllBatches(ctx.Request.Context())
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, model.ErrorResponse{Code: errorCode(err), Error: err.Error()})
+		utils.Record(logrus.ErrorLevel, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, model.GetAllBatchesResponse{Data: batches})
+}
+
+// Export the S/N(s) of a batch as a CSV file.
+//
+// @Summary Export the S/N(s) of a batch
+// @Description Export the S/N(s) of a batch as a CSV file with the columns serial_number, product, key, note, revoked_at and metadata.
+// @Tags Batch
+// @Produce text/csv
+// @Param X-RunTime-Code header string false "Security code for admin access. Check path_to_qcs/configs/server.toml for more information."
+// @Param X-Access-Token header string false "Security token for admin access. The tokens are managed by /tokens and bootstrapped from path_to_qcs/configs/allowlist.toml."
+// @Param id path string true "Batch ID"
+// @Success 200 {file} file
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /batch/{id}/export [get]
+func ExportBatch(ctx *gin.Context) {
+	batchID := ctx.Param("id")
+
+	if _, err := certStore.IsBatchExist(ctx.Request.Context(), batchID); err != nil {
+		if errors.Is(err, data.ErrBatchNotFound) {
+			errMsg := fmt.Sprintf("The batch [%s] does not exist.", batchID)
+			ctx.JSON(http.StatusNotFound, model.ErrorResponse{Code: model.ErrCodeBatchNotFound, Error: errMsg})
+			utils.Record(logrus.WarnLevel, errMsg)
+		} else {
+			ctx.JSON(http.StatusInternalServerError, model.ErrorResponse{Code: errorCode(err), Error: err.Error()})
+			utils.Record(logrus.ErrorLevel, err.Error())
+		}
+		return
+	}
+
+	ctx.Header("Content-Type", "text/csv")
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"batch-%s.csv\"", batchID))
+	ctx.Status(http.StatusOK)
+
+	w := csv.NewWriter(ctx.Writer)
+	w.Write([]string{"serial_number", "product", "key", "note", "revoked_at", "metadata"})
+
+	err := certStore.ForEachCertInBatch(ctx.Request.Context(), batchID, func(cert model.Cert) error {
+		revokedAt := ""
+		if cert.RevokedAt != nil {
+			revokedAt = cert.RevokedAt.Format(time.RFC3339)
+		}
+
+		metadata, err := json.Marshal(cert.Metadata)
+		if err != nil {
+			return err
+		}
+
+		return w.Write([]string{cert.SerialNumber, cert.Product, cert.Key, cert.Note, revokedAt, string(metadata)})
+	})
+
+	w.Flush()
+
+	// The status has been sent already, the error can only be logged.
+	if err == nil {
+		err = w.Error()
+	}
+
+	if err != nil {
+		utils.Record(logrus.ErrorLevel, fmt.Sprintf("Failed to export the batch [%s]. Due to: %s", batchID, err.Error()))
+		return
+	}
+
+	utils.Record(logrus.InfoLevel, fmt.Sprintf("Successfully exported the batch [%s].", batchID))
+}
+
+// Revoke all S/N(s) of a batch, revoked S/N(s) can no longer be bound to a device.
+//
+// @Summary Revoke all S/N(s) of a batch
+// @Description Revoke all S/N(s) of a batch. Revoked S/N(s) can no longer be bound to a device.
+// @Tags Batch
+// @Accept json
+// @Produce json
+// @Param X-RunTime-Code header string false "Security code for admin access. Check path_to_qcs/configs/server.toml for more information."
+// @Param X-Access-Token header string false "Security token for admin access. The tokens are managed by /tokens and bootstrapped from path_to_qcs/configs/allowlist.toml."
+// @Param id path string true "Batch ID"
+// @Success 200 {object} model.RevokeBatchResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /batch/{id}/revoke [post]
+func RevokeBatch(ctx *gin.Context) {
+	batchID := ctx.Param("id")
+	revoked, err := certStore.RevokeBatch(ctx.Request.Context(), batchID)
+
+	if err != nil {
+		if errors.Is(err, data.ErrBatchNotFound) {
+			errMsg := fmt.Sprintf("The batch [%s] does not exist.", batchID)
+			ctx.JSON(http.StatusNotFound, model.ErrorResponse{Code: model.ErrCodeBatchNotFound, Error: errMsg})
+			utils.Record(logrus.WarnLevel, errMsg)
+		} else {
+			ctx.JSON(http.StatusInternalServerError, model.ErrorResponse{Code: errorCode(err), Error: err.Error()})
+			utils.Record(logrus.ErrorLevel, err.Error())
+		}
+		return
+	}
+
+	ctx.JSON(
+		http.StatusOK,
+		model.RevokeBatchResponse{
+			Msg:     "Successfully revoked the S/N(s) of the batch.",
+			BatchID: batchID,
+			Revoked: revoked,
+		},
+	)
+	utils.Record(logrus.InfoLevel,
+		fmt.Sprintf("Admin [%s] revoked %d S/N(s) of the batch [%s].", ctx.GetString("admin"), revoked, batchID),
+	)
+}
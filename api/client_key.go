@@ -0,0 +1,150 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/mmq88/quickcerts/data"
+	"github.com/mmq88/quickcerts/model"
+	"github.com/mmq88/quickcerts/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// Get all client keys, only requests with valid tokens are allowed.
+//
+// @Summary Get all client keys
+// @Description Get all client keys with their scopes and usage, the newest first. The keys themselves are never returned.
+// @Tags ClientKeys
+// @Accept json
+// @Produce json
+// @Param X-RunTime-Code header string false "Security code for admin access. Check path_to_qcs/configs/server.toml for more information."
+// @Param X-Access-Token header string false "Security token for admin access. The tokens are managed by /tokens and bootstrapped from path_to_qcs/configs/allowlist.toml."
+// @Success 200 {object} model.GetAllClientKeysResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /client-keys [get]
+func GetAllClientKeys(ctx *gin.Context) {
+	keys, err := clientKeyStore.GetAllClientKeys(ctx.Request.Context())
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, model.ErrorResponse{Code: errorCode(err), Error: err.Error()})
+		utils.Record(logrus.ErrorLevel, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, model.GetAllClientKeysResponse{Data: keys})
+}
+
+// Create a new client key, only requests with valid tokens are allowed.
+//
+// @Summary Create a new client key
+// @Description Create a new client key scoped to the given products and apply endpoints. The key is only shown in this response.
+// @Tags ClientKeys
+// @Accept json
+// @Produce json
+// @Param X-RunTime-Code header string false "Security code for admin access. Check path_to_qcs/configs/server.toml for more information."
+// @Param X-Access-Token header string false "Security token for admin access. The tokens are managed by /tokens and bootstrapped from path_to_qcs/configs/allowlist.toml."
+// @Param clientKeyInfo body model.ClientKeyInfo true "Name, products, scopes and rate limit"
+// @Success 200 {object} model.CreateClientKeyResponse
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /client-keys [post]
+func CreateClientKey(ctx *gin.Context) {
+	keyInfo := model.ClientKeyInfo{}
+	err := ctx.ShouldBindJSON(&keyInfo)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Code: model.ErrCodeInvalidRequest, Error: "Invalid data format."})
+		utils.Record(logrus.ErrorLevel, err.Error())
+		return
+	}
+
+	for _, scope := range keyInfo.Scopes {
+		if !model.IsValidClientScope(scope) {
+			errMsg := fmt.Sprintf("The scope [%s] is not valid (Require: apply_cert, apply_temp_permit).", scope)
+			ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Code: model.ErrCodeInvalidRequest, Error: errMsg})
+			utils.Record(logrus.WarnLevel, errMsg)
+			return
+		}
+	}
+
+	for _, product := range keyInfo.Products {
+		if product == "" {
+			ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Code: model.ErrCodeInvalidRequest, Error: "The products must not be empty."})
+			utils.Record(logrus.WarnLevel, "Empty product in the client key products.")
+			return
+		}
+	}
+
+	admin := ctx.GetString("admin")
+	record, key, err := clientKeyStore.CreateClientKey(
+		ctx.Request.Context(), keyInfo.Name, keyInfo.Products, keyInfo.Scopes, keyInfo.RateLimit, admin,
+	)
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, model.ErrorResponse{Code: errorCode(err), Error: err.Error()})
+		utils.Record(logrus.ErrorLevel, err.Error())
+		return
+	}
+
+	ctx.JSON(
+		http.StatusOK,
+		model.CreateClientKeyResponse{
+			Msg:  "Successfully created the client key, it will not be shown again.",
+			Key:  key,
+			Data: record,
+		},
+	)
+	utils.Record(
+		logrus.InfoLevel,
+		fmt.Sprintf("Admin [%s] created the client key [%s] for [%s].", admin, record.ID, record.Name),
+	)
+}
+
+// Revoke a client key, only requests with valid tokens are allowed.
+//
+// @Summary Revoke a client key
+// @Description Revoke a client key, a revoked key can no longer be used.
+// @Tags ClientKeys
+// @Accept json
+// @Produce json
+// @Param X-RunTime-Code header string false "Security code for admin access. Check path_to_qcs/configs/server.toml for more information."
+// @Param X-Access-Token header string false "Security token for admin access. The tokens are managed by /tokens and bootstrapped from path_to_qcs/configs/allowlist.toml."
+// @Param id path string true "Client key ID"
+// @Success 200 {object} model.RevokeClientKeyResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /client-keys/{id}/revoke [post]
+func RevokeClientKey(ctx *gin.Context) {
+	keyID := ctx.Param("id")
+	record, err := clientKeyStore.RevokeClientKey(ctx.Request.Context(), keyID)
+
+	if err != nil {
+		if errors.Is(err, data.ErrClientKeyNotFound) {
+			errMsg := fmt.Sprintf("The client key [%s] does not exist.", keyID)
+			ctx.JSON(http.StatusNotFound, model.ErrorResponse{Code: model.ErrCodeClientKeyNotFound, Error: errMsg})
+			utils.Record(logrus.WarnLevel, errMsg)
+		} else {
+			ctx.JSON(http.StatusInternalServerError, model.ErrorResponse{Code: errorCode(err), Error: err.Error()})
+			utils.Record(logrus.ErrorLevel, err.Error())
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, model.RevokeClientKeyResponse{Msg: "Successfully revoked the client key.", Data: record})
+	utils.Record(
+		logrus.InfoLevel,
+		fmt.Sprintf("Admin [%s] revoked the client key [%s].", ctx.GetString("admin"), keyID),
+	)
+}
@@ -0,0 +1,21 @@
+package api
+
+import (
+	"errors"
+
+	"github.com/mmq88/quickcerts/data"
+	"github.com/mmq88/quickcerts/jobs"
+	"github.com/mmq88/quickcerts/model"
+)
+
+// Get the error code of the response for the given error, see model/error_code.go.
+func errorCode(err error) string {
+	switch {
+	case errors.Is(err, jobs.ErrQueueFull), errors.Is(err, jobs.ErrWorkersNotRunning):
+		return model.ErrCodeJobQueueUnavailable
+	case errors.Is(err, jobs.ErrJobFinished):
+		return model.ErrCodeJobAlreadyFinished
+	default:
+		return data.ErrorCode(err)
+	}
+}
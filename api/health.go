@@ -0,0 +1,84 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/mmq88/quickcerts/model"
+	"github.com/mmq88/quickcerts/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// A component checked by Readiness, check returns nil if the component is up.
+//
+// The server is still ready without the optional components, but reported as degraded.
+type readinessCheck struct {
+	name     string
+	optional bool
+	check    func(ctx context.Context) error
+}
+
+var readinessChecks = []readinessCheck{
+	{name: "signing_key", check: func(context.Context) error { return utils.CheckSigningKey() }},
+}
+
+// Add a component checked by Readiness, e.g. the databases chosen by DRIVER and their schema. It should be called before the server starts.
+func AddReadinessCheck(name string, optional bool, check func(ctx context.Context) error) {
+	readinessChecks = append(readinessChecks, readinessCheck{name: name, optional: optional, check: check})
+}
+
+// Check a component by the availability kept by its watcher, e.g. data.WatchDB, instead of pinging it on each probe.
+//
+// The check returns err while available reports false.
+func AvailabilityCheck(available func() bool, err error) func(ctx context.Context) error {
+	return func(context.Context) error {
+		if !available() {
+			return err
+		}
+
+		return nil
+	}
+}
+
+// Report that the server process is alive, for the liveness probes.
+//
+// It checks nothing else, so a server waiting for its databases is not restarted.
+func Liveness(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, model.LivenessResponse{Status: "alive"})
+}
+
+// Report if the server is ready to serve requests, for the readiness probes.
+//
+// Responds 200 if the server is ready or degraded, i.e. only the optional components are down, otherwise 503.
+// The status of each component is given in the response.
+func Readiness(ctx *gin.Context) {
+	response := model.ReadinessResponse{
+		Status:     model.ReadinessStatusReady,
+		Components: make(map[string]model.ComponentHealth, len(readinessChecks)),
+	}
+
+	for _, readiness := range readinessChecks {
+		health := model.ComponentHealth{Status: model.ComponentStatusUp, Optional: readiness.optional}
+
+		if err := readiness.check(ctx.Request.Context()); err != nil {
+			health.Status = model.ComponentStatusDown
+			health.Error = err.Error()
+
+			if !readiness.optional {
+				response.Status = model.ReadinessStatusNotReady
+			} else if response.Status == model.ReadinessStatusReady {
+				response.Status = model.ReadinessStatusDegraded
+			}
+		}
+
+		response.Components[readiness.name] = health
+	}
+
+	if response.Status == model.ReadinessStatusNotReady {
+		ctx.JSON(http.StatusServiceUnavailable, response)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mmq88/quickcerts/data"
+	"github.com/mmq88/quickcerts/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLiveness(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/healthz", Liveness)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/healthz", nil)
+	router.ServeHTTP(w, req)
+
+	var livenessResponse model.LivenessResponse
+	err := json.Unmarshal(w.Body.Bytes(), &livenessResponse)
+
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "alive", livenessResponse.Status)
+}
+
+func TestReadiness(t *testing.T) {
+	backupChecks := readinessChecks
+	defer func() {
+		readinessChecks = backupChecks
+	}()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/readyz", Readiness)
+
+	ready := func() (int, model.ReadinessResponse) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/readyz", nil)
+		router.ServeHTTP(w, req)
+
+		var readinessResponse model.ReadinessResponse
+		json.Unmarshal(w.Body.Bytes(), &readinessResponse)
+
+		return w.Code, readinessResponse
+	}
+
+	// Test invalid case (The databases are not connected)
+	AddReadinessCheck("postgres", false, AvailabilityCheck(data.DBAvailable, data.ErrDBNotConnected))
+	AddReadinessCheck("redis", true, AvailabilityCheck(data.RDBAvailable, data.ErrRDBNotConnected))
+
+	code, readinessResponse := ready()
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, model.ReadinessStatusNotReady, readinessResponse.Status)
+	assert.Equal(t, model.ComponentStatusDown, readinessResponse.Components["postgres"].Status)
+	assert.Equal(t, "currently not connecting the database", readinessResponse.Components["postgres"].Error)
+	assert.Equal(t, model.ComponentStatusDown, readinessResponse.Components["redis"].Status)
+	assert.Equal(t, "currently not connecting the redis database", readinessResponse.Components["redis"].Error)
+	assert.True(t, readinessResponse.Components["redis"].Optional)
+
+	// Test valid case
+	up := func(context.Context) error { return nil }
+	down := func(context.Context) error { return errors.New("connection refused") }
+
+	readinessChecks = nil
+	AddReadinessCheck("postgres", false, up)
+	AddReadinessCheck("redis", true, up)
+
+	code, readinessResponse = ready()
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, model.ReadinessStatusReady, readinessResponse.Status)
+	assert.Equal(t, model.ComponentStatusUp, readinessResponse.Components["redis"].Status)
+
+	// The server is degraded without the optional components.
+	readinessChecks[1].check = down
+
+	code, readinessResponse = ready()
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, model.ReadinessStatusDegraded, readinessResponse.Status)
+	assert.Equal(t, "connection refused", readinessResponse.Components["redis"].Error)
+
+	// Test invalid case (A required component is down)
+	AddReadinessCheck("migrations", false, down)
+
+	code, readinessResponse = ready()
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, model.ReadinessStatusNotReady, readinessResponse.Status)
+	assert.Equal(t, model.ComponentStatusUp, readinessResponse.Components["postgres"].Status)
+	assert.Equal(t, model.ComponentStatusDown, readinessResponse.Components["migrations"].Status)
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+
+	"github.com/mmq88/quickcerts/data"
+	"github.com/mmq88/quickcerts/jobs"
+	"github.com/mmq88/quickcerts/model"
+	"github.com/mmq88/quickcerts/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// Get the status and progress of an admin job, only requests with valid tokens are allowed.
+//
+// @Summary Get the status and progress of an admin job
+// @Description Get the status and progress of an admin job, e.g. a background S/N generation.
+// @Tags Jobs
+// @Accept json
+// @Produce json
+// @Param X-RunTime-Code header string false "Security code for admin access. Check path_to_qcs/configs/server.toml for more information."
+// @Param X-Access-Token header string false "Security token for admin access. The tokens are managed by /tokens and bootstrapped from path_to_qcs/configs/allowlist.toml."
+// @Param id path string true "Job ID"
+// @Success 200 {object} model.Job
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /jobs/{id} [get]
+func GetJob(ctx *gin.Context) {
+	jobID := ctx.Param("id")
+	job, err := jobs.Get(ctx.Request.Context(), jobID)
+
+	if err != nil {
+		if errors.Is(err, data.ErrJobNotFound) {
+			errMsg := fmt.Sprintf("The job [%s] does not exist.", jobID)
+			ctx.JSON(http.StatusNotFound, model.ErrorResponse{Code: model.ErrCodeJobNotFound, Error: errMsg})
+			utils.Record(logrus.WarnLevel, errMsg)
+		} else {
+			ctx.JSON(http.StatusInternalServerError, model.ErrorResponse{Code: errorCode(err), Error: err.Error()})
+			utils.Record(logrus.ErrorLevel, err.Error())
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, job)
+}
+
+// Cancel a queued or running admin job, only requests with valid tokens are allowed.
+//
+// @Summary Cancel an admin job
+// @Description Cancel a queued or running admin job. The changes made by a cancelled job are rolled back.
+// @Tags Jobs
+// @Accept json
+// @Produce json
+// @Param X-RunTime-Code header string false "Security code for admin access. Check path_to_qcs/configs/server.toml for more information."
+// @Param X-Access-Token header string false "Security token for admin access. The tokens are managed by /tokens and bootstrapped from path_to_qcs/configs/allowlist.toml."
+// @Param id path string true "Job ID"
+// @Success 200 {object} model.CancelJobResponse
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /jobs/{id}/cancel [post]
+func CancelJob(ctx *gin.Context) {
+	jobID := ctx.Param("id")
+
+	if err := jobs.Cancel(ctx.Request.Context(), jobID); err != nil {
+		switch {
+		case errors.Is(err, data.ErrJobNotFound):
+			errMsg := fmt.Sprintf("The job [%s] does not exist.", jobID)
+			ctx.JSON(http.StatusNotFound, model.ErrorResponse{Code: model.ErrCodeJobNotFound, Error: errMsg})
+			utils.Record(logrus.WarnLevel, errMsg)
+		case errors.Is(err, jobs.ErrJobFinished):
+			errMsg := fmt.Sprintf("The job [%s] has already finished.", jobID)
+			ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Code: model.ErrCodeJobAlreadyFinished, Error: errMsg})
+			utils.Record(logrus.WarnLevel, errMsg)
+		default:
+			ctx.JSON(http.StatusInternalServerError, model.ErrorResponse{Code: errorCode(err), Error: err.Error()})
+			utils.Record(logrus.ErrorLevel, err.Error())
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, model.CancelJobResponse{Msg: "Successfully cancelled the job.", JobID: jobID})
+	utils.Record(logrus.InfoLevel, fmt.Sprintf("Successfully cancelled the job [%s].", jobID))
+}
+
+// Download the output of a succeeded admin job, only requests with valid tokens are allowed.
+//
+// @Summary Download the output of an admin job
+// @Description Download the output of a succeeded admin job, e.g. the S/N(s) created by a background generation.
+// @Tags Jobs
+// @Produce octet-stream
+// @Param X-RunTime-Code header string false "Security code for admin access. Check path_to_qcs/configs/server.toml for more information."
+// @Param X-Access-Token header string false "Security token for admin access. The tokens are managed by /tokens and bootstrapped from path_to_qcs/configs/allowlist.toml."
+// @Param id path string true "Job ID"
+// @Success 200 {file} file
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /jobs/{id}/result [get]
+func GetJobResult(ctx *gin.Context) {
+	jobID := ctx.Param("id")
+	job, err := jobs.Get(ctx.Request.Context(), jobID)
+
+	if err != nil {
+		if errors.Is(err, data.ErrJobNotFound) {
+			errMsg := fmt.Sprintf("The job [%s] does not exist.", jobID)
+			ctx.JSON(http.StatusNotFound, model.ErrorResponse{Code: model.ErrCodeJobNotFound, Error: errMsg})
+			utils.Record(logrus.WarnLevel, errMsg)
+		} else {
+			ctx.JSON(http.StatusInternalServerError, model.ErrorResponse{Code: errorCode(err), Error: err.Error()})
+			utils.Record(logrus.ErrorLevel, err.Error())
+		}
+		return
+	}
+
+	if job.Status != model.JobStatusSucceeded || job.Result == "" {
+		errMsg := fmt.Sprintf("The job [%s] has no result.", jobID)
+		ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Code: model.ErrCodeInvalidRequest, Error: errMsg})
+		utils.Record(logrus.WarnLevel, errMsg)
+		return
+	}
+
+	ctx.FileAttachment(job.Result, filepath.Base(job.Result))
+}
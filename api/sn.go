@@ -1,10 +1,15 @@
 package api
 
 import (
+	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 
+	cfg "github.com/mmq88/quickcerts/configs"
 	"github.com/mmq88/quickcerts/data"
+	"github.com/mmq88/quickcerts/jobs"
 	"github.com/mmq88/quickcerts/model"
 	"github.com/mmq88/quickcerts/utils"
 
@@ -20,11 +25,13 @@ import (
 // @Accept json
 // @Produce json
 // @Param X-RunTime-Code header string false "Security code for admin access. Check path_to_qcs/configs/server.toml for more information."
-// @Param X-Access-Token header string false "Security token for admin access. This value is set in path_to_qcs/configs/allowlist.toml."
+// @Param X-Access-Token header string false "Security token for admin access. The tokens are managed by /tokens and bootstrapped from path_to_qcs/configs/allowlist.toml."
 // @Param snInfo body model.SNInfo true "Serial number information"
 // @Success 200 {object} model.CreateSNResponse
 // @Failure 400 {object} model.ErrorResponse
 // @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
 // @Failure 500 {object} model.ErrorResponse
 // @Router /sn/create [post]
 func CreateSN(ctx *gin.Context) {
@@ -32,17 +39,17 @@ func CreateSN(ctx *gin.Context) {
 	err := ctx.ShouldBindJSON(&creationInfo)
 
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid data format."})
+		ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Code: model.ErrCodeInvalidRequest, Error: "Invalid data format."})
 		utils.Record(logrus.ErrorLevel, err.Error())
 		return
 	}
 
-	if err := data.AddNewSN(creationInfo.SerialNumber); err != nil {
-		if err.Error() == "the s/n already exists" {
-			ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "The S/N already exists."})
+	if err := certStore.AddNewSN(ctx.Request.Context(), creationInfo.SerialNumber, creationInfo.Product); err != nil {
+		if errors.Is(err, data.ErrSNAlreadyExists) {
+			ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Code: model.ErrCodeSNAlreadyExists, Error: "The S/N already exists."})
 			utils.Record(logrus.WarnLevel, fmt.Sprintf("The S/N [%s] already exists.", creationInfo.SerialNumber))
 		} else {
-			ctx.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
+			ctx.JSON(http.StatusInternalServerError, model.ErrorResponse{Code: errorCode(err), Error: err.Error()})
 			utils.Record(logrus.ErrorLevel, err.Error())
 		}
 	} else {
@@ -61,16 +68,19 @@ func CreateSN(ctx *gin.Context) {
 // Generate serial number(s) to the database, only requests with valid tokens are allowed.
 //
 // @Summary Generate serial number(s) to the database
-// @Description Generate serial number(s) by providing the count and the reason. only requests with valid tokens are allowed.
+// @Description Generate serial number(s) by providing the count and the reason. only requests with valid tokens are allowed. Large counts are generated by a background job, and the job ID is returned instead (see /jobs/{id}).
 // @Tags SN
 // @Accept json
 // @Produce json
 // @Param X-RunTime-Code header string false "Security code for admin access. Check path_to_qcs/configs/server.toml for more information."
-// @Param X-Access-Token header string false "Security token for admin access. This value is set in path_to_qcs/configs/allowlist.toml."
+// @Param X-Access-Token header string false "Security token for admin access. The tokens are managed by /tokens and bootstrapped from path_to_qcs/configs/allowlist.toml."
 // @Param snInfo body model.SNsInfo true "Serial number(s) information"
-// @Success 200 {object} model.CreateSNResponse
+// @Success 200 {object} model.GenerateSNResponse
+// @Success 202 {object} model.GenerateSNJobResponse
 // @Failure 400 {object} model.ErrorResponse
 // @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
 // @Failure 500 {object} model.ErrorResponse
 // @Router /sn/generate [post]
 func GenerateSN(ctx *gin.Context) {
@@ -78,29 +88,75 @@ func GenerateSN(ctx *gin.Context) {
 	err := ctx.ShouldBindJSON(&generateSNInfo)
 
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid data format."})
+		ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Code: model.ErrCodeInvalidRequest, Error: "Invalid data format."})
 		utils.Record(logrus.ErrorLevel, err.Error())
 		return
 	}
 
-	snList := []string{}
-
 	if generateSNInfo.Count <= 0 {
-		ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "The count must be greater than 0."})
+		ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Code: model.ErrCodeInvalidRequest, Error: "The count must be greater than 0."})
 		utils.Record(logrus.WarnLevel, fmt.Sprintf("Invalid count(<=0) [%d].", generateSNInfo.Count))
 		return
 	}
 
-	for i := 0; i < generateSNInfo.Count; i++ {
-		sn, _ := utils.GenerateSN()
-		snList = append(snList, sn)
+	if generateSNInfo.Count > cfg.SERVER_CONFIG.SN_GENERATE_MAX_COUNT {
+		errMsg := fmt.Sprintf("The count must not be greater than %d.", cfg.SERVER_CONFIG.SN_GENERATE_MAX_COUNT)
+		ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Code: model.ErrCodeInvalidRequest, Error: errMsg})
+		utils.Record(logrus.WarnLevel, fmt.Sprintf("Invalid count(>max) [%d].", generateSNInfo.Count))
+		return
+	}
+
+	// The generated S/N(s) are recorded as a batch.
+	batchID, err := utils.GenerateID()
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, model.ErrorResponse{Code: model.ErrCodeInternal, Error: "Internal server error."})
+		utils.Record(logrus.ErrorLevel, err.Error())
+		return
+	}
+
+	batch := model.Batch{
+		ID:          batchID,
+		Reason:      generateSNInfo.Reason,
+		CreatedBy:   ctx.GetString("admin"),
+		Reseller:    generateSNInfo.Reseller,
+		OrderNumber: generateSNInfo.OrderNumber,
+		Product:     generateSNInfo.Product,
+	}
+
+	// Large generations run in the background, the client polls the progress with the job ID.
+	if generateSNInfo.Count > cfg.SERVER_CONFIG.SN_GENERATE_ASYNC_COUNT {
+		jobID, err := jobs.Submit(
+			ctx.Request.Context(), "generate_sn", generateSNInfo.Count, batch.CreatedBy,
+			generateSNTask(batch, generateSNInfo.Count),
+		)
+
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, model.ErrorResponse{Code: errorCode(err), Error: err.Error()})
+			utils.Record(logrus.ErrorLevel, err.Error())
+			return
+		}
+
+		ctx.JSON(
+			http.StatusAccepted,
+			model.GenerateSNJobResponse{
+				Msg:     "The generation is running in the background.",
+				BatchID: batchID,
+				JobID:   jobID,
+			},
+		)
+		utils.Record(logrus.InfoLevel,
+			fmt.Sprintf("Submitted job [%s] to generate new S/N (%d) of batch [%s] with reason (%s).",
+				jobID, generateSNInfo.Count, batchID, generateSNInfo.Reason),
+		)
+		return
 	}
 
-	// Insert snList into database.
-	err = data.AddNewSNs(snList)
+	// Insert the generated S/N(s) into database.
+	snList, err := certStore.AddGeneratedSNs(ctx.Request.Context(), batch, generateSNInfo.Count, utils.GenerateSN, nil)
 
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
+		ctx.JSON(http.StatusInternalServerError, model.ErrorResponse{Code: errorCode(err), Error: err.Error()})
 		utils.Record(logrus.ErrorLevel, err.Error())
 	} else {
 		msg := fmt.Sprintf("Successfully uploaded new S/N (%d) with reason (%s).",
@@ -110,7 +166,7 @@ func GenerateSN(ctx *gin.Context) {
 			utils.Record(logrus.InfoLevel, fmt.Sprintf("[%s]", sn))
 		}
 
-		ctx.JSON(http.StatusOK, model.GenerateSNResponse{Msg: msg, SerialNumbers: snList})
+		ctx.JSON(http.StatusOK, model.GenerateSNResponse{Msg: msg, BatchID: batchID, SerialNumbers: snList})
 	}
 }
 
@@ -122,11 +178,13 @@ func GenerateSN(ctx *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param X-RunTime-Code header string false "Security code for admin access. Check path_to_qcs/configs/server.toml for more information."
-// @Param X-Access-Token header string false "Security token for admin access. This value is set in path_to_qcs/configs/allowlist.toml."
+// @Param X-Access-Token header string false "Security token for admin access. The tokens are managed by /tokens and bootstrapped from path_to_qcs/configs/allowlist.toml."
 // @Param certNote body model.CertNote true "Serial number and note"
 // @Success 200 {object} model.UpdateCertNoteResponse
 // @Failure 400 {object} model.ErrorResponse
 // @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
 // @Failure 500 {object} model.ErrorResponse
 // @Router /sn/update [post]
 func UpdateCertNote(ctx *gin.Context) {
@@ -134,18 +192,18 @@ func UpdateCertNote(ctx *gin.Context) {
 	err := ctx.ShouldBindJSON(&updateInfo)
 
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid data format."})
+		ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Code: model.ErrCodeInvalidRequest, Error: "Invalid data format."})
 		utils.Record(logrus.ErrorLevel, err.Error())
 		return
 	}
 
-	if err := data.UpdateCertNote(updateInfo.SerialNumber, updateInfo.Note); err != nil {
-		if err.Error() == "the s/n does not exist" {
+	if err := certStore.UpdateCertNote(ctx.Request.Context(), updateInfo.SerialNumber, updateInfo.Note); err != nil {
+		if errors.Is(err, data.ErrSNNotFound) {
 			errMsg := fmt.Sprintf("The S/N [%s] does not exist.", updateInfo.SerialNumber)
-			ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Error: errMsg})
+			ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Code: model.ErrCodeSNNotFound, Error: errMsg})
 			utils.Record(logrus.WarnLevel, errMsg)
 		} else {
-			ctx.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
+			ctx.JSON(http.StatusInternalServerError, model.ErrorResponse{Code: errorCode(err), Error: err.Error()})
 			utils.Record(logrus.ErrorLevel, err.Error())
 		}
 
@@ -164,6 +222,186 @@ func UpdateCertNote(ctx *gin.Context) {
 	}
 }
 
+// Merge the given metadata into the metadata of a serial number, only requests with valid tokens are allowed.
+//
+// @Summary Update the metadata of a serial number
+// @Description Merge the given metadata into the current metadata of a serial number. The given keys overwrite the existing ones, and the keys with null values are removed.
+// @Tags SN
+// @Accept json
+// @Produce json
+// @Param X-RunTime-Code header string false "Security code for admin access. Check path_to_qcs/configs/server.toml for more information."
+// @Param X-Access-Token header string false "Security token for admin access. The tokens are managed by /tokens and bootstrapped from path_to_qcs/configs/allowlist.toml."
+// @Param certMetadata body model.CertMetadataPatch true "Serial number and metadata patch"
+// @Success 200 {object} model.UpdateCertMetadataResponse
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /sn/metadata [post]
+func UpdateCertMetadata(ctx *gin.Context) {
+	patchInfo := model.CertMetadataPatch{}
+	err := ctx.ShouldBindJSON(&patchInfo)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Code: model.ErrCodeInvalidRequest, Error: "Invalid data format."})
+		utils.Record(logrus.ErrorLevel, err.Error())
+		return
+	}
+
+	metadata, err := certStore.PatchCertMetadata(ctx.Request.Context(), patchInfo.SerialNumber, patchInfo.Metadata)
+
+	if err != nil {
+		if errors.Is(err, data.ErrSNNotFound) {
+			errMsg := fmt.Sprintf("The S/N [%s] does not exist.", patchInfo.SerialNumber)
+			ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Code: model.ErrCodeSNNotFound, Error: errMsg})
+			utils.Record(logrus.WarnLevel, errMsg)
+		} else {
+			ctx.JSON(http.StatusInternalServerError, model.ErrorResponse{Code: errorCode(err), Error: err.Error()})
+			utils.Record(logrus.ErrorLevel, err.Error())
+		}
+		return
+	}
+
+	ctx.JSON(
+		http.StatusOK,
+		model.UpdateCertMetadataResponse{
+			Msg:          "Successfully updated the metadata of specified S/N.",
+			SerialNumber: patchInfo.SerialNumber,
+			Metadata:     metadata,
+		},
+	)
+	utils.Record(
+		logrus.InfoLevel,
+		fmt.Sprintf("Successfully updated the metadata of the S/N [%s].", patchInfo.SerialNumber),
+	)
+}
+
+// Search the certificate records by their metadata, only requests with valid tokens are allowed.
+//
+// @Summary Search the certificate records by metadata
+// @Description Get the certificate records whose metadata contains all of the given keys and values.
+// @Tags SN
+// @Accept json
+// @Produce json
+// @Param X-RunTime-Code header string false "Security code for admin access. Check path_to_qcs/configs/server.toml for more information."
+// @Param X-Access-Token header string false "Security token for admin access. The tokens are managed by /tokens and bootstrapped from path_to_qcs/configs/allowlist.toml."
+// @Param metadataQuery body model.CertMetadataQuery true "Metadata keys and values to match"
+// @Success 200 {object} model.SearchCertsResponse
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /sn/search [post]
+func SearchCerts(ctx *gin.Context) {
+	query := model.CertMetadataQuery{}
+	err := ctx.ShouldBindJSON(&query)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Code: model.ErrCodeInvalidRequest, Error: "Invalid data format."})
+		utils.Record(logrus.ErrorLevel, err.Error())
+		return
+	}
+
+	certList, err := certStore.FindCertsByMetadata(ctx.Request.Context(), query.Metadata)
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, model.ErrorResponse{Code: errorCode(err), Error: err.Error()})
+		utils.Record(logrus.ErrorLevel, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, model.SearchCertsResponse{Data: certList})
+}
+
+// Delete a serial number and move its record into the archive, only requests with valid tokens are allowed.
+//
+// @Summary Delete a serial number
+// @Description Delete a serial number and move its record into the archive. A serial number which has been bound to a device is refused unless force is true.
+// @Tags SN
+// @Accept json
+// @Produce json
+// @Param X-RunTime-Code header string false "Security code for admin access. Check path_to_qcs/configs/server.toml for more information."
+// @Param X-Access-Token header string false "Security token for admin access. The tokens are managed by /tokens and bootstrapped from path_to_qcs/configs/allowlist.toml."
+// @Param snDeleteInfo body model.SNDeleteInfo true "Serial number, reason and whether to force the deletion"
+// @Success 200 {object} model.DeleteSNResponse
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /sn/delete [post]
+func DeleteSN(ctx *gin.Context) {
+	deleteInfo := model.SNDeleteInfo{}
+	err := ctx.ShouldBindJSON(&deleteInfo)
+
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Code: model.ErrCodeInvalidRequest, Error: "Invalid data format."})
+		utils.Record(logrus.ErrorLevel, err.Error())
+		return
+	}
+
+	admin := ctx.GetString("admin")
+	err = certStore.ArchiveSN(ctx.Request.Context(), deleteInfo.SerialNumber, deleteInfo.Reason, admin, deleteInfo.Force)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrSNNotFound):
+			errMsg := fmt.Sprintf("The S/N [%s] does not exist.", deleteInfo.SerialNumber)
+			ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Code: model.ErrCodeSNNotFound, Error: errMsg})
+			utils.Record(logrus.WarnLevel, errMsg)
+		case errors.Is(err, data.ErrSNAlreadyBound):
+			errMsg := fmt.Sprintf(
+				"The S/N [%s] has been bound to a device, set force to delete it anyway.", deleteInfo.SerialNumber,
+			)
+			ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Code: model.ErrCodeSNAlreadyBound, Error: errMsg})
+			utils.Record(logrus.WarnLevel, errMsg)
+		default:
+			ctx.JSON(http.StatusInternalServerError, model.ErrorResponse{Code: errorCode(err), Error: err.Error()})
+			utils.Record(logrus.ErrorLevel, err.Error())
+		}
+		return
+	}
+
+	ctx.JSON(
+		http.StatusOK,
+		model.DeleteSNResponse{Msg: "Successfully deleted the S/N.", SerialNumber: deleteInfo.SerialNumber},
+	)
+	utils.Record(
+		logrus.InfoLevel,
+		fmt.Sprintf("Admin [%s] deleted the S/N [%s] (force: %t, reason: %s).",
+			admin, deleteInfo.SerialNumber, deleteInfo.Force, deleteInfo.Reason),
+	)
+}
+
+// Get the archived cert list from the database.
+//
+// @Summary Get the archived cert list from the database
+// @Description Get the records of the deleted serial numbers, the newest first.
+// @Tags SN
+// @Accept json
+// @Produce json
+// @Param X-RunTime-Code header string false "Security code for admin access. Check path_to_qcs/configs/server.toml for more information."
+// @Param X-Access-Token header string false "Security token for admin access. The tokens are managed by /tokens and bootstrapped from path_to_qcs/configs/allowlist.toml."
+// @Success 200 {object} model.GetArchivedCertsResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Failure 500 {object} model.ErrorResponse
+// @Router /sn/get-archived [get]
+func GetArchivedRecords(ctx *gin.Context) {
+	certList, err := certStore.GetArchivedCerts(ctx.Request.Context())
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, model.ErrorResponse{Code: errorCode(err), Error: err.Error()})
+		utils.Record(logrus.ErrorLevel, err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, model.GetArchivedCertsResponse{Data: certList})
+}
+
 // Get cert list from the database.
 //
 // @Summary Get cert list from the database
@@ -172,17 +410,19 @@ func UpdateCertNote(ctx *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param X-RunTime-Code header string false "Security code for admin access. Check path_to_qcs/configs/server.toml for more information."
-// @Param X-Access-Token header string false "Security token for admin access. This value is set in path_to_qcs/configs/allowlist.toml."
+// @Param X-Access-Token header string false "Security token for admin access. The tokens are managed by /tokens and bootstrapped from path_to_qcs/configs/allowlist.toml."
 // @Success 200 {object} model.GetAllRecordsResponse
 // @Failure 400 {object} model.ErrorResponse
 // @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
 // @Failure 500 {object} model.ErrorResponse
 // @Router /sn/get-all [get]
 func GetAllRecords(ctx *gin.Context) {
-	certList, err := data.GetAllCerts()
+	certList, err := certStore.GetAllCerts(ctx.Request.Context())
 
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
+		ctx.JSON(http.StatusInternalServerError, model.ErrorResponse{Code: errorCode(err), Error: err.Error()})
 		utils.Record(logrus.ErrorLevel, err.Error())
 		return
 	}
@@ -198,20 +438,54 @@ func GetAllRecords(ctx *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param X-RunTime-Code header string false "Security code for admin access. Check path_to_qcs/configs/server.toml for more information."
-// @Param X-Access-Token header string false "Security token for admin access. This value is set in path_to_qcs/configs/allowlist.toml."
+// @Param X-Access-Token header string false "Security token for admin access. The tokens are managed by /tokens and bootstrapped from path_to_qcs/configs/allowlist.toml."
 // @Success 200 {object} model.GetAvaliableSNResponse
 // @Failure 400 {object} model.ErrorResponse
 // @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
 // @Failure 500 {object} model.ErrorResponse
 // @Router /sn/get-available [get]
 func GetAvaliableSN(ctx *gin.Context) {
-	snList, err := data.GetAvaliableSN()
+	snList, err := certStore.GetAvaliableSN(ctx.Request.Context())
 
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
+		ctx.JSON(http.StatusInternalServerError, model.ErrorResponse{Code: errorCode(err), Error: err.Error()})
 		utils.Record(logrus.ErrorLevel, err.Error())
 		return
 	}
 
 	ctx.JSON(http.StatusOK, model.GetAvaliableSNResponse{Data: snList})
 }
+
+// Build the job task generating S/N(s) in the background, the S/N(s) are written to the job result file.
+func generateSNTask(batch model.Batch, count int) jobs.Task {
+	return func(ctx context.Context, jobID string, report func(progress int) error) (string, error) {
+		snList, err := certStore.AddGeneratedSNs(ctx, batch, count, utils.GenerateSN, report)
+		if err != nil {
+			return "", err
+		}
+
+		f, location, err := jobs.CreateResultFile(jobID, ".txt")
+		if err != nil {
+			return "", err
+		}
+
+		defer f.Close()
+
+		w := bufio.NewWriter(f)
+		for _, sn := range snList {
+			w.WriteString(sn + "\n")
+		}
+
+		if err := w.Flush(); err != nil {
+			return "", err
+		}
+
+		utils.Record(logrus.InfoLevel,
+			fmt.Sprintf("Successfully uploaded new S/N (%d) of batch [%s] with reason (%s).",
+				count, batch.ID, batch.Reason),
+		)
+		return location, nil
+	}
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -19,6 +20,8 @@ import (
 )
 
 func TestCreateSN(t *testing.T) {
+	ctx := context.Background()
+
 	backupDBHost := cfg.DB_CONFIG.HOST
 	backupDBPort := cfg.DB_CONFIG.PORT
 
@@ -123,11 +126,13 @@ func TestCreateSN(t *testing.T) {
 	)
 
 	// Delete test data
-	err = data.DeleteTestingData("DELETE FROM certs WHERE sn = $1", testSN)
+	err = data.DeleteTestingData(ctx, "DELETE FROM certs WHERE sn = $1", testSN)
 	assert.Nil(t, err)
 }
 
 func TestCreateSNs(t *testing.T) {
+	ctx := context.Background()
+
 	backupDBHost := cfg.DB_CONFIG.HOST
 	backupDBPort := cfg.DB_CONFIG.PORT
 
@@ -180,6 +185,7 @@ func TestCreateSNs(t *testing.T) {
 		fmt.Sprintf("Successfully uploaded new S/N (%d) with reason (%s).", creationInfo.Count, creationInfo.Reason),
 	)
 	assert.Equal(t, len(generateSNResponse.SerialNumbers), targetGenerationCount)
+	assert.NotEmpty(t, generateSNResponse.BatchID)
 
 	// Test invalid case (Required fields are empty or not exist)
 	w = httptest.NewRecorder()
@@ -229,14 +235,54 @@ func TestCreateSNs(t *testing.T) {
 	)
 
 	// Delete test data
-	err = data.DeleteTestingData(
+	err = data.DeleteTestingData(ctx,
 		"DELETE FROM certs WHERE sn IN ($1, $2)",
 		generateSNResponse.SerialNumbers[0], generateSNResponse.SerialNumbers[1],
 	)
 	assert.Nil(t, err)
+	err = data.DeleteTestingData(ctx, "DELETE FROM batches WHERE id = $1", generateSNResponse.BatchID)
+	assert.Nil(t, err)
+}
+
+func TestGenerateSNMaxCount(t *testing.T) {
+	defer func() {
+		utils.TestBuffer = ""
+	}()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/api/v1/sn/generate", GenerateSN)
+
+	// Test invalid case (The count exceeds the configured maximum)
+	creationInfo := model.SNsInfo{
+		Count:  cfg.SERVER_CONFIG.SN_GENERATE_MAX_COUNT + 1,
+		Reason: "testReason",
+	}
+
+	jsonValue, _ := json.Marshal(creationInfo)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/sn/generate", bytes.NewBuffer(jsonValue))
+	req.Header.Set("Content-Type", "application/json")
+
+	router.ServeHTTP(w, req)
+
+	res := w.Body.String()
+	var errorResponse model.ErrorResponse
+	err := json.Unmarshal([]byte(res), &errorResponse)
+
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t,
+		fmt.Sprintf("The count must not be greater than %d.", cfg.SERVER_CONFIG.SN_GENERATE_MAX_COUNT),
+		errorResponse.Error,
+	)
+	assert.Equal(t, fmt.Sprintf("Invalid count(>max) [%d].", creationInfo.Count), utils.TestBuffer)
 }
 
 func TestUpdateCertNote(t *testing.T) {
+	ctx := context.Background()
+
 	backupDBHost := cfg.DB_CONFIG.HOST
 	backupDBPort := cfg.DB_CONFIG.PORT
 
@@ -263,7 +309,7 @@ func TestUpdateCertNote(t *testing.T) {
 	router.POST("/api/v1/sn/update", UpdateCertNote)
 
 	testSNList := []string{"testSN1", "testSN2", "testSN3"}
-	err = data.AddNewSNs(testSNList)
+	err = data.AddNewSNs(ctx, testSNList)
 	assert.Nil(t, err)
 
 	// Test valid case
@@ -342,13 +388,15 @@ func TestUpdateCertNote(t *testing.T) {
 	)
 
 	// Delete test data
-	err = data.DeleteTestingData(
+	err = data.DeleteTestingData(ctx,
 		"DELETE FROM certs WHERE sn IN ($1, $2, $3)",
 		testSNList[0], testSNList[1], testSNList[2],
 	)
 }
 
 func TestGetAllRecords(t *testing.T) {
+	ctx := context.Background()
+
 	backupDBHost := cfg.DB_CONFIG.HOST
 	backupDBPort := cfg.DB_CONFIG.PORT
 
@@ -375,7 +423,7 @@ func TestGetAllRecords(t *testing.T) {
 	router.GET("/api/v1/sn/get-all", GetAllRecords)
 
 	testSNList := []string{"testSN1", "testSN2", "testSN3"}
-	err = data.AddNewSNs(testSNList)
+	err = data.AddNewSNs(ctx, testSNList)
 	assert.Nil(t, err)
 
 	// Test valid case
@@ -399,13 +447,15 @@ func TestGetAllRecords(t *testing.T) {
 	}
 
 	// Delete test data
-	err = data.DeleteTestingData(
+	err = data.DeleteTestingData(ctx,
 		"DELETE FROM certs WHERE sn IN ($1, $2, $3)",
 		testSNList[0], testSNList[1], testSNList[2],
 	)
 }
 
 func TestGetAvaliableSN(t *testing.T) {
+	ctx := context.Background()
+
 	backupDBHost := cfg.DB_CONFIG.HOST
 	backupDBPort := cfg.DB_CONFIG.PORT
 
@@ -432,7 +482,7 @@ func TestGetAvaliableSN(t *testing.T) {
 	router.GET("/api/v1/sn/get-available", GetAvaliableSN)
 
 	testSNList := []string{"testSN1", "testSN2", "testSN3"}
-	err = data.AddNewSNs(testSNList)
+	err = data.AddNewSNs(ctx, testSNList)
 	assert.Nil(t, err)
 
 	// Test valid case
@@ -454,10 +504,93 @@ func TestGetAvaliableSN(t *testing.T) {
 	}
 
 	// Delete test data
-	err = data.DeleteTestingData(
+	err = data.DeleteTestingData(ctx,
 		"DELETE FROM certs WHERE sn IN ($1, $2, $3)",
 		testSNList[0], testSNList[1], testSNList[2],
 	)
 
 	assert.Nil(t, err)
 }
+
+func TestSNWithMemoryStore(t *testing.T) {
+	ctx := context.Background()
+
+	store := useMemoryStores(t)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/api/v1/sn/create", CreateSN)
+	router.POST("/api/v1/sn/update", UpdateCertNote)
+	router.POST("/api/v1/sn/metadata", UpdateCertMetadata)
+	router.POST("/api/v1/sn/search", SearchCerts)
+	router.POST("/api/v1/sn/delete", DeleteSN)
+	router.GET("/api/v1/sn/get-available", GetAvaliableSN)
+
+	request := func(method string, path string, body any) *httptest.ResponseRecorder {
+		jsonValue, _ := json.Marshal(body)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(jsonValue))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+
+		return w
+	}
+
+	testSN := "testSN"
+
+	// Test valid case
+	w := request("POST", "/api/v1/sn/create", model.SNInfo{SerialNumber: testSN})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = request("POST", "/api/v1/sn/update", model.CertNote{SerialNumber: testSN, Note: "testNote"})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = request("POST", "/api/v1/sn/metadata", model.CertMetadataPatch{
+		SerialNumber: testSN,
+		Metadata:     map[string]any{"crm_id": "C-1024", "seats": 5},
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = request("POST", "/api/v1/sn/search", model.CertMetadataQuery{Metadata: map[string]any{"seats": 5}})
+	var searchCertsResponse model.SearchCertsResponse
+	err := json.Unmarshal(w.Body.Bytes(), &searchCertsResponse)
+	assert.Nil(t, err)
+	if assert.Len(t, searchCertsResponse.Data, 1) {
+		assert.Equal(t, "testNote", searchCertsResponse.Data[0].Note)
+	}
+
+	w = request("GET", "/api/v1/sn/get-available", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), testSN)
+
+	// Test invalid case (The S/N already exists)
+	w = request("POST", "/api/v1/sn/create", model.SNInfo{SerialNumber: testSN})
+	var errorResponse model.ErrorResponse
+	err = json.Unmarshal(w.Body.Bytes(), &errorResponse)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, model.ErrCodeSNAlreadyExists, errorResponse.Code)
+
+	// Test invalid case (The S/N has been bound to a device)
+	_, err = store.BindCertificate(ctx, model.CertActivation{SerialNumber: testSN, Key: "key"}, nil)
+	assert.Nil(t, err)
+
+	w = request("POST", "/api/v1/sn/delete", model.SNDeleteInfo{SerialNumber: testSN})
+	err = json.Unmarshal(w.Body.Bytes(), &errorResponse)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, model.ErrCodeSNAlreadyBound, errorResponse.Code)
+
+	w = request("POST", "/api/v1/sn/delete", model.SNDeleteInfo{SerialNumber: testSN, Force: true})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	archived, err := store.GetArchivedCerts(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, archived, 1)
+
+	// Test invalid case (The S/N does not exist)
+	w = request("POST", "/api/v1/sn/update", model.CertNote{SerialNumber: testSN, Note: "testNote"})
+	err = json.Unmarshal(w.Body.Bytes(), &errorResponse)
+	assert.Nil(t, err)
+	assert.Equal(t, model.ErrCodeSNNotFound, errorResponse.Code)
+}
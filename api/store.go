@@ -0,0 +1,31 @@
+package api
+
+import "github.com/mmq88/quickcerts/data"
+
+// The stores used by the handlers, Postgres and redis by default.
+var (
+	certStore       data.CertStore       = data.PostgresStore{}
+	permitStore     data.PermitStore     = data.PostgresStore{}
+	keyCache        data.KeyCache        = data.RedisKeyCache{}
+	adminTokenStore data.AdminTokenStore = data.PostgresStore{}
+	clientKeyStore  data.ClientKeyStore  = data.PostgresStore{}
+	auditStore      data.AuditStore      = data.PostgresStore{}
+)
+
+// Replace the stores used by the handlers, e.g. with data.NewMemoryStore and data.NewMemoryKeyCache to run the API
+// without Postgres and redis. It should be called before the server starts.
+func UseStores(certs data.CertStore, permits data.PermitStore, cache data.KeyCache) {
+	certStore = certs
+	permitStore = permits
+	keyCache = cache
+}
+
+// Replace the stores used by the handlers of the admin tokens, client keys and audit logs. It should be called
+// before the server starts, along with middleware.UseStores.
+//
+// The jobs are read through the job pool, see jobs.UseStore.
+func UseAdminStores(tokens data.AdminTokenStore, clientKeys data.ClientKeyStore, audit data.AuditStore) {
+	adminTokenStore = tokens
+	clientKeyStore = clientKeys
+	auditStore = audit
+}
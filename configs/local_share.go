@@ -1,7 +1,11 @@
 package configs
 
 import (
+	"crypto/ed25519"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"net/netip"
 	"os"
 	"strings"
 	"time"
@@ -11,16 +15,51 @@ import (
 )
 
 type DBConfig struct {
-	HOST    string `toml:"HOST"`
-	PORT    int    `toml:"PORT"`
-	USER    string `toml:"USER"`
-	PWD     string `toml:"PWD"`
-	DB_NAME string `toml:"DB_NAME"`
+	DRIVER                 string `toml:"DRIVER"`
+	HOST                   string `toml:"HOST"`
+	PORT                   int    `toml:"PORT"`
+	USER                   string `toml:"USER"`
+	PWD                    string `toml:"PWD"`
+	DB_NAME                string `toml:"DB_NAME"`
+	SSL_MODE               string `toml:"SSL_MODE"`
+	SSL_ROOT_CERT          string `toml:"SSL_ROOT_CERT"`
+	MAX_OPEN_CONNS         int    `toml:"MAX_OPEN_CONNS"`
+	MAX_IDLE_CONNS         int    `toml:"MAX_IDLE_CONNS"`
+	CONN_MAX_LIFETIME      int    `toml:"CONN_MAX_LIFETIME"`
+	CONN_MAX_LIFETIME_UNIT string `toml:"CONN_MAX_LIFETIME_UNIT"`
+	QUERY_TIMEOUT          int    `toml:"QUERY_TIMEOUT"`
+	QUERY_TIMEOUT_UNIT     string `toml:"QUERY_TIMEOUT_UNIT"`
+	SQLITE_PATH            string `toml:"SQLITE_PATH"`
+	AUTO_MIGRATE           bool   `toml:"AUTO_MIGRATE"`
+	CONNECT_RETRIES        int    `toml:"CONNECT_RETRIES"`
 }
 
+// The roles of admin permissions.
+//
+// viewer: read-only access.
+//
+// support: viewer, and updating the notes and metadata of S/N(s).
+//
+// issuer: viewer, and creating and generating S/N(s).
+//
+// owner: full access, including deleting and revoking S/N(s) and reading the audit logs.
+const (
+	RoleViewer  = "viewer"
+	RoleSupport = "support"
+	RoleIssuer  = "issuer"
+	RoleOwner   = "owner"
+)
+
+// The minimum length of the admin tokens and client keys, the generated ones are 64 hex characters.
+const MinTokenLength = 32
+
 type Permission struct {
-	NAME  string `toml:"NAME"`
-	TOKEN string `toml:"TOKEN"`
+	NAME               string `toml:"NAME"`
+	TOKEN              string `toml:"TOKEN"`
+	ROLE               string `toml:"ROLE"`
+	CERT_SUBJECT       string `toml:"CERT_SUBJECT"`
+	TOTP_SECRET        string `toml:"TOTP_SECRET"`
+	SIGNING_PUBLIC_KEY string `toml:"SIGNING_PUBLIC_KEY"`
 }
 
 type Allowedlist struct {
@@ -29,9 +68,20 @@ type Allowedlist struct {
 
 type ServerConfig struct {
 	ALLOWED_IPs                []string      `toml:"ALLOWED_IPs"`
+	DENIED_IPs                 []string      `toml:"DENIED_IPs"`
+	TRUSTED_PROXIES            []string      `toml:"TRUSTED_PROXIES"`
 	USE_RUNTIME_CODE           bool          `toml:"USE_RUNTIME_CODE"`
 	RUNTIME_CODE_LENGTH        int           `toml:"RUNTIME_CODE_LENGTH"`
+	RUNTIME_CODE_TYPE          string        `toml:"RUNTIME_CODE_TYPE"`
+	REQUIRE_REQUEST_SIGNATURE  bool          `toml:"REQUIRE_REQUEST_SIGNATURE"`
+	REQUEST_SIGNATURE_MAX_AGE  int           `toml:"REQUEST_SIGNATURE_MAX_AGE"`
+	ADMIN_LOCKOUT_THRESHOLD    int           `toml:"ADMIN_LOCKOUT_THRESHOLD"`
+	ADMIN_LOCKOUT_BASE_TIME    int           `toml:"ADMIN_LOCKOUT_BASE_TIME"`
+	ADMIN_LOCKOUT_MAX_TIME     int           `toml:"ADMIN_LOCKOUT_MAX_TIME"`
 	CLIENT_AUTH_TOKEN          []string      `toml:"CLIENT_AUTH_TOKEN"`
+	APPLY_RATE_LIMIT_PER_IP    int           `toml:"APPLY_RATE_LIMIT_PER_IP"`
+	APPLY_RATE_LIMIT_PER_KEY   int           `toml:"APPLY_RATE_LIMIT_PER_KEY"`
+	APPLY_RATE_LIMIT_PER_SN    int           `toml:"APPLY_RATE_LIMIT_PER_SN"`
 	PORT                       string        `toml:"PORT"`
 	KEEP_ALIVE_TIMEOUT         time.Duration `toml:"KEEP_ALIVE_TIMEOUT"`
 	KEEP_ALIVE_TIMEOUT_UNIT    string        `toml:"KEEP_ALIVE_TIMEOUT_UNIT"`
@@ -39,9 +89,21 @@ type ServerConfig struct {
 	TLS_CERT_PATH              string        `toml:"TLS_CERT_PATH"`
 	TLS_KEY_PATH               string        `toml:"TLS_KEY_PATH"`
 	TLS_PORT                   string        `toml:"TLS_PORT"`
+	ADMIN_ADDRESS              string        `toml:"ADMIN_ADDRESS"`
+	ADMIN_USE_TLS              bool          `toml:"ADMIN_USE_TLS"`
+	ADMIN_TLS_CERT_PATH        string        `toml:"ADMIN_TLS_CERT_PATH"`
+	ADMIN_TLS_KEY_PATH         string        `toml:"ADMIN_TLS_KEY_PATH"`
+	USE_ADMIN_MTLS             bool          `toml:"USE_ADMIN_MTLS"`
+	ADMIN_MTLS_CLIENT_CA_PATH  string        `toml:"ADMIN_MTLS_CLIENT_CA_PATH"`
 	TEMPORARY_PERMIT_TIME      int           `toml:"TEMPORARY_PERMIT_TIME"`
 	TEMPORARY_PERMIT_TIME_UNIT string        `toml:"TEMPORARY_PERMIT_TIME_UNIT"`
 	HASHING_METHOD             string        `toml:"HASHING_METHOD"`
+	SN_GENERATE_MAX_COUNT      int           `toml:"SN_GENERATE_MAX_COUNT"`
+	SN_GENERATE_BATCH_SIZE     int           `toml:"SN_GENERATE_BATCH_SIZE"`
+	SN_GENERATE_ASYNC_COUNT    int           `toml:"SN_GENERATE_ASYNC_COUNT"`
+	JOB_WORKER_COUNT           int           `toml:"JOB_WORKER_COUNT"`
+	JOB_QUEUE_SIZE             int           `toml:"JOB_QUEUE_SIZE"`
+	JOB_RESULT_DIR             string        `toml:"JOB_RESULT_DIR"`
 	LOG_TEST_MODE              bool          `toml:"LOG_TEST_MODE"`
 	LOG_TIME_UNIT              string        `toml:"LOG_TIME_UNIT"`
 	LOG_MAX_AGE                int           `toml:"LOG_MAX_AGE"`
@@ -50,17 +112,34 @@ type ServerConfig struct {
 }
 
 type CacheConfig struct {
-	HOST            string `toml:"HOST"`
-	PORT            int    `toml:"PORT"`
-	PASSWORD        string `toml:"PASSWORD"`
-	EXPIRATION      int    `toml:"EXPIRATION"`
-	EXPIRATION_UNIT string `toml:"EXPIRATION_UNIT"`
+	MODE              string   `toml:"MODE"`
+	HOST              string   `toml:"HOST"`
+	PORT              int      `toml:"PORT"`
+	ADDRS             []string `toml:"ADDRS"`
+	MASTER_NAME       string   `toml:"MASTER_NAME"`
+	SENTINEL_PASSWORD string   `toml:"SENTINEL_PASSWORD"`
+	USERNAME          string   `toml:"USERNAME"`
+	PASSWORD          string   `toml:"PASSWORD"`
+	DB                int      `toml:"DB"`
+	KEY_PREFIX        string   `toml:"KEY_PREFIX"`
+	USE_TLS           bool     `toml:"USE_TLS"`
+	TLS_CA_CERT_PATH  string   `toml:"TLS_CA_CERT_PATH"`
+	EXPIRATION        int      `toml:"EXPIRATION"`
+	EXPIRATION_UNIT   string   `toml:"EXPIRATION_UNIT"`
+}
+
+// The parsed ALLOWED_IPs, DENIED_IPs and TRUSTED_PROXIES, set when the configs are validated.
+type IPRanges struct {
+	ALLOWED_IPs     []netip.Prefix
+	DENIED_IPs      []netip.Prefix
+	TRUSTED_PROXIES []netip.Prefix
 }
 
 var SERVER_CONFIG ServerConfig
 var DB_CONFIG DBConfig
 var ALLOWEDLIST Allowedlist
 var CACHE_CONFIG CacheConfig
+var IP_RANGES IPRanges
 
 func init() {
 	defer func() {
@@ -96,12 +175,231 @@ func init() {
 	checkValid()
 }
 
+// Parse the entries of an IP list, which are IP addresses (IPv4 or IPv6), CIDR ranges or "*" for all addresses.
+func ParseIPRanges(entries []string) ([]netip.Prefix, error) {
+	ranges := make([]netip.Prefix, 0, len(entries))
+
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+
+		if entry == "*" {
+			ranges = append(ranges, netip.MustParsePrefix("0.0.0.0/0"), netip.MustParsePrefix("::/0"))
+			continue
+		}
+
+		if strings.Contains(entry, "/") {
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid IP or CIDR: %s", entry)
+			}
+			ranges = append(ranges, prefix.Masked())
+			continue
+		}
+
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IP or CIDR: %s", entry)
+		}
+		addr = addr.Unmap()
+		ranges = append(ranges, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+
+	return ranges, nil
+}
+
+func checkIPRanges() {
+	lists := []struct {
+		name    string
+		entries []string
+		ranges  *[]netip.Prefix
+	}{
+		{"ALLOWED_IPs", SERVER_CONFIG.ALLOWED_IPs, &IP_RANGES.ALLOWED_IPs},
+		{"DENIED_IPs", SERVER_CONFIG.DENIED_IPs, &IP_RANGES.DENIED_IPs},
+		{"TRUSTED_PROXIES", SERVER_CONFIG.TRUSTED_PROXIES, &IP_RANGES.TRUSTED_PROXIES},
+	}
+
+	for _, list := range lists {
+		ranges, err := ParseIPRanges(list.entries)
+		if err != nil {
+			panic(fmt.Errorf("%s contains an %s", list.name, err.Error()))
+		}
+		*list.ranges = ranges
+	}
+}
+
 func checkRunTimeCodeLength() {
 	if SERVER_CONFIG.USE_RUNTIME_CODE && SERVER_CONFIG.RUNTIME_CODE_LENGTH < 6 {
 		panic(errors.New("RUNTIME_CODE_LENGTH should be bigger or equal to 6"))
 	}
 }
 
+// The types of the runtime code.
+//
+// console: a single code generated on the console at startup.
+//
+// totp: the TOTP code of each admin, generated from the TOTP secret of the admin by an authenticator app.
+const (
+	RuntimeCodeConsole = "console"
+	RuntimeCodeTOTP    = "totp"
+)
+
+func checkRunTimeCodeType() {
+	switch strings.ToLower(SERVER_CONFIG.RUNTIME_CODE_TYPE) {
+	case "", RuntimeCodeConsole, RuntimeCodeTOTP:
+	default:
+		panic(errors.New("RUNTIME_CODE_TYPE is not valid (Require: console, totp)"))
+	}
+}
+
+// Every admin in the allowlist needs a TOTP secret to be authenticated when the runtime code is the TOTP code.
+func checkPermissionTOTPSecrets() {
+	if !UseTOTPRuntimeCode() {
+		return
+	}
+
+	for _, permission := range ALLOWEDLIST.PERMISSIONS {
+		if permission.TOTP_SECRET == "" {
+			panic(fmt.Errorf("TOTP_SECRET of [%s] should not be empty when RUNTIME_CODE_TYPE is totp", permission.NAME))
+		}
+	}
+}
+
+// The signing public key of an admin is the hex Ed25519 public key verifying the signed requests of the admin.
+func checkPermissionSigningPublicKeys() {
+	for _, permission := range ALLOWEDLIST.PERMISSIONS {
+		if permission.SIGNING_PUBLIC_KEY == "" {
+			continue
+		}
+
+		key, err := hex.DecodeString(permission.SIGNING_PUBLIC_KEY)
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			panic(fmt.Errorf("SIGNING_PUBLIC_KEY of [%s] should be 64 hex characters", permission.NAME))
+		}
+	}
+}
+
+// Check if the admins are required to carry their TOTP codes as the runtime code.
+func UseTOTPRuntimeCode() bool {
+	return SERVER_CONFIG.USE_RUNTIME_CODE && strings.EqualFold(SERVER_CONFIG.RUNTIME_CODE_TYPE, RuntimeCodeTOTP)
+}
+
+// The databases of the server.
+const (
+	DBDriverPostgres = "postgres"
+	DBDriverSQLite   = "sqlite"
+)
+
+func checkDatabaseDriver() {
+	switch strings.ToLower(DB_CONFIG.DRIVER) {
+	case "", DBDriverPostgres:
+	case DBDriverSQLite:
+		if DB_CONFIG.SQLITE_PATH == "" {
+			panic(errors.New("SQLITE_PATH is required when DRIVER is sqlite"))
+		}
+	default:
+		panic(errors.New("DRIVER is not valid (Require: postgres, sqlite)"))
+	}
+}
+
+// Check if all data is stored in SQLite instead of Postgres.
+func UseSQLite() bool {
+	return strings.EqualFold(DB_CONFIG.DRIVER, DBDriverSQLite)
+}
+
+func checkDatabaseSSLMode() {
+	switch strings.ToLower(DB_CONFIG.SSL_MODE) {
+	case "disable", "require", "verify-ca", "verify-full":
+	default:
+		panic(errors.New("SSL_MODE is not valid (Require: disable, require, verify-ca, verify-full)"))
+	}
+}
+
+func checkDatabasePool() {
+	if DB_CONFIG.MAX_OPEN_CONNS < 0 {
+		panic(errors.New("MAX_OPEN_CONNS should be bigger or equal to 0"))
+	}
+
+	if DB_CONFIG.MAX_IDLE_CONNS < 0 {
+		panic(errors.New("MAX_IDLE_CONNS should be bigger or equal to 0"))
+	}
+
+	if DB_CONFIG.CONN_MAX_LIFETIME < 0 {
+		panic(errors.New("CONN_MAX_LIFETIME should be bigger or equal to 0"))
+	}
+
+	switch strings.ToLower(DB_CONFIG.CONN_MAX_LIFETIME_UNIT) {
+	case "hour", "minute", "second":
+	default:
+		panic(errors.New("CONN_MAX_LIFETIME_UNIT is not valid (Require: hour, minute, second)"))
+	}
+}
+
+func checkDatabaseConnectRetries() {
+	if DB_CONFIG.CONNECT_RETRIES < 0 {
+		panic(errors.New("CONNECT_RETRIES should be bigger or equal to 0"))
+	}
+}
+
+func checkDatabaseQueryTimeout() {
+	if DB_CONFIG.QUERY_TIMEOUT < 0 {
+		panic(errors.New("QUERY_TIMEOUT should be bigger or equal to 0"))
+	}
+
+	switch strings.ToLower(DB_CONFIG.QUERY_TIMEOUT_UNIT) {
+	case "minute", "second", "millisecond":
+	default:
+		panic(errors.New("QUERY_TIMEOUT_UNIT is not valid (Require: minute, second, millisecond)"))
+	}
+}
+
+func checkRequestSignatureMaxAge() {
+	if SERVER_CONFIG.REQUEST_SIGNATURE_MAX_AGE <= 0 {
+		panic(errors.New("REQUEST_SIGNATURE_MAX_AGE should be bigger than 0"))
+	}
+}
+
+func checkAdminLockout() {
+	if SERVER_CONFIG.ADMIN_LOCKOUT_THRESHOLD < 0 {
+		panic(errors.New("ADMIN_LOCKOUT_THRESHOLD should be bigger or equal to 0"))
+	}
+
+	if SERVER_CONFIG.ADMIN_LOCKOUT_THRESHOLD == 0 {
+		return
+	}
+
+	if SERVER_CONFIG.ADMIN_LOCKOUT_BASE_TIME <= 0 {
+		panic(errors.New("ADMIN_LOCKOUT_BASE_TIME should be bigger than 0"))
+	}
+
+	if SERVER_CONFIG.ADMIN_LOCKOUT_MAX_TIME < SERVER_CONFIG.ADMIN_LOCKOUT_BASE_TIME {
+		panic(errors.New("ADMIN_LOCKOUT_MAX_TIME should be bigger or equal to ADMIN_LOCKOUT_BASE_TIME"))
+	}
+}
+
+func checkApplyRateLimits() {
+	if SERVER_CONFIG.APPLY_RATE_LIMIT_PER_IP < 0 ||
+		SERVER_CONFIG.APPLY_RATE_LIMIT_PER_KEY < 0 ||
+		SERVER_CONFIG.APPLY_RATE_LIMIT_PER_SN < 0 {
+		panic(errors.New("APPLY_RATE_LIMIT_PER_IP, APPLY_RATE_LIMIT_PER_KEY and APPLY_RATE_LIMIT_PER_SN should be bigger or equal to 0"))
+	}
+}
+
+// The empty CLIENT_AUTH_TOKEN used to allow any client, which is refused instead of silently requiring client keys.
+func checkClientAuthTokens() {
+	for _, token := range SERVER_CONFIG.CLIENT_AUTH_TOKEN {
+		if token == "" {
+			panic(errors.New(
+				"CLIENT_AUTH_TOKEN should not contain empty values, the open access is no longer supported " +
+					"(Require: client keys of at least 32 characters)",
+			))
+		}
+
+		if len(token) < MinTokenLength {
+			panic(fmt.Errorf("CLIENT_AUTH_TOKEN should be at least %d characters", MinTokenLength))
+		}
+	}
+}
+
 func checkKeepAliveTimeout() {
 	if SERVER_CONFIG.KEEP_ALIVE_TIMEOUT < 0 {
 		panic(errors.New("KEEP_ALIVE_TIMEOUT should be bigger or equal to 0"))
@@ -130,6 +428,44 @@ func checkTemporaryPermitTimeUnit() {
 	}
 }
 
+func checkSNGenerateMaxCount() {
+	if SERVER_CONFIG.SN_GENERATE_MAX_COUNT <= 0 {
+		panic(errors.New("SN_GENERATE_MAX_COUNT should be bigger than 0"))
+	}
+}
+
+func checkSNGenerateBatchSize() {
+	// Each S/N takes one parameter besides the shared batch ID and product, and PostgreSQL allows at most 65535
+	// parameters per statement.
+	if SERVER_CONFIG.SN_GENERATE_BATCH_SIZE <= 0 || SERVER_CONFIG.SN_GENERATE_BATCH_SIZE > 65533 {
+		panic(errors.New("SN_GENERATE_BATCH_SIZE should be between 1 and 65533"))
+	}
+}
+
+func checkSNGenerateAsyncCount() {
+	if SERVER_CONFIG.SN_GENERATE_ASYNC_COUNT <= 0 {
+		panic(errors.New("SN_GENERATE_ASYNC_COUNT should be bigger than 0"))
+	}
+}
+
+func checkJobWorkerCount() {
+	if SERVER_CONFIG.JOB_WORKER_COUNT <= 0 {
+		panic(errors.New("JOB_WORKER_COUNT should be bigger than 0"))
+	}
+}
+
+func checkJobQueueSize() {
+	if SERVER_CONFIG.JOB_QUEUE_SIZE <= 0 {
+		panic(errors.New("JOB_QUEUE_SIZE should be bigger than 0"))
+	}
+}
+
+func checkJobResultDir() {
+	if SERVER_CONFIG.JOB_RESULT_DIR == "" {
+		panic(errors.New("JOB_RESULT_DIR should not be empty"))
+	}
+}
+
 func checkLogMaxAge() {
 	if SERVER_CONFIG.LOG_MAX_AGE <= 0 {
 		panic(errors.New("LOG_MAX_AGE should be bigger than 0"))
@@ -150,6 +486,92 @@ func checkLogTimeUnit() {
 	}
 }
 
+// Check if the given role is one of the admin roles.
+func IsValidRole(role string) bool {
+	switch role {
+	case RoleViewer, RoleSupport, RoleIssuer, RoleOwner:
+		return true
+	default:
+		return false
+	}
+}
+
+func checkPermissionRoles() {
+	for _, permission := range ALLOWEDLIST.PERMISSIONS {
+		if !IsValidRole(permission.ROLE) {
+			panic(fmt.Errorf(
+				"ROLE of [%s] is not valid (Require: viewer, support, issuer, owner)", permission.NAME,
+			))
+		}
+	}
+}
+
+func checkPermissionTokens() {
+	for _, permission := range ALLOWEDLIST.PERMISSIONS {
+		if permission.TOKEN != "" && len(permission.TOKEN) < MinTokenLength {
+			panic(fmt.Errorf("TOKEN of [%s] should be at least %d characters", permission.NAME, MinTokenLength))
+		}
+	}
+}
+
+func checkAdminListener() {
+	if SERVER_CONFIG.ADMIN_ADDRESS == "" {
+		return
+	}
+
+	if SERVER_CONFIG.ADMIN_ADDRESS == SERVER_CONFIG.PORT || SERVER_CONFIG.ADMIN_ADDRESS == SERVER_CONFIG.TLS_PORT {
+		panic(errors.New("ADMIN_ADDRESS should be different from PORT and TLS_PORT"))
+	}
+
+	if SERVER_CONFIG.ADMIN_USE_TLS && (SERVER_CONFIG.ADMIN_TLS_CERT_PATH == "" || SERVER_CONFIG.ADMIN_TLS_KEY_PATH == "") {
+		panic(errors.New("ADMIN_TLS_CERT_PATH and ADMIN_TLS_KEY_PATH should not be empty when ADMIN_USE_TLS is enabled"))
+	}
+}
+
+func checkAdminMTLS() {
+	if !SERVER_CONFIG.USE_ADMIN_MTLS {
+		return
+	}
+
+	if SERVER_CONFIG.ADMIN_ADDRESS == "" || !SERVER_CONFIG.ADMIN_USE_TLS {
+		panic(errors.New("USE_ADMIN_MTLS requires the admin listener with TLS (ADMIN_ADDRESS and ADMIN_USE_TLS)"))
+	}
+
+	if SERVER_CONFIG.ADMIN_MTLS_CLIENT_CA_PATH == "" {
+		panic(errors.New("ADMIN_MTLS_CLIENT_CA_PATH should not be empty when USE_ADMIN_MTLS is enabled"))
+	}
+
+	subjects := make(map[string]bool)
+
+	for _, permission := range ALLOWEDLIST.PERMISSIONS {
+		if permission.CERT_SUBJECT == "" {
+			continue
+		}
+
+		if subjects[permission.CERT_SUBJECT] {
+			panic(fmt.Errorf("CERT_SUBJECT of [%s] is duplicated", permission.NAME))
+		}
+		subjects[permission.CERT_SUBJECT] = true
+	}
+}
+
+// Find the admin permission whose CERT_SUBJECT matches the subject of a client certificate.
+//
+// The subject is in the form returned by pkix.Name.String(), e.g. "CN=alice,O=Example".
+func FindPermissionByCertSubject(subject string) (Permission, bool) {
+	if subject == "" {
+		return Permission{}, false
+	}
+
+	for _, permission := range ALLOWEDLIST.PERMISSIONS {
+		if permission.CERT_SUBJECT == subject {
+			return permission, true
+		}
+	}
+
+	return Permission{}, false
+}
+
 func checkCacheExpiration() {
 	if CACHE_CONFIG.EXPIRATION <= 0 {
 		panic(errors.New("EXPIRATION should be bigger than 0"))
@@ -164,17 +586,75 @@ func checkCacheExpirationUnit() {
 	}
 }
 
+// The ways to address the redis database.
+const (
+	CacheModeStandalone = "standalone"
+	CacheModeSentinel   = "sentinel"
+	CacheModeCluster    = "cluster"
+)
+
+func checkCacheMode() {
+	switch strings.ToLower(CACHE_CONFIG.MODE) {
+	case "", CacheModeStandalone:
+	case CacheModeSentinel:
+		if len(CACHE_CONFIG.ADDRS) == 0 || CACHE_CONFIG.MASTER_NAME == "" {
+			panic(errors.New("ADDRS and MASTER_NAME are required when MODE is sentinel"))
+		}
+	case CacheModeCluster:
+		if len(CACHE_CONFIG.ADDRS) == 0 {
+			panic(errors.New("ADDRS is required when MODE is cluster"))
+		}
+
+		if CACHE_CONFIG.DB != 0 {
+			panic(errors.New("DB should be 0 when MODE is cluster"))
+		}
+	default:
+		panic(errors.New("MODE is not valid (Require: standalone, sentinel, cluster)"))
+	}
+}
+
+func checkCacheDB() {
+	if CACHE_CONFIG.DB < 0 {
+		panic(errors.New("DB should be bigger or equal to 0"))
+	}
+}
+
 func checkValid() {
+	checkIPRanges()
 	checkRunTimeCodeLength()
+	checkRunTimeCodeType()
+	checkRequestSignatureMaxAge()
+	checkAdminLockout()
+	checkApplyRateLimits()
+	checkClientAuthTokens()
 	checkKeepAliveTimeout()
 	checkKeepAliveTimeoutUnit()
 	checkTemporaryPermitTime()
 	checkTemporaryPermitTimeUnit()
+	checkSNGenerateMaxCount()
+	checkSNGenerateBatchSize()
+	checkSNGenerateAsyncCount()
+	checkJobWorkerCount()
+	checkJobQueueSize()
+	checkJobResultDir()
 	checkLogMaxAge()
 	checkLogRotationTime()
 	checkLogTimeUnit()
+	checkPermissionRoles()
+	checkPermissionTokens()
+	checkPermissionTOTPSecrets()
+	checkPermissionSigningPublicKeys()
+	checkAdminListener()
+	checkAdminMTLS()
 	checkCacheExpiration()
 	checkCacheExpirationUnit()
+	checkCacheDB()
+	checkCacheMode()
+	checkDatabaseDriver()
+	checkDatabaseSSLMode()
+	checkDatabasePool()
+	checkDatabaseQueryTimeout()
+	checkDatabaseConnectRetries()
 }
 
 // Ensure that the current working directory is the root directory of the project.
@@ -2,6 +2,7 @@ package configs
 
 import (
 	"fmt"
+	"net/netip"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -230,3 +231,546 @@ func TestCheckCacheExpirationUnit(t *testing.T) {
 
 	CACHE_CONFIG.EXPIRATION_UNIT = backup_expiration_unit
 }
+
+func TestCheckSNGenerateBatchSize(t *testing.T) {
+	backup_sn_generate_batch_size := SERVER_CONFIG.SN_GENERATE_BATCH_SIZE
+	defer func() {
+		SERVER_CONFIG.SN_GENERATE_BATCH_SIZE = backup_sn_generate_batch_size
+	}()
+
+	// Test valid case
+	assert.NotPanics(t, checkSNGenerateBatchSize)
+
+	// Test invalid case
+	SERVER_CONFIG.SN_GENERATE_BATCH_SIZE = 0
+	assert.PanicsWithError(t, "SN_GENERATE_BATCH_SIZE should be between 1 and 65533", checkSNGenerateBatchSize)
+
+	SERVER_CONFIG.SN_GENERATE_BATCH_SIZE = 65534
+	assert.PanicsWithError(t, "SN_GENERATE_BATCH_SIZE should be between 1 and 65533", checkSNGenerateBatchSize)
+}
+
+func TestCheckPermissionRoles(t *testing.T) {
+	backup_permissions := ALLOWEDLIST.PERMISSIONS
+	defer func() {
+		ALLOWEDLIST.PERMISSIONS = backup_permissions
+	}()
+
+	// Test valid case
+	ALLOWEDLIST.PERMISSIONS = []Permission{
+		{NAME: "viewer", ROLE: RoleViewer},
+		{NAME: "support", ROLE: RoleSupport},
+		{NAME: "issuer", ROLE: RoleIssuer},
+		{NAME: "owner", ROLE: RoleOwner},
+	}
+	assert.NotPanics(t, checkPermissionRoles)
+
+	// Test invalid case
+	ALLOWEDLIST.PERMISSIONS = []Permission{{NAME: "admin", ROLE: ""}}
+	assert.PanicsWithError(t, "ROLE of [admin] is not valid (Require: viewer, support, issuer, owner)", checkPermissionRoles)
+
+	ALLOWEDLIST.PERMISSIONS = []Permission{{NAME: "admin", ROLE: "root"}}
+	assert.PanicsWithError(t, "ROLE of [admin] is not valid (Require: viewer, support, issuer, owner)", checkPermissionRoles)
+}
+
+func TestCheckPermissionTokens(t *testing.T) {
+	backup_permissions := ALLOWEDLIST.PERMISSIONS
+	defer func() {
+		ALLOWEDLIST.PERMISSIONS = backup_permissions
+	}()
+
+	// Test valid case
+	assert.NotPanics(t, checkPermissionTokens)
+
+	// The admins identified only by the client certificates have no tokens.
+	ALLOWEDLIST.PERMISSIONS = []Permission{{NAME: "admin", CERT_SUBJECT: "CN=admin"}}
+	assert.NotPanics(t, checkPermissionTokens)
+
+	// Test invalid case
+	ALLOWEDLIST.PERMISSIONS = []Permission{{NAME: "admin", TOKEN: "token"}}
+	assert.PanicsWithError(t, "TOKEN of [admin] should be at least 32 characters", checkPermissionTokens)
+}
+
+func TestCheckPermissionTOTPSecrets(t *testing.T) {
+	backup_server_config := SERVER_CONFIG
+	backup_permissions := ALLOWEDLIST.PERMISSIONS
+	defer func() {
+		SERVER_CONFIG = backup_server_config
+		ALLOWEDLIST.PERMISSIONS = backup_permissions
+	}()
+
+	ALLOWEDLIST.PERMISSIONS = []Permission{{NAME: "admin"}}
+
+	// Test valid case
+	SERVER_CONFIG.USE_RUNTIME_CODE = true
+	SERVER_CONFIG.RUNTIME_CODE_TYPE = RuntimeCodeConsole
+	assert.NotPanics(t, checkPermissionTOTPSecrets)
+
+	SERVER_CONFIG.USE_RUNTIME_CODE = false
+	SERVER_CONFIG.RUNTIME_CODE_TYPE = RuntimeCodeTOTP
+	assert.NotPanics(t, checkPermissionTOTPSecrets)
+
+	SERVER_CONFIG.USE_RUNTIME_CODE = true
+	ALLOWEDLIST.PERMISSIONS = []Permission{{NAME: "admin", TOTP_SECRET: "GEZDGNBV"}}
+	assert.NotPanics(t, checkPermissionTOTPSecrets)
+
+	// Test invalid case
+	ALLOWEDLIST.PERMISSIONS = []Permission{{NAME: "admin", TOTP_SECRET: "GEZDGNBV"}, {NAME: "another admin"}}
+	assert.PanicsWithError(t, "TOTP_SECRET of [another admin] should not be empty when RUNTIME_CODE_TYPE is totp",
+		checkPermissionTOTPSecrets)
+}
+
+func TestCheckPermissionSigningPublicKeys(t *testing.T) {
+	backup_permissions := ALLOWEDLIST.PERMISSIONS
+	defer func() {
+		ALLOWEDLIST.PERMISSIONS = backup_permissions
+	}()
+
+	// Test valid case
+	ALLOWEDLIST.PERMISSIONS = []Permission{
+		{NAME: "admin"},
+		{NAME: "another admin", SIGNING_PUBLIC_KEY: "67d3b5eaf0c0bf6b5a602d359daecc86a7a74053490ec37ae08e71360587c870"},
+	}
+	assert.NotPanics(t, checkPermissionSigningPublicKeys)
+
+	// Test invalid case
+	ALLOWEDLIST.PERMISSIONS = []Permission{{NAME: "admin", SIGNING_PUBLIC_KEY: "67d3b5eaf0c0bf6b"}}
+	assert.PanicsWithError(t, "SIGNING_PUBLIC_KEY of [admin] should be 64 hex characters",
+		checkPermissionSigningPublicKeys)
+}
+
+func TestCheckClientAuthTokens(t *testing.T) {
+	backup_client_auth_token := SERVER_CONFIG.CLIENT_AUTH_TOKEN
+	defer func() {
+		SERVER_CONFIG.CLIENT_AUTH_TOKEN = backup_client_auth_token
+	}()
+
+	// Test valid case
+	assert.NotPanics(t, checkClientAuthTokens)
+
+	SERVER_CONFIG.CLIENT_AUTH_TOKEN = []string{}
+	assert.NotPanics(t, checkClientAuthTokens)
+
+	// Test invalid case
+	SERVER_CONFIG.CLIENT_AUTH_TOKEN = []string{""}
+	assert.PanicsWithError(t, "CLIENT_AUTH_TOKEN should not contain empty values, the open access is no longer "+
+		"supported (Require: client keys of at least 32 characters)", checkClientAuthTokens)
+
+	SERVER_CONFIG.CLIENT_AUTH_TOKEN = []string{"QcsTestToken"}
+	assert.PanicsWithError(t, "CLIENT_AUTH_TOKEN should be at least 32 characters", checkClientAuthTokens)
+}
+
+func TestCheckRequestSignatureMaxAge(t *testing.T) {
+	backup_request_signature_max_age := SERVER_CONFIG.REQUEST_SIGNATURE_MAX_AGE
+	defer func() {
+		SERVER_CONFIG.REQUEST_SIGNATURE_MAX_AGE = backup_request_signature_max_age
+	}()
+
+	// Test valid case
+	assert.NotPanics(t, checkRequestSignatureMaxAge)
+
+	// Test invalid case
+	SERVER_CONFIG.REQUEST_SIGNATURE_MAX_AGE = 0
+	assert.PanicsWithError(t, "REQUEST_SIGNATURE_MAX_AGE should be bigger than 0", checkRequestSignatureMaxAge)
+}
+
+func TestCheckAdminListener(t *testing.T) {
+	backup_server_config := SERVER_CONFIG
+	defer func() {
+		SERVER_CONFIG = backup_server_config
+	}()
+
+	SERVER_CONFIG.PORT = ":33333"
+	SERVER_CONFIG.TLS_PORT = ":33334"
+
+	// Test disabled case
+	SERVER_CONFIG.ADMIN_ADDRESS = ""
+	SERVER_CONFIG.ADMIN_USE_TLS = true
+	SERVER_CONFIG.ADMIN_TLS_CERT_PATH = ""
+	assert.NotPanics(t, checkAdminListener)
+
+	// Test valid case
+	SERVER_CONFIG.ADMIN_ADDRESS = "127.0.0.1:33335"
+	SERVER_CONFIG.ADMIN_USE_TLS = false
+	assert.NotPanics(t, checkAdminListener)
+
+	SERVER_CONFIG.ADMIN_USE_TLS = true
+	SERVER_CONFIG.ADMIN_TLS_CERT_PATH = "./self_cert/admin.crt"
+	SERVER_CONFIG.ADMIN_TLS_KEY_PATH = "./self_cert/admin.key"
+	assert.NotPanics(t, checkAdminListener)
+
+	// Test invalid case
+	SERVER_CONFIG.ADMIN_TLS_KEY_PATH = ""
+	assert.PanicsWithError(t,
+		"ADMIN_TLS_CERT_PATH and ADMIN_TLS_KEY_PATH should not be empty when ADMIN_USE_TLS is enabled",
+		checkAdminListener,
+	)
+
+	SERVER_CONFIG.ADMIN_ADDRESS = ":33333"
+	assert.PanicsWithError(t, "ADMIN_ADDRESS should be different from PORT and TLS_PORT", checkAdminListener)
+}
+
+func TestCheckAdminMTLS(t *testing.T) {
+	backup_server_config := SERVER_CONFIG
+	backup_permissions := ALLOWEDLIST.PERMISSIONS
+	defer func() {
+		SERVER_CONFIG = backup_server_config
+		ALLOWEDLIST.PERMISSIONS = backup_permissions
+	}()
+
+	// Test disabled case
+	SERVER_CONFIG.USE_ADMIN_MTLS = false
+	SERVER_CONFIG.ADMIN_MTLS_CLIENT_CA_PATH = ""
+	assert.NotPanics(t, checkAdminMTLS)
+
+	// Test valid case
+	SERVER_CONFIG.USE_ADMIN_MTLS = true
+	SERVER_CONFIG.ADMIN_ADDRESS = "127.0.0.1:33335"
+	SERVER_CONFIG.ADMIN_USE_TLS = true
+	SERVER_CONFIG.ADMIN_MTLS_CLIENT_CA_PATH = "./self_cert/admin_ca.crt"
+	ALLOWEDLIST.PERMISSIONS = []Permission{
+		{NAME: "admin 0", ROLE: RoleOwner, CERT_SUBJECT: "CN=admin0"},
+		{NAME: "admin 1", ROLE: RoleViewer},
+		{NAME: "admin 2", ROLE: RoleViewer},
+	}
+	assert.NotPanics(t, checkAdminMTLS)
+
+	// Test invalid case
+	SERVER_CONFIG.ADMIN_MTLS_CLIENT_CA_PATH = ""
+	assert.PanicsWithError(t,
+		"ADMIN_MTLS_CLIENT_CA_PATH should not be empty when USE_ADMIN_MTLS is enabled",
+		checkAdminMTLS,
+	)
+
+	SERVER_CONFIG.ADMIN_MTLS_CLIENT_CA_PATH = "./self_cert/admin_ca.crt"
+	SERVER_CONFIG.ADMIN_USE_TLS = false
+	assert.PanicsWithError(t,
+		"USE_ADMIN_MTLS requires the admin listener with TLS (ADMIN_ADDRESS and ADMIN_USE_TLS)",
+		checkAdminMTLS,
+	)
+
+	SERVER_CONFIG.ADMIN_USE_TLS = true
+	SERVER_CONFIG.ADMIN_ADDRESS = ""
+	assert.PanicsWithError(t,
+		"USE_ADMIN_MTLS requires the admin listener with TLS (ADMIN_ADDRESS and ADMIN_USE_TLS)",
+		checkAdminMTLS,
+	)
+
+	SERVER_CONFIG.ADMIN_ADDRESS = "127.0.0.1:33335"
+	ALLOWEDLIST.PERMISSIONS = append(ALLOWEDLIST.PERMISSIONS,
+		Permission{NAME: "admin 3", ROLE: RoleViewer, CERT_SUBJECT: "CN=admin0"},
+	)
+	assert.PanicsWithError(t, "CERT_SUBJECT of [admin 3] is duplicated", checkAdminMTLS)
+}
+
+func TestFindPermissionByCertSubject(t *testing.T) {
+	backup_permissions := ALLOWEDLIST.PERMISSIONS
+	defer func() {
+		ALLOWEDLIST.PERMISSIONS = backup_permissions
+	}()
+
+	ALLOWEDLIST.PERMISSIONS = []Permission{
+		{NAME: "admin 0", ROLE: RoleOwner, CERT_SUBJECT: "CN=admin0,O=Example"},
+		{NAME: "admin 1", ROLE: RoleViewer},
+	}
+
+	permission, ok := FindPermissionByCertSubject("CN=admin0,O=Example")
+	assert.True(t, ok)
+	assert.Equal(t, "admin 0", permission.NAME)
+	assert.Equal(t, RoleOwner, permission.ROLE)
+
+	_, ok = FindPermissionByCertSubject("CN=admin0")
+	assert.False(t, ok)
+
+	_, ok = FindPermissionByCertSubject("")
+	assert.False(t, ok)
+}
+
+func TestParseIPRanges(t *testing.T) {
+	ranges, err := ParseIPRanges([]string{"10.0.0.0/8", "192.168.1.10", "2001:db8::/32", "::1", "::ffff:172.16.0.1"})
+	assert.Nil(t, err)
+	assert.Equal(t, []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("192.168.1.10/32"),
+		netip.MustParsePrefix("2001:db8::/32"),
+		netip.MustParsePrefix("::1/128"),
+		netip.MustParsePrefix("172.16.0.1/32"),
+	}, ranges)
+
+	ranges, err = ParseIPRanges([]string{"*"})
+	assert.Nil(t, err)
+	assert.Equal(t, []netip.Prefix{netip.MustParsePrefix("0.0.0.0/0"), netip.MustParsePrefix("::/0")}, ranges)
+
+	// Host bits of the CIDR ranges are masked.
+	ranges, err = ParseIPRanges([]string{"10.1.2.3/8"})
+	assert.Nil(t, err)
+	assert.Equal(t, []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}, ranges)
+
+	_, err = ParseIPRanges([]string{"localhost"})
+	assert.EqualError(t, err, "invalid IP or CIDR: localhost")
+
+	_, err = ParseIPRanges([]string{"10.0.0.0/33"})
+	assert.EqualError(t, err, "invalid IP or CIDR: 10.0.0.0/33")
+}
+
+func TestCheckIPRanges(t *testing.T) {
+	backup_server_config := SERVER_CONFIG
+	backup_ip_ranges := IP_RANGES
+	defer func() {
+		SERVER_CONFIG = backup_server_config
+		IP_RANGES = backup_ip_ranges
+	}()
+
+	// Test valid case
+	SERVER_CONFIG.ALLOWED_IPs = []string{"*"}
+	SERVER_CONFIG.DENIED_IPs = []string{"10.0.0.1"}
+	SERVER_CONFIG.TRUSTED_PROXIES = []string{"127.0.0.1", "::1"}
+	assert.NotPanics(t, checkIPRanges)
+	assert.Len(t, IP_RANGES.ALLOWED_IPs, 2)
+	assert.Equal(t, []netip.Prefix{netip.MustParsePrefix("10.0.0.1/32")}, IP_RANGES.DENIED_IPs)
+	assert.Len(t, IP_RANGES.TRUSTED_PROXIES, 2)
+
+	// Test invalid case
+	SERVER_CONFIG.DENIED_IPs = []string{"10.0.0"}
+	assert.PanicsWithError(t, "DENIED_IPs contains an invalid IP or CIDR: 10.0.0", checkIPRanges)
+
+	SERVER_CONFIG.DENIED_IPs = nil
+	SERVER_CONFIG.TRUSTED_PROXIES = []string{"proxy"}
+	assert.PanicsWithError(t, "TRUSTED_PROXIES contains an invalid IP or CIDR: proxy", checkIPRanges)
+}
+
+func TestCheckAdminLockout(t *testing.T) {
+	backup_server_config := SERVER_CONFIG
+	defer func() {
+		SERVER_CONFIG = backup_server_config
+	}()
+
+	// Test disabled case
+	SERVER_CONFIG.ADMIN_LOCKOUT_THRESHOLD = 0
+	SERVER_CONFIG.ADMIN_LOCKOUT_BASE_TIME = 0
+	SERVER_CONFIG.ADMIN_LOCKOUT_MAX_TIME = 0
+	assert.NotPanics(t, checkAdminLockout)
+
+	// Test valid case
+	SERVER_CONFIG.ADMIN_LOCKOUT_THRESHOLD = 5
+	SERVER_CONFIG.ADMIN_LOCKOUT_BASE_TIME = 30
+	SERVER_CONFIG.ADMIN_LOCKOUT_MAX_TIME = 3600
+	assert.NotPanics(t, checkAdminLockout)
+
+	// Test invalid case
+	SERVER_CONFIG.ADMIN_LOCKOUT_THRESHOLD = -1
+	assert.PanicsWithError(t, "ADMIN_LOCKOUT_THRESHOLD should be bigger or equal to 0", checkAdminLockout)
+
+	SERVER_CONFIG.ADMIN_LOCKOUT_THRESHOLD = 5
+	SERVER_CONFIG.ADMIN_LOCKOUT_BASE_TIME = 0
+	assert.PanicsWithError(t, "ADMIN_LOCKOUT_BASE_TIME should be bigger than 0", checkAdminLockout)
+
+	SERVER_CONFIG.ADMIN_LOCKOUT_BASE_TIME = 30
+	SERVER_CONFIG.ADMIN_LOCKOUT_MAX_TIME = 10
+	assert.PanicsWithError(t, "ADMIN_LOCKOUT_MAX_TIME should be bigger or equal to ADMIN_LOCKOUT_BASE_TIME", checkAdminLockout)
+}
+
+func TestCheckApplyRateLimits(t *testing.T) {
+	backup_server_config := SERVER_CONFIG
+	defer func() {
+		SERVER_CONFIG = backup_server_config
+	}()
+
+	// Test valid case
+	SERVER_CONFIG.APPLY_RATE_LIMIT_PER_IP = 60
+	SERVER_CONFIG.APPLY_RATE_LIMIT_PER_KEY = 0
+	SERVER_CONFIG.APPLY_RATE_LIMIT_PER_SN = 10
+	assert.NotPanics(t, checkApplyRateLimits)
+
+	// Test invalid case
+	SERVER_CONFIG.APPLY_RATE_LIMIT_PER_SN = -1
+	assert.PanicsWithError(t,
+		"APPLY_RATE_LIMIT_PER_IP, APPLY_RATE_LIMIT_PER_KEY and APPLY_RATE_LIMIT_PER_SN should be bigger or equal to 0",
+		checkApplyRateLimits,
+	)
+}
+
+func TestCheckRunTimeCodeType(t *testing.T) {
+	backup_server_config := SERVER_CONFIG
+	defer func() {
+		SERVER_CONFIG = backup_server_config
+	}()
+
+	// Test valid case
+	for _, codeType := range []string{"", "console", "totp", "TOTP"} {
+		SERVER_CONFIG.RUNTIME_CODE_TYPE = codeType
+		assert.NotPanics(t, checkRunTimeCodeType)
+	}
+
+	// Test invalid case
+	SERVER_CONFIG.RUNTIME_CODE_TYPE = "sms"
+	assert.PanicsWithError(t, "RUNTIME_CODE_TYPE is not valid (Require: console, totp)", checkRunTimeCodeType)
+}
+
+func TestUseTOTPRuntimeCode(t *testing.T) {
+	backup_server_config := SERVER_CONFIG
+	defer func() {
+		SERVER_CONFIG = backup_server_config
+	}()
+
+	SERVER_CONFIG.USE_RUNTIME_CODE = true
+	SERVER_CONFIG.RUNTIME_CODE_TYPE = "Totp"
+	assert.True(t, UseTOTPRuntimeCode())
+
+	SERVER_CONFIG.RUNTIME_CODE_TYPE = "console"
+	assert.False(t, UseTOTPRuntimeCode())
+
+	SERVER_CONFIG.USE_RUNTIME_CODE = false
+	SERVER_CONFIG.RUNTIME_CODE_TYPE = "totp"
+	assert.False(t, UseTOTPRuntimeCode())
+}
+
+func TestCheckDatabaseDriver(t *testing.T) {
+	backup_db_config := DB_CONFIG
+	defer func() {
+		DB_CONFIG = backup_db_config
+	}()
+
+	// Test valid case
+	DB_CONFIG.SQLITE_PATH = ""
+	for _, driver := range []string{"", "postgres", "Postgres"} {
+		DB_CONFIG.DRIVER = driver
+		assert.NotPanics(t, checkDatabaseDriver)
+		assert.False(t, UseSQLite())
+	}
+
+	DB_CONFIG.DRIVER = "SQLite"
+	DB_CONFIG.SQLITE_PATH = "local/quickcerts.db"
+	assert.NotPanics(t, checkDatabaseDriver)
+	assert.True(t, UseSQLite())
+
+	// Test invalid case
+	DB_CONFIG.SQLITE_PATH = ""
+	assert.PanicsWithError(t, "SQLITE_PATH is required when DRIVER is sqlite", checkDatabaseDriver)
+
+	DB_CONFIG.DRIVER = "mysql"
+	assert.PanicsWithError(t, "DRIVER is not valid (Require: postgres, sqlite)", checkDatabaseDriver)
+}
+
+func TestCheckDatabaseSSLMode(t *testing.T) {
+	backup_db_config := DB_CONFIG
+	defer func() {
+		DB_CONFIG = backup_db_config
+	}()
+
+	// Test valid case
+	for _, mode := range []string{"disable", "require", "Verify-CA", "verify-full"} {
+		DB_CONFIG.SSL_MODE = mode
+		assert.NotPanics(t, checkDatabaseSSLMode)
+	}
+
+	// Test invalid case
+	DB_CONFIG.SSL_MODE = "prefer"
+	assert.PanicsWithError(t, "SSL_MODE is not valid (Require: disable, require, verify-ca, verify-full)", checkDatabaseSSLMode)
+}
+
+func TestCheckDatabasePool(t *testing.T) {
+	backup_db_config := DB_CONFIG
+	defer func() {
+		DB_CONFIG = backup_db_config
+	}()
+
+	// Test valid case
+	DB_CONFIG.MAX_OPEN_CONNS = 0
+	DB_CONFIG.MAX_IDLE_CONNS = 0
+	DB_CONFIG.CONN_MAX_LIFETIME = 0
+	for _, unit := range []string{"hour", "Minute", "second"} {
+		DB_CONFIG.CONN_MAX_LIFETIME_UNIT = unit
+		assert.NotPanics(t, checkDatabasePool)
+	}
+
+	// Test invalid case
+	DB_CONFIG.CONN_MAX_LIFETIME_UNIT = "day"
+	assert.PanicsWithError(t, "CONN_MAX_LIFETIME_UNIT is not valid (Require: hour, minute, second)", checkDatabasePool)
+
+	DB_CONFIG.CONN_MAX_LIFETIME = -1
+	assert.PanicsWithError(t, "CONN_MAX_LIFETIME should be bigger or equal to 0", checkDatabasePool)
+
+	DB_CONFIG.MAX_IDLE_CONNS = -1
+	assert.PanicsWithError(t, "MAX_IDLE_CONNS should be bigger or equal to 0", checkDatabasePool)
+
+	DB_CONFIG.MAX_OPEN_CONNS = -1
+	assert.PanicsWithError(t, "MAX_OPEN_CONNS should be bigger or equal to 0", checkDatabasePool)
+}
+
+func TestCheckDatabaseQueryTimeout(t *testing.T) {
+	backup_db_config := DB_CONFIG
+	defer func() {
+		DB_CONFIG = backup_db_config
+	}()
+
+	// Test valid case
+	DB_CONFIG.QUERY_TIMEOUT = 0
+	for _, unit := range []string{"minute", "Second", "millisecond"} {
+		DB_CONFIG.QUERY_TIMEOUT_UNIT = unit
+		assert.NotPanics(t, checkDatabaseQueryTimeout)
+	}
+
+	// Test invalid case
+	DB_CONFIG.QUERY_TIMEOUT_UNIT = "hour"
+	assert.PanicsWithError(t, "QUERY_TIMEOUT_UNIT is not valid (Require: minute, second, millisecond)", checkDatabaseQueryTimeout)
+
+	DB_CONFIG.QUERY_TIMEOUT = -1
+	assert.PanicsWithError(t, "QUERY_TIMEOUT should be bigger or equal to 0", checkDatabaseQueryTimeout)
+}
+
+func TestCheckCacheMode(t *testing.T) {
+	backup_cache_config := CACHE_CONFIG
+	defer func() {
+		CACHE_CONFIG = backup_cache_config
+	}()
+
+	// Test valid case
+	CACHE_CONFIG.ADDRS = nil
+	CACHE_CONFIG.MASTER_NAME = ""
+	CACHE_CONFIG.DB = 1
+	for _, mode := range []string{"", "standalone", "Standalone"} {
+		CACHE_CONFIG.MODE = mode
+		assert.NotPanics(t, checkCacheMode)
+		assert.NotPanics(t, checkCacheDB)
+	}
+
+	CACHE_CONFIG.MODE = "sentinel"
+	CACHE_CONFIG.ADDRS = []string{"localhost:26379"}
+	CACHE_CONFIG.MASTER_NAME = "qcs-master"
+	assert.NotPanics(t, checkCacheMode)
+
+	CACHE_CONFIG.MODE = "cluster"
+	CACHE_CONFIG.DB = 0
+	assert.NotPanics(t, checkCacheMode)
+
+	// Test invalid case
+	CACHE_CONFIG.DB = 1
+	assert.PanicsWithError(t, "DB should be 0 when MODE is cluster", checkCacheMode)
+
+	CACHE_CONFIG.ADDRS = nil
+	assert.PanicsWithError(t, "ADDRS is required when MODE is cluster", checkCacheMode)
+
+	CACHE_CONFIG.MODE = "sentinel"
+	assert.PanicsWithError(t, "ADDRS and MASTER_NAME are required when MODE is sentinel", checkCacheMode)
+
+	CACHE_CONFIG.MODE = "replica"
+	assert.PanicsWithError(t, "MODE is not valid (Require: standalone, sentinel, cluster)", checkCacheMode)
+
+	CACHE_CONFIG.DB = -1
+	assert.PanicsWithError(t, "DB should be bigger or equal to 0", checkCacheDB)
+}
+
+func TestCheckDatabaseConnectRetries(t *testing.T) {
+	backup_db_config := DB_CONFIG
+	defer func() {
+		DB_CONFIG = backup_db_config
+	}()
+
+	// Test valid case
+	DB_CONFIG.CONNECT_RETRIES = 0
+	assert.NotPanics(t, checkDatabaseConnectRetries)
+
+	// Test invalid case
+	DB_CONFIG.CONNECT_RETRIES = -1
+	assert.PanicsWithError(t, "CONNECT_RETRIES should be bigger or equal to 0", checkDatabaseConnectRetries)
+}
@@ -0,0 +1,129 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/mmq88/quickcerts/model"
+)
+
+// Bind the S/N to the key of the device, and record the attempt in the activation history if the S/N exists.
+//
+// The S/N row is locked until the binding and the history are committed in one transaction, so when devices race for
+// one S/N exactly one of them is bound, and the others get BindStatusTaken.
+//
+// allowsProduct reports if the caller may apply the S/N of the given product, nil allows all products.
+//
+// The error is only returned when the database fails, the result of the binding is reported by the status.
+func BindCertificate(
+	ctx context.Context, activation model.CertActivation, allowsProduct func(product string) bool,
+) (model.BindStatus, error) {
+	if db == nil {
+		return "", ErrDBNotConnected
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+
+	defer tx.Rollback()
+
+	var key, product sql.NullString
+	var revoked bool
+	err = tx.QueryRowContext(ctx,
+		"SELECT key, product, revoked_at IS NOT NULL FROM certs WHERE sn = $1 FOR UPDATE", activation.SerialNumber,
+	).Scan(&key, &product, &revoked)
+
+	var status model.BindStatus
+
+	switch {
+	case err == sql.ErrNoRows:
+		status = model.BindStatusNotFound
+	case err != nil:
+		return "", err
+	case allowsProduct != nil && !allowsProduct(product.String):
+		status = model.BindStatusNotAllowed
+	case revoked:
+		status = model.BindStatusRevoked
+	case !key.Valid:
+		status = model.BindStatusBound
+	case key.String == activation.Key:
+		status = model.BindStatusRebound
+	default:
+		status = model.BindStatusTaken
+	}
+
+	if status == model.BindStatusBound {
+		_, err = tx.ExecContext(ctx, "UPDATE certs SET key = $1 WHERE sn = $2", activation.Key, activation.SerialNumber)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// The attempts for the unknown S/N(s) are not recorded, so guessing S/N(s) does not grow the history.
+	if status == model.BindStatusNotFound {
+		return status, nil
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO cert_activations (sn, key, status, client_key_id, ip)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''))
+	`, activation.SerialNumber, activation.Key, status, activation.ClientKeyID, activation.IP)
+
+	if err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+
+	return status, nil
+}
+
+// Get the activation history of the given S/N, the oldest first.
+func GetCertActivations(ctx context.Context, sn string) ([]model.CertActivation, error) {
+	if db == nil {
+		return nil, ErrDBNotConnected
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT sn, key, status, client_key_id, ip, created_at
+		FROM cert_activations
+		WHERE sn = $1
+		ORDER BY id
+	`, sn)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	activations := []model.CertActivation{}
+
+	for rows.Next() {
+		var activation model.CertActivation
+		var clientKeyID, ip sql.NullString
+
+		err := rows.Scan(
+			&activation.SerialNumber, &activation.Key, &activation.Status, &clientKeyID, &ip, &activation.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		activation.ClientKeyID = clientKeyID.String
+		activation.IP = ip.String
+		activations = append(activations, activation)
+	}
+
+	return activations, rows.Err()
+}
@@ -0,0 +1,158 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	cfg "github.com/mmq88/quickcerts/configs"
+	"github.com/mmq88/quickcerts/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBindCertificate(t *testing.T) {
+	ctx := context.Background()
+
+	backupHost := cfg.DB_CONFIG.HOST
+	backupPort := cfg.DB_CONFIG.PORT
+	defer func() {
+		cfg.DB_CONFIG.HOST = backupHost
+		cfg.DB_CONFIG.PORT = backupPort
+	}()
+
+	// Test invalid case
+	_, err := BindCertificate(ctx, model.CertActivation{SerialNumber: "XXXX-XXXX-XXXX-XXXX-XXXX-XXXX", Key: "key"}, nil)
+	assert.Equal(t, "currently not connecting the database", err.Error())
+
+	// Test valid case
+	// Uses docker-compose config
+	cfg.DB_CONFIG.HOST = "localhost"
+	cfg.DB_CONFIG.PORT = 33332
+
+	err = ConnectDB()
+	assert.Nil(t, err)
+	defer func() {
+		err = DisconnectDB()
+		assert.Nil(t, err)
+	}()
+
+	sn := "XXXX-XXXX-XXXX-XXXX-XXXX-XXXX"
+	err = AddNewSN(ctx, sn, "quickcerts-pro")
+	assert.Nil(t, err)
+
+	activation := model.CertActivation{SerialNumber: sn, Key: "valid key", ClientKeyID: "client", IP: "203.0.113.7"}
+	allowsPro := model.ClientKey{Products: []string{"quickcerts-pro"}}.AllowsProduct
+	allowsLite := model.ClientKey{Products: []string{"quickcerts-lite"}}.AllowsProduct
+
+	status, err := BindCertificate(ctx, activation, allowsLite)
+	assert.Nil(t, err)
+	assert.Equal(t, model.BindStatusNotAllowed, status)
+
+	status, err = BindCertificate(ctx, activation, allowsPro)
+	assert.Nil(t, err)
+	assert.Equal(t, model.BindStatusBound, status)
+
+	// Apply again with the same key should be ok
+	status, err = BindCertificate(ctx, activation, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, model.BindStatusRebound, status)
+
+	// Test invalid case
+	status, err = BindCertificate(ctx, model.CertActivation{SerialNumber: sn, Key: "another key"}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, model.BindStatusTaken, status)
+
+	status, err = BindCertificate(ctx, model.CertActivation{SerialNumber: "invalid sn", Key: "key"}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, model.BindStatusNotFound, status)
+
+	// Every attempt for the existing S/N is recorded in the activation history
+	activations, err := GetCertActivations(ctx, sn)
+	assert.Nil(t, err)
+	if assert.Len(t, activations, 4) {
+		assert.Equal(t, model.BindStatusBound, activations[1].Status)
+		assert.Equal(t, "client", activations[1].ClientKeyID)
+		assert.Equal(t, "203.0.113.7", activations[1].IP)
+		assert.Equal(t, "", activations[3].ClientKeyID)
+	}
+
+	// The attempts for the unknown S/N(s) are not recorded
+	activations, err = GetCertActivations(ctx, "invalid sn")
+	assert.Nil(t, err)
+	assert.Empty(t, activations)
+
+	// Delete the added test data
+	err = DeleteTestingData(ctx, "DELETE FROM certs WHERE sn = $1", sn)
+	assert.Nil(t, err)
+	err = DeleteTestingData(ctx, "DELETE FROM cert_activations WHERE sn = $1", sn)
+	assert.Nil(t, err)
+}
+
+func TestBindCertificateRace(t *testing.T) {
+	ctx := context.Background()
+
+	backupHost := cfg.DB_CONFIG.HOST
+	backupPort := cfg.DB_CONFIG.PORT
+	defer func() {
+		cfg.DB_CONFIG.HOST = backupHost
+		cfg.DB_CONFIG.PORT = backupPort
+	}()
+
+	// Uses docker-compose config
+	cfg.DB_CONFIG.HOST = "localhost"
+	cfg.DB_CONFIG.PORT = 33332
+
+	err := ConnectDB()
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer func() {
+		err = DisconnectDB()
+		assert.Nil(t, err)
+	}()
+
+	sn := "XXXX-XXXX-XXXX-XXXX-XXXX-XXXX"
+	err = AddNewSN(ctx, sn, "")
+	assert.Nil(t, err)
+
+	// Two devices race for one S/N many times, exactly one of them is bound each time.
+	for round := 0; round < 20; round++ {
+		_, err = db.Exec("UPDATE certs SET key = NULL WHERE sn = $1", sn)
+		assert.Nil(t, err)
+
+		statuses := make([]model.BindStatus, 2)
+		start := make(chan struct{})
+		var wg sync.WaitGroup
+
+		for i := range statuses {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				<-start
+
+				status, err := BindCertificate(ctx,
+					model.CertActivation{SerialNumber: sn, Key: fmt.Sprintf("device %d", i)}, nil,
+				)
+				assert.Nil(t, err)
+				statuses[i] = status
+			}(i)
+		}
+
+		close(start)
+		wg.Wait()
+
+		assert.ElementsMatch(t, []model.BindStatus{model.BindStatusBound, model.BindStatusTaken}, statuses)
+	}
+
+	activations, err := GetCertActivations(ctx, sn)
+	assert.Nil(t, err)
+	assert.Len(t, activations, 40)
+
+	// Delete the added test data
+	err = DeleteTestingData(ctx, "DELETE FROM certs WHERE sn = $1", sn)
+	assert.Nil(t, err)
+	err = DeleteTestingData(ctx, "DELETE FROM cert_activations WHERE sn = $1", sn)
+	assert.Nil(t, err)
+}
@@ -0,0 +1,357 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	cfg "github.com/mmq88/quickcerts/configs"
+	"github.com/mmq88/quickcerts/model"
+	"github.com/mmq88/quickcerts/utils"
+)
+
+const (
+	// The key of the advisory lock serializing the bootstrap of the admin tokens.
+	adminTokenLockKey = 0x51435342
+
+	adminTokenColumns = "id, name, role, salt, token_hash, signing_public_key, totp_secret, created_by, " +
+		"created_at, expires_at, revoked_at, last_used_at"
+)
+
+// Either *sql.Row or *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// Either *sql.DB or *sql.Tx.
+type rowQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// Create a new admin token with a new TOTP secret and signing secret, and return its record along with the token
+// and the signing secret.
+//
+// The token and the signing secret are only returned here, the database keeps the salted hash of the token and the
+// public key of the signing secret.
+func CreateAdminToken(
+	ctx context.Context, name string, role string, createdBy string, expiresAt *time.Time,
+) (model.AdminToken, string, string, error) {
+	if db == nil {
+		return model.AdminToken{}, "", "", ErrDBNotConnected
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	token, err := utils.GenerateToken()
+	if err != nil {
+		return model.AdminToken{}, "", "", err
+	}
+
+	totpSecret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return model.AdminToken{}, "", "", err
+	}
+
+	signingSecret, signingPublicKey, err := utils.GenerateSigningSecret()
+	if err != nil {
+		return model.AdminToken{}, "", "", err
+	}
+
+	record, err := insertAdminToken(ctx, db, name, role, createdBy, token, totpSecret, signingPublicKey, expiresAt)
+	if err != nil {
+		return model.AdminToken{}, "", "", err
+	}
+
+	return record, token, signingSecret, nil
+}
+
+// Add the permissions of allowlist.toml as admin tokens if there is no admin token in the database yet.
+//
+// Returns the number of added tokens. The permissions without tokens are skipped, and the TOTP secrets and signing
+// public keys of the permissions are imported along with the tokens.
+func BootstrapAdminTokens(ctx context.Context, permissions []cfg.Permission) (int, error) {
+	if db == nil {
+		return 0, ErrDBNotConnected
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", adminTokenLockKey); err != nil {
+		return 0, err
+	}
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM admin_tokens)").Scan(&exists); err != nil {
+		return 0, err
+	}
+
+	if exists {
+		return 0, nil
+	}
+
+	added := 0
+
+	for _, permission := range permissions {
+		if permission.TOKEN == "" {
+			continue
+		}
+
+		_, err := insertAdminToken(
+			ctx, tx, permission.NAME, permission.ROLE, "allowlist.toml", permission.TOKEN, permission.TOTP_SECRET,
+			permission.SIGNING_PUBLIC_KEY, nil,
+		)
+		if err != nil {
+			return 0, err
+		}
+
+		added++
+	}
+
+	return added, tx.Commit()
+}
+
+// Find the active admin token matching the given token, and update the time it was last used.
+//
+// The tokens added before the lookup hashes were introduced are compared one by one, and get their lookup hashes
+// once matched.
+func AuthenticateAdminToken(ctx context.Context, token string) (model.AdminToken, error) {
+	if db == nil {
+		return model.AdminToken{}, ErrDBNotConnected
+	}
+
+	if len(token) < cfg.MinTokenLength {
+		return model.AdminToken{}, ErrAdminTokenInvalid
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	lookupHash := utils.TokenLookupHash(token)
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT `+adminTokenColumns+` FROM admin_tokens
+		WHERE (lookup_hash = $1 OR lookup_hash IS NULL) AND revoked_at IS NULL
+			AND (expires_at IS NULL OR expires_at > NOW())
+	`, lookupHash)
+
+	if err != nil {
+		return model.AdminToken{}, err
+	}
+
+	defer rows.Close()
+
+	var matched *model.AdminToken
+
+	// Every candidate is compared, so the time taken does not reveal which one matched.
+	for rows.Next() {
+		record, err := scanAdminToken(rows)
+		if err != nil {
+			return model.AdminToken{}, err
+		}
+
+		if utils.VerifyToken(token, record.Salt, record.TokenHash) && matched == nil {
+			matched = &record
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return model.AdminToken{}, err
+	}
+
+	if matched == nil {
+		return model.AdminToken{}, ErrAdminTokenInvalid
+	}
+
+	_, err = db.ExecContext(ctx,
+		"UPDATE admin_tokens SET last_used_at = NOW(), lookup_hash = $2 WHERE id = $1", matched.ID, lookupHash,
+	)
+	if err != nil {
+		return model.AdminToken{}, err
+	}
+
+	return *matched, nil
+}
+
+// Find the active admin token with the given ID whose request signature is accepted by verify, and update the time
+// it was last used.
+//
+// The signed requests only carry the ID of the token, so the token itself is never sent along with them.
+func AuthenticateSignedAdminToken(
+	ctx context.Context, id string, verify func(token model.AdminToken) bool,
+) (model.AdminToken, error) {
+	if db == nil {
+		return model.AdminToken{}, ErrDBNotConnected
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	row := db.QueryRowContext(ctx, `
+		SELECT `+adminTokenColumns+` FROM admin_tokens
+		WHERE id = $1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())
+	`, id)
+	record, err := scanAdminToken(row)
+
+	if err == sql.ErrNoRows {
+		return model.AdminToken{}, ErrAdminTokenInvalid
+	} else if err != nil {
+		return model.AdminToken{}, err
+	}
+
+	if !verify(record) {
+		return model.AdminToken{}, ErrAdminTokenInvalid
+	}
+
+	if _, err := db.ExecContext(ctx, "UPDATE admin_tokens SET last_used_at = NOW() WHERE id = $1", id); err != nil {
+		return model.AdminToken{}, err
+	}
+
+	return record, nil
+}
+
+// Get all admin tokens, the newest first.
+func GetAllAdminTokens(ctx context.Context) ([]model.AdminToken, error) {
+	if db == nil {
+		return nil, ErrDBNotConnected
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, "SELECT "+adminTokenColumns+" FROM admin_tokens ORDER BY created_at DESC")
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var tokens []model.AdminToken
+
+	for rows.Next() {
+		record, err := scanAdminToken(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		tokens = append(tokens, record)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// Revoke the given admin token, a revoked token can no longer be used.
+func RevokeAdminToken(ctx context.Context, id string) (model.AdminToken, error) {
+	return updateAdminToken(ctx, "UPDATE admin_tokens SET revoked_at = COALESCE(revoked_at, NOW()) WHERE id = $1", id)
+}
+
+// Set the expiration time of the given admin token.
+func ExpireAdminToken(ctx context.Context, id string, expiresAt time.Time) (model.AdminToken, error) {
+	return updateAdminToken(ctx, "UPDATE admin_tokens SET expires_at = $2 WHERE id = $1", id, expiresAt)
+}
+
+// Replace the TOTP secret of the given admin token with a new one, and return the record along with the secret.
+func ResetAdminTokenTOTP(ctx context.Context, id string) (model.AdminToken, string, error) {
+	totpSecret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return model.AdminToken{}, "", err
+	}
+
+	record, err := updateAdminToken(ctx, "UPDATE admin_tokens SET totp_secret = $2 WHERE id = $1", id, totpSecret)
+	if err != nil {
+		return model.AdminToken{}, "", err
+	}
+
+	return record, totpSecret, nil
+}
+
+func updateAdminToken(ctx context.Context, stmt string, id string, args ...any) (model.AdminToken, error) {
+	if db == nil {
+		return model.AdminToken{}, ErrDBNotConnected
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	row := db.QueryRowContext(ctx, stmt+" RETURNING "+adminTokenColumns, append([]any{id}, args...)...)
+	record, err := scanAdminToken(row)
+
+	if err == sql.ErrNoRows {
+		return model.AdminToken{}, ErrAdminTokenNotFound
+	} else if err != nil {
+		return model.AdminToken{}, err
+	}
+
+	return record, nil
+}
+
+func insertAdminToken(
+	ctx context.Context, q rowQuerier, name string, role string, createdBy string, token string, totpSecret string,
+	signingPublicKey string, expiresAt *time.Time,
+) (model.AdminToken, error) {
+	if len(token) < cfg.MinTokenLength {
+		return model.AdminToken{}, ErrTokenTooShort
+	}
+
+	id, err := utils.GenerateID()
+	if err != nil {
+		return model.AdminToken{}, err
+	}
+
+	salt, err := utils.GenerateID()
+	if err != nil {
+		return model.AdminToken{}, err
+	}
+
+	row := q.QueryRowContext(ctx, `
+		INSERT INTO admin_tokens (
+			id, name, role, lookup_hash, salt, token_hash, signing_public_key, totp_secret, created_by, expires_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''), $10)
+		RETURNING `+adminTokenColumns,
+		id, name, role, utils.TokenLookupHash(token), salt, utils.HashToken(token, salt), signingPublicKey, totpSecret,
+		createdBy, expiresAt,
+	)
+
+	return scanAdminToken(row)
+}
+
+func scanAdminToken(row rowScanner) (model.AdminToken, error) {
+	var record model.AdminToken
+	var tmpCreatedBy sql.NullString
+	var tmpExpiresAt, tmpRevokedAt, tmpLastUsedAt sql.NullTime
+
+	err := row.Scan(
+		&record.ID, &record.Name, &record.Role, &record.Salt, &record.TokenHash, &record.SigningPublicKey,
+		&record.TOTPSecret, &tmpCreatedBy, &record.CreatedAt, &tmpExpiresAt, &tmpRevokedAt, &tmpLastUsedAt,
+	)
+
+	if err != nil {
+		return model.AdminToken{}, err
+	}
+
+	record.CreatedBy = tmpCreatedBy.String
+	if tmpExpiresAt.Valid {
+		record.ExpiresAt = &tmpExpiresAt.Time
+	}
+	if tmpRevokedAt.Valid {
+		record.RevokedAt = &tmpRevokedAt.Time
+	}
+	if tmpLastUsedAt.Valid {
+		record.LastUsedAt = &tmpLastUsedAt.Time
+	}
+
+	return record, nil
+}
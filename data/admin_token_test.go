@@ -0,0 +1,116 @@
+package data
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	cfg "github.com/mmq88/quickcerts/configs"
+	"github.com/mmq88/quickcerts/utils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminToken(t *testing.T) {
+	ctx := context.Background()
+
+	backupHost := cfg.DB_CONFIG.HOST
+	backupPort := cfg.DB_CONFIG.PORT
+	defer func() {
+		cfg.DB_CONFIG.HOST = backupHost
+		cfg.DB_CONFIG.PORT = backupPort
+	}()
+
+	// Test invalid case
+	_, _, _, err := CreateAdminToken(ctx, "tester", cfg.RoleViewer, "tester", nil)
+	assert.Equal(t, "currently not connecting the database", err.Error())
+
+	_, err = AuthenticateAdminToken(ctx, "token")
+	assert.Equal(t, "currently not connecting the database", err.Error())
+
+	// Test valid case
+	// Uses docker-compose config
+	cfg.DB_CONFIG.HOST = "localhost"
+	cfg.DB_CONFIG.PORT = 33332
+
+	err = ConnectDB()
+	assert.Nil(t, err)
+	defer func() {
+		err = DisconnectDB()
+		assert.Nil(t, err)
+	}()
+
+	record, token, _, err := CreateAdminToken(ctx, "tester", cfg.RoleSupport, "tester", nil)
+	assert.Nil(t, err)
+	assert.Len(t, token, 64)
+	assert.NotContains(t, record.TokenHash, token)
+	assert.Len(t, record.TOTPSecret, 32)
+
+	authenticated, err := AuthenticateAdminToken(ctx, token)
+	assert.Nil(t, err)
+	assert.Equal(t, record.ID, authenticated.ID)
+	assert.Equal(t, cfg.RoleSupport, authenticated.Role)
+	assert.Equal(t, record.TOTPSecret, authenticated.TOTPSecret)
+
+	// The TOTP secret is replaced by a new one.
+	reset, totpSecret, err := ResetAdminTokenTOTP(ctx, record.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, totpSecret, reset.TOTPSecret)
+	assert.NotEqual(t, record.TOTPSecret, totpSecret)
+
+	_, err = AuthenticateAdminToken(ctx, token[:63]+"x")
+	assert.Equal(t, "the admin token is invalid", err.Error())
+
+	_, err = AuthenticateAdminToken(ctx, token[:8])
+	assert.Equal(t, "the admin token is invalid", err.Error())
+
+	// The tokens added before the lookup hashes were introduced get them once authenticated.
+	_, err = db.ExecContext(ctx, "UPDATE admin_tokens SET lookup_hash = NULL WHERE id = $1", record.ID)
+	assert.Nil(t, err)
+
+	authenticated, err = AuthenticateAdminToken(ctx, token)
+	assert.Nil(t, err)
+	assert.Equal(t, record.ID, authenticated.ID)
+
+	var lookupHash string
+	err = db.QueryRowContext(ctx, "SELECT lookup_hash FROM admin_tokens WHERE id = $1", record.ID).Scan(&lookupHash)
+	assert.Nil(t, err)
+	assert.Equal(t, utils.TokenLookupHash(token), lookupHash)
+
+	// Expired tokens can not be used.
+	_, err = ExpireAdminToken(ctx, record.ID, time.Now().Add(-time.Second))
+	assert.Nil(t, err)
+
+	_, err = AuthenticateAdminToken(ctx, token)
+	assert.Equal(t, "the admin token is invalid", err.Error())
+
+	_, err = ExpireAdminToken(ctx, record.ID, time.Now().Add(time.Hour))
+	assert.Nil(t, err)
+
+	_, err = AuthenticateAdminToken(ctx, token)
+	assert.Nil(t, err)
+
+	// Revoked tokens can not be used.
+	revoked, err := RevokeAdminToken(ctx, record.ID)
+	assert.Nil(t, err)
+	assert.NotNil(t, revoked.RevokedAt)
+
+	_, err = AuthenticateAdminToken(ctx, token)
+	assert.Equal(t, "the admin token is invalid", err.Error())
+
+	// Test invalid case
+	_, err = RevokeAdminToken(ctx, "invalid id")
+	assert.Equal(t, "the admin token does not exist", err.Error())
+
+	_, _, err = ResetAdminTokenTOTP(ctx, "invalid id")
+	assert.Equal(t, "the admin token does not exist", err.Error())
+
+	// The permissions are not imported once any admin token exists.
+	added, err := BootstrapAdminTokens(ctx, []cfg.Permission{{NAME: "tester", TOKEN: "token", ROLE: cfg.RoleOwner}})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, added)
+
+	// Delete the added test data
+	err = DeleteTestingData(ctx, "DELETE FROM admin_tokens WHERE id = $1", record.ID)
+	assert.Nil(t, err)
+}
@@ -0,0 +1,119 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/mmq88/quickcerts/model"
+)
+
+// Delete the given S/N and move its record into the archive.
+//
+// A S/N which has been bound to a device is refused unless force is true.
+func ArchiveSN(ctx context.Context, sn string, reason string, archivedBy string, force bool) error {
+	if db == nil {
+		return ErrDBNotConnected
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	var bound bool
+	err = tx.QueryRowContext(ctx, "SELECT key IS NOT NULL FROM certs WHERE sn = $1 FOR UPDATE", sn).Scan(&bound)
+
+	if err == sql.ErrNoRows {
+		return ErrSNNotFound
+	} else if err != nil {
+		return err
+	}
+
+	if bound && !force {
+		return ErrSNAlreadyBound
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO archived_certs (sn, key, note, batch_id, revoked_at, metadata, product, reason, archived_by)
+		SELECT sn, key, note, batch_id, revoked_at, metadata, product, NULLIF($2, ''), NULLIF($3, '')
+		FROM certs WHERE sn = $1
+	`, sn, reason, archivedBy)
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM certs WHERE sn = $1", sn); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Get all archived certificate records, the newest first.
+func GetArchivedCerts(ctx context.Context) ([]model.ArchivedCert, error) {
+	if db == nil {
+		return nil, ErrDBNotConnected
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT sn, key, note, batch_id, revoked_at, metadata, product, reason, archived_by, archived_at
+		FROM archived_certs
+		ORDER BY archived_at DESC, id DESC
+	`
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var certs []model.ArchivedCert
+
+	for rows.Next() {
+		var cert model.ArchivedCert
+		var tmpKey, tmpNote, tmpBatchID, tmpProduct, tmpReason, tmpArchivedBy sql.NullString
+		var tmpRevokedAt sql.NullTime
+		var rawMetadata []byte
+
+		err := rows.Scan(
+			&cert.SerialNumber, &tmpKey, &tmpNote, &tmpBatchID, &tmpRevokedAt, &rawMetadata, &tmpProduct,
+			&tmpReason, &tmpArchivedBy, &cert.ArchivedAt,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		cert.Key = tmpKey.String
+		cert.Note = tmpNote.String
+		cert.BatchID = tmpBatchID.String
+		cert.Product = tmpProduct.String
+		cert.Reason = tmpReason.String
+		cert.ArchivedBy = tmpArchivedBy.String
+		if tmpRevokedAt.Valid {
+			cert.RevokedAt = &tmpRevokedAt.Time
+		}
+
+		if cert.Metadata, err = decodeMetadata(rawMetadata); err != nil {
+			return nil, err
+		}
+
+		certs = append(certs, cert)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return certs, nil
+}
@@ -0,0 +1,74 @@
+package data
+
+import (
+	"context"
+	"testing"
+
+	cfg "github.com/mmq88/quickcerts/configs"
+	"github.com/mmq88/quickcerts/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArchiveSN(t *testing.T) {
+	ctx := context.Background()
+
+	backupHost := cfg.DB_CONFIG.HOST
+	backupPort := cfg.DB_CONFIG.PORT
+	defer func() {
+		cfg.DB_CONFIG.HOST = backupHost
+		cfg.DB_CONFIG.PORT = backupPort
+	}()
+
+	// Test invalid case
+	err := ArchiveSN(ctx, "XXXX-XXXX-XXXX-XXXX-XXXX-XXXX", "For testing.", "tester", false)
+	assert.Equal(t, "currently not connecting the database", err.Error())
+
+	// Test valid case
+	// Uses docker-compose config
+	cfg.DB_CONFIG.HOST = "localhost"
+	cfg.DB_CONFIG.PORT = 33332
+
+	err = ConnectDB()
+	assert.Nil(t, err)
+	defer func() {
+		err = DisconnectDB()
+		assert.Nil(t, err)
+	}()
+
+	sn := "XXXX-XXXX-XXXX-XXXX-XXXX-XXXX"
+	err = AddNewSN(ctx, sn, "")
+	assert.Nil(t, err)
+
+	status, err := BindCertificate(ctx, model.CertActivation{SerialNumber: sn, Key: "key"}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, model.BindStatusBound, status)
+
+	// A bound S/N is refused unless forced.
+	err = ArchiveSN(ctx, sn, "For testing.", "tester", false)
+	assert.Equal(t, "the s/n has been bound to a device", err.Error())
+
+	err = ArchiveSN(ctx, sn, "For testing.", "tester", true)
+	assert.Nil(t, err)
+
+	err = UpdateCertNote(ctx, sn, "")
+	assert.Equal(t, "the s/n does not exist", err.Error())
+
+	certs, err := GetArchivedCerts(ctx)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, certs)
+	assert.Equal(t, sn, certs[0].SerialNumber)
+	assert.Equal(t, "key", certs[0].Key)
+	assert.Equal(t, "For testing.", certs[0].Reason)
+	assert.Equal(t, "tester", certs[0].ArchivedBy)
+
+	// Test invalid case
+	err = ArchiveSN(ctx, sn, "For testing.", "tester", true)
+	assert.Equal(t, "the s/n does not exist", err.Error())
+
+	// Delete the added test data
+	err = DeleteTestingData(ctx, "DELETE FROM archived_certs WHERE sn = $1", sn)
+	assert.Nil(t, err)
+	err = DeleteTestingData(ctx, "DELETE FROM cert_activations WHERE sn = $1", sn)
+	assert.Nil(t, err)
+}
@@ -0,0 +1,228 @@
+package data
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mmq88/quickcerts/model"
+)
+
+// The key of the advisory lock serializing the appends of the audit log chain.
+const auditLogLockKey = 0x51435341
+
+// Append an admin action to the audit logs, chained to the hash of the last record.
+//
+// The ID, CreatedAt, PrevHash and Hash fields of the given log are filled in by this function.
+func AddAuditLog(ctx context.Context, log model.AuditLog) error {
+	if db == nil {
+		return ErrDBNotConnected
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", auditLogLockKey); err != nil {
+		return err
+	}
+
+	err = tx.QueryRowContext(ctx, "SELECT hash FROM audit_logs ORDER BY id DESC LIMIT 1").Scan(&log.PrevHash)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	if len(log.Params) == 0 {
+		log.Params = json.RawMessage("{}")
+	}
+
+	// The database keeps microseconds, the hash is computed from the value stored.
+	log.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	log.Hash = computeAuditHash(log)
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO audit_logs (admin, role, ip, method, route, params, status, result, created_at, prev_hash, hash)
+		VALUES (NULLIF($1, ''), NULLIF($2, ''), $3, $4, $5, $6, $7, NULLIF($8, ''), $9, $10, $11)
+	`, log.Admin, log.Role, log.IP, log.Method, log.Route, string(log.Params), log.Status, log.Result,
+		log.CreatedAt, log.PrevHash, log.Hash,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Get the audit logs matching the given filter, the newest first.
+func GetAuditLogs(ctx context.Context, filter model.AuditFilter) ([]model.AuditLog, error) {
+	if db == nil {
+		return nil, ErrDBNotConnected
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var conditions []string
+	var args []any
+
+	addCondition := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.Admin != "" {
+		addCondition("admin = $%d", filter.Admin)
+	}
+	if filter.Role != "" {
+		addCondition("role = $%d", filter.Role)
+	}
+	if filter.IP != "" {
+		addCondition("ip = $%d", filter.IP)
+	}
+	if filter.Route != "" {
+		addCondition("route = $%d", filter.Route)
+	}
+	if filter.Status != 0 {
+		addCondition("status = $%d", filter.Status)
+	}
+	if !filter.From.IsZero() {
+		addCondition("created_at >= $%d", filter.From)
+	}
+	if !filter.To.IsZero() {
+		addCondition("created_at < $%d", filter.To)
+	}
+
+	query := `
+		SELECT id, admin, role, ip, method, route, params, status, result, created_at, prev_hash, hash
+		FROM audit_logs
+	`
+
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	args = append(args, filter.Limit, filter.Offset)
+	query += fmt.Sprintf(" ORDER BY id DESC LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var logs []model.AuditLog
+
+	for rows.Next() {
+		log, err := scanAuditLog(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		logs = append(logs, log)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return logs, nil
+}
+
+// Walk through the audit log chain from the oldest record and check that no record has been modified,
+// inserted or deleted.
+//
+// Returns the number of checked records, and the ID of the first broken record or 0 if the chain is intact.
+func VerifyAuditLogs(ctx context.Context) (int64, int64, error) {
+	if db == nil {
+		return 0, 0, ErrDBNotConnected
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, admin, role, ip, method, route, params, status, result, created_at, prev_hash, hash
+		FROM audit_logs
+		ORDER BY id
+	`)
+
+	if err != nil {
+		return 0, 0, err
+	}
+
+	defer rows.Close()
+
+	var checked int64
+	prevHash := ""
+
+	for rows.Next() {
+		log, err := scanAuditLog(rows)
+		if err != nil {
+			return checked, 0, err
+		}
+
+		if log.PrevHash != prevHash || computeAuditHash(log) != log.Hash {
+			return checked, log.ID, nil
+		}
+
+		prevHash = log.Hash
+		checked++
+	}
+
+	if err := rows.Err(); err != nil {
+		return checked, 0, err
+	}
+
+	return checked, 0, nil
+}
+
+func scanAuditLog(rows *sql.Rows) (model.AuditLog, error) {
+	var log model.AuditLog
+	var tmpAdmin, tmpRole, tmpResult sql.NullString
+	var params string
+
+	err := rows.Scan(
+		&log.ID, &tmpAdmin, &tmpRole, &log.IP, &log.Method, &log.Route, &params, &log.Status, &tmpResult,
+		&log.CreatedAt, &log.PrevHash, &log.Hash,
+	)
+
+	if err != nil {
+		return model.AuditLog{}, err
+	}
+
+	log.Admin = tmpAdmin.String
+	log.Role = tmpRole.String
+	log.Result = tmpResult.String
+	log.Params = json.RawMessage(params)
+
+	return log, nil
+}
+
+// Compute the hash of the given audit log, which covers the hash of the previous record.
+func computeAuditHash(log model.AuditLog) string {
+	content, _ := json.Marshal([]any{
+		log.PrevHash,
+		log.CreatedAt.UTC().Format(time.RFC3339Nano),
+		log.Admin,
+		log.Role,
+		log.IP,
+		log.Method,
+		log.Route,
+		string(log.Params),
+		log.Status,
+		log.Result,
+	})
+
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
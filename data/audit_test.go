@@ -0,0 +1,107 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	cfg "github.com/mmq88/quickcerts/configs"
+	"github.com/mmq88/quickcerts/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComputeAuditHash(t *testing.T) {
+	log := model.AuditLog{
+		Admin:     "tester",
+		IP:        "127.0.0.1",
+		Method:    "POST",
+		Route:     "/api/v1/sn/create",
+		Params:    json.RawMessage(`{"body":{"serial_number":"XXXX"}}`),
+		Status:    200,
+		CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	hash := computeAuditHash(log)
+	assert.Len(t, hash, 64)
+	assert.Equal(t, hash, computeAuditHash(log))
+
+	// The same moment in another time zone has the same hash.
+	sameLog := log
+	sameLog.CreatedAt = log.CreatedAt.In(time.FixedZone("UTC+8", 8*60*60))
+	assert.Equal(t, hash, computeAuditHash(sameLog))
+
+	// Any modification changes the hash.
+	tampered := log
+	tampered.Status = 401
+	assert.NotEqual(t, hash, computeAuditHash(tampered))
+
+	tampered = log
+	tampered.PrevHash = hash
+	assert.NotEqual(t, hash, computeAuditHash(tampered))
+}
+
+func TestAddAuditLog(t *testing.T) {
+	ctx := context.Background()
+
+	backupHost := cfg.DB_CONFIG.HOST
+	backupPort := cfg.DB_CONFIG.PORT
+	defer func() {
+		cfg.DB_CONFIG.HOST = backupHost
+		cfg.DB_CONFIG.PORT = backupPort
+	}()
+
+	// Test invalid case
+	err := AddAuditLog(ctx, model.AuditLog{Admin: "tester"})
+	assert.Equal(t, "currently not connecting the database", err.Error())
+
+	// Test valid case
+	// Uses docker-compose config
+	cfg.DB_CONFIG.HOST = "localhost"
+	cfg.DB_CONFIG.PORT = 33332
+
+	err = ConnectDB()
+	assert.Nil(t, err)
+	defer func() {
+		err = DisconnectDB()
+		assert.Nil(t, err)
+	}()
+
+	since := time.Now().Add(-time.Second)
+
+	for _, status := range []int{200, 401} {
+		err = AddAuditLog(ctx, model.AuditLog{
+			Admin:  "tester",
+			Role:   "owner",
+			IP:     "127.0.0.1",
+			Method: "POST",
+			Route:  "/api/v1/sn/create",
+			Params: json.RawMessage(`{"body":{"serial_number":"XXXX-XXXX-XXXX-XXXX-XXXX-XXXX"}}`),
+			Status: status,
+		})
+		assert.Nil(t, err)
+	}
+
+	logs, err := GetAuditLogs(ctx, model.AuditFilter{Admin: "tester", From: since, Limit: 10})
+	assert.Nil(t, err)
+	assert.Len(t, logs, 2)
+
+	// The newest first, and each record is chained to the previous one.
+	assert.Equal(t, 401, logs[0].Status)
+	assert.Equal(t, "owner", logs[0].Role)
+	assert.Equal(t, logs[1].Hash, logs[0].PrevHash)
+
+	logs, err = GetAuditLogs(ctx, model.AuditFilter{Admin: "tester", Status: 401, From: since, Limit: 10})
+	assert.Nil(t, err)
+	assert.Len(t, logs, 1)
+
+	checked, brokenID, err := VerifyAuditLogs(ctx)
+	assert.Nil(t, err)
+	assert.GreaterOrEqual(t, checked, int64(2))
+	assert.Equal(t, int64(0), brokenID)
+
+	// The audit logs are append-only.
+	err = DeleteTestingData(ctx, "DELETE FROM audit_logs WHERE id = $1", logs[0].ID)
+	assert.NotNil(t, err)
+}
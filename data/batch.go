@@ -0,0 +1,139 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/mmq88/quickcerts/model"
+)
+
+// Get all batch records with the statistics of their S/N(s), the newest first.
+func GetAllBatches(ctx context.Context) ([]model.Batch, error) {
+	if db == nil {
+		return nil, ErrDBNotConnected
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT b.id, b.reason, b.created_by, b.reseller, b.order_number, b.product, b.created_at,
+			COUNT(c.sn), COUNT(c.key), COUNT(c.revoked_at)
+		FROM batches b
+		LEFT JOIN certs c ON c.batch_id = b.id
+		GROUP BY b.id
+		ORDER BY b.created_at DESC
+	`
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var batches []model.Batch
+
+	for rows.Next() {
+		var batch model.Batch
+		var tmpReason, tmpCreatedBy, tmpReseller, tmpOrderNumber, tmpProduct sql.NullString
+
+		err := rows.Scan(
+			&batch.ID, &tmpReason, &tmpCreatedBy, &tmpReseller, &tmpOrderNumber, &tmpProduct, &batch.CreatedAt,
+			&batch.Count, &batch.Bound, &batch.Revoked,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		batch.Reason = tmpReason.String
+		batch.CreatedBy = tmpCreatedBy.String
+		batch.Reseller = tmpReseller.String
+		batch.OrderNumber = tmpOrderNumber.String
+		batch.Product = tmpProduct.String
+		batches = append(batches, batch)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return batches, nil
+}
+
+// Check if the given batch exists in the database.
+func IsBatchExist(ctx context.Context, id string) (bool, error) {
+	if db == nil {
+		return false, ErrDBNotConnected
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var exists bool
+	err := db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM batches WHERE id = $1)", id).Scan(&exists)
+	if err != nil {
+		return false, err
+	}
+
+	if !exists {
+		return false, ErrBatchNotFound
+	}
+
+	return true, nil
+}
+
+// Call fn with each certificate record in the given batch, ordered by S/N.
+//
+// The records are read one by one, so large batches can be streamed without being loaded into memory.
+func ForEachCertInBatch(ctx context.Context, id string, fn func(cert model.Cert) error) error {
+	if db == nil {
+		return ErrDBNotConnected
+	}
+
+	query := `
+		SELECT sn, key, note, batch_id, revoked_at, metadata, product FROM certs
+		WHERE batch_id = $1
+		ORDER BY sn
+	`
+
+	rows, err := db.QueryContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		cert, err := scanCert(rows)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(cert); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// Revoke all S/N(s) in the given batch which have not been revoked yet.
+//
+// Revoked S/N(s) can no longer be bound to a device. Returns the number of newly revoked S/N(s).
+func RevokeBatch(ctx context.Context, id string) (int64, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	if _, err := IsBatchExist(ctx, id); err != nil {
+		return 0, err
+	}
+
+	res, err := db.ExecContext(ctx, "UPDATE certs SET revoked_at = NOW() WHERE batch_id = $1 AND revoked_at IS NULL", id)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
@@ -0,0 +1,157 @@
+package data
+
+import (
+	"context"
+	"testing"
+
+	cfg "github.com/mmq88/quickcerts/configs"
+	"github.com/mmq88/quickcerts/model"
+	"github.com/mmq88/quickcerts/utils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetAllBatches(t *testing.T) {
+	ctx := context.Background()
+
+	backupHost := cfg.DB_CONFIG.HOST
+	backupPort := cfg.DB_CONFIG.PORT
+	defer func() {
+		cfg.DB_CONFIG.HOST = backupHost
+		cfg.DB_CONFIG.PORT = backupPort
+	}()
+
+	// Test invalid case
+	_, err := GetAllBatches(ctx)
+	assert.Equal(t, "currently not connecting the database", err.Error())
+
+	// Test valid case
+	// Uses docker-compose config
+	cfg.DB_CONFIG.HOST = "localhost"
+	cfg.DB_CONFIG.PORT = 33332
+
+	err = ConnectDB()
+	assert.Nil(t, err)
+	defer func() {
+		err = DisconnectDB()
+		assert.Nil(t, err)
+	}()
+
+	batch := model.Batch{
+		ID:          "testBatch",
+		Reason:      "testReason",
+		CreatedBy:   "tester",
+		Reseller:    "testReseller",
+		OrderNumber: "testOrder",
+	}
+	snList, err := AddGeneratedSNs(ctx, batch, 2, utils.GenerateSN, nil)
+	assert.Nil(t, err)
+
+	status, err := BindCertificate(ctx, model.CertActivation{SerialNumber: snList[0], Key: "key"}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, model.BindStatusBound, status)
+
+	batches, err := GetAllBatches(ctx)
+	assert.Nil(t, err)
+
+	found := false
+	for _, b := range batches {
+		if b.ID != batch.ID {
+			continue
+		}
+
+		found = true
+		assert.Equal(t, batch.Reason, b.Reason)
+		assert.Equal(t, batch.CreatedBy, b.CreatedBy)
+		assert.Equal(t, batch.Reseller, b.Reseller)
+		assert.Equal(t, batch.OrderNumber, b.OrderNumber)
+		assert.Equal(t, 2, b.Count)
+		assert.Equal(t, 1, b.Bound)
+		assert.Equal(t, 0, b.Revoked)
+	}
+	assert.True(t, found)
+
+	// Delete the added test data
+	err = DeleteTestingData(ctx, "DELETE FROM cert_activations WHERE sn = $1", snList[0])
+	assert.Nil(t, err)
+	err = DeleteTestingData(ctx, "DELETE FROM certs WHERE batch_id = $1", batch.ID)
+	assert.Nil(t, err)
+	err = DeleteTestingData(ctx, "DELETE FROM batches WHERE id = $1", batch.ID)
+	assert.Nil(t, err)
+}
+
+func TestExportAndRevokeBatch(t *testing.T) {
+	ctx := context.Background()
+
+	backupHost := cfg.DB_CONFIG.HOST
+	backupPort := cfg.DB_CONFIG.PORT
+	defer func() {
+		cfg.DB_CONFIG.HOST = backupHost
+		cfg.DB_CONFIG.PORT = backupPort
+	}()
+
+	// Test invalid case
+	_, err := RevokeBatch(ctx, "testBatch")
+	assert.Equal(t, "currently not connecting the database", err.Error())
+
+	// Test valid case
+	// Uses docker-compose config
+	cfg.DB_CONFIG.HOST = "localhost"
+	cfg.DB_CONFIG.PORT = 33332
+
+	err = ConnectDB()
+	assert.Nil(t, err)
+	defer func() {
+		err = DisconnectDB()
+		assert.Nil(t, err)
+	}()
+
+	batch := model.Batch{ID: "testBatch", Reason: "testReason"}
+	snList, err := AddGeneratedSNs(ctx, batch, 3, utils.GenerateSN, nil)
+	assert.Nil(t, err)
+
+	exported := []string{}
+	err = ForEachCertInBatch(ctx, batch.ID, func(cert model.Cert) error {
+		exported = append(exported, cert.SerialNumber)
+		assert.Nil(t, cert.RevokedAt)
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, snList, exported)
+
+	revoked, err := RevokeBatch(ctx, batch.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), revoked)
+
+	// Revoking again affects nothing.
+	revoked, err = RevokeBatch(ctx, batch.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), revoked)
+
+	// Revoked S/N(s) are no longer available and can not be bound.
+	available, err := GetAvaliableSN(ctx)
+	assert.Nil(t, err)
+	assert.NotContains(t, available, snList[0])
+
+	status, err := BindCertificate(ctx, model.CertActivation{SerialNumber: snList[0], Key: "key"}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, model.BindStatusRevoked, status)
+
+	err = ForEachCertInBatch(ctx, batch.ID, func(cert model.Cert) error {
+		assert.NotNil(t, cert.RevokedAt)
+		return nil
+	})
+	assert.Nil(t, err)
+
+	// Test invalid case
+	_, err = RevokeBatch(ctx, "none")
+	assert.Equal(t, "the batch does not exist", err.Error())
+
+	// Delete the added test data
+	err = DeleteTestingData(ctx, "DELETE FROM cert_activations WHERE sn = $1", snList[0])
+	assert.Nil(t, err)
+	err = DeleteTestingData(ctx, "DELETE FROM certs WHERE batch_id = $1", batch.ID)
+	assert.Nil(t, err)
+	err = DeleteTestingData(ctx, "DELETE FROM batches WHERE id = $1", batch.ID)
+	assert.Nil(t, err)
+}
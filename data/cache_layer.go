@@ -2,54 +2,212 @@ package data
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
+	"fmt"
+	"os"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	cfg "github.com/mmq88/quickcerts/configs"
+	"github.com/mmq88/quickcerts/utils"
 
 	"github.com/redis/go-redis/v9"
 )
 
-var ctx = context.Background()
-var rdb *redis.Client = nil
+var (
+	rdb   redis.UniversalClient = nil
+	rdbMu sync.RWMutex
+	// If the redis database answered the latest ping, see RDBAvailable.
+	rdbAvailable atomic.Bool
+)
 
-// Connect to the redis database.
+// Connect to the redis database, addressed by MODE in cache.toml.
 func ConnectRDB() error {
-	rdb = redis.NewClient(&redis.Options{
-		Addr:     cfg.CACHE_CONFIG.HOST + ":" + strconv.Itoa(cfg.CACHE_CONFIG.PORT),
-		Password: cfg.CACHE_CONFIG.PASSWORD,
-		DB:       0,
-	})
+	tlsConfig, err := redisTLSConfig()
+	if err != nil {
+		return err
+	}
+
+	var client redis.UniversalClient
+
+	switch strings.ToLower(cfg.CACHE_CONFIG.MODE) {
+	case cfg.CacheModeSentinel:
+		client = redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:       cfg.CACHE_CONFIG.MASTER_NAME,
+			SentinelAddrs:    cfg.CACHE_CONFIG.ADDRS,
+			SentinelPassword: cfg.CACHE_CONFIG.SENTINEL_PASSWORD,
+			Username:         cfg.CACHE_CONFIG.USERNAME,
+			Password:         cfg.CACHE_CONFIG.PASSWORD,
+			DB:               cfg.CACHE_CONFIG.DB,
+			TLSConfig:        tlsConfig,
+		})
+	case cfg.CacheModeCluster:
+		client = redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:     cfg.CACHE_CONFIG.ADDRS,
+			Username:  cfg.CACHE_CONFIG.USERNAME,
+			Password:  cfg.CACHE_CONFIG.PASSWORD,
+			TLSConfig: tlsConfig,
+		})
+	default:
+		client = redis.NewClient(&redis.Options{
+			Addr:      cfg.CACHE_CONFIG.HOST + ":" + strconv.Itoa(cfg.CACHE_CONFIG.PORT),
+			Username:  cfg.CACHE_CONFIG.USERNAME,
+			Password:  cfg.CACHE_CONFIG.PASSWORD,
+			DB:        cfg.CACHE_CONFIG.DB,
+			TLSConfig: tlsConfig,
+		})
+	}
 
-	_, err := rdb.Ping(ctx).Result()
+	_, err = client.Ping(context.Background()).Result()
 
 	if err != nil {
-		rdb = nil
-		return errors.New("failed to access the redis database")
+		client.Close()
+		return ErrRDBAccessFailed
 	}
 
+	rdbMu.Lock()
+	defer rdbMu.Unlock()
+
+	if rdb != nil {
+		rdb.Close()
+	}
+
+	rdb = client
+	rdbAvailable.Store(true)
+
 	return nil
 }
 
+// Get the TLS config of the redis connections, or nil if USE_TLS is disabled.
+func redisTLSConfig() (*tls.Config, error) {
+	if !cfg.CACHE_CONFIG.USE_TLS {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if cfg.CACHE_CONFIG.TLS_CA_CERT_PATH != "" {
+		caBundle, err := os.ReadFile(cfg.CACHE_CONFIG.TLS_CA_CERT_PATH)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the CA certificate of the redis database: %w", err)
+		}
+
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(caBundle) {
+			return nil, errors.New("no valid certificate is found in TLS_CA_CERT_PATH")
+		}
+	}
+
+	return tlsConfig, nil
+}
+
+// Get the key stored in the redis database, prefixed by KEY_PREFIX in cache.toml.
+func cacheKey(key string) string {
+	return cfg.CACHE_CONFIG.KEY_PREFIX + key
+}
+
 // Disconnect from the redis database.
 func DisconnectRDB() error {
+	rdbMu.Lock()
+	defer rdbMu.Unlock()
+
 	if rdb == nil {
-		return errors.New("currently not connecting the redis database")
+		return ErrRDBNotConnected
 	}
 
 	err := rdb.Close()
 	rdb = nil
+	rdbAvailable.Store(false)
 	return err
 }
 
+// Get the client of the redis database, or nil if it is not connected.
+func currentRDB() redis.UniversalClient {
+	rdbMu.RLock()
+	defer rdbMu.RUnlock()
+
+	return rdb
+}
+
+// Get the client of the redis database, or nil if it is not connected or did not answer the latest ping, so the
+// callers do not wait for the timeout of each query while it is unreachable.
+func availableRDB() redis.UniversalClient {
+	if !RDBAvailable() {
+		return nil
+	}
+
+	return currentRDB()
+}
+
+// Check if the redis database is reachable, i.e. it answered the latest ping of WatchRDB.
+//
+// The key cache is skipped while it is not, and RedisGuardCache keeps the rate limits, admin lockouts and nonces in
+// memory instead, i.e. the server runs in the degraded mode.
+func RDBAvailable() bool {
+	return rdbAvailable.Load()
+}
+
+// Keep checking the redis database until ctx is done, and reconnect it with an exponential backoff while it is
+// unreachable, even if it has never been connected.
+func WatchRDB(ctx context.Context) {
+	watchConnection(ctx, &rdbAvailable, checkRDB,
+		"The redis database is unreachable, the key cache is skipped and the rate limits, admin lockouts and "+
+			"nonces are kept in memory until it is reconnected.",
+		"The redis database is reachable again, the key cache, rate limits, admin lockouts and nonces are restored.",
+	)
+}
+
+// Ping the redis database, or connect it if it has not been connected.
+func checkRDB(ctx context.Context) error {
+	if currentRDB() == nil {
+		return ConnectRDB()
+	}
+
+	return pingRDB(ctx)
+}
+
+// Ping the redis database, with the timeout of a query.
+func pingRDB(ctx context.Context) error {
+	client := currentRDB()
+	if client == nil {
+		return ErrRDBNotConnected
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	return client.Ping(ctx).Err()
+}
+
+// How long the key of a device is cached if EXPIRATION in cache.toml is not valid.
+const defaultDeviceKeyCacheTTL = time.Hour * 24 * 7
+
+// Get how long the key of a device is cached, see EXPIRATION in cache.toml.
+func deviceKeyCacheTTL() time.Duration {
+	timeUnit, err := utils.TimeUnitStrToTimeDuration(cfg.CACHE_CONFIG.EXPIRATION_UNIT)
+	if err != nil || cfg.CACHE_CONFIG.EXPIRATION <= 0 {
+		return defaultDeviceKeyCacheTTL
+	}
+
+	return time.Duration(cfg.CACHE_CONFIG.EXPIRATION) * timeUnit
+}
+
 // Set the key cache corresponding to the device.
-func SetDeviceKeyCache(key string, value interface{}) error {
-	if rdb == nil {
-		return errors.New("currently not connecting the redis database")
+func SetDeviceKeyCache(ctx context.Context, key string, value interface{}) error {
+	client := availableRDB()
+	if client == nil {
+		return ErrRDBNotConnected
 	}
 
-	err := rdb.Set(ctx, key, value, time.Hour*24*7).Err()
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	err := client.Set(ctx, cacheKey(key), value, deviceKeyCacheTTL()).Err()
 	if err != nil {
 		return err
 	}
@@ -58,15 +216,19 @@ func SetDeviceKeyCache(key string, value interface{}) error {
 }
 
 // Get the key cache corresponding to the device. if exists, or return "".
-func GetDeviceKeyCache(deviceInfoBase string) (string, error) {
-	if rdb == nil {
-		return "", errors.New("currently not connecting the redis database")
+func GetDeviceKeyCache(ctx context.Context, deviceInfoBase string) (string, error) {
+	client := availableRDB()
+	if client == nil {
+		return "", ErrRDBNotConnected
 	}
 
-	key, err := rdb.Get(ctx, deviceInfoBase).Result()
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	key, err := client.Get(ctx, cacheKey(deviceInfoBase)).Result()
 	if err != nil {
-		if err.Error() == "redis: nil" {
-			return "", errors.New("the key not exist in the cache")
+		if errors.Is(err, redis.Nil) {
+			return "", ErrCacheKeyNotFound
 		}
 		return "", err
 	}
@@ -74,13 +236,133 @@ func GetDeviceKeyCache(deviceInfoBase string) (string, error) {
 	return key, nil
 }
 
+// Count a request in the current fixed window of a rate limit, e.g. the requests of a client key per minute.
+//
+// Returns the number of requests made by the id in the window so far, and the time until the window resets.
+func CountRateLimitedRequest(
+	ctx context.Context, scope string, id string, window time.Duration,
+) (int64, time.Duration, error) {
+	client := availableRDB()
+	if client == nil {
+		return 0, 0, ErrRDBNotConnected
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	counterKey, resetIn := rateLimitWindow(scope, id, window)
+	counterKey = cacheKey(counterKey)
+
+	pipe := client.TxPipeline()
+	count := pipe.Incr(ctx, counterKey)
+	pipe.Expire(ctx, counterKey, window*2)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, 0, err
+	}
+
+	return count.Val(), resetIn, nil
+}
+
+// Get the remaining time of the lockout of the admin authentication from the IP address, or 0 if it is not locked.
+func GetAdminLockout(ctx context.Context, ip string) (time.Duration, error) {
+	client := availableRDB()
+	if client == nil {
+		return 0, ErrRDBNotConnected
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	ttl, err := client.PTTL(ctx, cacheKey(adminLockKey(ip))).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	if ttl < 0 {
+		return 0, nil
+	}
+
+	return ttl, nil
+}
+
+// Record a failed admin authentication from the IP address.
+//
+// Once the failures reach the threshold, the IP address is locked out for the base duration, which is doubled by
+// each further failure up to the max duration. The failures are forgotten after a day without failures.
+// Returns the duration of the lockout, or 0 if the IP address is not locked out.
+func RecordAdminAuthFailure(
+	ctx context.Context, ip string, threshold int, base time.Duration, max time.Duration,
+) (time.Duration, error) {
+	client := availableRDB()
+	if client == nil {
+		return 0, ErrRDBNotConnected
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	failuresKey := cacheKey(adminFailuresKey(ip))
+
+	pipe := client.TxPipeline()
+	failures := pipe.Incr(ctx, failuresKey)
+	pipe.Expire(ctx, failuresKey, adminFailuresTTL)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+
+	lockout := adminLockoutDuration(failures.Val(), threshold, base, max)
+	if lockout == 0 {
+		return 0, nil
+	}
+
+	if err := client.Set(ctx, cacheKey(adminLockKey(ip)), 1, lockout).Err(); err != nil {
+		return 0, err
+	}
+
+	return lockout, nil
+}
+
+// Forget the failed admin authentications from the IP address after a successful one.
+func ResetAdminAuthFailures(ctx context.Context, ip string) error {
+	client := availableRDB()
+	if client == nil {
+		return ErrRDBNotConnected
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	return client.Del(ctx, cacheKey(adminFailuresKey(ip))).Err()
+}
+
+// Claim the nonce of a signed admin request for the given period.
+//
+// Returns false if the nonce has already been claimed by the same admin token, i.e. the request is replayed.
+func ClaimRequestNonce(ctx context.Context, tokenID string, nonce string, ttl time.Duration) (bool, error) {
+	client := availableRDB()
+	if client == nil {
+		return false, ErrRDBNotConnected
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	return client.SetNX(ctx, cacheKey(requestNonceKey(tokenID, nonce)), 1, ttl).Result()
+}
+
 // Not a secure way to delete cache, only for testing.
-func DeleteTestingCache(deviceInfoBase string) error {
-	if rdb == nil {
-		return errors.New("currently not connecting the redis database")
+func DeleteTestingCache(ctx context.Context, deviceInfoBase string) error {
+	client := currentRDB()
+	if client == nil {
+		return ErrRDBNotConnected
 	}
 
-	err := rdb.Del(ctx, deviceInfoBase).Err()
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	err := client.Del(ctx, cacheKey(deviceInfoBase)).Err()
 	if err != nil {
 		return err
 	}
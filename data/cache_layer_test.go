@@ -1,10 +1,16 @@
 package data
 
 import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
 	"testing"
+	"time"
 
 	cfg "github.com/mmq88/quickcerts/configs"
 
+	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -37,7 +43,122 @@ func TestConnectAndDisconnectRDB(t *testing.T) {
 	assert.Equal(t, err.Error(), "failed to access the redis database")
 }
 
+func TestRedisOptions(t *testing.T) {
+	backup_cache_config := cfg.CACHE_CONFIG
+	defer func() {
+		cfg.CACHE_CONFIG = backup_cache_config
+	}()
+
+	// The TTL of the key cache follows EXPIRATION.
+	cfg.CACHE_CONFIG.EXPIRATION = 3
+	cfg.CACHE_CONFIG.EXPIRATION_UNIT = "Hour"
+	assert.Equal(t, 3*time.Hour, deviceKeyCacheTTL())
+
+	cfg.CACHE_CONFIG.EXPIRATION_UNIT = "invalid"
+	assert.Equal(t, defaultDeviceKeyCacheTTL, deviceKeyCacheTTL())
+
+	// The keys are prefixed by KEY_PREFIX.
+	cfg.CACHE_CONFIG.KEY_PREFIX = "qcs:"
+	assert.Equal(t, "qcs:admin_auth_lock:127.0.0.1", cacheKey("admin_auth_lock:127.0.0.1"))
+
+	// The TLS config is built only if USE_TLS is enabled.
+	cfg.CACHE_CONFIG.USE_TLS = false
+	tlsConfig, err := redisTLSConfig()
+	assert.Nil(t, err)
+	assert.Nil(t, tlsConfig)
+
+	cfg.CACHE_CONFIG.USE_TLS = true
+	cfg.CACHE_CONFIG.TLS_CA_CERT_PATH = ""
+	tlsConfig, err = redisTLSConfig()
+	assert.Nil(t, err)
+	assert.Nil(t, tlsConfig.RootCAs)
+
+	caPath := filepath.Join(t.TempDir(), "ca.pem")
+	err = os.WriteFile(caPath, []byte("invalid"), 0o600)
+	assert.Nil(t, err)
+
+	cfg.CACHE_CONFIG.TLS_CA_CERT_PATH = caPath
+	_, err = redisTLSConfig()
+	assert.Equal(t, "no valid certificate is found in TLS_CA_CERT_PATH", err.Error())
+
+	cfg.CACHE_CONFIG.TLS_CA_CERT_PATH = filepath.Join(t.TempDir(), "none.pem")
+	err = ConnectRDB()
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestWatchRDB(t *testing.T) {
+	backupHost := cfg.CACHE_CONFIG.HOST
+	backupPort := cfg.CACHE_CONFIG.PORT
+
+	defer func() {
+		cfg.CACHE_CONFIG.HOST = backupHost
+		cfg.CACHE_CONFIG.PORT = backupPort
+	}()
+
+	// The redis database is unreachable, the server keeps running in the degraded mode.
+	cfg.CACHE_CONFIG.HOST = "unknown"
+	cfg.CACHE_CONFIG.PORT = 12345
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		WatchRDB(ctx)
+		close(done)
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	assert.False(t, RDBAvailable())
+
+	// The watcher stops once the context is done.
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("WatchRDB did not stop after the context was done")
+	}
+}
+
+func TestUnreachableRDB(t *testing.T) {
+	ctx := context.Background()
+
+	// The client is connected to a redis database that has become unreachable, i.e. did not answer the latest ping.
+	rdbMu.Lock()
+	backupRDB := rdb
+	rdb = redis.NewClient(&redis.Options{Addr: "10.255.255.1:6379"})
+	rdbMu.Unlock()
+
+	backupAvailable := rdbAvailable.Load()
+	rdbAvailable.Store(false)
+
+	defer func() {
+		rdbMu.Lock()
+		rdb.Close()
+		rdb = backupRDB
+		rdbMu.Unlock()
+		rdbAvailable.Store(backupAvailable)
+	}()
+
+	// The queries fail at once instead of waiting for their timeouts.
+	start := time.Now()
+
+	_, err := GetDeviceKeyCache(ctx, "testDevice")
+	assert.ErrorIs(t, err, ErrRDBNotConnected)
+	assert.ErrorIs(t, SetDeviceKeyCache(ctx, "testDevice", "testKey"), ErrRDBNotConnected)
+	_, _, err = CountRateLimitedRequest(ctx, "test", "testID", time.Minute)
+	assert.ErrorIs(t, err, ErrRDBNotConnected)
+	_, err = GetAdminLockout(ctx, "127.0.0.1")
+	assert.ErrorIs(t, err, ErrRDBNotConnected)
+	_, err = ClaimRequestNonce(ctx, "testID", "testNonce", time.Minute)
+	assert.ErrorIs(t, err, ErrRDBNotConnected)
+
+	assert.Less(t, time.Since(start), time.Second)
+}
+
 func TestSetAndGetKeyCache(t *testing.T) {
+	ctx := context.Background()
+
 	backupHost := cfg.CACHE_CONFIG.HOST
 	backupPort := cfg.CACHE_CONFIG.PORT
 
@@ -57,29 +178,29 @@ func TestSetAndGetKeyCache(t *testing.T) {
 	base := "testSN&testBP&testBN&testMAC"
 	expectedKey := "5578c9d3cd718345af4319f3021157999b993f2e991481524234746f38b84c03"
 
-	err = SetDeviceKeyCache(base, expectedKey)
+	err = SetDeviceKeyCache(ctx, base, expectedKey)
 	assert.Nil(t, err)
 
-	actualKey, err := GetDeviceKeyCache(base)
+	actualKey, err := GetDeviceKeyCache(ctx, base)
 	assert.Nil(t, err)
 	assert.Equal(t, actualKey, expectedKey)
 
-	err = DeleteTestingCache(base)
+	err = DeleteTestingCache(ctx, base)
 	assert.Nil(t, err)
 
 	err = DisconnectRDB()
 	assert.Nil(t, err)
 
 	// Test invalid case
-	err = SetDeviceKeyCache("test", "test")
+	err = SetDeviceKeyCache(ctx, "test", "test")
 	assert.Equal(t, "currently not connecting the redis database", err.Error())
 
-	_, err = GetDeviceKeyCache("test")
+	_, err = GetDeviceKeyCache(ctx, "test")
 	assert.Equal(t, "currently not connecting the redis database", err.Error())
 
 	err = ConnectRDB()
 	assert.Nil(t, err)
-	actualKey, err = GetDeviceKeyCache("test")
+	actualKey, err = GetDeviceKeyCache(ctx, "test")
 	assert.Equal(t, "the key not exist in the cache", err.Error())
 	assert.Equal(t, "", actualKey)
 
@@ -88,6 +209,102 @@ func TestSetAndGetKeyCache(t *testing.T) {
 }
 
 func TestDeleteTestingCache(t *testing.T) {
-	err := DeleteTestingCache("test")
+	ctx := context.Background()
+
+	err := DeleteTestingCache(ctx, "test")
+	assert.Equal(t, "currently not connecting the redis database", err.Error())
+}
+
+func TestCountRateLimitedRequest(t *testing.T) {
+	ctx := context.Background()
+
+	backupHost := cfg.CACHE_CONFIG.HOST
+	backupPort := cfg.CACHE_CONFIG.PORT
+
+	defer func() {
+		cfg.CACHE_CONFIG.HOST = backupHost
+		cfg.CACHE_CONFIG.PORT = backupPort
+	}()
+
+	// Test invalid case
+	_, _, err := CountRateLimitedRequest(ctx, "test", "test", time.Minute)
 	assert.Equal(t, "currently not connecting the redis database", err.Error())
+
+	// Test valid case
+	// Uses docker-compose config
+	cfg.CACHE_CONFIG.HOST = "localhost"
+	cfg.CACHE_CONFIG.PORT = 33334
+
+	err = ConnectRDB()
+	assert.Nil(t, err)
+
+	id := "test-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	count, retryAfter, err := CountRateLimitedRequest(ctx, "test", id, time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), count)
+	assert.True(t, retryAfter > 0 && retryAfter <= time.Minute)
+
+	count, _, err = CountRateLimitedRequest(ctx, "test", id, time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), count)
+
+	err = DisconnectRDB()
+	assert.Nil(t, err)
+}
+
+func TestAdminAuthLockout(t *testing.T) {
+	ctx := context.Background()
+
+	backupHost := cfg.CACHE_CONFIG.HOST
+	backupPort := cfg.CACHE_CONFIG.PORT
+
+	defer func() {
+		cfg.CACHE_CONFIG.HOST = backupHost
+		cfg.CACHE_CONFIG.PORT = backupPort
+	}()
+
+	// Test invalid case
+	_, err := GetAdminLockout(ctx, "test")
+	assert.Equal(t, "currently not connecting the redis database", err.Error())
+
+	_, err = RecordAdminAuthFailure(ctx, "test", 3, time.Second, time.Minute)
+	assert.Equal(t, "currently not connecting the redis database", err.Error())
+
+	// Test valid case
+	// Uses docker-compose config
+	cfg.CACHE_CONFIG.HOST = "localhost"
+	cfg.CACHE_CONFIG.PORT = 33334
+
+	err = ConnectRDB()
+	assert.Nil(t, err)
+
+	ip := "test-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	for i := 0; i < 2; i++ {
+		lockout, err := RecordAdminAuthFailure(ctx, ip, 3, time.Second, 3*time.Second)
+		assert.Nil(t, err)
+		assert.Equal(t, time.Duration(0), lockout)
+	}
+
+	lockout, err := GetAdminLockout(ctx, ip)
+	assert.Nil(t, err)
+	assert.Equal(t, time.Duration(0), lockout)
+
+	// The lockout is doubled by each further failure up to the max duration.
+	for _, expected := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second} {
+		lockout, err = RecordAdminAuthFailure(ctx, ip, 3, time.Second, 3*time.Second)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, lockout)
+	}
+
+	lockout, err = GetAdminLockout(ctx, ip)
+	assert.Nil(t, err)
+	assert.True(t, lockout > 0 && lockout <= 3*time.Second)
+
+	err = ResetAdminAuthFailures(ctx, ip)
+	assert.Nil(t, err)
+
+	err = DisconnectRDB()
+	assert.Nil(t, err)
 }
@@ -0,0 +1,272 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+	cfg "github.com/mmq88/quickcerts/configs"
+	"github.com/mmq88/quickcerts/model"
+	"github.com/mmq88/quickcerts/utils"
+)
+
+const (
+	// The key of the advisory lock serializing the bootstrap of the client keys.
+	clientKeyLockKey = 0x51435343
+
+	clientKeyColumns = "id, name, salt, token_hash, products, scopes, rate_limit, usage_count, " +
+		"created_by, created_at, revoked_at, last_used_at"
+)
+
+// Create a new client key, and return its record along with the key itself.
+//
+// The key is only returned here, the database keeps its salted hash.
+func CreateClientKey(
+	ctx context.Context, name string, products []string, scopes []string, rateLimit int, createdBy string,
+) (model.ClientKey, string, error) {
+	if db == nil {
+		return model.ClientKey{}, "", ErrDBNotConnected
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	key, err := utils.GenerateToken()
+	if err != nil {
+		return model.ClientKey{}, "", err
+	}
+
+	record, err := insertClientKey(ctx, db, name, key, products, scopes, rateLimit, createdBy)
+	if err != nil {
+		return model.ClientKey{}, "", err
+	}
+
+	return record, key, nil
+}
+
+// Add the CLIENT_AUTH_TOKEN entries of server.toml as client keys if there is no client key in the database yet.
+//
+// The added keys may apply the S/N(s) of all products through all apply endpoints without a rate limit.
+// Returns the number of added keys.
+func BootstrapClientKeys(ctx context.Context, tokens []string) (int, error) {
+	if db == nil {
+		return 0, ErrDBNotConnected
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", clientKeyLockKey); err != nil {
+		return 0, err
+	}
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM client_keys)").Scan(&exists); err != nil {
+		return 0, err
+	}
+
+	if exists {
+		return 0, nil
+	}
+
+	products := []string{model.AllProducts}
+	scopes := []string{model.ClientScopeApplyCert, model.ClientScopeApplyTempPermit}
+	added := 0
+
+	for i, token := range tokens {
+		name := fmt.Sprintf("CLIENT_AUTH_TOKEN #%d", i)
+		if _, err := insertClientKey(ctx, tx, name, token, products, scopes, 0, "server.toml"); err != nil {
+			return 0, err
+		}
+
+		added++
+	}
+
+	return added, tx.Commit()
+}
+
+// Find the active client key matching the given key, and count the request into its usage.
+//
+// The keys added before the lookup hashes were introduced are compared one by one, and get their lookup hashes once
+// matched.
+func AuthenticateClientKey(ctx context.Context, key string) (model.ClientKey, error) {
+	if db == nil {
+		return model.ClientKey{}, ErrDBNotConnected
+	}
+
+	if len(key) < cfg.MinTokenLength {
+		return model.ClientKey{}, ErrClientKeyInvalid
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	lookupHash := utils.TokenLookupHash(key)
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT `+clientKeyColumns+` FROM client_keys
+		WHERE (lookup_hash = $1 OR lookup_hash IS NULL) AND revoked_at IS NULL
+	`, lookupHash)
+
+	if err != nil {
+		return model.ClientKey{}, err
+	}
+
+	defer rows.Close()
+
+	var matched *model.ClientKey
+
+	// Every candidate is compared, so the time taken does not reveal which one matched.
+	for rows.Next() {
+		record, err := scanClientKey(rows)
+		if err != nil {
+			return model.ClientKey{}, err
+		}
+
+		if utils.VerifyToken(key, record.Salt, record.TokenHash) && matched == nil {
+			matched = &record
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return model.ClientKey{}, err
+	}
+
+	if matched == nil {
+		return model.ClientKey{}, ErrClientKeyInvalid
+	}
+
+	err = db.QueryRowContext(ctx, `
+		UPDATE client_keys SET usage_count = usage_count + 1, last_used_at = NOW(), lookup_hash = $2
+		WHERE id = $1
+		RETURNING usage_count
+	`, matched.ID, lookupHash).Scan(&matched.UsageCount)
+
+	if err != nil {
+		return model.ClientKey{}, err
+	}
+
+	return *matched, nil
+}
+
+// Get all client keys, the newest first.
+func GetAllClientKeys(ctx context.Context) ([]model.ClientKey, error) {
+	if db == nil {
+		return nil, ErrDBNotConnected
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, "SELECT "+clientKeyColumns+" FROM client_keys ORDER BY created_at DESC")
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var keys []model.ClientKey
+
+	for rows.Next() {
+		record, err := scanClientKey(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, record)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// Revoke the given client key, a revoked key can no longer be used.
+func RevokeClientKey(ctx context.Context, id string) (model.ClientKey, error) {
+	if db == nil {
+		return model.ClientKey{}, ErrDBNotConnected
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	row := db.QueryRowContext(ctx, `
+		UPDATE client_keys SET revoked_at = COALESCE(revoked_at, NOW())
+		WHERE id = $1
+		RETURNING `+clientKeyColumns, id)
+
+	record, err := scanClientKey(row)
+
+	if err == sql.ErrNoRows {
+		return model.ClientKey{}, ErrClientKeyNotFound
+	} else if err != nil {
+		return model.ClientKey{}, err
+	}
+
+	return record, nil
+}
+
+func insertClientKey(
+	ctx context.Context, q rowQuerier, name string, key string, products []string, scopes []string, rateLimit int,
+	createdBy string,
+) (model.ClientKey, error) {
+	if len(key) < cfg.MinTokenLength {
+		return model.ClientKey{}, ErrTokenTooShort
+	}
+
+	id, err := utils.GenerateID()
+	if err != nil {
+		return model.ClientKey{}, err
+	}
+
+	salt, err := utils.GenerateID()
+	if err != nil {
+		return model.ClientKey{}, err
+	}
+
+	row := q.QueryRowContext(ctx, `
+		INSERT INTO client_keys (id, name, lookup_hash, salt, token_hash, products, scopes, rate_limit, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''))
+		RETURNING `+clientKeyColumns,
+		id, name, utils.TokenLookupHash(key), salt, utils.HashToken(key, salt),
+		pq.Array(products), pq.Array(scopes), rateLimit, createdBy,
+	)
+
+	return scanClientKey(row)
+}
+
+func scanClientKey(row rowScanner) (model.ClientKey, error) {
+	var record model.ClientKey
+	var tmpCreatedBy sql.NullString
+	var tmpRevokedAt, tmpLastUsedAt sql.NullTime
+
+	err := row.Scan(
+		&record.ID, &record.Name, &record.Salt, &record.TokenHash,
+		pq.Array(&record.Products), pq.Array(&record.Scopes), &record.RateLimit, &record.UsageCount,
+		&tmpCreatedBy, &record.CreatedAt, &tmpRevokedAt, &tmpLastUsedAt,
+	)
+
+	if err != nil {
+		return model.ClientKey{}, err
+	}
+
+	record.CreatedBy = tmpCreatedBy.String
+	if tmpRevokedAt.Valid {
+		record.RevokedAt = &tmpRevokedAt.Time
+	}
+	if tmpLastUsedAt.Valid {
+		record.LastUsedAt = &tmpLastUsedAt.Time
+	}
+
+	return record, nil
+}
@@ -0,0 +1,93 @@
+package data
+
+import (
+	"context"
+	"testing"
+
+	cfg "github.com/mmq88/quickcerts/configs"
+	"github.com/mmq88/quickcerts/model"
+	"github.com/mmq88/quickcerts/utils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientKey(t *testing.T) {
+	ctx := context.Background()
+
+	backupHost := cfg.DB_CONFIG.HOST
+	backupPort := cfg.DB_CONFIG.PORT
+	defer func() {
+		cfg.DB_CONFIG.HOST = backupHost
+		cfg.DB_CONFIG.PORT = backupPort
+	}()
+
+	products := []string{"quickcerts-pro"}
+	scopes := []string{model.ClientScopeApplyCert}
+
+	// Test invalid case
+	_, _, err := CreateClientKey(ctx, "tester", products, scopes, 60, "tester")
+	assert.Equal(t, "currently not connecting the database", err.Error())
+
+	_, err = AuthenticateClientKey(ctx, "key")
+	assert.Equal(t, "currently not connecting the database", err.Error())
+
+	// Test valid case
+	// Uses docker-compose config
+	cfg.DB_CONFIG.HOST = "localhost"
+	cfg.DB_CONFIG.PORT = 33332
+
+	err = ConnectDB()
+	assert.Nil(t, err)
+	defer func() {
+		err = DisconnectDB()
+		assert.Nil(t, err)
+	}()
+
+	record, key, err := CreateClientKey(ctx, "tester", products, scopes, 60, "tester")
+	assert.Nil(t, err)
+	assert.Len(t, key, 64)
+	assert.Equal(t, products, record.Products)
+	assert.Equal(t, scopes, record.Scopes)
+	assert.Equal(t, int64(0), record.UsageCount)
+
+	authenticated, err := AuthenticateClientKey(ctx, key)
+	assert.Nil(t, err)
+	assert.Equal(t, record.ID, authenticated.ID)
+	assert.Equal(t, 60, authenticated.RateLimit)
+	assert.Equal(t, int64(1), authenticated.UsageCount)
+
+	_, err = AuthenticateClientKey(ctx, key[:63]+"x")
+	assert.Equal(t, "the client key is invalid", err.Error())
+
+	_, err = AuthenticateClientKey(ctx, key[:8])
+	assert.Equal(t, "the client key is invalid", err.Error())
+
+	// The keys added before the lookup hashes were introduced get them once authenticated.
+	_, err = db.ExecContext(ctx, "UPDATE client_keys SET lookup_hash = NULL WHERE id = $1", record.ID)
+	assert.Nil(t, err)
+
+	authenticated, err = AuthenticateClientKey(ctx, key)
+	assert.Nil(t, err)
+	assert.Equal(t, record.ID, authenticated.ID)
+
+	var lookupHash string
+	err = db.QueryRowContext(ctx, "SELECT lookup_hash FROM client_keys WHERE id = $1", record.ID).Scan(&lookupHash)
+	assert.Nil(t, err)
+	assert.Equal(t, utils.TokenLookupHash(key), lookupHash)
+
+	// Revoked keys can not be used.
+	revoked, err := RevokeClientKey(ctx, record.ID)
+	assert.Nil(t, err)
+	assert.NotNil(t, revoked.RevokedAt)
+
+	_, err = AuthenticateClientKey(ctx, key)
+	assert.Equal(t, "the client key is invalid", err.Error())
+
+	// Test invalid case
+	_, err = RevokeClientKey(ctx, "invalid id")
+	assert.Equal(t, "the client key does not exist", err.Error())
+
+	// Delete the added test data
+	err = DeleteTestingData(ctx, "DELETE FROM client_keys WHERE id = $1", record.ID)
+	assert.Nil(t, err)
+}
@@ -1,10 +1,11 @@
 package data
 
 import (
+	"context"
 	"database/sql"
-	"errors"
 	"fmt"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	_ "github.com/lib/pq"
@@ -14,58 +15,131 @@ import (
 	"github.com/mmq88/quickcerts/utils"
 )
 
-var db *sql.DB = nil
+var (
+	db *sql.DB = nil
+	// If the database answered the latest ping, see DBAvailable.
+	dbAvailable atomic.Bool
+)
 
 // Connect to the specified database.
 func ConnectDB() error {
-	psqlInfo := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
-		cfg.DB_CONFIG.HOST, cfg.DB_CONFIG.PORT, cfg.DB_CONFIG.USER, cfg.DB_CONFIG.PWD, cfg.DB_CONFIG.DB_NAME)
 	var err error
-	db, err = sql.Open("postgres", psqlInfo)
+	db, err = sql.Open("postgres", postgresDataSource())
 
 	if err != nil {
 		db = nil
-		return errors.New("failed to connect the database")
+		return ErrDBConnectFailed
+	}
+
+	db.SetMaxOpenConns(cfg.DB_CONFIG.MAX_OPEN_CONNS)
+	if cfg.DB_CONFIG.MAX_IDLE_CONNS > 0 {
+		db.SetMaxIdleConns(cfg.DB_CONFIG.MAX_IDLE_CONNS)
+	}
+
+	if lifetimeUnit, err := utils.TimeUnitStrToTimeDuration(cfg.DB_CONFIG.CONN_MAX_LIFETIME_UNIT); err == nil {
+		db.SetConnMaxLifetime(time.Duration(cfg.DB_CONFIG.CONN_MAX_LIFETIME) * lifetimeUnit)
 	}
 
 	err = db.Ping()
 
 	if err != nil {
+		db.Close()
 		db = nil
-		return errors.New("failed to access the database")
+		return ErrDBAccessFailed
 	}
 
+	dbAvailable.Store(true)
+
 	return nil
 }
 
+// Get the connection string of the database specified in database.toml.
+func postgresDataSource() string {
+	psqlInfo := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		cfg.DB_CONFIG.HOST, cfg.DB_CONFIG.PORT, cfg.DB_CONFIG.USER, cfg.DB_CONFIG.PWD, cfg.DB_CONFIG.DB_NAME,
+		strings.ToLower(cfg.DB_CONFIG.SSL_MODE))
+
+	if cfg.DB_CONFIG.SSL_ROOT_CERT != "" {
+		psqlInfo += " sslrootcert=" + cfg.DB_CONFIG.SSL_ROOT_CERT
+	}
+
+	return psqlInfo
+}
+
+// Connect to the specified database, and retry it with an exponential backoff up to CONNECT_RETRIES in
+// database.toml, e.g. while docker compose is still starting the database.
+func ConnectDBWithRetries(ctx context.Context) error {
+	return connectWithRetries(ctx, "database", cfg.DB_CONFIG.CONNECT_RETRIES, ConnectDB)
+}
+
+// Check if the database is reachable, i.e. it answered the latest ping of WatchDB, e.g. for the readiness probes.
+func DBAvailable() bool {
+	return dbAvailable.Load()
+}
+
+// Keep checking the database until ctx is done, and check it again with an exponential backoff while it is
+// unreachable. The connections are reopened by the pool once the database is reachable again.
+func WatchDB(ctx context.Context) {
+	watchConnection(ctx, &dbAvailable, pingDB,
+		"The database is unreachable, the requests fail until it is reconnected.",
+		"The database is reachable again.",
+	)
+}
+
+// Ping the database, with the timeout of a query.
+func pingDB(ctx context.Context) error {
+	if db == nil {
+		return ErrDBNotConnected
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	return db.PingContext(ctx)
+}
+
+// Derive the context of a query from the given one, which is cancelled after QUERY_TIMEOUT in database.toml.
+func withQueryTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	timeUnit, err := utils.TimeUnitStrToTimeDuration(cfg.DB_CONFIG.QUERY_TIMEOUT_UNIT)
+	if err != nil || cfg.DB_CONFIG.QUERY_TIMEOUT <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, time.Duration(cfg.DB_CONFIG.QUERY_TIMEOUT)*timeUnit)
+}
+
 // Disconnect from the database.
 func DisconnectDB() error {
 	if db == nil {
-		return errors.New("currently not connecting the database")
+		return ErrDBNotConnected
 	}
 
 	err := db.Close()
 	db = nil
+	dbAvailable.Store(false)
 	return err
 }
 
-// Add a new S/N into the database.
-func AddNewSN(sn string) error {
+// Add a new S/N of the given product into the database, product is "" if the S/N belongs to no product.
+func AddNewSN(ctx context.Context, sn string, product string) error {
 	if db == nil {
-		return errors.New("currently not connecting the database")
+		return ErrDBNotConnected
 	}
 
-	stmt, err := db.Prepare("INSERT INTO certs (sn, key, note) VALUES ($1, $2, $3)")
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	stmt, err := db.PrepareContext(ctx, "INSERT INTO certs (sn, key, note, product) VALUES ($1, $2, $3, NULLIF($4, ''))")
 	if err != nil {
 		return err
 	}
 
 	defer stmt.Close()
 
-	_, err = stmt.Exec(sn, sql.NullString{}, sql.NullString{})
+	_, err = stmt.ExecContext(ctx, sn, sql.NullString{}, sql.NullString{}, product)
 	if err != nil {
 		if strings.Contains(err.Error(), "duplicate key") {
-			return errors.New("the s/n already exists")
+			return ErrSNAlreadyExists
 		}
 		return err
 	}
@@ -74,103 +148,189 @@ func AddNewSN(sn string) error {
 }
 
 // Add new S/N(s) into the database.
-func AddNewSNs(snList []string) error {
+//
+// The S/N(s) are inserted in batches within one transaction, so either all of them are added or none.
+func AddNewSNs(ctx context.Context, snList []string) error {
 	if db == nil {
-		return errors.New("currently not connecting the database")
+		return ErrDBNotConnected
 	}
 
-	var valuesStrings []string
-
-	for _, sn := range snList {
-		valuesStrings = append(valuesStrings, fmt.Sprintf("('%s', NULL, NULL)", sn))
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
 	}
 
-	query := fmt.Sprintf("INSERT INTO certs (sn, key, note) VALUES %s;", strings.Join(valuesStrings, ", "))
+	defer tx.Rollback()
 
-	_, err := db.Exec(query)
-	if err != nil {
-		if strings.Contains(err.Error(), "duplicate key") {
-			return errors.New("some s/ns already exist")
+	batchSize := cfg.SERVER_CONFIG.SN_GENERATE_BATCH_SIZE
+
+	for start := 0; start < len(snList); start += batchSize {
+		end := min(start+batchSize, len(snList))
+
+		if _, err := insertSNBatch(ctx, tx, snList[start:end], "", "", false); err != nil {
+			if strings.Contains(err.Error(), "duplicate key") {
+				return ErrSNsAlreadyExist
+			}
+			return err
 		}
-		return err
 	}
 
-	return nil
+	return tx.Commit()
 }
 
-// Check if the given S/N exists in the database.
-func IsSNExist(sn string) (bool, error) {
+// Generate and add the given number of new S/N(s) into the database, and record them as the given batch.
+//
+// The batch record and the S/N(s) are inserted within one transaction. S/N(s) colliding with existing records are
+// regenerated and retried, so exactly `count` S/N(s) are added when no error occurs.
+//
+// onProgress is called after each batch with the number of S/N(s) inserted so far. Returning an error
+// from it aborts the generation and rolls back the transaction.
+func AddGeneratedSNs(
+	ctx context.Context, batch model.Batch, count int, generate func() (string, error),
+	onProgress func(inserted int) error,
+) ([]string, error) {
 	if db == nil {
-		return false, errors.New("currently not connecting the database")
+		return nil, ErrDBNotConnected
 	}
 
-	query := "SELECT EXISTS (SELECT 1 FROM certs WHERE sn = $1)"
-
-	var exists bool
-	err := db.QueryRow(query, sn).Scan(&exists)
-	if !exists {
-		return false, errors.New("the s/n does not exist")
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
 	}
 
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO batches (id, reason, created_by, reseller, order_number, product)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''))
+	`, batch.ID, batch.Reason, batch.CreatedBy, batch.Reseller, batch.OrderNumber, batch.Product)
+
 	if err != nil {
-		return false, err
+		return nil, err
+	}
+
+	batchSize := cfg.SERVER_CONFIG.SN_GENERATE_BATCH_SIZE
+	snList := make([]string, 0, count)
+	retries := 0
+
+	for len(snList) < count {
+		candidates := make([]string, 0, min(batchSize, count-len(snList)))
+
+		for len(candidates) < cap(candidates) {
+			sn, err := generate()
+			if err != nil {
+				return nil, err
+			}
+			candidates = append(candidates, sn)
+		}
+
+		inserted, err := insertSNBatch(ctx, tx, candidates, batch.ID, batch.Product, true)
+		if err != nil {
+			return nil, err
+		}
+
+		// Only the collided S/N(s) are regenerated in the next round.
+		if len(inserted) < len(candidates) {
+			retries++
+			if retries > maxCollisionRetries {
+				return nil, ErrSNCollisions
+			}
+		}
+
+		snList = append(snList, inserted...)
+
+		if onProgress != nil {
+			if err := onProgress(len(snList)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
 	}
 
-	return exists, nil
+	return snList, nil
 }
 
-// Bind the given serial number to the key. (Update the key field corresponding to the given S/N.)
-func BindSNWithKey(sn string, key string) error {
-	if db == nil {
-		return errors.New("currently not connecting the database")
+// The number of batches that may contain collided S/N(s) before the generation is given up.
+const maxCollisionRetries = 10
+
+// Insert a batch of S/N(s) with a parameterized statement and return the inserted ones.
+//
+// The S/N(s) are linked to the given batch ID and product, or to no batch/product if it is "".
+//
+// If skipExisting is true, the S/N(s) that already exist are skipped instead of failing the statement.
+func insertSNBatch(
+	ctx context.Context, tx *sql.Tx, batch []string, batchID string, product string, skipExisting bool,
+) ([]string, error) {
+	if len(batch) == 0 {
+		return nil, nil
 	}
 
-	stmt, err := db.Prepare(`
-		UPDATE certs
-		SET key = $1 
-		WHERE sn = $2 
-		AND (key IS NULL OR key = $1)
-	`)
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
 
-	if err != nil {
-		return err
+	placeholders := make([]string, len(batch))
+	args := make([]any, len(batch)+2)
+	args[0] = sql.NullString{String: batchID, Valid: batchID != ""}
+	args[1] = sql.NullString{String: product, Valid: product != ""}
+
+	for i, sn := range batch {
+		placeholders[i] = fmt.Sprintf("($%d, $1, $2)", i+3)
+		args[i+2] = sn
 	}
 
-	defer stmt.Close()
+	query := fmt.Sprintf("INSERT INTO certs (sn, batch_id, product) VALUES %s", strings.Join(placeholders, ", "))
+
+	if skipExisting {
+		query += " ON CONFLICT (sn) DO NOTHING"
+	}
+	query += " RETURNING sn"
 
-	res, err := stmt.Exec(key, sn)
+	rows, err := tx.QueryContext(ctx, query, args...)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	count, err := res.RowsAffected()
+	defer rows.Close()
+
+	inserted := make([]string, 0, len(batch))
 
-	if err != nil {
-		return err
+	for rows.Next() {
+		var sn string
+		if err := rows.Scan(&sn); err != nil {
+			return nil, err
+		}
+
+		inserted = append(inserted, sn)
 	}
 
-	if count == 0 {
-		return errors.New("the s/n does not exist or has already been used")
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 
-	return err
+	return inserted, nil
 }
 
 // Get the remaining trial period for the given key.
 //
 // If the key is not found, allow for temporary permit application.
-func GetTemporaryPermitExpiredTime(key string) (int64, error) {
+func GetTemporaryPermitExpiredTime(ctx context.Context, key string) (int64, error) {
 	if db == nil {
-		return 0, errors.New("currently not connecting the database")
+		return 0, ErrDBNotConnected
 	}
 
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	var expiration time.Time
 
 	query := "SELECT expiration FROM temporary_permits WHERE key = $1"
-	err := db.QueryRow(query, key).Scan(&expiration)
+	err := db.QueryRowContext(ctx, query, key).Scan(&expiration)
 
 	if err == sql.ErrNoRows {
-		return 0, fmt.Errorf("allowed new key: %s", key)
+		return 0, fmt.Errorf("%w: %s", ErrPermitNotFound, key)
 	} else if err != nil {
 		return 0, err
 	}
@@ -184,25 +344,27 @@ func GetTemporaryPermitExpiredTime(key string) (int64, error) {
 }
 
 // Providing temporary usage rights to trial clients.
-func AddTemporaryPermit(key string) (int64, error) {
+func AddTemporaryPermit(ctx context.Context, key string) (int64, error) {
 	if db == nil {
-		return 0, errors.New("currently not connecting the database")
+		return 0, ErrDBNotConnected
 	}
 
-	stmt, err := db.Prepare("INSERT INTO temporary_permits (key, expiration) VALUES ($1, $2)")
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	stmt, err := db.PrepareContext(ctx, "INSERT INTO temporary_permits (key, expiration) VALUES ($1, $2)")
 	if err != nil {
 		return 0, err
 	}
 
 	defer stmt.Close()
 
-	timeUnit, err := utils.TimeUnitStrToTimeDuration(cfg.SERVER_CONFIG.TEMPORARY_PERMIT_TIME_UNIT)
+	expiration, err := temporaryPermitExpiration()
 	if err != nil {
 		return 0, err
 	}
 
-	expiration := time.Now().Add(time.Duration(cfg.SERVER_CONFIG.TEMPORARY_PERMIT_TIME) * timeUnit)
-	_, err = stmt.Exec(key, expiration)
+	_, err = stmt.ExecContext(ctx, key, expiration)
 
 	if err != nil {
 		return 0, err
@@ -213,15 +375,28 @@ func AddTemporaryPermit(key string) (int64, error) {
 	return timeLeft, nil
 }
 
+// Get the expiration of a temporary permit applied now, see TEMPORARY_PERMIT_TIME in server.toml.
+func temporaryPermitExpiration() (time.Time, error) {
+	timeUnit, err := utils.TimeUnitStrToTimeDuration(cfg.SERVER_CONFIG.TEMPORARY_PERMIT_TIME_UNIT)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Now().Add(time.Duration(cfg.SERVER_CONFIG.TEMPORARY_PERMIT_TIME) * timeUnit), nil
+}
+
 // Get all certificate records in the database.
-func GetAllCerts() ([]model.Cert, error) {
+func GetAllCerts(ctx context.Context) ([]model.Cert, error) {
 	if db == nil {
-		return nil, errors.New("currently not connecting the database")
+		return nil, ErrDBNotConnected
 	}
 
-	query := "SELECT * FROM certs"
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := "SELECT sn, key, note, batch_id, revoked_at, metadata, product FROM certs"
 
-	rows, err := db.Query(query)
+	rows, err := db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -231,15 +406,11 @@ func GetAllCerts() ([]model.Cert, error) {
 	var certs []model.Cert
 
 	for rows.Next() {
-		var cert model.Cert
-		var tmpKey sql.NullString
-		var tmpNote sql.NullString
-		if err := rows.Scan(&cert.SerialNumber, &tmpKey, &tmpNote); err != nil {
+		cert, err := scanCert(rows)
+		if err != nil {
 			return nil, err
 		}
 
-		cert.Key = tmpKey.String
-		cert.Note = tmpNote.String
 		certs = append(certs, cert)
 	}
 
@@ -251,14 +422,17 @@ func GetAllCerts() ([]model.Cert, error) {
 }
 
 // Get available S/N in the database.
-func GetAvaliableSN() ([]string, error) {
+func GetAvaliableSN(ctx context.Context) ([]string, error) {
 	if db == nil {
-		return nil, errors.New("currently not connecting the database")
+		return nil, ErrDBNotConnected
 	}
 
-	query := "SELECT sn FROM certs where key is NULL"
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
 
-	rows, err := db.Query(query)
+	query := "SELECT sn FROM certs where key is NULL AND revoked_at IS NULL"
+
+	rows, err := db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -284,19 +458,22 @@ func GetAvaliableSN() ([]string, error) {
 }
 
 // Update the note field corresponding to the given S/N.
-func UpdateCertNote(sn string, note string) error {
+func UpdateCertNote(ctx context.Context, sn string, note string) error {
 	if db == nil {
-		return errors.New("currently not connecting the database")
+		return ErrDBNotConnected
 	}
 
-	stmt, err := db.Prepare("UPDATE certs SET note = $1 WHERE sn = $2")
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	stmt, err := db.PrepareContext(ctx, "UPDATE certs SET note = $1 WHERE sn = $2")
 	if err != nil {
 		return err
 	}
 
 	defer stmt.Close()
 
-	res, err := stmt.Exec(note, sn)
+	res, err := stmt.ExecContext(ctx, note, sn)
 	if err != nil {
 		return err
 	}
@@ -307,19 +484,22 @@ func UpdateCertNote(sn string, note string) error {
 	}
 
 	if rowsAffected == 0 {
-		return errors.New("the s/n does not exist")
+		return ErrSNNotFound
 	}
 
 	return nil
 }
 
 // Not a secure way to delete data, only for testing.
-func DeleteTestingData(stmt string, args ...any) error {
+func DeleteTestingData(ctx context.Context, stmt string, args ...any) error {
 	if db == nil {
-		return errors.New("currently not connecting the database")
+		return ErrDBNotConnected
 	}
 
-	_, err := db.Exec(stmt, args...)
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := db.ExecContext(ctx, stmt, args...)
 	if err != nil {
 		return err
 	}
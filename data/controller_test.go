@@ -1,10 +1,13 @@
 package data
 
 import (
+	"context"
+	"errors"
 	"testing"
 	"time"
 
 	cfg "github.com/mmq88/quickcerts/configs"
+	"github.com/mmq88/quickcerts/model"
 
 	"github.com/mmq88/quickcerts/utils"
 
@@ -40,6 +43,8 @@ func TestConnectAndDisconnect(t *testing.T) {
 }
 
 func TestAddNewSN(t *testing.T) {
+	ctx := context.Background()
+
 	backupHost := cfg.DB_CONFIG.HOST
 	backupPort := cfg.DB_CONFIG.PORT
 	defer func() {
@@ -48,7 +53,7 @@ func TestAddNewSN(t *testing.T) {
 	}()
 
 	// Test invalid case
-	err := AddNewSN("XXXX-XXXX-XXXX-XXXX-XXXX-XXXX")
+	err := AddNewSN(ctx, "XXXX-XXXX-XXXX-XXXX-XXXX-XXXX", "")
 	assert.Equal(t, "currently not connecting the database", err.Error())
 
 	// Test valid case
@@ -64,19 +69,21 @@ func TestAddNewSN(t *testing.T) {
 	}()
 
 	sn := "XXXX-XXXX-XXXX-XXXX-XXXX-XXXX"
-	err = AddNewSN(sn)
+	err = AddNewSN(ctx, sn, "")
 	assert.Nil(t, err)
 
 	// Test invalid case
-	err = AddNewSN(sn)
+	err = AddNewSN(ctx, sn, "")
 	assert.Equal(t, err.Error(), "the s/n already exists")
 
 	// Delete the added test data
-	err = DeleteTestingData("DELETE FROM certs WHERE sn = $1", sn)
+	err = DeleteTestingData(ctx, "DELETE FROM certs WHERE sn = $1", sn)
 	assert.Nil(t, err)
 }
 
 func TestAddNewSNs(t *testing.T) {
+	ctx := context.Background()
+
 	backupHost := cfg.DB_CONFIG.HOST
 	backupPort := cfg.DB_CONFIG.PORT
 	defer func() {
@@ -85,7 +92,7 @@ func TestAddNewSNs(t *testing.T) {
 	}()
 
 	// Test invalid case
-	err := AddNewSNs([]string{"XXXX-XXXX-XXXX-XXXX-XXXX-XXXX"})
+	err := AddNewSNs(ctx, []string{"XXXX-XXXX-XXXX-XXXX-XXXX-XXXX"})
 	assert.Equal(t, "currently not connecting the database", err.Error())
 
 	// Test valid case
@@ -105,74 +112,40 @@ func TestAddNewSNs(t *testing.T) {
 		"YYYY-YYYY-YYYY-YYYY-YYYY-YYYY",
 		"ZZZZ-ZZZZ-ZZZZ-ZZZZ-ZZZZ-ZZZZ",
 	}
-	err = AddNewSNs(snList)
+	err = AddNewSNs(ctx, snList)
 	assert.Nil(t, err)
 
 	// Test invalid case
-	err = AddNewSNs(snList)
+	err = AddNewSNs(ctx, snList)
 	assert.Equal(t, err.Error(), "some s/ns already exist")
 
 	// Delete the added test data
-	err = DeleteTestingData("DELETE FROM certs WHERE sn IN ($1, $2, $3)", snList[0], snList[1], snList[2])
+	err = DeleteTestingData(ctx, "DELETE FROM certs WHERE sn IN ($1, $2, $3)", snList[0], snList[1], snList[2])
 	assert.Nil(t, err)
 }
 
-func TestIsSNExist(t *testing.T) {
-	backupHost := cfg.DB_CONFIG.HOST
-	backupPort := cfg.DB_CONFIG.PORT
-	defer func() {
-		cfg.DB_CONFIG.HOST = backupHost
-		cfg.DB_CONFIG.PORT = backupPort
-	}()
-
-	// Test invalid case
-	_, err := IsSNExist("XXXX-XXXX-XXXX-XXXX-XXXX-XXXX")
-	assert.Equal(t, "currently not connecting the database", err.Error())
-
-	// Test valid case
-	// Uses docker-compose config
-	cfg.DB_CONFIG.HOST = "localhost"
-	cfg.DB_CONFIG.PORT = 33332
-
-	err = ConnectDB()
-	assert.Nil(t, err)
-	defer func() {
-		err = DisconnectDB()
-		assert.Nil(t, err)
-	}()
-
-	sn := "XXXX-XXXX-XXXX-XXXX-XXXX-XXXX"
-	err = AddNewSN(sn)
-	assert.Nil(t, err)
-
-	_, err = IsSNExist(sn)
-	assert.Nil(t, err)
+func TestAddGeneratedSNs(t *testing.T) {
+	ctx := context.Background()
 
-	// Test invalid case
-	_, err = IsSNExist("YYYY-YYYY-YYYY-YYYY-YYYY-YYYY")
-	assert.Equal(t, err.Error(), "the s/n does not exist")
-
-	// Delete the added test data
-	err = DeleteTestingData("DELETE FROM certs WHERE sn = $1", sn)
-	assert.Nil(t, err)
-}
-
-func TestBindSNWithKey(t *testing.T) {
 	backupHost := cfg.DB_CONFIG.HOST
 	backupPort := cfg.DB_CONFIG.PORT
+	backupBatchSize := cfg.SERVER_CONFIG.SN_GENERATE_BATCH_SIZE
 	defer func() {
 		cfg.DB_CONFIG.HOST = backupHost
 		cfg.DB_CONFIG.PORT = backupPort
+		cfg.SERVER_CONFIG.SN_GENERATE_BATCH_SIZE = backupBatchSize
 	}()
 
 	// Test invalid case
-	err := BindSNWithKey("XXXX-XXXX-XXXX-XXXX-XXXX-XXXX", "key")
+	batch := model.Batch{ID: "testBatch", Reason: "testReason", CreatedBy: "tester"}
+	_, err := AddGeneratedSNs(ctx, batch, 1, utils.GenerateSN, nil)
 	assert.Equal(t, "currently not connecting the database", err.Error())
 
 	// Test valid case
 	// Uses docker-compose config
 	cfg.DB_CONFIG.HOST = "localhost"
 	cfg.DB_CONFIG.PORT = 33332
+	cfg.SERVER_CONFIG.SN_GENERATE_BATCH_SIZE = 2
 
 	err = ConnectDB()
 	assert.Nil(t, err)
@@ -181,31 +154,55 @@ func TestBindSNWithKey(t *testing.T) {
 		assert.Nil(t, err)
 	}()
 
-	sn := "XXXX-XXXX-XXXX-XXXX-XXXX-XXXX"
-	key := "valid key"
-	err = AddNewSN(sn)
+	existingSN := "XXXX-XXXX-XXXX-XXXX-XXXX-XXXX"
+	err = AddNewSN(ctx, existingSN, "")
 	assert.Nil(t, err)
 
-	err = BindSNWithKey(sn, key)
-	assert.Nil(t, err)
+	// The first generated S/N collides with the existing one and has to be regenerated.
+	candidates := []string{
+		existingSN,
+		"YYYY-YYYY-YYYY-YYYY-YYYY-YYYY",
+		"ZZZZ-ZZZZ-ZZZZ-ZZZZ-ZZZZ-ZZZZ",
+		"1234-1234-1234-1234-1234-1234",
+	}
+	next := 0
+	generate := func() (string, error) {
+		sn := candidates[next]
+		next++
+		return sn, nil
+	}
 
-	// Assign the same key again should be ok
-	err = BindSNWithKey(sn, key)
+	progress := []int{}
+	snList, err := AddGeneratedSNs(ctx, batch, 3, generate, func(inserted int) error {
+		progress = append(progress, inserted)
+		return nil
+	})
 	assert.Nil(t, err)
+	assert.Equal(t, candidates[1:], snList)
+	assert.Equal(t, []int{1, 3}, progress)
 
-	// Test invalid case
-	err = BindSNWithKey(sn, "invalid key")
-	assert.Equal(t, err.Error(), "the s/n does not exist or has already been used")
+	_, err = IsBatchExist(ctx, batch.ID)
+	assert.Nil(t, err)
 
-	err = BindSNWithKey("invalid sn", "invalid key")
-	assert.Equal(t, err.Error(), "the s/n does not exist or has already been used")
+	// Test invalid case (The progress callback aborts the generation)
+	_, err = AddGeneratedSNs(ctx, model.Batch{ID: "testBatch2"}, 1, utils.GenerateSN, func(inserted int) error {
+		return errors.New("aborted")
+	})
+	assert.Equal(t, "aborted", err.Error())
 
 	// Delete the added test data
-	err = DeleteTestingData("DELETE FROM certs WHERE sn = $1", sn)
+	err = DeleteTestingData(ctx,
+		"DELETE FROM certs WHERE sn IN ($1, $2, $3, $4)",
+		candidates[0], candidates[1], candidates[2], candidates[3],
+	)
+	assert.Nil(t, err)
+	err = DeleteTestingData(ctx, "DELETE FROM batches WHERE id = $1", batch.ID)
 	assert.Nil(t, err)
 }
 
 func TestAddTemporaryPermit(t *testing.T) {
+	ctx := context.Background()
+
 	backupHost := cfg.DB_CONFIG.HOST
 	backupPort := cfg.DB_CONFIG.PORT
 	defer func() {
@@ -214,7 +211,7 @@ func TestAddTemporaryPermit(t *testing.T) {
 	}()
 
 	// Test invalid case
-	_, err := AddTemporaryPermit("key")
+	_, err := AddTemporaryPermit(ctx, "key")
 	assert.Equal(t, "currently not connecting the database", err.Error())
 
 	// Test valid case
@@ -229,7 +226,7 @@ func TestAddTemporaryPermit(t *testing.T) {
 		assert.Nil(t, err)
 	}()
 
-	remainingTime, err := AddTemporaryPermit("key")
+	remainingTime, err := AddTemporaryPermit(ctx, "key")
 
 	timeUnit, _ := utils.TimeUnitStrToTimeDuration(cfg.SERVER_CONFIG.TEMPORARY_PERMIT_TIME_UNIT)
 	expectedRemainingTime := time.Duration(cfg.SERVER_CONFIG.TEMPORARY_PERMIT_TIME) * timeUnit / time.Second
@@ -237,11 +234,13 @@ func TestAddTemporaryPermit(t *testing.T) {
 	assert.Equal(t, int64(expectedRemainingTime), remainingTime)
 
 	// Delete the added test data
-	err = DeleteTestingData("DELETE FROM temporary_permits WHERE key = $1", "key")
+	err = DeleteTestingData(ctx, "DELETE FROM temporary_permits WHERE key = $1", "key")
 	assert.Nil(t, err)
 }
 
 func TestGetTemporaryPermitExpiredTime(t *testing.T) {
+	ctx := context.Background()
+
 	backupHost := cfg.DB_CONFIG.HOST
 	backupPort := cfg.DB_CONFIG.PORT
 	defer func() {
@@ -250,7 +249,7 @@ func TestGetTemporaryPermitExpiredTime(t *testing.T) {
 	}()
 
 	// Test invalid case
-	_, err := AddTemporaryPermit("key")
+	_, err := AddTemporaryPermit(ctx, "key")
 	assert.Equal(t, "currently not connecting the database", err.Error())
 
 	// Test valid case
@@ -265,9 +264,9 @@ func TestGetTemporaryPermitExpiredTime(t *testing.T) {
 		assert.Nil(t, err)
 	}()
 
-	_, err = AddTemporaryPermit("key")
+	_, err = AddTemporaryPermit(ctx, "key")
 	assert.Nil(t, err)
-	remainingTime, err := GetTemporaryPermitExpiredTime("key")
+	remainingTime, err := GetTemporaryPermitExpiredTime(ctx, "key")
 	assert.Nil(t, err)
 
 	timeUnit, _ := utils.TimeUnitStrToTimeDuration(cfg.SERVER_CONFIG.TEMPORARY_PERMIT_TIME_UNIT)
@@ -276,11 +275,13 @@ func TestGetTemporaryPermitExpiredTime(t *testing.T) {
 	assert.LessOrEqual(t, remainingTime, NewRemainingTime)
 
 	// Delete the added test data
-	err = DeleteTestingData("DELETE FROM temporary_permits WHERE key = $1", "key")
+	err = DeleteTestingData(ctx, "DELETE FROM temporary_permits WHERE key = $1", "key")
 	assert.Nil(t, err)
 }
 
 func TestUpdateCertNote(t *testing.T) {
+	ctx := context.Background()
+
 	backupHost := cfg.DB_CONFIG.HOST
 	backupPort := cfg.DB_CONFIG.PORT
 	defer func() {
@@ -289,7 +290,7 @@ func TestUpdateCertNote(t *testing.T) {
 	}()
 
 	// Test invalid case
-	err := UpdateCertNote("XXXX-XXXX-XXXX-XXXX-XXXX-XXXX", "note")
+	err := UpdateCertNote(ctx, "XXXX-XXXX-XXXX-XXXX-XXXX-XXXX", "note")
 	assert.Equal(t, "currently not connecting the database", err.Error())
 
 	// Test valid case
@@ -306,22 +307,24 @@ func TestUpdateCertNote(t *testing.T) {
 
 	sn := "XXXX-XXXX-XXXX-XXXX-XXXX-XXXX"
 	note := "note"
-	err = AddNewSN(sn)
+	err = AddNewSN(ctx, sn, "")
 	assert.Nil(t, err)
 
-	err = UpdateCertNote(sn, note)
+	err = UpdateCertNote(ctx, sn, note)
 	assert.Nil(t, err)
 
 	// Test invalid case
-	err = UpdateCertNote("invalid sn", note)
+	err = UpdateCertNote(ctx, "invalid sn", note)
 	assert.Equal(t, err.Error(), "the s/n does not exist")
 
 	// Delete the added test data
-	err = DeleteTestingData("DELETE FROM certs WHERE sn = $1", sn)
+	err = DeleteTestingData(ctx, "DELETE FROM certs WHERE sn = $1", sn)
 	assert.Nil(t, err)
 }
 
 func TestGetAvaliableSN(t *testing.T) {
+	ctx := context.Background()
+
 	backupHost := cfg.DB_CONFIG.HOST
 	backupPort := cfg.DB_CONFIG.PORT
 	defer func() {
@@ -330,7 +333,7 @@ func TestGetAvaliableSN(t *testing.T) {
 	}()
 
 	// Test invalid case
-	_, err := GetAvaliableSN()
+	_, err := GetAvaliableSN(ctx)
 	assert.Equal(t, "currently not connecting the database", err.Error())
 
 	// Test valid case
@@ -344,7 +347,7 @@ func TestGetAvaliableSN(t *testing.T) {
 		assert.Nil(t, err)
 	}()
 
-	resList, err := GetAvaliableSN()
+	resList, err := GetAvaliableSN(ctx)
 	assert.Nil(t, err)
 
 	snList := []string{
@@ -357,10 +360,10 @@ func TestGetAvaliableSN(t *testing.T) {
 	assert.NotContains(t, resList, snList[1])
 	assert.NotContains(t, resList, snList[2])
 
-	err = AddNewSNs(snList)
+	err = AddNewSNs(ctx, snList)
 	assert.Nil(t, err)
 
-	resList, err = GetAvaliableSN()
+	resList, err = GetAvaliableSN(ctx)
 	assert.Nil(t, err)
 
 	assert.Contains(t, resList, snList[0])
@@ -368,11 +371,13 @@ func TestGetAvaliableSN(t *testing.T) {
 	assert.Contains(t, resList, snList[2])
 
 	// Delete the added test data
-	err = DeleteTestingData("DELETE FROM certs WHERE sn IN ($1, $2, $3)", snList[0], snList[1], snList[2])
+	err = DeleteTestingData(ctx, "DELETE FROM certs WHERE sn IN ($1, $2, $3)", snList[0], snList[1], snList[2])
 	assert.Nil(t, err)
 }
 
 func TestGetAllCerts(t *testing.T) {
+	ctx := context.Background()
+
 	backupHost := cfg.DB_CONFIG.HOST
 	backupPort := cfg.DB_CONFIG.PORT
 	defer func() {
@@ -381,7 +386,7 @@ func TestGetAllCerts(t *testing.T) {
 	}()
 
 	// Test invalid case
-	_, err := GetAllCerts()
+	_, err := GetAllCerts(ctx)
 	assert.Equal(t, "currently not connecting the database", err.Error())
 
 	// Test valid case
@@ -395,15 +400,15 @@ func TestGetAllCerts(t *testing.T) {
 		assert.Nil(t, err)
 	}()
 
-	resList, err := GetAllCerts()
+	resList, err := GetAllCerts(ctx)
 	assert.Nil(t, err)
 
 	allCertsLength := len(resList)
 	sn := "XXXX-XXXX-XXXX-XXXX-XXXX-XXXX"
-	err = AddNewSN(sn)
+	err = AddNewSN(ctx, sn, "")
 	assert.Nil(t, err)
 
-	resList, err = GetAllCerts()
+	resList, err = GetAllCerts(ctx)
 	assert.Nil(t, err)
 	assert.Equal(t, allCertsLength+1, len(resList))
 
@@ -416,11 +421,13 @@ func TestGetAllCerts(t *testing.T) {
 	}
 
 	// Delete the added test data
-	err = DeleteTestingData("DELETE FROM certs WHERE sn = $1", sn)
+	err = DeleteTestingData(ctx, "DELETE FROM certs WHERE sn = $1", sn)
 	assert.Nil(t, err)
 }
 
 func TestDeleteTestingData(t *testing.T) {
-	err := DeleteTestingData("", "")
+	ctx := context.Background()
+
+	err := DeleteTestingData(ctx, "", "")
 	assert.Equal(t, "currently not connecting the database", err.Error())
 }
@@ -0,0 +1,65 @@
+package data
+
+import (
+	"errors"
+
+	"github.com/mmq88/quickcerts/model"
+)
+
+// The errors returned by the data layer, which are checked with errors.Is.
+var (
+	ErrDBNotConnected     = errors.New("currently not connecting the database")
+	ErrDBConnectFailed    = errors.New("failed to connect the database")
+	ErrDBAccessFailed     = errors.New("failed to access the database")
+	ErrRDBNotConnected    = errors.New("currently not connecting the redis database")
+	ErrRDBAccessFailed    = errors.New("failed to access the redis database")
+	ErrCacheKeyNotFound   = errors.New("the key not exist in the cache")
+	ErrSNNotFound         = errors.New("the s/n does not exist")
+	ErrSNAlreadyExists    = errors.New("the s/n already exists")
+	ErrSNsAlreadyExist    = errors.New("some s/ns already exist")
+	ErrSNAlreadyBound     = errors.New("the s/n has been bound to a device")
+	ErrSNCollisions       = errors.New("too many s/n collisions")
+	ErrPermitNotFound     = errors.New("allowed new key")
+	ErrBatchNotFound      = errors.New("the batch does not exist")
+	ErrJobNotFound        = errors.New("the job does not exist")
+	ErrAdminTokenInvalid  = errors.New("the admin token is invalid")
+	ErrAdminTokenNotFound = errors.New("the admin token does not exist")
+	ErrTokenTooShort      = errors.New("the token is too short")
+	ErrClientKeyInvalid   = errors.New("the client key is invalid")
+	ErrClientKeyNotFound  = errors.New("the client key does not exist")
+	ErrSchemaTooNew       = errors.New("the schema of the database is newer than the server supports")
+	ErrSchemaOutdated     = errors.New("the schema of the database is outdated")
+)
+
+var errorCodes = []struct {
+	err  error
+	code string
+}{
+	{ErrDBNotConnected, model.ErrCodeDatabaseUnavailable},
+	{ErrDBConnectFailed, model.ErrCodeDatabaseUnavailable},
+	{ErrDBAccessFailed, model.ErrCodeDatabaseUnavailable},
+	{ErrRDBNotConnected, model.ErrCodeCacheUnavailable},
+	{ErrRDBAccessFailed, model.ErrCodeCacheUnavailable},
+	{ErrSNNotFound, model.ErrCodeSNNotFound},
+	{ErrSNAlreadyExists, model.ErrCodeSNAlreadyExists},
+	{ErrSNsAlreadyExist, model.ErrCodeSNAlreadyExists},
+	{ErrSNAlreadyBound, model.ErrCodeSNAlreadyBound},
+	{ErrBatchNotFound, model.ErrCodeBatchNotFound},
+	{ErrJobNotFound, model.ErrCodeJobNotFound},
+	{ErrAdminTokenInvalid, model.ErrCodeUnauthorized},
+	{ErrAdminTokenNotFound, model.ErrCodeAdminTokenNotFound},
+	{ErrTokenTooShort, model.ErrCodeInvalidRequest},
+	{ErrClientKeyInvalid, model.ErrCodeUnauthorized},
+	{ErrClientKeyNotFound, model.ErrCodeClientKeyNotFound},
+}
+
+// Get the error code of the response for the given error, or INTERNAL_ERROR if it is not a known error.
+func ErrorCode(err error) string {
+	for _, errorCode := range errorCodes {
+		if errors.Is(err, errorCode.err) {
+			return errorCode.code
+		}
+	}
+
+	return model.ErrCodeInternal
+}
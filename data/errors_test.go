@@ -0,0 +1,19 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/mmq88/quickcerts/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorCode(t *testing.T) {
+	assert.Equal(t, model.ErrCodeSNNotFound, ErrorCode(ErrSNNotFound))
+	assert.Equal(t, model.ErrCodeSNAlreadyBound, ErrorCode(fmt.Errorf("archive: %w", ErrSNAlreadyBound)))
+	assert.Equal(t, model.ErrCodeCacheUnavailable, ErrorCode(ErrRDBNotConnected))
+	assert.Equal(t, model.ErrCodeDatabaseUnavailable, ErrorCode(ErrDBAccessFailed))
+	assert.Equal(t, model.ErrCodeInternal, ErrorCode(errors.New("unexpected")))
+}
@@ -0,0 +1,281 @@
+package data
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// How long the failed admin authentications from an IP address are kept after the latest one.
+const adminFailuresTTL = time.Hour * 24
+
+// Get the key of the counter of the current fixed window of a rate limit, and the time until the window resets.
+func rateLimitWindow(scope string, id string, window time.Duration) (string, time.Duration) {
+	now := time.Now()
+	windowIndex := now.UnixNano() / int64(window)
+	resetAt := time.Unix(0, (windowIndex+1)*int64(window))
+
+	return "rate_limit:" + scope + ":" + id + ":" + strconv.FormatInt(windowIndex, 10), resetAt.Sub(now)
+}
+
+func adminFailuresKey(ip string) string {
+	return "admin_auth_failures:" + ip
+}
+
+func adminLockKey(ip string) string {
+	return "admin_auth_lock:" + ip
+}
+
+func requestNonceKey(tokenID string, nonce string) string {
+	return "admin_nonce:" + tokenID + ":" + nonce
+}
+
+// Get how long an IP address is locked out after the given number of failed admin authentications, or 0 if the
+// failures have not reached the threshold, see RecordAdminAuthFailure.
+func adminLockoutDuration(failures int64, threshold int, base time.Duration, max time.Duration) time.Duration {
+	exceeded := failures - int64(threshold)
+	if exceeded < 0 {
+		return 0
+	}
+
+	if exceeded < 32 && base<<exceeded < max {
+		return base << exceeded
+	}
+
+	return max
+}
+
+// The counters with expiry behind a GuardCache, which work as the redis commands of the same names.
+type guardEntries interface {
+	// Increase the counter by 1, and expire it after ttl.
+	incr(ctx context.Context, key string, ttl time.Duration) (int64, error)
+	// Get the remaining time of the counter, or 0 if it does not exist.
+	ttl(ctx context.Context, key string) (time.Duration, error)
+	// Set the counter to 1 and expire it after ttl, replacing the existing one.
+	set(ctx context.Context, key string, ttl time.Duration) error
+	// Set the counter if it does not exist, and return false if it does.
+	setNX(ctx context.Context, key string, ttl time.Duration) (bool, error)
+	del(ctx context.Context, key string) error
+}
+
+func countRateLimitedEntry(
+	ctx context.Context, entries guardEntries, scope string, id string, window time.Duration,
+) (int64, time.Duration, error) {
+	counterKey, resetIn := rateLimitWindow(scope, id, window)
+
+	count, err := entries.incr(ctx, counterKey, window*2)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return count, resetIn, nil
+}
+
+func recordAdminAuthFailureEntry(
+	ctx context.Context, entries guardEntries, ip string, threshold int, base time.Duration, max time.Duration,
+) (time.Duration, error) {
+	failures, err := entries.incr(ctx, adminFailuresKey(ip), adminFailuresTTL)
+	if err != nil {
+		return 0, err
+	}
+
+	lockout := adminLockoutDuration(failures, threshold, base, max)
+	if lockout == 0 {
+		return 0, nil
+	}
+
+	if err := entries.set(ctx, adminLockKey(ip), lockout); err != nil {
+		return 0, err
+	}
+
+	return lockout, nil
+}
+
+// The GuardCache kept in memory, the entries expire as the ones in redis.
+//
+// Create it with NewMemoryGuardCache.
+type MemoryGuardCache struct {
+	mu      sync.Mutex
+	entries map[string]memoryGuardEntry
+	sweptAt time.Time
+}
+
+type memoryGuardEntry struct {
+	value     int64
+	expiresAt time.Time
+}
+
+// How often the expired entries of a MemoryGuardCache are removed.
+const memoryGuardSweepInterval = time.Minute
+
+var (
+	_ GuardCache = (*MemoryGuardCache)(nil)
+	_ GuardCache = (*RedisGuardCache)(nil)
+)
+
+// Create an empty MemoryGuardCache.
+func NewMemoryGuardCache() *MemoryGuardCache {
+	return &MemoryGuardCache{entries: map[string]memoryGuardEntry{}, sweptAt: time.Now()}
+}
+
+func (c *MemoryGuardCache) CountRateLimitedRequest(
+	ctx context.Context, scope string, id string, window time.Duration,
+) (int64, time.Duration, error) {
+	return countRateLimitedEntry(ctx, c, scope, id, window)
+}
+
+func (c *MemoryGuardCache) GetAdminLockout(ctx context.Context, ip string) (time.Duration, error) {
+	return c.ttl(ctx, adminLockKey(ip))
+}
+
+func (c *MemoryGuardCache) RecordAdminAuthFailure(
+	ctx context.Context, ip string, threshold int, base time.Duration, max time.Duration,
+) (time.Duration, error) {
+	return recordAdminAuthFailureEntry(ctx, c, ip, threshold, base, max)
+}
+
+func (c *MemoryGuardCache) ResetAdminAuthFailures(ctx context.Context, ip string) error {
+	return c.del(ctx, adminFailuresKey(ip))
+}
+
+func (c *MemoryGuardCache) ClaimRequestNonce(
+	ctx context.Context, tokenID string, nonce string, ttl time.Duration,
+) (bool, error) {
+	return c.setNX(ctx, requestNonceKey(tokenID, nonce), ttl)
+}
+
+// Get the entry of the key if it has not expired, and remove the expired entries once in a while, so the keys of
+// the past windows and nonces do not pile up. The lock should be held.
+func (c *MemoryGuardCache) lookup(key string, now time.Time) (memoryGuardEntry, bool) {
+	if now.Sub(c.sweptAt) >= memoryGuardSweepInterval {
+		for k, entry := range c.entries {
+			if !now.Before(entry.expiresAt) {
+				delete(c.entries, k)
+			}
+		}
+		c.sweptAt = now
+	}
+
+	entry, ok := c.entries[key]
+	if !ok || !now.Before(entry.expiresAt) {
+		return memoryGuardEntry{}, false
+	}
+
+	return entry, true
+}
+
+func (c *MemoryGuardCache) incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	entry, _ := c.lookup(key, now)
+	entry.value++
+	entry.expiresAt = now.Add(ttl)
+	c.entries[key] = entry
+
+	return entry.value, nil
+}
+
+func (c *MemoryGuardCache) ttl(ctx context.Context, key string) (time.Duration, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	entry, ok := c.lookup(key, now)
+	if !ok {
+		return 0, nil
+	}
+
+	return entry.expiresAt.Sub(now), nil
+}
+
+func (c *MemoryGuardCache) set(ctx context.Context, key string, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[key] = memoryGuardEntry{value: 1, expiresAt: time.Now().Add(ttl)}
+	return nil
+}
+
+func (c *MemoryGuardCache) setNX(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if _, ok := c.lookup(key, now); ok {
+		return false, nil
+	}
+
+	c.entries[key] = memoryGuardEntry{value: 1, expiresAt: now.Add(ttl)}
+	return true, nil
+}
+
+func (c *MemoryGuardCache) del(ctx context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, key)
+	return nil
+}
+
+// The GuardCache backed by the redis database connected by ConnectRDB.
+//
+// While the redis database is unreachable, the rate limits, lockouts and nonces are kept in memory instead, so they
+// are still enforced by this process, though without the ones recorded in redis before.
+// Create it with NewRedisGuardCache.
+type RedisGuardCache struct {
+	fallback *MemoryGuardCache
+}
+
+// Create a RedisGuardCache with an empty fallback.
+func NewRedisGuardCache() *RedisGuardCache {
+	return &RedisGuardCache{fallback: NewMemoryGuardCache()}
+}
+
+func (c *RedisGuardCache) CountRateLimitedRequest(
+	ctx context.Context, scope string, id string, window time.Duration,
+) (int64, time.Duration, error) {
+	if !RDBAvailable() {
+		return c.fallback.CountRateLimitedRequest(ctx, scope, id, window)
+	}
+
+	return CountRateLimitedRequest(ctx, scope, id, window)
+}
+
+func (c *RedisGuardCache) GetAdminLockout(ctx context.Context, ip string) (time.Duration, error) {
+	if !RDBAvailable() {
+		return c.fallback.GetAdminLockout(ctx, ip)
+	}
+
+	return GetAdminLockout(ctx, ip)
+}
+
+func (c *RedisGuardCache) RecordAdminAuthFailure(
+	ctx context.Context, ip string, threshold int, base time.Duration, max time.Duration,
+) (time.Duration, error) {
+	if !RDBAvailable() {
+		return c.fallback.RecordAdminAuthFailure(ctx, ip, threshold, base, max)
+	}
+
+	return RecordAdminAuthFailure(ctx, ip, threshold, base, max)
+}
+
+func (c *RedisGuardCache) ResetAdminAuthFailures(ctx context.Context, ip string) error {
+	if !RDBAvailable() {
+		return c.fallback.ResetAdminAuthFailures(ctx, ip)
+	}
+
+	return ResetAdminAuthFailures(ctx, ip)
+}
+
+func (c *RedisGuardCache) ClaimRequestNonce(
+	ctx context.Context, tokenID string, nonce string, ttl time.Duration,
+) (bool, error) {
+	if !RDBAvailable() {
+		return c.fallback.ClaimRequestNonce(ctx, tokenID, nonce, ttl)
+	}
+
+	return ClaimRequestNonce(ctx, tokenID, nonce, ttl)
+}
@@ -0,0 +1,118 @@
+package data
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminLockoutDuration(t *testing.T) {
+	assert.Equal(t, time.Duration(0), adminLockoutDuration(2, 3, time.Minute, time.Hour))
+	assert.Equal(t, time.Minute, adminLockoutDuration(3, 3, time.Minute, time.Hour))
+	assert.Equal(t, 4*time.Minute, adminLockoutDuration(5, 3, time.Minute, time.Hour))
+	assert.Equal(t, time.Hour, adminLockoutDuration(100, 3, time.Minute, time.Hour))
+}
+
+// Test the GuardCache methods shared by the backends, the entries are expected to be empty.
+func testGuardCache(t *testing.T, cache GuardCache) {
+	ctx := context.Background()
+
+	count, resetIn, err := cache.CountRateLimitedRequest(ctx, "apply_ip", "127.0.0.1", time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), count)
+	assert.True(t, resetIn > 0 && resetIn <= time.Minute)
+
+	count, _, err = cache.CountRateLimitedRequest(ctx, "apply_ip", "127.0.0.1", time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), count)
+
+	count, _, err = cache.CountRateLimitedRequest(ctx, "apply_key", "127.0.0.1", time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), count)
+
+	// The IP address is locked out once the failures reach the threshold.
+	lockout, err := cache.RecordAdminAuthFailure(ctx, "127.0.0.1", 2, time.Minute, time.Hour)
+	assert.Nil(t, err)
+	assert.Equal(t, time.Duration(0), lockout)
+
+	lockout, err = cache.GetAdminLockout(ctx, "127.0.0.1")
+	assert.Nil(t, err)
+	assert.Equal(t, time.Duration(0), lockout)
+
+	lockout, err = cache.RecordAdminAuthFailure(ctx, "127.0.0.1", 2, time.Minute, time.Hour)
+	assert.Nil(t, err)
+	assert.Equal(t, time.Minute, lockout)
+
+	lockout, err = cache.GetAdminLockout(ctx, "127.0.0.1")
+	assert.Nil(t, err)
+	assert.True(t, lockout > 0 && lockout <= time.Minute)
+
+	// The lockout is doubled by the next failure.
+	lockout, err = cache.RecordAdminAuthFailure(ctx, "127.0.0.1", 2, time.Minute, time.Hour)
+	assert.Nil(t, err)
+	assert.Equal(t, 2*time.Minute, lockout)
+
+	lockout, err = cache.GetAdminLockout(ctx, "127.0.0.1")
+	assert.Nil(t, err)
+	assert.True(t, lockout > time.Minute && lockout <= 2*time.Minute)
+
+	// The failures are forgotten, but the current lockout is kept.
+	err = cache.ResetAdminAuthFailures(ctx, "127.0.0.1")
+	assert.Nil(t, err)
+
+	lockout, err = cache.RecordAdminAuthFailure(ctx, "127.0.0.1", 2, time.Minute, time.Hour)
+	assert.Nil(t, err)
+	assert.Equal(t, time.Duration(0), lockout)
+
+	claimed, err := cache.ClaimRequestNonce(ctx, "token", "nonce", time.Minute)
+	assert.Nil(t, err)
+	assert.True(t, claimed)
+
+	claimed, err = cache.ClaimRequestNonce(ctx, "token", "nonce", time.Minute)
+	assert.Nil(t, err)
+	assert.False(t, claimed)
+
+	claimed, err = cache.ClaimRequestNonce(ctx, "other-token", "nonce", time.Minute)
+	assert.Nil(t, err)
+	assert.True(t, claimed)
+
+	// The nonce can be claimed again once it expires.
+	claimed, err = cache.ClaimRequestNonce(ctx, "token", "short", time.Millisecond)
+	assert.Nil(t, err)
+	assert.True(t, claimed)
+
+	time.Sleep(5 * time.Millisecond)
+
+	claimed, err = cache.ClaimRequestNonce(ctx, "token", "short", time.Millisecond)
+	assert.Nil(t, err)
+	assert.True(t, claimed)
+}
+
+func TestMemoryGuardCache(t *testing.T) {
+	testGuardCache(t, NewMemoryGuardCache())
+}
+
+func TestMemoryGuardCacheSweep(t *testing.T) {
+	ctx := context.Background()
+	cache := NewMemoryGuardCache()
+
+	_, err := cache.ClaimRequestNonce(ctx, "token", "nonce", time.Millisecond)
+	assert.Nil(t, err)
+	assert.Len(t, cache.entries, 1)
+
+	// The expired entries are removed by the next access after the sweep interval.
+	time.Sleep(5 * time.Millisecond)
+	cache.sweptAt = time.Now().Add(-memoryGuardSweepInterval)
+
+	_, err = cache.GetAdminLockout(ctx, "127.0.0.1")
+	assert.Nil(t, err)
+	assert.Empty(t, cache.entries)
+}
+
+func TestRedisGuardCacheWithoutRedis(t *testing.T) {
+	// The redis database is not connected, the entries are kept in memory instead of failing the requests.
+	assert.False(t, RDBAvailable())
+	testGuardCache(t, NewRedisGuardCache())
+}
@@ -0,0 +1,144 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/mmq88/quickcerts/model"
+)
+
+// Add a new job into the database.
+func AddJob(ctx context.Context, job model.Job) error {
+	if db == nil {
+		return ErrDBNotConnected
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	stmt, err := db.PrepareContext(ctx, `
+		INSERT INTO jobs (id, type, status, progress, total, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`)
+
+	if err != nil {
+		return err
+	}
+
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, job.ID, job.Type, job.Status, job.Progress, job.Total, job.CreatedBy)
+	return err
+}
+
+// Get the job with the given ID.
+func GetJob(ctx context.Context, id string) (model.Job, error) {
+	if db == nil {
+		return model.Job{}, ErrDBNotConnected
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT id, type, status, progress, total, result, error, created_by, created_at, updated_at
+		FROM jobs WHERE id = $1
+	`
+
+	var job model.Job
+	var tmpResult sql.NullString
+	var tmpError sql.NullString
+	var tmpCreatedBy sql.NullString
+
+	err := db.QueryRowContext(ctx, query, id).Scan(
+		&job.ID, &job.Type, &job.Status, &job.Progress, &job.Total,
+		&tmpResult, &tmpError, &tmpCreatedBy, &job.CreatedAt, &job.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return model.Job{}, ErrJobNotFound
+	} else if err != nil {
+		return model.Job{}, err
+	}
+
+	job.Result = tmpResult.String
+	job.Error = tmpError.String
+	job.CreatedBy = tmpCreatedBy.String
+
+	return job, nil
+}
+
+// Update the progress of the given job.
+func UpdateJobProgress(ctx context.Context, id string, progress int) error {
+	if db == nil {
+		return ErrDBNotConnected
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := db.ExecContext(ctx, "UPDATE jobs SET progress = $1, updated_at = NOW() WHERE id = $2", progress, id)
+	return err
+}
+
+// Update the status of the given job, along with the result location or the error message.
+func UpdateJobStatus(ctx context.Context, id string, status string, result string, errMsg string) error {
+	if db == nil {
+		return ErrDBNotConnected
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	stmt, err := db.PrepareContext(ctx, `
+		UPDATE jobs
+		SET status = $1, result = NULLIF($2, ''), error = NULLIF($3, ''), updated_at = NOW()
+		WHERE id = $4
+	`)
+
+	if err != nil {
+		return err
+	}
+
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, status, result, errMsg, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrJobNotFound
+	}
+
+	return nil
+}
+
+// Mark the jobs that were queued or running when the server stopped as failed.
+//
+// Returns the number of affected jobs.
+func FailUnfinishedJobs(ctx context.Context, errMsg string) (int64, error) {
+	if db == nil {
+		return 0, ErrDBNotConnected
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := db.ExecContext(ctx, `
+		UPDATE jobs
+		SET status = $1, error = $2, updated_at = NOW()
+		WHERE status IN ($3, $4)
+	`, model.JobStatusFailed, errMsg, model.JobStatusQueued, model.JobStatusRunning)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
@@ -0,0 +1,111 @@
+package data
+
+import (
+	"context"
+	"testing"
+
+	cfg "github.com/mmq88/quickcerts/configs"
+	"github.com/mmq88/quickcerts/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddAndGetJob(t *testing.T) {
+	ctx := context.Background()
+
+	backupHost := cfg.DB_CONFIG.HOST
+	backupPort := cfg.DB_CONFIG.PORT
+	defer func() {
+		cfg.DB_CONFIG.HOST = backupHost
+		cfg.DB_CONFIG.PORT = backupPort
+	}()
+
+	// Test invalid case
+	_, err := GetJob(ctx, "testJob")
+	assert.Equal(t, "currently not connecting the database", err.Error())
+
+	// Test valid case
+	// Uses docker-compose config
+	cfg.DB_CONFIG.HOST = "localhost"
+	cfg.DB_CONFIG.PORT = 33332
+
+	err = ConnectDB()
+	assert.Nil(t, err)
+	defer func() {
+		err = DisconnectDB()
+		assert.Nil(t, err)
+	}()
+
+	err = AddJob(ctx, model.Job{ID: "testJob", Type: "test", Status: model.JobStatusQueued, Total: 10, CreatedBy: "tester"})
+	assert.Nil(t, err)
+
+	err = UpdateJobProgress(ctx, "testJob", 4)
+	assert.Nil(t, err)
+
+	job, err := GetJob(ctx, "testJob")
+	assert.Nil(t, err)
+	assert.Equal(t, model.JobStatusQueued, job.Status)
+	assert.Equal(t, 4, job.Progress)
+	assert.Equal(t, 10, job.Total)
+	assert.Equal(t, "tester", job.CreatedBy)
+	assert.False(t, job.IsFinished())
+
+	err = UpdateJobStatus(ctx, "testJob", model.JobStatusSucceeded, "job_results/testJob.txt", "")
+	assert.Nil(t, err)
+
+	job, err = GetJob(ctx, "testJob")
+	assert.Nil(t, err)
+	assert.Equal(t, model.JobStatusSucceeded, job.Status)
+	assert.Equal(t, "job_results/testJob.txt", job.Result)
+	assert.True(t, job.IsFinished())
+
+	// Test invalid case
+	_, err = GetJob(ctx, "none")
+	assert.Equal(t, "the job does not exist", err.Error())
+
+	err = UpdateJobStatus(ctx, "none", model.JobStatusFailed, "", "")
+	assert.Equal(t, "the job does not exist", err.Error())
+
+	// Delete the added test data
+	err = DeleteTestingData(ctx, "DELETE FROM jobs WHERE id = $1", "testJob")
+	assert.Nil(t, err)
+}
+
+func TestFailUnfinishedJobs(t *testing.T) {
+	ctx := context.Background()
+
+	backupHost := cfg.DB_CONFIG.HOST
+	backupPort := cfg.DB_CONFIG.PORT
+	defer func() {
+		cfg.DB_CONFIG.HOST = backupHost
+		cfg.DB_CONFIG.PORT = backupPort
+	}()
+
+	// Test valid case
+	// Uses docker-compose config
+	cfg.DB_CONFIG.HOST = "localhost"
+	cfg.DB_CONFIG.PORT = 33332
+
+	err := ConnectDB()
+	assert.Nil(t, err)
+	defer func() {
+		err = DisconnectDB()
+		assert.Nil(t, err)
+	}()
+
+	err = AddJob(ctx, model.Job{ID: "testJob", Type: "test", Status: model.JobStatusRunning})
+	assert.Nil(t, err)
+
+	count, err := FailUnfinishedJobs(ctx, "interrupted")
+	assert.Nil(t, err)
+	assert.GreaterOrEqual(t, count, int64(1))
+
+	job, err := GetJob(ctx, "testJob")
+	assert.Nil(t, err)
+	assert.Equal(t, model.JobStatusFailed, job.Status)
+	assert.Equal(t, "interrupted", job.Error)
+
+	// Delete the added test data
+	err = DeleteTestingData(ctx, "DELETE FROM jobs WHERE id = $1", "testJob")
+	assert.Nil(t, err)
+}
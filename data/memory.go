@@ -0,0 +1,1022 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	cfg "github.com/mmq88/quickcerts/configs"
+	"github.com/mmq88/quickcerts/model"
+	"github.com/mmq88/quickcerts/utils"
+)
+
+// The stores kept in memory, e.g. for the tests and the programs embedding the API without Postgres.
+//
+// Every method holds the lock while it runs, so the operations are atomic as the transactions of PostgresStore.
+// Create it with NewMemoryStore.
+type MemoryStore struct {
+	mu          sync.Mutex
+	certs       map[string]*model.Cert
+	batches     map[string]model.Batch
+	archived    []model.ArchivedCert
+	activations []model.CertActivation
+	permits     map[string]time.Time
+	adminTokens []*model.AdminToken
+	clientKeys  []*model.ClientKey
+	// The admin tokens and client keys keyed by the lookup hashes of the tokens, see utils.TokenLookupHash.
+	adminTokenLookup map[string]*model.AdminToken
+	clientKeyLookup  map[string]*model.ClientKey
+	auditLogs        []model.AuditLog
+	jobs             map[string]*model.Job
+}
+
+var (
+	_ Store    = (*MemoryStore)(nil)
+	_ KeyCache = (*MemoryKeyCache)(nil)
+)
+
+var errTokenExists = errors.New("the token already exists")
+
+// Create an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		certs:            map[string]*model.Cert{},
+		batches:          map[string]model.Batch{},
+		permits:          map[string]time.Time{},
+		adminTokenLookup: map[string]*model.AdminToken{},
+		clientKeyLookup:  map[string]*model.ClientKey{},
+		jobs:             map[string]*model.Job{},
+	}
+}
+
+func (s *MemoryStore) AddNewSN(ctx context.Context, sn string, product string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.certs[sn]; ok {
+		return ErrSNAlreadyExists
+	}
+
+	s.certs[sn] = &model.Cert{SerialNumber: sn, Product: product, Metadata: map[string]any{}}
+	return nil
+}
+
+// Generate and add the given number of new S/N(s), and record them as the given batch.
+//
+// The S/N(s) are only added when all of them are generated, see AddGeneratedSNs for the details.
+func (s *MemoryStore) AddGeneratedSNs(
+	ctx context.Context, batch model.Batch, count int, generate func() (string, error),
+	onProgress func(inserted int) error,
+) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.batches[batch.ID]; ok {
+		return nil, fmt.Errorf("the batch [%s] already exists", batch.ID)
+	}
+
+	batchSize := max(cfg.SERVER_CONFIG.SN_GENERATE_BATCH_SIZE, 1)
+	generated := map[string]bool{}
+	snList := make([]string, 0, count)
+	retries := 0
+
+	for len(snList) < count {
+		candidates := min(batchSize, count-len(snList))
+		inserted := 0
+
+		for i := 0; i < candidates; i++ {
+			sn, err := generate()
+			if err != nil {
+				return nil, err
+			}
+
+			if _, ok := s.certs[sn]; ok || generated[sn] {
+				continue
+			}
+
+			generated[sn] = true
+			snList = append(snList, sn)
+			inserted++
+		}
+
+		// Only the collided S/N(s) are regenerated in the next round.
+		if inserted < candidates {
+			retries++
+			if retries > maxCollisionRetries {
+				return nil, ErrSNCollisions
+			}
+		}
+
+		if onProgress != nil {
+			if err := onProgress(len(snList)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	batch.CreatedAt = time.Now()
+	s.batches[batch.ID] = batch
+
+	for _, sn := range snList {
+		s.certs[sn] = &model.Cert{SerialNumber: sn, BatchID: batch.ID, Product: batch.Product, Metadata: map[string]any{}}
+	}
+
+	return snList, nil
+}
+
+func (s *MemoryStore) BindCertificate(
+	ctx context.Context, activation model.CertActivation, allowsProduct func(product string) bool,
+) (model.BindStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cert, ok := s.certs[activation.SerialNumber]
+	var status model.BindStatus
+
+	switch {
+	case !ok:
+		status = model.BindStatusNotFound
+	case allowsProduct != nil && !allowsProduct(cert.Product):
+		status = model.BindStatusNotAllowed
+	case cert.RevokedAt != nil:
+		status = model.BindStatusRevoked
+	case cert.Key == "":
+		status = model.BindStatusBound
+		cert.Key = activation.Key
+	case cert.Key == activation.Key:
+		status = model.BindStatusRebound
+	default:
+		status = model.BindStatusTaken
+	}
+
+	if status == model.BindStatusNotFound {
+		return status, nil
+	}
+
+	activation.Status = status
+	activation.CreatedAt = time.Now()
+	s.activations = append(s.activations, activation)
+
+	return status, nil
+}
+
+func (s *MemoryStore) GetCertActivations(ctx context.Context, sn string) ([]model.CertActivation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	activations := []model.CertActivation{}
+
+	for _, activation := range s.activations {
+		if activation.SerialNumber == sn {
+			activations = append(activations, activation)
+		}
+	}
+
+	return activations, nil
+}
+
+func (s *MemoryStore) GetAllCerts(ctx context.Context) ([]model.Cert, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.findCerts(func(cert *model.Cert) bool { return true }), nil
+}
+
+func (s *MemoryStore) GetAvaliableSN(ctx context.Context) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var res []string
+
+	for _, cert := range s.findCerts(func(cert *model.Cert) bool { return cert.Key == "" && cert.RevokedAt == nil }) {
+		res = append(res, cert.SerialNumber)
+	}
+
+	return res, nil
+}
+
+func (s *MemoryStore) UpdateCertNote(ctx context.Context, sn string, note string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cert, ok := s.certs[sn]
+	if !ok {
+		return ErrSNNotFound
+	}
+
+	cert.Note = note
+	return nil
+}
+
+func (s *MemoryStore) PatchCertMetadata(ctx context.Context, sn string, patch map[string]any) (map[string]any, error) {
+	patch, err := normalizeMetadata(patch)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cert, ok := s.certs[sn]
+	if !ok {
+		return nil, ErrSNNotFound
+	}
+
+	for k, v := range patch {
+		if v == nil {
+			delete(cert.Metadata, k)
+		} else {
+			cert.Metadata[k] = v
+		}
+	}
+
+	return maps.Clone(cert.Metadata), nil
+}
+
+// Get all certificate records whose metadata contains the filter, as the `@>` operator of Postgres.
+func (s *MemoryStore) FindCertsByMetadata(ctx context.Context, filter map[string]any) ([]model.Cert, error) {
+	filter, err := normalizeMetadata(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.findCerts(func(cert *model.Cert) bool { return containsJSON(cert.Metadata, filter) }), nil
+}
+
+func (s *MemoryStore) ArchiveSN(ctx context.Context, sn string, reason string, archivedBy string, force bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cert, ok := s.certs[sn]
+	if !ok {
+		return ErrSNNotFound
+	}
+
+	if cert.Key != "" && !force {
+		return ErrSNAlreadyBound
+	}
+
+	s.archived = append(s.archived, model.ArchivedCert{
+		Cert:       copyCert(cert),
+		Reason:     reason,
+		ArchivedBy: archivedBy,
+		ArchivedAt: time.Now(),
+	})
+	delete(s.certs, sn)
+
+	return nil
+}
+
+// Get all archived certificate records, the newest first.
+func (s *MemoryStore) GetArchivedCerts(ctx context.Context) ([]model.ArchivedCert, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	archived := slices.Clone(s.archived)
+	slices.Reverse(archived)
+
+	return archived, nil
+}
+
+// Get all batch records with the statistics of their S/N(s), the newest first.
+func (s *MemoryStore) GetAllBatches(ctx context.Context) ([]model.Batch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var batches []model.Batch
+
+	for _, batch := range s.batches {
+		for _, cert := range s.certs {
+			if cert.BatchID != batch.ID {
+				continue
+			}
+
+			batch.Count++
+			if cert.Key != "" {
+				batch.Bound++
+			}
+			if cert.RevokedAt != nil {
+				batch.Revoked++
+			}
+		}
+
+		batches = append(batches, batch)
+	}
+
+	sort.SliceStable(batches, func(i, j int) bool { return batches[i].CreatedAt.After(batches[j].CreatedAt) })
+
+	return batches, nil
+}
+
+func (s *MemoryStore) IsBatchExist(ctx context.Context, id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.batches[id]; !ok {
+		return false, ErrBatchNotFound
+	}
+
+	return true, nil
+}
+
+// Call fn with each certificate record in the given batch, ordered by S/N.
+//
+// fn is called after the lock is released, so it may use the store.
+func (s *MemoryStore) ForEachCertInBatch(ctx context.Context, id string, fn func(cert model.Cert) error) error {
+	s.mu.Lock()
+	certs := s.findCerts(func(cert *model.Cert) bool { return cert.BatchID == id })
+	s.mu.Unlock()
+
+	for _, cert := range certs {
+		if err := fn(cert); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) RevokeBatch(ctx context.Context, id string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.batches[id]; !ok {
+		return 0, ErrBatchNotFound
+	}
+
+	now := time.Now()
+	var revoked int64
+
+	for _, cert := range s.certs {
+		if cert.BatchID == id && cert.RevokedAt == nil {
+			cert.RevokedAt = &now
+			revoked++
+		}
+	}
+
+	return revoked, nil
+}
+
+func (s *MemoryStore) GetTemporaryPermitExpiredTime(ctx context.Context, key string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expiration, ok := s.permits[key]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrPermitNotFound, key)
+	}
+
+	return max(expiration.Unix()-time.Now().Unix(), 0), nil
+}
+
+func (s *MemoryStore) AddTemporaryPermit(ctx context.Context, key string) (int64, error) {
+	expiration, err := temporaryPermitExpiration()
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.permits[key]; ok {
+		return 0, fmt.Errorf("the temporary permit of [%s] already exists", key)
+	}
+
+	s.permits[key] = expiration
+
+	return expiration.Unix() - time.Now().Unix(), nil
+}
+
+// Create a new admin token with a new TOTP secret and signing secret, and return its record along with the token
+// and the signing secret.
+func (s *MemoryStore) CreateAdminToken(
+	ctx context.Context, name string, role string, createdBy string, expiresAt *time.Time,
+) (model.AdminToken, string, string, error) {
+	token, err := utils.GenerateToken()
+	if err != nil {
+		return model.AdminToken{}, "", "", err
+	}
+
+	totpSecret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return model.AdminToken{}, "", "", err
+	}
+
+	signingSecret, signingPublicKey, err := utils.GenerateSigningSecret()
+	if err != nil {
+		return model.AdminToken{}, "", "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, err := s.addAdminToken(name, role, createdBy, token, totpSecret, signingPublicKey, expiresAt)
+	if err != nil {
+		return model.AdminToken{}, "", "", err
+	}
+
+	return record, token, signingSecret, nil
+}
+
+// Add the permissions of allowlist.toml as admin tokens if there is no admin token yet, see BootstrapAdminTokens.
+func (s *MemoryStore) BootstrapAdminTokens(ctx context.Context, permissions []cfg.Permission) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.adminTokens) > 0 {
+		return 0, nil
+	}
+
+	added := 0
+
+	for _, permission := range permissions {
+		if permission.TOKEN == "" {
+			continue
+		}
+
+		_, err := s.addAdminToken(
+			permission.NAME, permission.ROLE, "allowlist.toml", permission.TOKEN, permission.TOTP_SECRET,
+			permission.SIGNING_PUBLIC_KEY, nil,
+		)
+		if err != nil {
+			// Roll back the tokens added so far, there was none before.
+			s.adminTokens = nil
+			clear(s.adminTokenLookup)
+			return 0, err
+		}
+
+		added++
+	}
+
+	return added, nil
+}
+
+func (s *MemoryStore) AuthenticateAdminToken(ctx context.Context, token string) (model.AdminToken, error) {
+	if len(token) < cfg.MinTokenLength {
+		return model.AdminToken{}, ErrAdminTokenInvalid
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.adminTokenLookup[utils.TokenLookupHash(token)]
+	if !ok || !record.IsActive(time.Now()) || !utils.VerifyToken(token, record.Salt, record.TokenHash) {
+		return model.AdminToken{}, ErrAdminTokenInvalid
+	}
+
+	now := time.Now()
+	record.LastUsedAt = &now
+
+	return *record, nil
+}
+
+// Find the active admin token with the given ID whose request signature is accepted by verify, and update the time
+// it was last used.
+func (s *MemoryStore) AuthenticateSignedAdminToken(
+	ctx context.Context, id string, verify func(token model.AdminToken) bool,
+) (model.AdminToken, error) {
+	s.mu.Lock()
+	record, ok := s.findAdminToken(id)
+	var snapshot model.AdminToken
+	if ok {
+		snapshot = *record
+	}
+	s.mu.Unlock()
+
+	// verify is called without the lock, since it may take a while.
+	if !ok || !snapshot.IsActive(time.Now()) || !verify(snapshot) {
+		return model.AdminToken{}, ErrAdminTokenInvalid
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	record.LastUsedAt = &now
+
+	return *record, nil
+}
+
+// Get all admin tokens, the newest first.
+func (s *MemoryStore) GetAllAdminTokens(ctx context.Context) ([]model.AdminToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var tokens []model.AdminToken
+
+	for i := len(s.adminTokens) - 1; i >= 0; i-- {
+		tokens = append(tokens, *s.adminTokens[i])
+	}
+
+	return tokens, nil
+}
+
+func (s *MemoryStore) RevokeAdminToken(ctx context.Context, id string) (model.AdminToken, error) {
+	return s.updateAdminToken(id, func(record *model.AdminToken) {
+		if record.RevokedAt == nil {
+			now := time.Now()
+			record.RevokedAt = &now
+		}
+	})
+}
+
+func (s *MemoryStore) ExpireAdminToken(ctx context.Context, id string, expiresAt time.Time) (model.AdminToken, error) {
+	return s.updateAdminToken(id, func(record *model.AdminToken) { record.ExpiresAt = &expiresAt })
+}
+
+func (s *MemoryStore) ResetAdminTokenTOTP(ctx context.Context, id string) (model.AdminToken, string, error) {
+	totpSecret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return model.AdminToken{}, "", err
+	}
+
+	record, err := s.updateAdminToken(id, func(record *model.AdminToken) { record.TOTPSecret = totpSecret })
+	if err != nil {
+		return model.AdminToken{}, "", err
+	}
+
+	return record, totpSecret, nil
+}
+
+// Create a new client key, and return its record along with the key itself.
+func (s *MemoryStore) CreateClientKey(
+	ctx context.Context, name string, products []string, scopes []string, rateLimit int, createdBy string,
+) (model.ClientKey, string, error) {
+	key, err := utils.GenerateToken()
+	if err != nil {
+		return model.ClientKey{}, "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, err := s.addClientKey(name, key, products, scopes, rateLimit, createdBy)
+	if err != nil {
+		return model.ClientKey{}, "", err
+	}
+
+	return record, key, nil
+}
+
+// Add the CLIENT_AUTH_TOKEN entries of server.toml as client keys if there is no client key yet, see
+// BootstrapClientKeys.
+func (s *MemoryStore) BootstrapClientKeys(ctx context.Context, tokens []string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.clientKeys) > 0 {
+		return 0, nil
+	}
+
+	products := []string{model.AllProducts}
+	scopes := []string{model.ClientScopeApplyCert, model.ClientScopeApplyTempPermit}
+
+	for i, token := range tokens {
+		name := fmt.Sprintf("CLIENT_AUTH_TOKEN #%d", i)
+		if _, err := s.addClientKey(name, token, products, scopes, 0, "server.toml"); err != nil {
+			// Roll back the keys added so far, there was none before.
+			s.clientKeys = nil
+			clear(s.clientKeyLookup)
+			return 0, err
+		}
+	}
+
+	return len(tokens), nil
+}
+
+func (s *MemoryStore) AuthenticateClientKey(ctx context.Context, key string) (model.ClientKey, error) {
+	if len(key) < cfg.MinTokenLength {
+		return model.ClientKey{}, ErrClientKeyInvalid
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.clientKeyLookup[utils.TokenLookupHash(key)]
+	if !ok || record.RevokedAt != nil || !utils.VerifyToken(key, record.Salt, record.TokenHash) {
+		return model.ClientKey{}, ErrClientKeyInvalid
+	}
+
+	now := time.Now()
+	record.LastUsedAt = &now
+	record.UsageCount++
+
+	return copyClientKey(record), nil
+}
+
+// Get all client keys, the newest first.
+func (s *MemoryStore) GetAllClientKeys(ctx context.Context) ([]model.ClientKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var keys []model.ClientKey
+
+	for i := len(s.clientKeys) - 1; i >= 0; i-- {
+		keys = append(keys, copyClientKey(s.clientKeys[i]))
+	}
+
+	return keys, nil
+}
+
+func (s *MemoryStore) RevokeClientKey(ctx context.Context, id string) (model.ClientKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, record := range s.clientKeys {
+		if record.ID != id {
+			continue
+		}
+
+		if record.RevokedAt == nil {
+			now := time.Now()
+			record.RevokedAt = &now
+		}
+
+		return copyClientKey(record), nil
+	}
+
+	return model.ClientKey{}, ErrClientKeyNotFound
+}
+
+// Append an admin action to the audit logs, chained to the hash of the last record, see AddAuditLog.
+func (s *MemoryStore) AddAuditLog(ctx context.Context, log model.AuditLog) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	log.ID = int64(len(s.auditLogs)) + 1
+	log.PrevHash = ""
+	if len(s.auditLogs) > 0 {
+		log.PrevHash = s.auditLogs[len(s.auditLogs)-1].Hash
+	}
+
+	if len(log.Params) == 0 {
+		log.Params = json.RawMessage("{}")
+	}
+
+	log.Params = slices.Clone(log.Params)
+	log.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	log.Hash = computeAuditHash(log)
+
+	s.auditLogs = append(s.auditLogs, log)
+
+	return nil
+}
+
+// Get the audit logs matching the given filter, the newest first.
+func (s *MemoryStore) GetAuditLogs(ctx context.Context, filter model.AuditFilter) ([]model.AuditLog, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var logs []model.AuditLog
+	skipped := 0
+
+	for i := len(s.auditLogs) - 1; i >= 0 && len(logs) < filter.Limit; i-- {
+		log := s.auditLogs[i]
+
+		switch {
+		case filter.Admin != "" && log.Admin != filter.Admin,
+			filter.Role != "" && log.Role != filter.Role,
+			filter.IP != "" && log.IP != filter.IP,
+			filter.Route != "" && log.Route != filter.Route,
+			filter.Status != 0 && log.Status != filter.Status,
+			!filter.From.IsZero() && log.CreatedAt.Before(filter.From),
+			!filter.To.IsZero() && !log.CreatedAt.Before(filter.To):
+			continue
+		}
+
+		if skipped < filter.Offset {
+			skipped++
+			continue
+		}
+
+		logs = append(logs, log)
+	}
+
+	return logs, nil
+}
+
+// Walk through the audit log chain from the oldest record, see VerifyAuditLogs.
+func (s *MemoryStore) VerifyAuditLogs(ctx context.Context) (int64, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var checked int64
+	prevHash := ""
+
+	for _, log := range s.auditLogs {
+		if log.PrevHash != prevHash || computeAuditHash(log) != log.Hash {
+			return checked, log.ID, nil
+		}
+
+		prevHash = log.Hash
+		checked++
+	}
+
+	return checked, 0, nil
+}
+
+func (s *MemoryStore) AddJob(ctx context.Context, job model.Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.jobs[job.ID]; ok {
+		return fmt.Errorf("the job [%s] already exists", job.ID)
+	}
+
+	job.CreatedAt = time.Now()
+	job.UpdatedAt = job.CreatedAt
+	s.jobs[job.ID] = &job
+
+	return nil
+}
+
+func (s *MemoryStore) GetJob(ctx context.Context, id string) (model.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return model.Job{}, ErrJobNotFound
+	}
+
+	return *job, nil
+}
+
+func (s *MemoryStore) UpdateJobProgress(ctx context.Context, id string, progress int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if job, ok := s.jobs[id]; ok {
+		job.Progress = progress
+		job.UpdatedAt = time.Now()
+	}
+
+	return nil
+}
+
+func (s *MemoryStore) UpdateJobStatus(ctx context.Context, id string, status string, result string, errMsg string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return ErrJobNotFound
+	}
+
+	job.Status = status
+	job.Result = result
+	job.Error = errMsg
+	job.UpdatedAt = time.Now()
+
+	return nil
+}
+
+// Mark the queued and running jobs as failed, see FailUnfinishedJobs.
+func (s *MemoryStore) FailUnfinishedJobs(ctx context.Context, errMsg string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var failed int64
+
+	for _, job := range s.jobs {
+		if job.Status == model.JobStatusQueued || job.Status == model.JobStatusRunning {
+			job.Status = model.JobStatusFailed
+			job.Error = errMsg
+			job.UpdatedAt = time.Now()
+			failed++
+		}
+	}
+
+	return failed, nil
+}
+
+// Add a new admin token with the given token, as insertAdminToken. The lock must be held.
+func (s *MemoryStore) addAdminToken(
+	name string, role string, createdBy string, token string, totpSecret string, signingPublicKey string,
+	expiresAt *time.Time,
+) (model.AdminToken, error) {
+	if len(token) < cfg.MinTokenLength {
+		return model.AdminToken{}, ErrTokenTooShort
+	}
+
+	lookupHash := utils.TokenLookupHash(token)
+	if _, ok := s.adminTokenLookup[lookupHash]; ok {
+		return model.AdminToken{}, errTokenExists
+	}
+
+	id, err := utils.GenerateID()
+	if err != nil {
+		return model.AdminToken{}, err
+	}
+
+	salt, err := utils.GenerateID()
+	if err != nil {
+		return model.AdminToken{}, err
+	}
+
+	record := &model.AdminToken{
+		ID:               id,
+		Name:             name,
+		Role:             role,
+		Salt:             salt,
+		TokenHash:        utils.HashToken(token, salt),
+		SigningPublicKey: signingPublicKey,
+		TOTPSecret:       totpSecret,
+		CreatedBy:        createdBy,
+		CreatedAt:        time.Now(),
+		ExpiresAt:        expiresAt,
+	}
+
+	s.adminTokens = append(s.adminTokens, record)
+	s.adminTokenLookup[lookupHash] = record
+
+	return *record, nil
+}
+
+// Apply the given change to the admin token, and return the updated record.
+func (s *MemoryStore) updateAdminToken(id string, update func(record *model.AdminToken)) (model.AdminToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.findAdminToken(id)
+	if !ok {
+		return model.AdminToken{}, ErrAdminTokenNotFound
+	}
+
+	update(record)
+	return *record, nil
+}
+
+// Find the admin token with the given ID. The lock must be held.
+func (s *MemoryStore) findAdminToken(id string) (*model.AdminToken, bool) {
+	for _, record := range s.adminTokens {
+		if record.ID == id {
+			return record, true
+		}
+	}
+
+	return nil, false
+}
+
+// Add a new client key with the given key, as insertClientKey. The lock must be held.
+func (s *MemoryStore) addClientKey(
+	name string, key string, products []string, scopes []string, rateLimit int, createdBy string,
+) (model.ClientKey, error) {
+	if len(key) < cfg.MinTokenLength {
+		return model.ClientKey{}, ErrTokenTooShort
+	}
+
+	lookupHash := utils.TokenLookupHash(key)
+	if _, ok := s.clientKeyLookup[lookupHash]; ok {
+		return model.ClientKey{}, errTokenExists
+	}
+
+	id, err := utils.GenerateID()
+	if err != nil {
+		return model.ClientKey{}, err
+	}
+
+	salt, err := utils.GenerateID()
+	if err != nil {
+		return model.ClientKey{}, err
+	}
+
+	record := &model.ClientKey{
+		ID:        id,
+		Name:      name,
+		Salt:      salt,
+		TokenHash: utils.HashToken(key, salt),
+		Products:  slices.Clone(products),
+		Scopes:    slices.Clone(scopes),
+		RateLimit: rateLimit,
+		CreatedBy: createdBy,
+		CreatedAt: time.Now(),
+	}
+
+	s.clientKeys = append(s.clientKeys, record)
+	s.clientKeyLookup[lookupHash] = record
+
+	return copyClientKey(record), nil
+}
+
+func copyClientKey(key *model.ClientKey) model.ClientKey {
+	res := *key
+	res.Products = slices.Clone(key.Products)
+	res.Scopes = slices.Clone(key.Scopes)
+
+	return res
+}
+
+// Get the copies of the certificate records matching the given function, ordered by S/N. The lock must be held.
+func (s *MemoryStore) findCerts(match func(cert *model.Cert) bool) []model.Cert {
+	var certs []model.Cert
+
+	for _, cert := range s.certs {
+		if match(cert) {
+			certs = append(certs, copyCert(cert))
+		}
+	}
+
+	sort.Slice(certs, func(i, j int) bool { return certs[i].SerialNumber < certs[j].SerialNumber })
+
+	return certs
+}
+
+func copyCert(cert *model.Cert) model.Cert {
+	res := *cert
+	res.Metadata = maps.Clone(cert.Metadata)
+
+	return res
+}
+
+// Convert the metadata to the JSON types, e.g. numbers to float64, as it is stored as JSONB in Postgres.
+func normalizeMetadata(metadata map[string]any) (map[string]any, error) {
+	raw, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeMetadata(raw)
+}
+
+// Check if the JSON value contains the other one, as the `@>` operator of Postgres.
+func containsJSON(container any, contained any) bool {
+	switch contained := contained.(type) {
+	case map[string]any:
+		container, ok := container.(map[string]any)
+		if !ok {
+			return false
+		}
+
+		for k, v := range contained {
+			if cv, ok := container[k]; !ok || !containsJSON(cv, v) {
+				return false
+			}
+		}
+
+		return true
+	case []any:
+		container, ok := container.([]any)
+		if !ok {
+			return false
+		}
+
+		for _, v := range contained {
+			if !slices.ContainsFunc(container, func(cv any) bool { return containsJSON(cv, v) }) {
+				return false
+			}
+		}
+
+		return true
+	default:
+		return container == contained
+	}
+}
+
+// The KeyCache kept in memory, the keys expire as the ones in redis.
+//
+// Create it with NewMemoryKeyCache.
+type MemoryKeyCache struct {
+	mu   sync.Mutex
+	keys map[string]memoryCacheEntry
+}
+
+type memoryCacheEntry struct {
+	key       string
+	expiresAt time.Time
+}
+
+// Create an empty MemoryKeyCache.
+func NewMemoryKeyCache() *MemoryKeyCache {
+	return &MemoryKeyCache{keys: map[string]memoryCacheEntry{}}
+}
+
+func (c *MemoryKeyCache) GetDeviceKey(ctx context.Context, deviceInfoBase string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.keys[deviceInfoBase]
+	if !ok || time.Now().After(entry.expiresAt) {
+		delete(c.keys, deviceInfoBase)
+		return "", ErrCacheKeyNotFound
+	}
+
+	return entry.key, nil
+}
+
+func (c *MemoryKeyCache) SetDeviceKey(ctx context.Context, deviceInfoBase string, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.keys[deviceInfoBase] = memoryCacheEntry{key: key, expiresAt: time.Now().Add(deviceKeyCacheTTL())}
+	return nil
+}
@@ -0,0 +1,323 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	cfg "github.com/mmq88/quickcerts/configs"
+	"github.com/mmq88/quickcerts/model"
+	"github.com/mmq88/quickcerts/utils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStoreBindCertificate(t *testing.T) {
+	ctx := context.Background()
+
+	store := NewMemoryStore()
+
+	sn := "XXXX-XXXX-XXXX-XXXX-XXXX-XXXX"
+	err := store.AddNewSN(ctx, sn, "quickcerts-pro")
+	assert.Nil(t, err)
+	err = store.AddNewSN(ctx, sn, "")
+	assert.Equal(t, ErrSNAlreadyExists, err)
+
+	allowsLite := model.ClientKey{Products: []string{"quickcerts-lite"}}.AllowsProduct
+	status, _ := store.BindCertificate(ctx, model.CertActivation{SerialNumber: sn, Key: "key"}, allowsLite)
+	assert.Equal(t, model.BindStatusNotAllowed, status)
+
+	status, _ = store.BindCertificate(ctx, model.CertActivation{SerialNumber: sn, Key: "key"}, nil)
+	assert.Equal(t, model.BindStatusBound, status)
+	status, _ = store.BindCertificate(ctx, model.CertActivation{SerialNumber: sn, Key: "key"}, nil)
+	assert.Equal(t, model.BindStatusRebound, status)
+	status, _ = store.BindCertificate(ctx, model.CertActivation{SerialNumber: sn, Key: "another key"}, nil)
+	assert.Equal(t, model.BindStatusTaken, status)
+	status, _ = store.BindCertificate(ctx, model.CertActivation{SerialNumber: "invalid sn", Key: "key"}, nil)
+	assert.Equal(t, model.BindStatusNotFound, status)
+
+	activations, err := store.GetCertActivations(ctx, "invalid sn")
+	assert.Nil(t, err)
+	assert.Empty(t, activations)
+
+	// Many devices race for one S/N, exactly one of them is bound.
+	raceSN := "YYYY-YYYY-YYYY-YYYY-YYYY-YYYY"
+	err = store.AddNewSN(ctx, raceSN, "")
+	assert.Nil(t, err)
+
+	statuses := make([]model.BindStatus, 10)
+	var wg sync.WaitGroup
+
+	for i := range statuses {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			statuses[i], _ = store.BindCertificate(ctx,
+				model.CertActivation{SerialNumber: raceSN, Key: fmt.Sprintf("device %d", i)}, nil,
+			)
+		}(i)
+	}
+
+	wg.Wait()
+
+	bound := 0
+	for _, status := range statuses {
+		if status == model.BindStatusBound {
+			bound++
+		} else {
+			assert.Equal(t, model.BindStatusTaken, status)
+		}
+	}
+	assert.Equal(t, 1, bound)
+
+	activations, err = store.GetCertActivations(ctx, raceSN)
+	assert.Nil(t, err)
+	assert.Len(t, activations, 10)
+
+	// Revoked S/N(s) can no longer be bound
+	err = store.ArchiveSN(ctx, sn, "", "", false)
+	assert.Equal(t, ErrSNAlreadyBound, err)
+	err = store.ArchiveSN(ctx, sn, "Refunded.", "EXAMPLE ADMIN 0", true)
+	assert.Nil(t, err)
+	err = store.UpdateCertNote(ctx, sn, "note")
+	assert.Equal(t, ErrSNNotFound, err)
+}
+
+func TestMemoryStoreBatches(t *testing.T) {
+	ctx := context.Background()
+
+	store := NewMemoryStore()
+
+	// The generator collides once, the collided S/N is regenerated.
+	generated := []string{"A", "B", "B", "C"}
+	generate := func() (string, error) {
+		sn := generated[0]
+		generated = generated[1:]
+		return sn, nil
+	}
+
+	snList, err := store.AddGeneratedSNs(ctx, model.Batch{ID: "batch", Product: "quickcerts-pro"}, 3, generate, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"A", "B", "C"}, snList)
+
+	// Nothing is added if the generation is aborted
+	_, err = store.AddGeneratedSNs(ctx, model.Batch{ID: "aborted"}, 1, func() (string, error) { return "D", nil },
+		func(inserted int) error { return errors.New("aborted") },
+	)
+	assert.EqualError(t, err, "aborted")
+	_, err = store.IsBatchExist(ctx, "aborted")
+	assert.Equal(t, ErrBatchNotFound, err)
+
+	status, _ := store.BindCertificate(ctx, model.CertActivation{SerialNumber: "A", Key: "key"}, nil)
+	assert.Equal(t, model.BindStatusBound, status)
+
+	revoked, err := store.RevokeBatch(ctx, "batch")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), revoked)
+
+	status, _ = store.BindCertificate(ctx, model.CertActivation{SerialNumber: "B", Key: "key"}, nil)
+	assert.Equal(t, model.BindStatusRevoked, status)
+
+	batches, err := store.GetAllBatches(ctx)
+	assert.Nil(t, err)
+	if assert.Len(t, batches, 1) {
+		assert.Equal(t, 3, batches[0].Count)
+		assert.Equal(t, 1, batches[0].Bound)
+		assert.Equal(t, 3, batches[0].Revoked)
+	}
+
+	var exported []string
+	err = store.ForEachCertInBatch(ctx, "batch", func(cert model.Cert) error {
+		exported = append(exported, cert.SerialNumber)
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"A", "B", "C"}, exported)
+
+	available, err := store.GetAvaliableSN(ctx)
+	assert.Nil(t, err)
+	assert.Empty(t, available)
+}
+
+func TestMemoryStoreMetadata(t *testing.T) {
+	ctx := context.Background()
+
+	store := NewMemoryStore()
+
+	err := store.AddNewSN(ctx, "A", "")
+	assert.Nil(t, err)
+	err = store.AddNewSN(ctx, "B", "")
+	assert.Nil(t, err)
+
+	metadata, err := store.PatchCertMetadata(
+		ctx, "A", map[string]any{"crm_id": "C-1024", "seats": 5, "tags": []string{"edu"}},
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, float64(5), metadata["seats"])
+
+	metadata, err = store.PatchCertMetadata(ctx, "A", map[string]any{"crm_id": nil})
+	assert.Nil(t, err)
+	assert.NotContains(t, metadata, "crm_id")
+
+	_, err = store.PatchCertMetadata(ctx, "C", map[string]any{"seats": 1})
+	assert.Equal(t, ErrSNNotFound, err)
+
+	certs, err := store.FindCertsByMetadata(ctx, map[string]any{"seats": 5, "tags": []string{"edu"}})
+	assert.Nil(t, err)
+	if assert.Len(t, certs, 1) {
+		assert.Equal(t, "A", certs[0].SerialNumber)
+	}
+
+	certs, err = store.FindCertsByMetadata(ctx, map[string]any{"seats": "5"})
+	assert.Nil(t, err)
+	assert.Empty(t, certs)
+}
+
+func TestMemoryStorePermitsAndKeyCache(t *testing.T) {
+	ctx := context.Background()
+
+	store := NewMemoryStore()
+
+	_, err := store.GetTemporaryPermitExpiredTime(ctx, "key")
+	assert.True(t, errors.Is(err, ErrPermitNotFound))
+	assert.Equal(t, "allowed new key: key", err.Error())
+
+	remaining, err := store.AddTemporaryPermit(ctx, "key")
+	assert.Nil(t, err)
+	assert.Greater(t, remaining, int64(0))
+
+	remaining, err = store.GetTemporaryPermitExpiredTime(ctx, "key")
+	assert.Nil(t, err)
+	assert.Greater(t, remaining, int64(0))
+
+	cache := NewMemoryKeyCache()
+
+	_, err = cache.GetDeviceKey(ctx, "base")
+	assert.Equal(t, ErrCacheKeyNotFound, err)
+
+	err = cache.SetDeviceKey(ctx, "base", "key")
+	assert.Nil(t, err)
+
+	key, err := cache.GetDeviceKey(ctx, "base")
+	assert.Nil(t, err)
+	assert.Equal(t, "key", key)
+}
+
+func TestMemoryStoreAdminTokensAndClientKeys(t *testing.T) {
+	ctx := context.Background()
+
+	store := NewMemoryStore()
+
+	token := "tokentokentokentokentokentokentoken"
+	permissions := []cfg.Permission{
+		{NAME: "admin", ROLE: cfg.RoleOwner, TOKEN: token},
+		{NAME: "no token", ROLE: cfg.RoleViewer},
+	}
+
+	added, err := store.BootstrapAdminTokens(ctx, permissions)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, added)
+
+	// The tokens are only bootstrapped once.
+	added, err = store.BootstrapAdminTokens(ctx, permissions)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, added)
+
+	record, err := store.AuthenticateAdminToken(ctx, token)
+	assert.Nil(t, err)
+	assert.Equal(t, "admin", record.Name)
+	assert.NotNil(t, record.LastUsedAt)
+
+	_, err = store.AuthenticateAdminToken(ctx, token+"x")
+	assert.Equal(t, ErrAdminTokenInvalid, err)
+
+	created, _, signingSecret, err := store.CreateAdminToken(ctx, "viewer", cfg.RoleViewer, "admin", nil)
+	assert.Nil(t, err)
+
+	publicKey, err := utils.SigningPublicKey(signingSecret)
+	assert.Nil(t, err)
+
+	signed, err := store.AuthenticateSignedAdminToken(ctx, created.ID, func(token model.AdminToken) bool {
+		return token.SigningPublicKey == publicKey
+	})
+	assert.Nil(t, err)
+	assert.NotNil(t, signed.LastUsedAt)
+
+	_, err = store.AuthenticateSignedAdminToken(ctx, created.ID, func(model.AdminToken) bool { return false })
+	assert.Equal(t, ErrAdminTokenInvalid, err)
+
+	tokens, err := store.GetAllAdminTokens(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, tokens, 2)
+	assert.Equal(t, "viewer", tokens[0].Name)
+
+	_, err = store.ExpireAdminToken(ctx, record.ID, time.Now().Add(-time.Second))
+	assert.Nil(t, err)
+
+	_, err = store.AuthenticateAdminToken(ctx, token)
+	assert.Equal(t, ErrAdminTokenInvalid, err)
+
+	_, err = store.RevokeAdminToken(ctx, "none")
+	assert.Equal(t, ErrAdminTokenNotFound, err)
+
+	// Test invalid case (The client keys are rolled back if any of them is invalid)
+	_, err = store.BootstrapClientKeys(ctx, []string{token, "short"})
+	assert.Equal(t, ErrTokenTooShort, err)
+
+	keys, err := store.GetAllClientKeys(ctx)
+	assert.Nil(t, err)
+	assert.Empty(t, keys)
+
+	added, err = store.BootstrapClientKeys(ctx, []string{token})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, added)
+
+	key, err := store.AuthenticateClientKey(ctx, token)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), key.UsageCount)
+	assert.True(t, key.AllowsProduct("any"))
+
+	_, err = store.RevokeClientKey(ctx, key.ID)
+	assert.Nil(t, err)
+
+	_, err = store.AuthenticateClientKey(ctx, token)
+	assert.Equal(t, ErrClientKeyInvalid, err)
+}
+
+func TestMemoryStoreAuditLogs(t *testing.T) {
+	ctx := context.Background()
+
+	store := NewMemoryStore()
+
+	for _, admin := range []string{"admin0", "admin1", "admin0"} {
+		err := store.AddAuditLog(ctx, model.AuditLog{Admin: admin, IP: "127.0.0.1", Method: "POST", Status: 200})
+		assert.Nil(t, err)
+	}
+
+	logs, err := store.GetAuditLogs(ctx, model.AuditFilter{Admin: "admin0", Limit: 10})
+	assert.Nil(t, err)
+	assert.Len(t, logs, 2)
+	assert.Equal(t, int64(3), logs[0].ID)
+	assert.Equal(t, "{}", string(logs[0].Params))
+
+	logs, err = store.GetAuditLogs(ctx, model.AuditFilter{Limit: 1, Offset: 1})
+	assert.Nil(t, err)
+	assert.Len(t, logs, 1)
+	assert.Equal(t, int64(2), logs[0].ID)
+
+	checked, brokenID, err := store.VerifyAuditLogs(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), checked)
+	assert.Equal(t, int64(0), brokenID)
+
+	// Modify a record, the chain is broken from it.
+	store.auditLogs[1].Admin = "admin2"
+
+	checked, brokenID, err = store.VerifyAuditLogs(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), checked)
+	assert.Equal(t, int64(2), brokenID)
+}
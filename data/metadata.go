@@ -0,0 +1,146 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/lib/pq"
+	"github.com/mmq88/quickcerts/model"
+)
+
+// Merge the given patch into the metadata of the given S/N, and return the updated metadata.
+//
+// The keys in the patch overwrite the existing ones, and the keys with null values are removed.
+func PatchCertMetadata(ctx context.Context, sn string, patch map[string]any) (map[string]any, error) {
+	if db == nil {
+		return nil, ErrDBNotConnected
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var removed []string
+	for k, v := range patch {
+		if v == nil {
+			removed = append(removed, k)
+		}
+	}
+
+	rawPatch, err := json.Marshal(patch)
+	if err != nil {
+		return nil, err
+	}
+
+	stmt, err := db.PrepareContext(ctx, `
+		UPDATE certs SET metadata = (metadata || $1::JSONB) - $2::TEXT[]
+		WHERE sn = $3
+		RETURNING metadata
+	`)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer stmt.Close()
+
+	var rawMetadata []byte
+	err = stmt.QueryRowContext(ctx, string(rawPatch), pq.Array(removed), sn).Scan(&rawMetadata)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrSNNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return decodeMetadata(rawMetadata)
+}
+
+// Get all certificate records whose metadata contains all of the given keys and values.
+func FindCertsByMetadata(ctx context.Context, filter map[string]any) ([]model.Cert, error) {
+	if db == nil {
+		return nil, ErrDBNotConnected
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rawFilter, err := json.Marshal(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT sn, key, note, batch_id, revoked_at, metadata, product FROM certs
+		WHERE metadata @> $1::JSONB
+		ORDER BY sn
+	`
+
+	rows, err := db.QueryContext(ctx, query, string(rawFilter))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var certs []model.Cert
+
+	for rows.Next() {
+		cert, err := scanCert(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		certs = append(certs, cert)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return certs, nil
+}
+
+// Scan a row of `sn, key, note, batch_id, revoked_at, metadata, product` into a certificate record.
+func scanCert(rows *sql.Rows) (model.Cert, error) {
+	var cert model.Cert
+	var tmpKey sql.NullString
+	var tmpNote sql.NullString
+	var tmpBatchID sql.NullString
+	var tmpRevokedAt sql.NullTime
+	var rawMetadata []byte
+	var tmpProduct sql.NullString
+
+	err := rows.Scan(&cert.SerialNumber, &tmpKey, &tmpNote, &tmpBatchID, &tmpRevokedAt, &rawMetadata, &tmpProduct)
+	if err != nil {
+		return model.Cert{}, err
+	}
+
+	cert.Key = tmpKey.String
+	cert.Note = tmpNote.String
+	cert.BatchID = tmpBatchID.String
+	cert.Product = tmpProduct.String
+	if tmpRevokedAt.Valid {
+		cert.RevokedAt = &tmpRevokedAt.Time
+	}
+
+	if cert.Metadata, err = decodeMetadata(rawMetadata); err != nil {
+		return model.Cert{}, err
+	}
+
+	return cert, nil
+}
+
+func decodeMetadata(raw []byte) (map[string]any, error) {
+	metadata := map[string]any{}
+
+	if len(raw) == 0 {
+		return metadata, nil
+	}
+
+	if err := json.Unmarshal(raw, &metadata); err != nil {
+		return nil, err
+	}
+
+	return metadata, nil
+}
@@ -0,0 +1,73 @@
+package data
+
+import (
+	"context"
+	"testing"
+
+	cfg "github.com/mmq88/quickcerts/configs"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPatchCertMetadata(t *testing.T) {
+	ctx := context.Background()
+
+	backupHost := cfg.DB_CONFIG.HOST
+	backupPort := cfg.DB_CONFIG.PORT
+	defer func() {
+		cfg.DB_CONFIG.HOST = backupHost
+		cfg.DB_CONFIG.PORT = backupPort
+	}()
+
+	// Test invalid case
+	_, err := PatchCertMetadata(ctx, "XXXX-XXXX-XXXX-XXXX-XXXX-XXXX", map[string]any{"crm_id": "C-1024"})
+	assert.Equal(t, "currently not connecting the database", err.Error())
+
+	// Test valid case
+	// Uses docker-compose config
+	cfg.DB_CONFIG.HOST = "localhost"
+	cfg.DB_CONFIG.PORT = 33332
+
+	err = ConnectDB()
+	assert.Nil(t, err)
+	defer func() {
+		err = DisconnectDB()
+		assert.Nil(t, err)
+	}()
+
+	sn := "XXXX-XXXX-XXXX-XXXX-XXXX-XXXX"
+	err = AddNewSN(ctx, sn, "")
+	assert.Nil(t, err)
+
+	metadata, err := PatchCertMetadata(ctx, sn, map[string]any{"customer_email": "user@example.com", "crm_id": "C-1024"})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]any{"customer_email": "user@example.com", "crm_id": "C-1024"}, metadata)
+
+	// Existing keys are overwritten and null values remove the keys.
+	metadata, err = PatchCertMetadata(ctx, sn, map[string]any{"crm_id": "C-2048", "customer_email": nil})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]any{"crm_id": "C-2048"}, metadata)
+
+	// The note is not affected by the metadata.
+	err = UpdateCertNote(ctx, sn, "note")
+	assert.Nil(t, err)
+
+	certs, err := FindCertsByMetadata(ctx, map[string]any{"crm_id": "C-2048"})
+	assert.Nil(t, err)
+	assert.Len(t, certs, 1)
+	assert.Equal(t, sn, certs[0].SerialNumber)
+	assert.Equal(t, "note", certs[0].Note)
+	assert.Equal(t, map[string]any{"crm_id": "C-2048"}, certs[0].Metadata)
+
+	certs, err = FindCertsByMetadata(ctx, map[string]any{"crm_id": "C-1024"})
+	assert.Nil(t, err)
+	assert.Empty(t, certs)
+
+	// Test invalid case
+	_, err = PatchCertMetadata(ctx, "invalid sn", map[string]any{"crm_id": "C-1024"})
+	assert.Equal(t, "the s/n does not exist", err.Error())
+
+	// Delete the added test data
+	err = DeleteTestingData(ctx, "DELETE FROM certs WHERE sn = $1", sn)
+	assert.Nil(t, err)
+}
@@ -0,0 +1,404 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// The versioned migrations of the schemas, `migrations/<dialect>/<version>_<name>.<up|down>.sql`.
+//
+// The versions start from 1 and have no gaps, and every version has both the up and down scripts.
+//
+//go:embed migrations
+var migrationsFS embed.FS
+
+var migrationFileRegexp = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// A migration of the schema.
+type Migration struct {
+	Version int
+	Name    string
+	up      string
+	down    string
+}
+
+// The statements which differ between the databases.
+type migrationDialect struct {
+	name string
+	// Creates the table recording the applied versions.
+	createVersionTable string
+	// Serializes the migrations of the concurrent servers within a transaction, "" if the transactions are serialized.
+	lock string
+	// Checks if the table named $1 exists.
+	tableExists string
+}
+
+var (
+	postgresDialect = migrationDialect{
+		name: "postgres",
+		createVersionTable: `
+			CREATE TABLE IF NOT EXISTS schema_migrations (
+				version INTEGER PRIMARY KEY,
+				name TEXT NOT NULL,
+				applied_at TIMESTAMP WITH TIME ZONE NOT NULL
+			)
+		`,
+		lock:        "SELECT pg_advisory_xact_lock(20240101)",
+		tableExists: "SELECT to_regclass($1::TEXT) IS NOT NULL",
+	}
+	sqliteDialect = migrationDialect{
+		name: "sqlite",
+		createVersionTable: `
+			CREATE TABLE IF NOT EXISTS schema_migrations (
+				version INTEGER PRIMARY KEY,
+				name TEXT NOT NULL,
+				applied_at TIMESTAMP NOT NULL
+			)
+		`,
+		tableExists: "SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = $1)",
+	}
+)
+
+// Applies and reverts the migrations of a database.
+//
+// Get it with PostgresMigrator or SQLiteStore.Migrator.
+type Migrator struct {
+	db         *sql.DB
+	dialect    migrationDialect
+	migrations []Migration
+}
+
+// Get the Migrator of the Postgres database connected by ConnectDB.
+func PostgresMigrator() (Migrator, error) {
+	if db == nil {
+		return Migrator{}, ErrDBNotConnected
+	}
+
+	return newMigrator(db, postgresDialect)
+}
+
+func newMigrator(db *sql.DB, dialect migrationDialect) (Migrator, error) {
+	migrations, err := loadMigrations(dialect.name)
+	if err != nil {
+		return Migrator{}, err
+	}
+
+	return Migrator{db: db, dialect: dialect, migrations: migrations}, nil
+}
+
+// Load the migrations of the given dialect, ordered by version.
+func loadMigrations(dialect string) ([]Migration, error) {
+	dir := path.Join("migrations", dialect)
+
+	entries, err := migrationsFS.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for the dialect [%s]: %w", dialect, err)
+	}
+
+	byVersion := map[int]*Migration{}
+
+	for _, entry := range entries {
+		matches := migrationFileRegexp.FindStringSubmatch(entry.Name())
+		if matches == nil {
+			return nil, fmt.Errorf("the migration [%s] is not named as <version>_<name>.<up|down>.sql", entry.Name())
+		}
+
+		version, _ := strconv.Atoi(matches[1])
+		script, err := migrationsFS.ReadFile(path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: matches[2]}
+			byVersion[version] = migration
+		} else if migration.Name != matches[2] {
+			return nil, fmt.Errorf("the migration %d of %s has two names [%s, %s]",
+				version, dialect, migration.Name, matches[2])
+		}
+
+		if matches[3] == "up" {
+			migration.up = string(script)
+		} else {
+			migration.down = string(script)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+
+	for _, migration := range byVersion {
+		migrations = append(migrations, *migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	for i, migration := range migrations {
+		if migration.Version != i+1 {
+			return nil, fmt.Errorf("the migration %d of %s is missing", i+1, dialect)
+		}
+
+		if migration.up == "" || migration.down == "" {
+			return nil, fmt.Errorf("the migration %d of %s requires both the up and down scripts",
+				migration.Version, dialect)
+		}
+	}
+
+	return migrations, nil
+}
+
+// Get all migrations known by the server, ordered by version.
+func (m Migrator) Migrations() []Migration {
+	return m.migrations
+}
+
+// Get the latest schema version known by the server.
+func (m Migrator) Latest() int {
+	return len(m.migrations)
+}
+
+// Get the schema version of the database, 0 if no migration has been applied.
+//
+// A database created by init.sql before the migrations were introduced is recorded as version 1.
+func (m Migrator) Version() (int, error) {
+	tx, err := m.begin()
+	if err != nil {
+		return 0, err
+	}
+
+	defer tx.Rollback()
+
+	version, err := currentVersion(tx)
+	if err != nil {
+		return 0, err
+	}
+
+	return version, tx.Commit()
+}
+
+// Check if the schema version of the database is the latest one, e.g. for the readiness probes.
+//
+// It only reads the version, without taking the migration lock or creating the version table as Version does.
+// Returns ErrSchemaTooNew if the database has been migrated by a newer server, or ErrSchemaOutdated if some
+// migrations have not been applied.
+func (m Migrator) Check(ctx context.Context) error {
+	version, err := m.peekVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	return m.compareVersion(version)
+}
+
+// Get the schema version of the database as Version, but read-only.
+func (m Migrator) peekVersion(ctx context.Context) (int, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var exists bool
+	if err := m.db.QueryRowContext(ctx, m.dialect.tableExists, "schema_migrations").Scan(&exists); err != nil {
+		return 0, err
+	}
+
+	// The version table is not created yet, see ensureVersionTable.
+	if !exists {
+		var legacy bool
+		if err := m.db.QueryRowContext(ctx, m.dialect.tableExists, "certs").Scan(&legacy); err != nil || !legacy {
+			return 0, err
+		}
+
+		return 1, nil
+	}
+
+	var version int
+	err := m.db.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version)
+
+	return version, err
+}
+
+// Compare the schema version of the database with the latest one, see Check.
+func (m Migrator) compareVersion(version int) error {
+	switch {
+	case version > m.Latest():
+		return fmt.Errorf("%w (database: %d, server: %d)", ErrSchemaTooNew, version, m.Latest())
+	case version < m.Latest():
+		return fmt.Errorf("%w (database: %d, server: %d)", ErrSchemaOutdated, version, m.Latest())
+	}
+
+	return nil
+}
+
+// Apply all pending migrations, each in its own transaction, and return the applied ones.
+func (m Migrator) Up() ([]Migration, error) {
+	var applied []Migration
+
+	for _, migration := range m.migrations {
+		done, err := m.apply(func(tx *sql.Tx, version int) (bool, error) {
+			if version >= migration.Version {
+				return false, nil
+			}
+
+			if _, err := tx.Exec(migration.up); err != nil {
+				return false, fmt.Errorf("failed to apply the migration %d [%s]: %w", migration.Version, migration.Name, err)
+			}
+
+			_, err := tx.Exec(
+				"INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)",
+				migration.Version, migration.Name, time.Now().UTC(),
+			)
+
+			return err == nil, err
+		})
+
+		if err != nil {
+			return applied, err
+		}
+
+		if done {
+			applied = append(applied, migration)
+		}
+	}
+
+	return applied, nil
+}
+
+// Revert the given number of the latest applied migrations, and return the reverted ones.
+func (m Migrator) Down(steps int) ([]Migration, error) {
+	var reverted []Migration
+
+	for i := 0; i < steps; i++ {
+		var migration Migration
+
+		done, err := m.apply(func(tx *sql.Tx, version int) (bool, error) {
+			if version == 0 {
+				return false, nil
+			}
+
+			migration = m.migrations[version-1]
+
+			if _, err := tx.Exec(migration.down); err != nil {
+				return false, fmt.Errorf("failed to revert the migration %d [%s]: %w", migration.Version, migration.Name, err)
+			}
+
+			_, err := tx.Exec("DELETE FROM schema_migrations WHERE version = $1", migration.Version)
+
+			return err == nil, err
+		})
+
+		if err != nil || !done {
+			return reverted, err
+		}
+
+		reverted = append(reverted, migration)
+	}
+
+	return reverted, nil
+}
+
+// Check the schema version of the database, and apply the pending migrations if auto is true.
+//
+// The server refuses to start if an error is returned.
+func (m Migrator) Prepare(auto bool) ([]Migration, error) {
+	version, err := m.Version()
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.compareVersion(version)
+	if auto && errors.Is(err, ErrSchemaOutdated) {
+		return m.Up()
+	}
+
+	return nil, err
+}
+
+// Run fn in a locked transaction with the current schema version, and commit it if fn returns true.
+//
+// The migrations unknown by the server are never touched.
+func (m Migrator) apply(fn func(tx *sql.Tx, version int) (bool, error)) (bool, error) {
+	tx, err := m.begin()
+	if err != nil {
+		return false, err
+	}
+
+	defer tx.Rollback()
+
+	version, err := currentVersion(tx)
+	if err != nil {
+		return false, err
+	}
+
+	if version > m.Latest() {
+		return false, fmt.Errorf("%w (database: %d, server: %d)", ErrSchemaTooNew, version, m.Latest())
+	}
+
+	done, err := fn(tx, version)
+	if err != nil || !done {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+// Begin a transaction holding the migration lock, and ensure the version table exists.
+func (m Migrator) begin() (*sql.Tx, error) {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	if m.dialect.lock != "" {
+		if _, err := tx.Exec(m.dialect.lock); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	if err := m.ensureVersionTable(tx); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	return tx, nil
+}
+
+// Create the version table, and record the schema created by init.sql as version 1.
+func (m Migrator) ensureVersionTable(tx *sql.Tx) error {
+	var exists, legacy bool
+	if err := tx.QueryRow(m.dialect.tableExists, "schema_migrations").Scan(&exists); err != nil || exists {
+		return err
+	}
+
+	if err := tx.QueryRow(m.dialect.tableExists, "certs").Scan(&legacy); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(m.dialect.createVersionTable); err != nil {
+		return err
+	}
+
+	if legacy {
+		_, err := tx.Exec(
+			"INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)",
+			1, m.migrations[0].Name, time.Now().UTC(),
+		)
+		return err
+	}
+
+	return nil
+}
+
+func currentVersion(tx *sql.Tx) (int, error) {
+	var version int
+	err := tx.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version)
+
+	return version, err
+}
@@ -0,0 +1,246 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	cfg "github.com/mmq88/quickcerts/configs"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	for _, dialect := range []migrationDialect{postgresDialect, sqliteDialect} {
+		migrations, err := loadMigrations(dialect.name)
+		assert.Nil(t, err)
+		assert.NotEmpty(t, migrations)
+
+		for i, migration := range migrations {
+			assert.Equal(t, i+1, migration.Version)
+			assert.NotEmpty(t, migration.up)
+			assert.NotEmpty(t, migration.down)
+		}
+	}
+
+	_, err := loadMigrations("mysql")
+	assert.NotNil(t, err)
+
+	// The first migration is the schema created by init.sql before the migrations were introduced, since such
+	// databases are recorded as version 1.
+	migrations, err := loadMigrations(postgresDialect.name)
+	assert.Nil(t, err)
+
+	var tables []string
+	for _, matches := range regexp.MustCompile(`CREATE TABLE (\w+)`).FindAllStringSubmatch(migrations[0].up, -1) {
+		tables = append(tables, matches[1])
+	}
+
+	assert.Equal(t, []string{"certs", "temporary_permits"}, tables)
+}
+
+func TestSQLiteMigrations(t *testing.T) {
+	ctx := context.Background()
+
+	store, err := OpenSQLiteStore(filepath.Join(t.TempDir(), "quickcerts.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer store.Close()
+
+	migrator, err := store.Migrator()
+	assert.Nil(t, err)
+
+	// The check is read-only, the version table is not created by it.
+	assert.True(t, errors.Is(migrator.Check(ctx), ErrSchemaOutdated))
+
+	var exists bool
+	err = store.db.QueryRow(sqliteDialect.tableExists, "schema_migrations").Scan(&exists)
+	assert.Nil(t, err)
+	assert.False(t, exists)
+
+	version, err := migrator.Version()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, version)
+
+	// The outdated schema is refused unless it is migrated automatically.
+	_, err = migrator.Prepare(false)
+	assert.True(t, errors.Is(err, ErrSchemaOutdated))
+
+	applied, err := migrator.Prepare(true)
+	assert.Nil(t, err)
+	assert.Len(t, applied, migrator.Latest())
+	assert.Nil(t, migrator.Check(ctx))
+	assert.Nil(t, store.AddNewSN(ctx, "A", ""))
+
+	applied, err = migrator.Up()
+	assert.Nil(t, err)
+	assert.Empty(t, applied)
+
+	// Reverting all migrations drops the tables.
+	reverted, err := migrator.Down(migrator.Latest() + 1)
+	assert.Nil(t, err)
+	assert.Len(t, reverted, migrator.Latest())
+	assert.NotNil(t, store.AddNewSN(ctx, "A", ""))
+
+	version, err = migrator.Version()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, version)
+
+	_, err = migrator.Up()
+	assert.Nil(t, err)
+	assert.Nil(t, store.AddNewSN(ctx, "A", ""))
+
+	// The schema migrated by a newer server is refused.
+	_, err = store.db.Exec("INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, 'future', 0)",
+		migrator.Latest()+1)
+	assert.Nil(t, err)
+
+	_, err = migrator.Prepare(true)
+	assert.True(t, errors.Is(err, ErrSchemaTooNew))
+	_, err = migrator.Down(1)
+	assert.True(t, errors.Is(err, ErrSchemaTooNew))
+}
+
+func TestLegacySchemaMigration(t *testing.T) {
+	store, err := OpenSQLiteStore(filepath.Join(t.TempDir(), "quickcerts.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer store.Close()
+
+	// The schema created before the migrations were introduced is recorded as version 1.
+	_, err = store.db.Exec("CREATE TABLE certs (sn TEXT PRIMARY KEY NOT NULL)")
+	assert.Nil(t, err)
+
+	migrator, err := store.Migrator()
+	assert.Nil(t, err)
+
+	version, err := migrator.Version()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, version)
+}
+
+func TestPostgresLegacySchemaMigration(t *testing.T) {
+	backupHost := cfg.DB_CONFIG.HOST
+	backupPort := cfg.DB_CONFIG.PORT
+	defer func() {
+		cfg.DB_CONFIG.HOST = backupHost
+		cfg.DB_CONFIG.PORT = backupPort
+	}()
+
+	// Uses docker-compose config
+	cfg.DB_CONFIG.HOST = "localhost"
+	cfg.DB_CONFIG.PORT = 33332
+
+	err := ConnectDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer DisconnectDB()
+
+	// The legacy database is created in its own schema, apart from the one migrated by the server.
+	_, err = db.Exec("DROP SCHEMA IF EXISTS legacy_migration_test CASCADE; CREATE SCHEMA legacy_migration_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Exec("DROP SCHEMA IF EXISTS legacy_migration_test CASCADE")
+
+	legacy, err := sql.Open("postgres", postgresDataSource()+" search_path=legacy_migration_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer legacy.Close()
+
+	// The schema created by init.sql before the migrations were introduced.
+	_, err = legacy.Exec(`
+		CREATE TABLE certs (
+			sn TEXT PRIMARY KEY NOT NULL,
+			key TEXT,
+			note TEXT
+		);
+
+		CREATE TABLE temporary_permits (
+			key TEXT PRIMARY KEY NOT NULL,
+			expiration TIMESTAMP WITH TIME ZONE NOT NULL
+		);
+
+		INSERT INTO certs (sn, key, note) VALUES ('A', 'key', 'note');
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	migrator, err := newMigrator(legacy, postgresDialect)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The legacy schema is checked as version 1 before the version table is created.
+	err = migrator.Check(context.Background())
+	assert.True(t, errors.Is(err, ErrSchemaOutdated))
+	assert.Contains(t, err.Error(), "database: 1,")
+
+	version, err := migrator.Version()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, version)
+
+	applied, err := migrator.Prepare(true)
+	assert.Nil(t, err)
+	assert.Len(t, applied, migrator.Latest()-1)
+	assert.Nil(t, migrator.Check(context.Background()))
+
+	// The existing S/N(s) are kept with the columns added later.
+	var key, metadata string
+	var product sql.NullString
+	err = legacy.QueryRow("SELECT key, metadata, product FROM certs WHERE sn = 'A'").Scan(&key, &metadata, &product)
+	assert.Nil(t, err)
+	assert.Equal(t, "key", key)
+	assert.Equal(t, "{}", metadata)
+	assert.False(t, product.Valid)
+
+	for _, table := range []string{
+		"jobs", "batches", "archived_certs", "audit_logs", "admin_tokens", "client_keys", "cert_activations",
+	} {
+		var exists bool
+		assert.Nil(t, legacy.QueryRow(postgresDialect.tableExists, table).Scan(&exists))
+		assert.True(t, exists, table)
+	}
+
+	// Reverting to the legacy schema keeps the S/N(s).
+	reverted, err := migrator.Down(migrator.Latest() - 1)
+	assert.Nil(t, err)
+	assert.Len(t, reverted, migrator.Latest()-1)
+
+	// The databases created by init.sql of the later versions are recorded as version 1 too, but already have the
+	// tables and columns up to the last version created by init.sql.
+	const lastInitVersion = 12
+	for _, migration := range migrator.Migrations()[1:lastInitVersion] {
+		_, err = legacy.Exec(migration.up)
+		assert.Nil(t, err)
+	}
+
+	applied, err = migrator.Up()
+	assert.Nil(t, err)
+	assert.Len(t, applied, migrator.Latest()-1)
+
+	reverted, err = migrator.Down(migrator.Latest() - 1)
+	assert.Nil(t, err)
+	assert.Len(t, reverted, migrator.Latest()-1)
+
+	err = legacy.QueryRow("SELECT key FROM certs WHERE sn = 'A'").Scan(&key)
+	assert.Nil(t, err)
+	assert.Equal(t, "key", key)
+
+	var exists bool
+	assert.Nil(t, legacy.QueryRow(postgresDialect.tableExists, "jobs").Scan(&exists))
+	assert.False(t, exists)
+}
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS temporary_permits;
+DROP TABLE IF EXISTS certs;
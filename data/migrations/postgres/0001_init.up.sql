@@ -0,0 +1,15 @@
+-- The schema created by init.sql before the migrations were introduced.
+--
+-- The later migrations create their tables and columns only if they do not exist, since the databases created by
+-- init.sql of the intermediate versions already have some of them, but are recorded as this version.
+
+CREATE TABLE certs (
+    sn TEXT PRIMARY KEY NOT NULL,
+    key TEXT,
+    note TEXT
+);
+
+CREATE TABLE temporary_permits (
+    key TEXT PRIMARY KEY NOT NULL,
+    expiration TIMESTAMP WITH TIME ZONE NOT NULL
+);
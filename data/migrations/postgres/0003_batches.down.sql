@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS certs_batch_id_idx;
+
+ALTER TABLE certs DROP COLUMN IF EXISTS revoked_at;
+ALTER TABLE certs DROP COLUMN IF EXISTS batch_id;
+
+DROP TABLE IF EXISTS batches;
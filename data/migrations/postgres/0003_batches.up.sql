@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS batches (
+    id TEXT PRIMARY KEY NOT NULL,
+    reason TEXT,
+    created_by TEXT,
+    reseller TEXT,
+    order_number TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE certs ADD COLUMN IF NOT EXISTS batch_id TEXT REFERENCES batches (id);
+ALTER TABLE certs ADD COLUMN IF NOT EXISTS revoked_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS certs_batch_id_idx ON certs (batch_id);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS certs_metadata_idx;
+
+ALTER TABLE certs DROP COLUMN IF EXISTS metadata;
@@ -0,0 +1,3 @@
+ALTER TABLE certs ADD COLUMN IF NOT EXISTS metadata JSONB NOT NULL DEFAULT '{}'::JSONB;
+
+CREATE INDEX IF NOT EXISTS certs_metadata_idx ON certs USING GIN (metadata jsonb_path_ops);
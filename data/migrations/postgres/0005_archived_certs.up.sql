@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS archived_certs (
+    id BIGSERIAL PRIMARY KEY,
+    sn TEXT NOT NULL,
+    key TEXT,
+    note TEXT,
+    batch_id TEXT,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    metadata JSONB NOT NULL DEFAULT '{}'::JSONB,
+    reason TEXT,
+    archived_by TEXT,
+    archived_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS archived_certs_sn_idx ON archived_certs (sn);
@@ -0,0 +1,5 @@
+ALTER TABLE archived_certs DROP COLUMN IF EXISTS product;
+ALTER TABLE certs DROP COLUMN IF EXISTS product;
+ALTER TABLE batches DROP COLUMN IF EXISTS product;
+
+DROP TABLE IF EXISTS client_keys;
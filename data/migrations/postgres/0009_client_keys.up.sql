@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS client_keys (
+    id TEXT PRIMARY KEY NOT NULL,
+    name TEXT NOT NULL,
+    prefix TEXT NOT NULL,
+    salt TEXT NOT NULL,
+    token_hash TEXT NOT NULL,
+    products TEXT[] NOT NULL,
+    scopes TEXT[] NOT NULL,
+    rate_limit INTEGER NOT NULL DEFAULT 0,
+    usage_count BIGINT NOT NULL DEFAULT 0,
+    created_by TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    last_used_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS client_keys_prefix_idx ON client_keys (prefix);
+
+-- The S/N(s) belong to products, which the client keys are restricted to.
+ALTER TABLE batches ADD COLUMN IF NOT EXISTS product TEXT;
+ALTER TABLE certs ADD COLUMN IF NOT EXISTS product TEXT;
+ALTER TABLE archived_certs ADD COLUMN IF NOT EXISTS product TEXT;
@@ -0,0 +1,2 @@
+-- The signing key is derived from the token, so the tokens added before can not sign requests until re-created.
+ALTER TABLE admin_tokens ADD COLUMN IF NOT EXISTS signing_key TEXT NOT NULL DEFAULT '';
@@ -0,0 +1 @@
+ALTER TABLE admin_tokens DROP COLUMN IF EXISTS totp_secret;
@@ -0,0 +1 @@
+ALTER TABLE admin_tokens ADD COLUMN IF NOT EXISTS totp_secret TEXT NOT NULL DEFAULT '';
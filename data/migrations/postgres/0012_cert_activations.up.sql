@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS cert_activations (
+    id BIGSERIAL PRIMARY KEY,
+    sn TEXT NOT NULL,
+    key TEXT NOT NULL,
+    status TEXT NOT NULL,
+    client_key_id TEXT,
+    ip TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS cert_activations_sn_idx ON cert_activations (sn);
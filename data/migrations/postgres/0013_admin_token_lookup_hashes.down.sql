@@ -0,0 +1,6 @@
+-- The prefixes can not be restored, so the older servers can only authenticate the tokens created afterwards.
+DROP INDEX IF EXISTS admin_tokens_lookup_hash_idx;
+ALTER TABLE admin_tokens DROP COLUMN IF EXISTS lookup_hash;
+ALTER TABLE admin_tokens ADD COLUMN IF NOT EXISTS prefix TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS admin_tokens_prefix_idx ON admin_tokens (prefix);
@@ -0,0 +1,8 @@
+-- The admin tokens are found by a keyed hash instead of their plaintext prefixes, see utils.TokenLookupHash.
+--
+-- The hashes of the existing tokens are filled in when they are authenticated next time.
+DROP INDEX IF EXISTS admin_tokens_prefix_idx;
+ALTER TABLE admin_tokens DROP COLUMN IF EXISTS prefix;
+ALTER TABLE admin_tokens ADD COLUMN IF NOT EXISTS lookup_hash TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS admin_tokens_lookup_hash_idx ON admin_tokens (lookup_hash);
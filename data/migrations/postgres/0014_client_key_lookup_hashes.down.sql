@@ -0,0 +1,6 @@
+-- The prefixes can not be restored, so the older servers can only authenticate the keys created afterwards.
+DROP INDEX IF EXISTS client_keys_lookup_hash_idx;
+ALTER TABLE client_keys DROP COLUMN IF EXISTS lookup_hash;
+ALTER TABLE client_keys ADD COLUMN IF NOT EXISTS prefix TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS client_keys_prefix_idx ON client_keys (prefix);
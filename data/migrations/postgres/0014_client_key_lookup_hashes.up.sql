@@ -0,0 +1,8 @@
+-- The client keys are found by a keyed hash instead of their plaintext prefixes, see utils.TokenLookupHash.
+--
+-- The hashes of the existing keys are filled in when they are authenticated next time.
+DROP INDEX IF EXISTS client_keys_prefix_idx;
+ALTER TABLE client_keys DROP COLUMN IF EXISTS prefix;
+ALTER TABLE client_keys ADD COLUMN IF NOT EXISTS lookup_hash TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS client_keys_lookup_hash_idx ON client_keys (lookup_hash);
@@ -0,0 +1,3 @@
+-- The derived keys can not be restored, so the older servers can only verify the tokens created afterwards.
+ALTER TABLE admin_tokens DROP COLUMN IF EXISTS signing_public_key;
+ALTER TABLE admin_tokens ADD COLUMN IF NOT EXISTS signing_key TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,6 @@
+-- The signed requests are verified by the public keys of the signing secrets issued along with the tokens, instead
+-- of the keys derived from the tokens, so the stored keys can not sign requests.
+--
+-- The tokens added before have no signing secrets, and can not sign requests until re-created.
+ALTER TABLE admin_tokens DROP COLUMN IF EXISTS signing_key;
+ALTER TABLE admin_tokens ADD COLUMN IF NOT EXISTS signing_public_key TEXT NOT NULL DEFAULT '';
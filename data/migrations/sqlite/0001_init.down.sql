@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS device_keys;
+DROP TABLE IF EXISTS temporary_permits;
+DROP TABLE IF EXISTS cert_activations;
+DROP TABLE IF EXISTS archived_certs;
+DROP TABLE IF EXISTS certs;
+DROP TABLE IF EXISTS batches;
@@ -0,0 +1,65 @@
+-- The schema of the SQLite storage backend, which only keeps the certificates, temporary permits and key cache.
+-- The timestamps are written by the server in UTC.
+
+CREATE TABLE batches (
+    id TEXT PRIMARY KEY NOT NULL,
+    reason TEXT,
+    created_by TEXT,
+    reseller TEXT,
+    order_number TEXT,
+    product TEXT,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE certs (
+    sn TEXT PRIMARY KEY NOT NULL,
+    key TEXT,
+    note TEXT,
+    batch_id TEXT REFERENCES batches (id),
+    revoked_at TIMESTAMP,
+    metadata TEXT NOT NULL DEFAULT '{}',
+    product TEXT
+);
+
+CREATE INDEX certs_batch_id_idx ON certs (batch_id);
+
+CREATE TABLE archived_certs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    sn TEXT NOT NULL,
+    key TEXT,
+    note TEXT,
+    batch_id TEXT,
+    revoked_at TIMESTAMP,
+    metadata TEXT NOT NULL DEFAULT '{}',
+    product TEXT,
+    reason TEXT,
+    archived_by TEXT,
+    archived_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX archived_certs_sn_idx ON archived_certs (sn);
+
+CREATE TABLE cert_activations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    sn TEXT NOT NULL,
+    key TEXT NOT NULL,
+    status TEXT NOT NULL,
+    client_key_id TEXT,
+    ip TEXT,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX cert_activations_sn_idx ON cert_activations (sn);
+
+CREATE TABLE temporary_permits (
+    key TEXT PRIMARY KEY NOT NULL,
+    expiration TIMESTAMP NOT NULL
+);
+
+CREATE TABLE device_keys (
+    base TEXT PRIMARY KEY NOT NULL,
+    key TEXT NOT NULL,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX device_keys_expires_at_idx ON device_keys (expires_at);
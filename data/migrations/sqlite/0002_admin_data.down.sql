@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS jobs;
+DROP TABLE IF EXISTS audit_logs;
+DROP TABLE IF EXISTS client_keys;
+DROP TABLE IF EXISTS admin_tokens;
@@ -0,0 +1,80 @@
+-- The admin tokens, client keys, audit logs and jobs, so the server runs without Postgres when DRIVER is sqlite.
+-- The arrays of the client keys are kept as JSON arrays.
+
+CREATE TABLE admin_tokens (
+    id TEXT PRIMARY KEY NOT NULL,
+    name TEXT NOT NULL,
+    role TEXT NOT NULL,
+    lookup_hash TEXT NOT NULL,
+    salt TEXT NOT NULL,
+    token_hash TEXT NOT NULL,
+    signing_key TEXT NOT NULL,
+    totp_secret TEXT NOT NULL DEFAULT '',
+    created_by TEXT,
+    created_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    last_used_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX admin_tokens_lookup_hash_idx ON admin_tokens (lookup_hash);
+
+CREATE TABLE client_keys (
+    id TEXT PRIMARY KEY NOT NULL,
+    name TEXT NOT NULL,
+    lookup_hash TEXT NOT NULL,
+    salt TEXT NOT NULL,
+    token_hash TEXT NOT NULL,
+    products TEXT NOT NULL,
+    scopes TEXT NOT NULL,
+    rate_limit INTEGER NOT NULL DEFAULT 0,
+    usage_count INTEGER NOT NULL DEFAULT 0,
+    created_by TEXT,
+    created_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP,
+    last_used_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX client_keys_lookup_hash_idx ON client_keys (lookup_hash);
+
+CREATE TABLE audit_logs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    admin TEXT,
+    role TEXT,
+    ip TEXT NOT NULL,
+    method TEXT NOT NULL,
+    route TEXT NOT NULL,
+    params TEXT NOT NULL,
+    status INTEGER NOT NULL,
+    result TEXT,
+    created_at TIMESTAMP NOT NULL,
+    prev_hash TEXT NOT NULL,
+    hash TEXT NOT NULL
+);
+
+CREATE INDEX audit_logs_admin_idx ON audit_logs (admin);
+CREATE INDEX audit_logs_created_at_idx ON audit_logs (created_at);
+
+-- The audit logs are append-only.
+CREATE TRIGGER audit_logs_no_update BEFORE UPDATE ON audit_logs
+BEGIN
+    SELECT RAISE(ABORT, 'audit_logs is append-only');
+END;
+
+CREATE TRIGGER audit_logs_no_delete BEFORE DELETE ON audit_logs
+BEGIN
+    SELECT RAISE(ABORT, 'audit_logs is append-only');
+END;
+
+CREATE TABLE jobs (
+    id TEXT PRIMARY KEY NOT NULL,
+    type TEXT NOT NULL,
+    status TEXT NOT NULL,
+    progress INTEGER NOT NULL DEFAULT 0,
+    total INTEGER NOT NULL DEFAULT 0,
+    result TEXT,
+    error TEXT,
+    created_by TEXT,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
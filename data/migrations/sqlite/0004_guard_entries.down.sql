@@ -0,0 +1 @@
+DROP TABLE guard_entries;
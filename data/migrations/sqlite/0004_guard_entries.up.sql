@@ -0,0 +1,10 @@
+-- The rate limit counters, the lockouts of the admin authentication and the nonces of the signed admin requests,
+-- which are kept in redis by the servers with Postgres.
+
+CREATE TABLE guard_entries (
+    key TEXT PRIMARY KEY NOT NULL,
+    value INTEGER NOT NULL,
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX guard_entries_expires_at_idx ON guard_entries (expires_at);
@@ -0,0 +1,1606 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+
+	cfg "github.com/mmq88/quickcerts/configs"
+	"github.com/mmq88/quickcerts/model"
+	"github.com/mmq88/quickcerts/utils"
+)
+
+// The stores and caches kept in one SQLite database file, for the deployments without Postgres and redis.
+//
+// The database is opened in the WAL mode, so the reads do not wait for the writes. Every transaction takes the write
+// lock when it begins, so the operations are atomic as the transactions of PostgresStore. Open it with
+// OpenSQLiteStore.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+var (
+	_ Store      = (*SQLiteStore)(nil)
+	_ KeyCache   = (*SQLiteStore)(nil)
+	_ GuardCache = (*SQLiteStore)(nil)
+)
+
+// Open the SQLite database at the given path, creating the file if needed.
+//
+// The schema is not created or upgraded here, apply the migrations with Migrator before using the store.
+func OpenSQLiteStore(path string) (*SQLiteStore, error) {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+
+	dsn := fmt.Sprintf("file:%s?_busy_timeout=5000&_foreign_keys=on&_journal_mode=WAL&_txlock=immediate", path)
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, ErrDBConnectFailed
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, ErrDBAccessFailed
+	}
+
+	return &SQLiteStore{db: db}, nil
+}
+
+// Get the Migrator of the SQLite database.
+func (s *SQLiteStore) Migrator() (Migrator, error) {
+	return newMigrator(s.db, sqliteDialect)
+}
+
+// Close the SQLite database.
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+// Ping the database file, with the timeout of a query.
+func (s *SQLiteStore) Ping(ctx context.Context) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	return s.db.PingContext(ctx)
+}
+
+func (s *SQLiteStore) AddNewSN(ctx context.Context, sn string, product string) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, "INSERT INTO certs (sn, product) VALUES (?, NULLIF(?, ''))", sn, product)
+	if isSQLiteConflict(err) {
+		return ErrSNAlreadyExists
+	}
+
+	return err
+}
+
+// Generate and add the given number of new S/N(s), and record them as the given batch.
+//
+// Each round of SN_GENERATE_BATCH_SIZE S/N(s) is committed on its own, so the applies are not blocked by the write
+// lock until the whole batch is generated. If the generation fails, the S/N(s) added so far are deleted along with
+// the batch, see AddGeneratedSNs for the details.
+func (s *SQLiteStore) AddGeneratedSNs(
+	ctx context.Context, batch model.Batch, count int, generate func() (string, error),
+	onProgress func(inserted int) error,
+) ([]string, error) {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO batches (id, reason, created_by, reseller, order_number, product, created_at)
+		VALUES (?, ?, ?, ?, ?, NULLIF(?, ''), ?)
+	`, batch.ID, batch.Reason, batch.CreatedBy, batch.Reseller, batch.OrderNumber, batch.Product, time.Now().UTC())
+
+	if err != nil {
+		return nil, err
+	}
+
+	snList, err := s.addGeneratedSNs(ctx, batch, count, generate, onProgress)
+	if err != nil {
+		if deleteErr := s.deleteBatch(batch.ID); deleteErr != nil {
+			return nil, errors.Join(err, deleteErr)
+		}
+
+		return nil, err
+	}
+
+	return snList, nil
+}
+
+func (s *SQLiteStore) addGeneratedSNs(
+	ctx context.Context, batch model.Batch, count int, generate func() (string, error),
+	onProgress func(inserted int) error,
+) ([]string, error) {
+	batchSize := max(cfg.SERVER_CONFIG.SN_GENERATE_BATCH_SIZE, 1)
+	snList := make([]string, 0, count)
+	retries := 0
+
+	for len(snList) < count {
+		candidates := min(batchSize, count-len(snList))
+
+		inserted, err := s.insertGeneratedSNs(ctx, batch, candidates, generate)
+		if err != nil {
+			return nil, err
+		}
+
+		snList = append(snList, inserted...)
+
+		// Only the collided S/N(s) are regenerated in the next round.
+		if len(inserted) < candidates {
+			retries++
+			if retries > maxCollisionRetries {
+				return nil, ErrSNCollisions
+			}
+		}
+
+		if onProgress != nil {
+			if err := onProgress(len(snList)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return snList, nil
+}
+
+// Generate and insert one round of S/N(s) in a transaction, and return the ones which did not collide.
+func (s *SQLiteStore) insertGeneratedSNs(
+	ctx context.Context, batch model.Batch, candidates int, generate func() (string, error),
+) ([]string, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx,
+		"INSERT INTO certs (sn, batch_id, product) VALUES (?, ?, NULLIF(?, '')) ON CONFLICT (sn) DO NOTHING",
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer stmt.Close()
+
+	var inserted []string
+
+	for i := 0; i < candidates; i++ {
+		sn, err := generate()
+		if err != nil {
+			return nil, err
+		}
+
+		res, err := stmt.ExecContext(ctx, sn, batch.ID, batch.Product)
+		if err != nil {
+			return nil, err
+		}
+
+		if affected, err := res.RowsAffected(); err != nil {
+			return nil, err
+		} else if affected > 0 {
+			inserted = append(inserted, sn)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return inserted, nil
+}
+
+// Delete the given batch along with its S/N(s), e.g. when its generation has failed or been cancelled.
+//
+// The rounds committed so far may have been applied already, so the bound S/N(s) are kept along with the batch.
+func (s *SQLiteStore) deleteBatch(id string) error {
+	// The generation may have been cancelled by ctx, the S/N(s) are deleted anyway.
+	ctx, cancel := withQueryTimeout(context.Background())
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM certs WHERE batch_id = ? AND key IS NULL", id); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"DELETE FROM batches WHERE id = ? AND NOT EXISTS (SELECT 1 FROM certs WHERE batch_id = ?)", id, id,
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Bind the S/N to the key of the device, and record the attempt in the activation history.
+//
+// See BindCertificate for the statuses.
+func (s *SQLiteStore) BindCertificate(
+	ctx context.Context, activation model.CertActivation, allowsProduct func(product string) bool,
+) (model.BindStatus, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+
+	defer tx.Rollback()
+
+	var key, product sql.NullString
+	var revoked bool
+	err = tx.QueryRowContext(ctx,
+		"SELECT key, product, revoked_at IS NOT NULL FROM certs WHERE sn = ?", activation.SerialNumber,
+	).Scan(&key, &product, &revoked)
+
+	var status model.BindStatus
+
+	switch {
+	case err == sql.ErrNoRows:
+		status = model.BindStatusNotFound
+	case err != nil:
+		return "", err
+	case allowsProduct != nil && !allowsProduct(product.String):
+		status = model.BindStatusNotAllowed
+	case revoked:
+		status = model.BindStatusRevoked
+	case !key.Valid:
+		status = model.BindStatusBound
+	case key.String == activation.Key:
+		status = model.BindStatusRebound
+	default:
+		status = model.BindStatusTaken
+	}
+
+	if status == model.BindStatusBound {
+		_, err = tx.ExecContext(ctx, "UPDATE certs SET key = ? WHERE sn = ?", activation.Key, activation.SerialNumber)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if status == model.BindStatusNotFound {
+		return status, nil
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO cert_activations (sn, key, status, client_key_id, ip, created_at)
+		VALUES (?, ?, ?, NULLIF(?, ''), NULLIF(?, ''), ?)
+	`, activation.SerialNumber, activation.Key, status, activation.ClientKeyID, activation.IP, time.Now().UTC())
+
+	if err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+
+	return status, nil
+}
+
+func (s *SQLiteStore) GetCertActivations(ctx context.Context, sn string) ([]model.CertActivation, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT sn, key, status, client_key_id, ip, created_at
+		FROM cert_activations
+		WHERE sn = ?
+		ORDER BY id
+	`, sn)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	activations := []model.CertActivation{}
+
+	for rows.Next() {
+		var activation model.CertActivation
+		var clientKeyID, ip sql.NullString
+
+		err := rows.Scan(
+			&activation.SerialNumber, &activation.Key, &activation.Status, &clientKeyID, &ip, &activation.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		activation.ClientKeyID = clientKeyID.String
+		activation.IP = ip.String
+		activations = append(activations, activation)
+	}
+
+	return activations, rows.Err()
+}
+
+func (s *SQLiteStore) GetAllCerts(ctx context.Context) ([]model.Cert, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	return s.queryCerts(ctx, "SELECT sn, key, note, batch_id, revoked_at, metadata, product FROM certs ORDER BY sn")
+}
+
+func (s *SQLiteStore) GetAvaliableSN(ctx context.Context) ([]string, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, "SELECT sn FROM certs WHERE key IS NULL AND revoked_at IS NULL ORDER BY sn")
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var res []string
+
+	for rows.Next() {
+		var sn string
+		if err := rows.Scan(&sn); err != nil {
+			return nil, err
+		}
+
+		res = append(res, sn)
+	}
+
+	return res, rows.Err()
+}
+
+func (s *SQLiteStore) UpdateCertNote(ctx context.Context, sn string, note string) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, "UPDATE certs SET note = ? WHERE sn = ?", note, sn)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrSNNotFound
+	}
+
+	return nil
+}
+
+// Merge the given patch into the metadata of the given S/N, and return the updated metadata.
+//
+// The keys in the patch overwrite the existing ones, and the keys with null values are removed.
+func (s *SQLiteStore) PatchCertMetadata(ctx context.Context, sn string, patch map[string]any) (map[string]any, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	patch, err := normalizeMetadata(patch)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	var rawMetadata []byte
+	err = tx.QueryRowContext(ctx, "SELECT metadata FROM certs WHERE sn = ?", sn).Scan(&rawMetadata)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrSNNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	metadata, err := decodeMetadata(rawMetadata)
+	if err != nil {
+		return nil, err
+	}
+
+	for k, v := range patch {
+		if v == nil {
+			delete(metadata, k)
+		} else {
+			metadata[k] = v
+		}
+	}
+
+	if rawMetadata, err = json.Marshal(metadata); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE certs SET metadata = ? WHERE sn = ?", string(rawMetadata), sn); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return metadata, nil
+}
+
+// Get all certificate records whose metadata contains the filter, as the `@>` operator of Postgres.
+//
+// The containment is checked after the records are read, as SQLite has no index for it.
+func (s *SQLiteStore) FindCertsByMetadata(ctx context.Context, filter map[string]any) ([]model.Cert, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	filter, err := normalizeMetadata(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	certs, err := s.GetAllCerts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []model.Cert
+
+	for _, cert := range certs {
+		if containsJSON(cert.Metadata, filter) {
+			res = append(res, cert)
+		}
+	}
+
+	return res, nil
+}
+
+// Delete the given S/N and move its record into the archive.
+//
+// A S/N which has been bound to a device is refused unless force is true.
+func (s *SQLiteStore) ArchiveSN(ctx context.Context, sn string, reason string, archivedBy string, force bool) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	var bound bool
+	err = tx.QueryRowContext(ctx, "SELECT key IS NOT NULL FROM certs WHERE sn = ?", sn).Scan(&bound)
+
+	if err == sql.ErrNoRows {
+		return ErrSNNotFound
+	} else if err != nil {
+		return err
+	}
+
+	if bound && !force {
+		return ErrSNAlreadyBound
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO archived_certs (
+			sn, key, note, batch_id, revoked_at, metadata, product, reason, archived_by, archived_at
+		)
+		SELECT sn, key, note, batch_id, revoked_at, metadata, product, NULLIF(?, ''), NULLIF(?, ''), ?
+		FROM certs WHERE sn = ?
+	`, reason, archivedBy, time.Now().UTC(), sn)
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM certs WHERE sn = ?", sn); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Get all archived certificate records, the newest first.
+func (s *SQLiteStore) GetArchivedCerts(ctx context.Context) ([]model.ArchivedCert, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT sn, key, note, batch_id, revoked_at, metadata, product, reason, archived_by, archived_at
+		FROM archived_certs
+		ORDER BY id DESC
+	`)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var certs []model.ArchivedCert
+
+	for rows.Next() {
+		var cert model.ArchivedCert
+		var tmpKey, tmpNote, tmpBatchID, tmpProduct, tmpReason, tmpArchivedBy sql.NullString
+		var tmpRevokedAt sql.NullTime
+		var rawMetadata []byte
+
+		err := rows.Scan(
+			&cert.SerialNumber, &tmpKey, &tmpNote, &tmpBatchID, &tmpRevokedAt, &rawMetadata, &tmpProduct,
+			&tmpReason, &tmpArchivedBy, &cert.ArchivedAt,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		cert.Key = tmpKey.String
+		cert.Note = tmpNote.String
+		cert.BatchID = tmpBatchID.String
+		cert.Product = tmpProduct.String
+		cert.Reason = tmpReason.String
+		cert.ArchivedBy = tmpArchivedBy.String
+		if tmpRevokedAt.Valid {
+			cert.RevokedAt = &tmpRevokedAt.Time
+		}
+
+		if cert.Metadata, err = decodeMetadata(rawMetadata); err != nil {
+			return nil, err
+		}
+
+		certs = append(certs, cert)
+	}
+
+	return certs, rows.Err()
+}
+
+// Get all batch records with the statistics of their S/N(s), the newest first.
+func (s *SQLiteStore) GetAllBatches(ctx context.Context) ([]model.Batch, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT b.id, b.reason, b.created_by, b.reseller, b.order_number, b.product, b.created_at,
+			COUNT(c.sn), COUNT(c.key), COUNT(c.revoked_at)
+		FROM batches b
+		LEFT JOIN certs c ON c.batch_id = b.id
+		GROUP BY b.id
+		ORDER BY b.created_at DESC, b.rowid DESC
+	`)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var batches []model.Batch
+
+	for rows.Next() {
+		var batch model.Batch
+		var tmpReason, tmpCreatedBy, tmpReseller, tmpOrderNumber, tmpProduct sql.NullString
+
+		err := rows.Scan(
+			&batch.ID, &tmpReason, &tmpCreatedBy, &tmpReseller, &tmpOrderNumber, &tmpProduct, &batch.CreatedAt,
+			&batch.Count, &batch.Bound, &batch.Revoked,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		batch.Reason = tmpReason.String
+		batch.CreatedBy = tmpCreatedBy.String
+		batch.Reseller = tmpReseller.String
+		batch.OrderNumber = tmpOrderNumber.String
+		batch.Product = tmpProduct.String
+		batches = append(batches, batch)
+	}
+
+	return batches, rows.Err()
+}
+
+func (s *SQLiteStore) IsBatchExist(ctx context.Context, id string) (bool, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var exists bool
+	err := s.db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM batches WHERE id = ?)", id).Scan(&exists)
+	if err != nil {
+		return false, err
+	}
+
+	if !exists {
+		return false, ErrBatchNotFound
+	}
+
+	return true, nil
+}
+
+// Call fn with each certificate record in the given batch, ordered by S/N.
+//
+// The records are read before fn is called, so fn may use the store without holding the rows open.
+func (s *SQLiteStore) ForEachCertInBatch(ctx context.Context, id string, fn func(cert model.Cert) error) error {
+	certs, err := s.queryCerts(ctx, `
+		SELECT sn, key, note, batch_id, revoked_at, metadata, product FROM certs
+		WHERE batch_id = ?
+		ORDER BY sn
+	`, id)
+
+	if err != nil {
+		return err
+	}
+
+	for _, cert := range certs {
+		if err := fn(cert); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *SQLiteStore) RevokeBatch(ctx context.Context, id string) (int64, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	if _, err := s.IsBatchExist(ctx, id); err != nil {
+		return 0, err
+	}
+
+	res, err := s.db.ExecContext(ctx,
+		"UPDATE certs SET revoked_at = ? WHERE batch_id = ? AND revoked_at IS NULL", time.Now().UTC(), id,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+func (s *SQLiteStore) GetTemporaryPermitExpiredTime(ctx context.Context, key string) (int64, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var expiration time.Time
+	err := s.db.QueryRowContext(ctx, "SELECT expiration FROM temporary_permits WHERE key = ?", key).Scan(&expiration)
+
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("%w: %s", ErrPermitNotFound, key)
+	} else if err != nil {
+		return 0, err
+	}
+
+	return max(expiration.Unix()-time.Now().Unix(), 0), nil
+}
+
+func (s *SQLiteStore) AddTemporaryPermit(ctx context.Context, key string) (int64, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	expiration, err := temporaryPermitExpiration()
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = s.db.ExecContext(ctx, "INSERT INTO temporary_permits (key, expiration) VALUES (?, ?)", key, expiration.UTC())
+	if isSQLiteConflict(err) {
+		return 0, fmt.Errorf("the temporary permit of [%s] already exists", key)
+	} else if err != nil {
+		return 0, err
+	}
+
+	return expiration.Unix() - time.Now().Unix(), nil
+}
+
+// Get the cached key of the device, the expired ones are deleted as they are read.
+func (s *SQLiteStore) GetDeviceKey(ctx context.Context, deviceInfoBase string) (string, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var key string
+	var expiresAt time.Time
+	err := s.db.QueryRowContext(ctx,
+		"SELECT key, expires_at FROM device_keys WHERE base = ?", deviceInfoBase,
+	).Scan(&key, &expiresAt)
+
+	if err == sql.ErrNoRows {
+		return "", ErrCacheKeyNotFound
+	} else if err != nil {
+		return "", err
+	}
+
+	if time.Now().After(expiresAt) {
+		if _, err := s.db.ExecContext(ctx, "DELETE FROM device_keys WHERE base = ?", deviceInfoBase); err != nil {
+			return "", err
+		}
+
+		return "", ErrCacheKeyNotFound
+	}
+
+	return key, nil
+}
+
+// Cache the key of the device, and purge the expired keys of the other devices.
+func (s *SQLiteStore) SetDeviceKey(ctx context.Context, deviceInfoBase string, key string) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	now := time.Now().UTC()
+
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM device_keys WHERE expires_at <= ?", now); err != nil {
+		return err
+	}
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO device_keys (base, key, expires_at) VALUES (?, ?, ?)
+		ON CONFLICT (base) DO UPDATE SET key = excluded.key, expires_at = excluded.expires_at
+	`, deviceInfoBase, key, now.Add(deviceKeyCacheTTL()))
+
+	return err
+}
+
+func (s *SQLiteStore) CountRateLimitedRequest(
+	ctx context.Context, scope string, id string, window time.Duration,
+) (int64, time.Duration, error) {
+	return countRateLimitedEntry(ctx, s, scope, id, window)
+}
+
+func (s *SQLiteStore) GetAdminLockout(ctx context.Context, ip string) (time.Duration, error) {
+	return s.ttl(ctx, adminLockKey(ip))
+}
+
+func (s *SQLiteStore) RecordAdminAuthFailure(
+	ctx context.Context, ip string, threshold int, base time.Duration, max time.Duration,
+) (time.Duration, error) {
+	return recordAdminAuthFailureEntry(ctx, s, ip, threshold, base, max)
+}
+
+func (s *SQLiteStore) ResetAdminAuthFailures(ctx context.Context, ip string) error {
+	return s.del(ctx, adminFailuresKey(ip))
+}
+
+// Claim the nonce of a signed admin request, and purge the expired entries of the guard cache.
+func (s *SQLiteStore) ClaimRequestNonce(
+	ctx context.Context, tokenID string, nonce string, ttl time.Duration,
+) (bool, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM guard_entries WHERE expires_at <= ?", time.Now().UTC()); err != nil {
+		return false, err
+	}
+
+	return s.setNX(ctx, requestNonceKey(tokenID, nonce), ttl)
+}
+
+// The expired entries are replaced as if they did not exist, so they need not be purged before each query.
+func (s *SQLiteStore) incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	now := time.Now().UTC()
+
+	var value int64
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO guard_entries (key, value, expires_at) VALUES (?, 1, ?)
+		ON CONFLICT (key) DO UPDATE SET
+			value = CASE WHEN guard_entries.expires_at <= ? THEN 1 ELSE guard_entries.value + 1 END,
+			expires_at = excluded.expires_at
+		RETURNING value
+	`, key, now.Add(ttl), now).Scan(&value)
+
+	return value, err
+}
+
+func (s *SQLiteStore) ttl(ctx context.Context, key string) (time.Duration, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var expiresAt time.Time
+	err := s.db.QueryRowContext(ctx, "SELECT expires_at FROM guard_entries WHERE key = ?", key).Scan(&expiresAt)
+
+	if err == sql.ErrNoRows {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	return max(time.Until(expiresAt), 0), nil
+}
+
+func (s *SQLiteStore) set(ctx context.Context, key string, ttl time.Duration) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO guard_entries (key, value, expires_at) VALUES (?, 1, ?)
+		ON CONFLICT (key) DO UPDATE SET value = 1, expires_at = excluded.expires_at
+	`, key, time.Now().UTC().Add(ttl))
+
+	return err
+}
+
+func (s *SQLiteStore) setNX(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	now := time.Now().UTC()
+
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO guard_entries (key, value, expires_at) VALUES (?, 1, ?)
+		ON CONFLICT (key) DO UPDATE SET value = 1, expires_at = excluded.expires_at
+		WHERE guard_entries.expires_at <= ?
+	`, key, now.Add(ttl), now)
+
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	return affected > 0, err
+}
+
+func (s *SQLiteStore) del(ctx context.Context, key string) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, "DELETE FROM guard_entries WHERE key = ?", key)
+	return err
+}
+
+// Create a new admin token with a new TOTP secret and signing secret, see CreateAdminToken.
+func (s *SQLiteStore) CreateAdminToken(
+	ctx context.Context, name string, role string, createdBy string, expiresAt *time.Time,
+) (model.AdminToken, string, string, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	token, err := utils.GenerateToken()
+	if err != nil {
+		return model.AdminToken{}, "", "", err
+	}
+
+	totpSecret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return model.AdminToken{}, "", "", err
+	}
+
+	signingSecret, signingPublicKey, err := utils.GenerateSigningSecret()
+	if err != nil {
+		return model.AdminToken{}, "", "", err
+	}
+
+	record, err := insertSQLiteAdminToken(
+		ctx, s.db, name, role, createdBy, token, totpSecret, signingPublicKey, expiresAt,
+	)
+	if err != nil {
+		return model.AdminToken{}, "", "", err
+	}
+
+	return record, token, signingSecret, nil
+}
+
+// Add the permissions of allowlist.toml as admin tokens if there is no admin token yet, see BootstrapAdminTokens.
+func (s *SQLiteStore) BootstrapAdminTokens(ctx context.Context, permissions []cfg.Permission) (int, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM admin_tokens)").Scan(&exists); err != nil {
+		return 0, err
+	}
+
+	if exists {
+		return 0, nil
+	}
+
+	added := 0
+
+	for _, permission := range permissions {
+		if permission.TOKEN == "" {
+			continue
+		}
+
+		_, err := insertSQLiteAdminToken(
+			ctx, tx, permission.NAME, permission.ROLE, "allowlist.toml", permission.TOKEN, permission.TOTP_SECRET,
+			permission.SIGNING_PUBLIC_KEY, nil,
+		)
+		if err != nil {
+			return 0, err
+		}
+
+		added++
+	}
+
+	return added, tx.Commit()
+}
+
+// Find the active admin token matching the given token, and update the time it was last used.
+func (s *SQLiteStore) AuthenticateAdminToken(ctx context.Context, token string) (model.AdminToken, error) {
+	if len(token) < cfg.MinTokenLength {
+		return model.AdminToken{}, ErrAdminTokenInvalid
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	row := s.db.QueryRowContext(ctx,
+		"SELECT "+adminTokenColumns+" FROM admin_tokens WHERE lookup_hash = ?", utils.TokenLookupHash(token),
+	)
+	record, err := scanAdminToken(row)
+
+	if err == sql.ErrNoRows {
+		return model.AdminToken{}, ErrAdminTokenInvalid
+	} else if err != nil {
+		return model.AdminToken{}, err
+	}
+
+	now := time.Now().UTC()
+
+	if !record.IsActive(now) || !utils.VerifyToken(token, record.Salt, record.TokenHash) {
+		return model.AdminToken{}, ErrAdminTokenInvalid
+	}
+
+	if _, err := s.db.ExecContext(ctx, "UPDATE admin_tokens SET last_used_at = ? WHERE id = ?", now, record.ID); err != nil {
+		return model.AdminToken{}, err
+	}
+
+	record.LastUsedAt = &now
+	return record, nil
+}
+
+// Find the active admin token with the given ID whose request signature is accepted by verify, see
+// AuthenticateSignedAdminToken.
+func (s *SQLiteStore) AuthenticateSignedAdminToken(
+	ctx context.Context, id string, verify func(token model.AdminToken) bool,
+) (model.AdminToken, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	row := s.db.QueryRowContext(ctx, "SELECT "+adminTokenColumns+" FROM admin_tokens WHERE id = ?", id)
+	record, err := scanAdminToken(row)
+
+	if err == sql.ErrNoRows {
+		return model.AdminToken{}, ErrAdminTokenInvalid
+	} else if err != nil {
+		return model.AdminToken{}, err
+	}
+
+	now := time.Now().UTC()
+
+	if !record.IsActive(now) || !verify(record) {
+		return model.AdminToken{}, ErrAdminTokenInvalid
+	}
+
+	if _, err := s.db.ExecContext(ctx, "UPDATE admin_tokens SET last_used_at = ? WHERE id = ?", now, record.ID); err != nil {
+		return model.AdminToken{}, err
+	}
+
+	record.LastUsedAt = &now
+	return record, nil
+}
+
+// Get all admin tokens, the newest first.
+func (s *SQLiteStore) GetAllAdminTokens(ctx context.Context) ([]model.AdminToken, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT "+adminTokenColumns+" FROM admin_tokens ORDER BY created_at DESC, rowid DESC",
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var tokens []model.AdminToken
+
+	for rows.Next() {
+		record, err := scanAdminToken(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		tokens = append(tokens, record)
+	}
+
+	return tokens, rows.Err()
+}
+
+func (s *SQLiteStore) RevokeAdminToken(ctx context.Context, id string) (model.AdminToken, error) {
+	return s.updateAdminToken(ctx,
+		"UPDATE admin_tokens SET revoked_at = COALESCE(revoked_at, ?) WHERE id = ?", time.Now().UTC(), id,
+	)
+}
+
+func (s *SQLiteStore) ExpireAdminToken(ctx context.Context, id string, expiresAt time.Time) (model.AdminToken, error) {
+	return s.updateAdminToken(ctx, "UPDATE admin_tokens SET expires_at = ? WHERE id = ?", expiresAt.UTC(), id)
+}
+
+func (s *SQLiteStore) ResetAdminTokenTOTP(ctx context.Context, id string) (model.AdminToken, string, error) {
+	totpSecret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return model.AdminToken{}, "", err
+	}
+
+	record, err := s.updateAdminToken(ctx, "UPDATE admin_tokens SET totp_secret = ? WHERE id = ?", totpSecret, id)
+	if err != nil {
+		return model.AdminToken{}, "", err
+	}
+
+	return record, totpSecret, nil
+}
+
+// Update the admin token by the statement whose last argument is the ID, and return the updated record.
+func (s *SQLiteStore) updateAdminToken(ctx context.Context, stmt string, args ...any) (model.AdminToken, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	row := s.db.QueryRowContext(ctx, stmt+" RETURNING "+adminTokenColumns, args...)
+	record, err := scanAdminToken(row)
+
+	if err == sql.ErrNoRows {
+		return model.AdminToken{}, ErrAdminTokenNotFound
+	} else if err != nil {
+		return model.AdminToken{}, err
+	}
+
+	return record, nil
+}
+
+// Create a new client key, and return its record along with the key itself.
+//
+// The key is only returned here, the database keeps its salted hash.
+func (s *SQLiteStore) CreateClientKey(
+	ctx context.Context, name string, products []string, scopes []string, rateLimit int, createdBy string,
+) (model.ClientKey, string, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	key, err := utils.GenerateToken()
+	if err != nil {
+		return model.ClientKey{}, "", err
+	}
+
+	record, err := insertSQLiteClientKey(ctx, s.db, name, key, products, scopes, rateLimit, createdBy)
+	if err != nil {
+		return model.ClientKey{}, "", err
+	}
+
+	return record, key, nil
+}
+
+// Add the CLIENT_AUTH_TOKEN entries of server.toml as client keys if there is no client key yet, see
+// BootstrapClientKeys.
+func (s *SQLiteStore) BootstrapClientKeys(ctx context.Context, tokens []string) (int, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM client_keys)").Scan(&exists); err != nil {
+		return 0, err
+	}
+
+	if exists {
+		return 0, nil
+	}
+
+	products := []string{model.AllProducts}
+	scopes := []string{model.ClientScopeApplyCert, model.ClientScopeApplyTempPermit}
+
+	for i, token := range tokens {
+		name := fmt.Sprintf("CLIENT_AUTH_TOKEN #%d", i)
+		if _, err := insertSQLiteClientKey(ctx, tx, name, token, products, scopes, 0, "server.toml"); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(tokens), tx.Commit()
+}
+
+// Find the active client key matching the given key, and count the request into its usage.
+func (s *SQLiteStore) AuthenticateClientKey(ctx context.Context, key string) (model.ClientKey, error) {
+	if len(key) < cfg.MinTokenLength {
+		return model.ClientKey{}, ErrClientKeyInvalid
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	row := s.db.QueryRowContext(ctx,
+		"SELECT "+clientKeyColumns+" FROM client_keys WHERE lookup_hash = ? AND revoked_at IS NULL",
+		utils.TokenLookupHash(key),
+	)
+	record, err := scanSQLiteClientKey(row)
+
+	if err == sql.ErrNoRows {
+		return model.ClientKey{}, ErrClientKeyInvalid
+	} else if err != nil {
+		return model.ClientKey{}, err
+	}
+
+	if !utils.VerifyToken(key, record.Salt, record.TokenHash) {
+		return model.ClientKey{}, ErrClientKeyInvalid
+	}
+
+	now := time.Now().UTC()
+
+	err = s.db.QueryRowContext(ctx, `
+		UPDATE client_keys SET usage_count = usage_count + 1, last_used_at = ?
+		WHERE id = ?
+		RETURNING usage_count
+	`, now, record.ID).Scan(&record.UsageCount)
+
+	if err != nil {
+		return model.ClientKey{}, err
+	}
+
+	record.LastUsedAt = &now
+	return record, nil
+}
+
+// Get all client keys, the newest first.
+func (s *SQLiteStore) GetAllClientKeys(ctx context.Context) ([]model.ClientKey, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT "+clientKeyColumns+" FROM client_keys ORDER BY created_at DESC, rowid DESC",
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var keys []model.ClientKey
+
+	for rows.Next() {
+		record, err := scanSQLiteClientKey(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, record)
+	}
+
+	return keys, rows.Err()
+}
+
+func (s *SQLiteStore) RevokeClientKey(ctx context.Context, id string) (model.ClientKey, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	row := s.db.QueryRowContext(ctx, `
+		UPDATE client_keys SET revoked_at = COALESCE(revoked_at, ?)
+		WHERE id = ?
+		RETURNING `+clientKeyColumns, time.Now().UTC(), id)
+
+	record, err := scanSQLiteClientKey(row)
+
+	if err == sql.ErrNoRows {
+		return model.ClientKey{}, ErrClientKeyNotFound
+	} else if err != nil {
+		return model.ClientKey{}, err
+	}
+
+	return record, nil
+}
+
+// Append an admin action to the audit logs, chained to the hash of the last record, see AddAuditLog.
+//
+// The transaction takes the write lock when it begins, so the appends are serialized.
+func (s *SQLiteStore) AddAuditLog(ctx context.Context, log model.AuditLog) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, "SELECT hash FROM audit_logs ORDER BY id DESC LIMIT 1").Scan(&log.PrevHash)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	if len(log.Params) == 0 {
+		log.Params = json.RawMessage("{}")
+	}
+
+	log.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	log.Hash = computeAuditHash(log)
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO audit_logs (admin, role, ip, method, route, params, status, result, created_at, prev_hash, hash)
+		VALUES (NULLIF(?, ''), NULLIF(?, ''), ?, ?, ?, ?, ?, NULLIF(?, ''), ?, ?, ?)
+	`, log.Admin, log.Role, log.IP, log.Method, log.Route, string(log.Params), log.Status, log.Result,
+		log.CreatedAt, log.PrevHash, log.Hash,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Get the audit logs matching the given filter, the newest first.
+func (s *SQLiteStore) GetAuditLogs(ctx context.Context, filter model.AuditFilter) ([]model.AuditLog, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var conditions []string
+	var args []any
+
+	addCondition := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, condition)
+	}
+
+	if filter.Admin != "" {
+		addCondition("admin = ?", filter.Admin)
+	}
+	if filter.Role != "" {
+		addCondition("role = ?", filter.Role)
+	}
+	if filter.IP != "" {
+		addCondition("ip = ?", filter.IP)
+	}
+	if filter.Route != "" {
+		addCondition("route = ?", filter.Route)
+	}
+	if filter.Status != 0 {
+		addCondition("status = ?", filter.Status)
+	}
+	// The timestamps are compared as the text written by the server, which is in UTC.
+	if !filter.From.IsZero() {
+		addCondition("created_at >= ?", filter.From.UTC())
+	}
+	if !filter.To.IsZero() {
+		addCondition("created_at < ?", filter.To.UTC())
+	}
+
+	query := `
+		SELECT id, admin, role, ip, method, route, params, status, result, created_at, prev_hash, hash
+		FROM audit_logs
+	`
+
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	query += " ORDER BY id DESC LIMIT ? OFFSET ?"
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var logs []model.AuditLog
+
+	for rows.Next() {
+		log, err := scanAuditLog(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		logs = append(logs, log)
+	}
+
+	return logs, rows.Err()
+}
+
+// Walk through the audit log chain from the oldest record, see VerifyAuditLogs.
+func (s *SQLiteStore) VerifyAuditLogs(ctx context.Context) (int64, int64, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, admin, role, ip, method, route, params, status, result, created_at, prev_hash, hash
+		FROM audit_logs
+		ORDER BY id
+	`)
+
+	if err != nil {
+		return 0, 0, err
+	}
+
+	defer rows.Close()
+
+	var checked int64
+	prevHash := ""
+
+	for rows.Next() {
+		log, err := scanAuditLog(rows)
+		if err != nil {
+			return checked, 0, err
+		}
+
+		if log.PrevHash != prevHash || computeAuditHash(log) != log.Hash {
+			return checked, log.ID, nil
+		}
+
+		prevHash = log.Hash
+		checked++
+	}
+
+	return checked, 0, rows.Err()
+}
+
+func (s *SQLiteStore) AddJob(ctx context.Context, job model.Job) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	now := time.Now().UTC()
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO jobs (id, type, status, progress, total, created_by, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, job.ID, job.Type, job.Status, job.Progress, job.Total, job.CreatedBy, now, now)
+
+	return err
+}
+
+func (s *SQLiteStore) GetJob(ctx context.Context, id string) (model.Job, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var job model.Job
+	var tmpResult, tmpError, tmpCreatedBy sql.NullString
+
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, type, status, progress, total, result, error, created_by, created_at, updated_at
+		FROM jobs WHERE id = ?
+	`, id).Scan(
+		&job.ID, &job.Type, &job.Status, &job.Progress, &job.Total,
+		&tmpResult, &tmpError, &tmpCreatedBy, &job.CreatedAt, &job.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return model.Job{}, ErrJobNotFound
+	} else if err != nil {
+		return model.Job{}, err
+	}
+
+	job.Result = tmpResult.String
+	job.Error = tmpError.String
+	job.CreatedBy = tmpCreatedBy.String
+
+	return job, nil
+}
+
+func (s *SQLiteStore) UpdateJobProgress(ctx context.Context, id string, progress int) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx,
+		"UPDATE jobs SET progress = ?, updated_at = ? WHERE id = ?", progress, time.Now().UTC(), id,
+	)
+	return err
+}
+
+func (s *SQLiteStore) UpdateJobStatus(ctx context.Context, id string, status string, result string, errMsg string) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE jobs
+		SET status = ?, result = NULLIF(?, ''), error = NULLIF(?, ''), updated_at = ?
+		WHERE id = ?
+	`, status, result, errMsg, time.Now().UTC(), id)
+
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrJobNotFound
+	}
+
+	return nil
+}
+
+// Mark the queued and running jobs as failed, see FailUnfinishedJobs.
+func (s *SQLiteStore) FailUnfinishedJobs(ctx context.Context, errMsg string) (int64, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE jobs
+		SET status = ?, error = ?, updated_at = ?
+		WHERE status IN (?, ?)
+	`, model.JobStatusFailed, errMsg, time.Now().UTC(), model.JobStatusQueued, model.JobStatusRunning)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+func insertSQLiteAdminToken(
+	ctx context.Context, q rowQuerier, name string, role string, createdBy string, token string, totpSecret string,
+	signingPublicKey string, expiresAt *time.Time,
+) (model.AdminToken, error) {
+	if len(token) < cfg.MinTokenLength {
+		return model.AdminToken{}, ErrTokenTooShort
+	}
+
+	id, err := utils.GenerateID()
+	if err != nil {
+		return model.AdminToken{}, err
+	}
+
+	salt, err := utils.GenerateID()
+	if err != nil {
+		return model.AdminToken{}, err
+	}
+
+	var tmpExpiresAt sql.NullTime
+	if expiresAt != nil {
+		tmpExpiresAt = sql.NullTime{Time: expiresAt.UTC(), Valid: true}
+	}
+
+	row := q.QueryRowContext(ctx, `
+		INSERT INTO admin_tokens (
+			id, name, role, lookup_hash, salt, token_hash, signing_public_key, totp_secret, created_by, created_at,
+			expires_at
+		)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), ?, ?)
+		RETURNING `+adminTokenColumns,
+		id, name, role, utils.TokenLookupHash(token), salt, utils.HashToken(token, salt), signingPublicKey, totpSecret,
+		createdBy, time.Now().UTC(), tmpExpiresAt,
+	)
+
+	return scanAdminToken(row)
+}
+
+func insertSQLiteClientKey(
+	ctx context.Context, q rowQuerier, name string, key string, products []string, scopes []string, rateLimit int,
+	createdBy string,
+) (model.ClientKey, error) {
+	if len(key) < cfg.MinTokenLength {
+		return model.ClientKey{}, ErrTokenTooShort
+	}
+
+	id, err := utils.GenerateID()
+	if err != nil {
+		return model.ClientKey{}, err
+	}
+
+	salt, err := utils.GenerateID()
+	if err != nil {
+		return model.ClientKey{}, err
+	}
+
+	rawProducts, err := json.Marshal(products)
+	if err != nil {
+		return model.ClientKey{}, err
+	}
+
+	rawScopes, err := json.Marshal(scopes)
+	if err != nil {
+		return model.ClientKey{}, err
+	}
+
+	row := q.QueryRowContext(ctx, `
+		INSERT INTO client_keys (
+			id, name, lookup_hash, salt, token_hash, products, scopes, rate_limit, created_by, created_at
+		)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), ?)
+		RETURNING `+clientKeyColumns,
+		id, name, utils.TokenLookupHash(key), salt, utils.HashToken(key, salt),
+		string(rawProducts), string(rawScopes), rateLimit, createdBy, time.Now().UTC(),
+	)
+
+	return scanSQLiteClientKey(row)
+}
+
+// Scan a client key whose products and scopes are JSON arrays.
+func scanSQLiteClientKey(row rowScanner) (model.ClientKey, error) {
+	var record model.ClientKey
+	var rawProducts, rawScopes []byte
+	var tmpCreatedBy sql.NullString
+	var tmpRevokedAt, tmpLastUsedAt sql.NullTime
+
+	err := row.Scan(
+		&record.ID, &record.Name, &record.Salt, &record.TokenHash, &rawProducts, &rawScopes, &record.RateLimit,
+		&record.UsageCount, &tmpCreatedBy, &record.CreatedAt, &tmpRevokedAt, &tmpLastUsedAt,
+	)
+
+	if err != nil {
+		return model.ClientKey{}, err
+	}
+
+	if err := json.Unmarshal(rawProducts, &record.Products); err != nil {
+		return model.ClientKey{}, err
+	}
+
+	if err := json.Unmarshal(rawScopes, &record.Scopes); err != nil {
+		return model.ClientKey{}, err
+	}
+
+	record.CreatedBy = tmpCreatedBy.String
+	if tmpRevokedAt.Valid {
+		record.RevokedAt = &tmpRevokedAt.Time
+	}
+	if tmpLastUsedAt.Valid {
+		record.LastUsedAt = &tmpLastUsedAt.Time
+	}
+
+	return record, nil
+}
+
+// Get the certificate records of the query selecting `sn, key, note, batch_id, revoked_at, metadata, product`.
+func (s *SQLiteStore) queryCerts(ctx context.Context, query string, args ...any) ([]model.Cert, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var certs []model.Cert
+
+	for rows.Next() {
+		cert, err := scanCert(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		certs = append(certs, cert)
+	}
+
+	return certs, rows.Err()
+}
+
+// Check if the error is caused by the primary key or unique constraint.
+func isSQLiteConflict(err error) bool {
+	var sqliteErr sqlite3.Error
+
+	return errors.As(err, &sqliteErr) &&
+		(sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey ||
+			sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique)
+}
@@ -0,0 +1,522 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	cfg "github.com/mmq88/quickcerts/configs"
+	"github.com/mmq88/quickcerts/model"
+	"github.com/mmq88/quickcerts/utils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func openTestSQLiteStore(t *testing.T) *SQLiteStore {
+	return openTestSQLiteStoreAt(t, filepath.Join(t.TempDir(), "quickcerts.db"))
+}
+
+// Open the SQLite database at the given path with the latest schema, it is closed when the test finishes.
+func openTestSQLiteStoreAt(t *testing.T, path string) *SQLiteStore {
+	store, err := OpenSQLiteStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { store.Close() })
+
+	migrator, err := store.Migrator()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := migrator.Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	return store
+}
+
+func TestSQLiteStoreBindCertificate(t *testing.T) {
+	ctx := context.Background()
+
+	store := openTestSQLiteStore(t)
+
+	sn := "XXXX-XXXX-XXXX-XXXX-XXXX-XXXX"
+	err := store.AddNewSN(ctx, sn, "quickcerts-pro")
+	assert.Nil(t, err)
+	err = store.AddNewSN(ctx, sn, "")
+	assert.Equal(t, ErrSNAlreadyExists, err)
+
+	allowsLite := model.ClientKey{Products: []string{"quickcerts-lite"}}.AllowsProduct
+	status, _ := store.BindCertificate(ctx, model.CertActivation{SerialNumber: sn, Key: "key"}, allowsLite)
+	assert.Equal(t, model.BindStatusNotAllowed, status)
+
+	status, _ = store.BindCertificate(ctx, model.CertActivation{SerialNumber: sn, Key: "key", IP: "127.0.0.1"}, nil)
+	assert.Equal(t, model.BindStatusBound, status)
+	status, _ = store.BindCertificate(ctx, model.CertActivation{SerialNumber: sn, Key: "key"}, nil)
+	assert.Equal(t, model.BindStatusRebound, status)
+	status, _ = store.BindCertificate(ctx, model.CertActivation{SerialNumber: sn, Key: "another key"}, nil)
+	assert.Equal(t, model.BindStatusTaken, status)
+	status, _ = store.BindCertificate(ctx, model.CertActivation{SerialNumber: "invalid sn", Key: "key"}, nil)
+	assert.Equal(t, model.BindStatusNotFound, status)
+
+	activations, err := store.GetCertActivations(ctx, sn)
+	assert.Nil(t, err)
+	if assert.Len(t, activations, 4) {
+		assert.Equal(t, model.BindStatusBound, activations[1].Status)
+		assert.Equal(t, "127.0.0.1", activations[1].IP)
+		assert.False(t, activations[1].CreatedAt.IsZero())
+	}
+
+	activations, err = store.GetCertActivations(ctx, "invalid sn")
+	assert.Nil(t, err)
+	assert.Empty(t, activations)
+
+	// Many devices race for one S/N, exactly one of them is bound.
+	raceSN := "YYYY-YYYY-YYYY-YYYY-YYYY-YYYY"
+	err = store.AddNewSN(ctx, raceSN, "")
+	assert.Nil(t, err)
+
+	statuses := make([]model.BindStatus, 10)
+	var wg sync.WaitGroup
+
+	for i := range statuses {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			statuses[i], _ = store.BindCertificate(ctx,
+				model.CertActivation{SerialNumber: raceSN, Key: fmt.Sprintf("device %d", i)}, nil,
+			)
+		}(i)
+	}
+
+	wg.Wait()
+
+	bound := 0
+	for _, status := range statuses {
+		if status == model.BindStatusBound {
+			bound++
+		} else {
+			assert.Equal(t, model.BindStatusTaken, status)
+		}
+	}
+	assert.Equal(t, 1, bound)
+
+	err = store.ArchiveSN(ctx, sn, "", "", false)
+	assert.Equal(t, ErrSNAlreadyBound, err)
+	err = store.ArchiveSN(ctx, sn, "Refunded.", "EXAMPLE ADMIN 0", true)
+	assert.Nil(t, err)
+	err = store.UpdateCertNote(ctx, sn, "note")
+	assert.Equal(t, ErrSNNotFound, err)
+
+	archived, err := store.GetArchivedCerts(ctx)
+	assert.Nil(t, err)
+	if assert.Len(t, archived, 1) {
+		assert.Equal(t, "key", archived[0].Key)
+		assert.Equal(t, "quickcerts-pro", archived[0].Product)
+		assert.Equal(t, "Refunded.", archived[0].Reason)
+	}
+}
+
+func TestSQLiteStoreBatches(t *testing.T) {
+	ctx := context.Background()
+
+	store := openTestSQLiteStore(t)
+
+	// The generator collides once, the collided S/N is regenerated.
+	generated := []string{"A", "B", "B", "C"}
+	generate := func() (string, error) {
+		sn := generated[0]
+		generated = generated[1:]
+		return sn, nil
+	}
+
+	snList, err := store.AddGeneratedSNs(ctx, model.Batch{ID: "batch", Product: "quickcerts-pro"}, 3, generate, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"A", "B", "C"}, snList)
+
+	// Nothing is added if the generation is aborted
+	_, err = store.AddGeneratedSNs(ctx, model.Batch{ID: "aborted"}, 1, func() (string, error) { return "D", nil },
+		func(inserted int) error { return errors.New("aborted") },
+	)
+	assert.EqualError(t, err, "aborted")
+	_, err = store.IsBatchExist(ctx, "aborted")
+	assert.Equal(t, ErrBatchNotFound, err)
+
+	status, _ := store.BindCertificate(ctx, model.CertActivation{SerialNumber: "A", Key: "key"}, nil)
+	assert.Equal(t, model.BindStatusBound, status)
+
+	revoked, err := store.RevokeBatch(ctx, "batch")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), revoked)
+
+	status, _ = store.BindCertificate(ctx, model.CertActivation{SerialNumber: "B", Key: "key"}, nil)
+	assert.Equal(t, model.BindStatusRevoked, status)
+
+	batches, err := store.GetAllBatches(ctx)
+	assert.Nil(t, err)
+	if assert.Len(t, batches, 1) {
+		assert.Equal(t, "quickcerts-pro", batches[0].Product)
+		assert.Equal(t, 3, batches[0].Count)
+		assert.Equal(t, 1, batches[0].Bound)
+		assert.Equal(t, 3, batches[0].Revoked)
+	}
+
+	var exported []string
+	err = store.ForEachCertInBatch(ctx, "batch", func(cert model.Cert) error {
+		exported = append(exported, cert.SerialNumber)
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"A", "B", "C"}, exported)
+
+	available, err := store.GetAvaliableSN(ctx)
+	assert.Nil(t, err)
+	assert.Empty(t, available)
+}
+
+func TestSQLiteStoreGenerateInRounds(t *testing.T) {
+	ctx := context.Background()
+
+	store := openTestSQLiteStore(t)
+
+	backupBatchSize := cfg.SERVER_CONFIG.SN_GENERATE_BATCH_SIZE
+	cfg.SERVER_CONFIG.SN_GENERATE_BATCH_SIZE = 1
+	defer func() {
+		cfg.SERVER_CONFIG.SN_GENERATE_BATCH_SIZE = backupBatchSize
+	}()
+
+	// Each round is committed on its own, so the applies are not blocked until the whole batch is generated.
+	generated := []string{"A", "B"}
+	generate := func() (string, error) {
+		sn := generated[0]
+		generated = generated[1:]
+		return sn, nil
+	}
+
+	var applied []model.BindStatus
+
+	snList, err := store.AddGeneratedSNs(ctx, model.Batch{ID: "batch"}, 2, generate, func(inserted int) error {
+		applyCtx, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+
+		status, err := store.BindCertificate(applyCtx, model.CertActivation{SerialNumber: "A", Key: "key"}, nil)
+		applied = append(applied, status)
+		return err
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"A", "B"}, snList)
+	assert.Equal(t, []model.BindStatus{model.BindStatusBound, model.BindStatusRebound}, applied)
+
+	// The committed rounds are deleted if the generation fails later.
+	generated = []string{"C", "D"}
+	_, err = store.AddGeneratedSNs(ctx, model.Batch{ID: "failed"}, 2, generate, func(inserted int) error {
+		if inserted > 1 {
+			return errors.New("failed")
+		}
+		return nil
+	})
+	assert.EqualError(t, err, "failed")
+
+	_, err = store.IsBatchExist(ctx, "failed")
+	assert.Equal(t, ErrBatchNotFound, err)
+
+	certs, err := store.GetAllCerts(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, certs, 2)
+
+	// The S/N(s) applied before the generation fails are kept along with the batch.
+	generated = []string{"E", "F"}
+	_, err = store.AddGeneratedSNs(ctx, model.Batch{ID: "applied"}, 2, generate, func(inserted int) error {
+		if inserted > 1 {
+			return errors.New("failed")
+		}
+
+		_, err := store.BindCertificate(ctx, model.CertActivation{SerialNumber: "E", Key: "key"}, nil)
+		return err
+	})
+	assert.EqualError(t, err, "failed")
+
+	exists, err := store.IsBatchExist(ctx, "applied")
+	assert.Nil(t, err)
+	assert.True(t, exists)
+
+	certs, err = store.GetAllCerts(ctx)
+	assert.Nil(t, err)
+	if assert.Len(t, certs, 3) {
+		assert.Equal(t, "E", certs[2].SerialNumber)
+	}
+}
+
+func TestSQLiteStoreGuardCache(t *testing.T) {
+	store := openTestSQLiteStore(t)
+	testGuardCache(t, store)
+
+	// The expired entries are purged when a nonce is claimed.
+	var count int
+	assert.Nil(t, store.db.QueryRow("SELECT COUNT(*) FROM guard_entries WHERE key LIKE 'admin_nonce:%'").Scan(&count))
+	assert.Equal(t, 3, count)
+
+	_, err := store.db.Exec("UPDATE guard_entries SET expires_at = ?", time.Now().Add(-time.Second).UTC())
+	assert.Nil(t, err)
+
+	claimed, err := store.ClaimRequestNonce(context.Background(), "token", "nonce", time.Minute)
+	assert.Nil(t, err)
+	assert.True(t, claimed)
+
+	assert.Nil(t, store.db.QueryRow("SELECT COUNT(*) FROM guard_entries").Scan(&count))
+	assert.Equal(t, 1, count)
+}
+
+func TestSQLiteStoreMetadata(t *testing.T) {
+	ctx := context.Background()
+
+	store := openTestSQLiteStore(t)
+
+	err := store.AddNewSN(ctx, "A", "")
+	assert.Nil(t, err)
+	err = store.AddNewSN(ctx, "B", "")
+	assert.Nil(t, err)
+
+	metadata, err := store.PatchCertMetadata(
+		ctx, "A", map[string]any{"crm_id": "C-1024", "seats": 5, "tags": []string{"edu"}},
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, float64(5), metadata["seats"])
+
+	metadata, err = store.PatchCertMetadata(ctx, "A", map[string]any{"crm_id": nil})
+	assert.Nil(t, err)
+	assert.NotContains(t, metadata, "crm_id")
+
+	_, err = store.PatchCertMetadata(ctx, "C", map[string]any{"seats": 1})
+	assert.Equal(t, ErrSNNotFound, err)
+
+	certs, err := store.FindCertsByMetadata(ctx, map[string]any{"seats": 5, "tags": []string{"edu"}})
+	assert.Nil(t, err)
+	if assert.Len(t, certs, 1) {
+		assert.Equal(t, "A", certs[0].SerialNumber)
+	}
+
+	certs, err = store.FindCertsByMetadata(ctx, map[string]any{"seats": "5"})
+	assert.Nil(t, err)
+	assert.Empty(t, certs)
+}
+
+func TestSQLiteStorePermitsAndKeyCache(t *testing.T) {
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "quickcerts.db")
+	store := openTestSQLiteStoreAt(t, path)
+
+	_, err := store.GetTemporaryPermitExpiredTime(ctx, "key")
+	assert.True(t, errors.Is(err, ErrPermitNotFound))
+	assert.Equal(t, "allowed new key: key", err.Error())
+
+	remaining, err := store.AddTemporaryPermit(ctx, "key")
+	assert.Nil(t, err)
+	assert.Greater(t, remaining, int64(0))
+	_, err = store.AddTemporaryPermit(ctx, "key")
+	assert.EqualError(t, err, "the temporary permit of [key] already exists")
+
+	_, err = store.GetDeviceKey(ctx, "base")
+	assert.Equal(t, ErrCacheKeyNotFound, err)
+
+	err = store.SetDeviceKey(ctx, "base", "key")
+	assert.Nil(t, err)
+
+	// The records are kept after the store is reopened.
+	assert.Nil(t, store.Close())
+	store = openTestSQLiteStoreAt(t, path)
+
+	remaining, err = store.GetTemporaryPermitExpiredTime(ctx, "key")
+	assert.Nil(t, err)
+	assert.Greater(t, remaining, int64(0))
+
+	key, err := store.GetDeviceKey(ctx, "base")
+	assert.Nil(t, err)
+	assert.Equal(t, "key", key)
+
+	// The expired keys are not returned.
+	_, err = store.db.Exec("UPDATE device_keys SET expires_at = ?", time.Now().Add(-time.Second).UTC())
+	assert.Nil(t, err)
+
+	_, err = store.GetDeviceKey(ctx, "base")
+	assert.Equal(t, ErrCacheKeyNotFound, err)
+}
+
+func TestSQLiteStoreAdminTokensAndClientKeys(t *testing.T) {
+	ctx := context.Background()
+
+	store := openTestSQLiteStore(t)
+
+	token := "tokentokentokentokentokentokentoken"
+	permissions := []cfg.Permission{
+		{NAME: "admin", ROLE: cfg.RoleOwner, TOKEN: token, TOTP_SECRET: "JBSWY3DPEHPK3PXP"},
+		{NAME: "no token", ROLE: cfg.RoleViewer},
+	}
+
+	added, err := store.BootstrapAdminTokens(ctx, permissions)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, added)
+
+	added, err = store.BootstrapAdminTokens(ctx, permissions)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, added)
+
+	record, err := store.AuthenticateAdminToken(ctx, token)
+	assert.Nil(t, err)
+	assert.Equal(t, "admin", record.Name)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", record.TOTPSecret)
+	assert.NotNil(t, record.LastUsedAt)
+
+	_, err = store.AuthenticateAdminToken(ctx, token+"x")
+	assert.Equal(t, ErrAdminTokenInvalid, err)
+
+	created, newToken, signingSecret, err := store.CreateAdminToken(ctx, "viewer", cfg.RoleViewer, "admin", nil)
+	assert.Nil(t, err)
+	assert.NotEqual(t, "", created.TOTPSecret)
+
+	// Only the public key of the signing secret is kept to verify the signed requests.
+	publicKey, err := utils.SigningPublicKey(signingSecret)
+	assert.Nil(t, err)
+
+	signed, err := store.AuthenticateSignedAdminToken(ctx, created.ID, func(token model.AdminToken) bool {
+		return token.SigningPublicKey == publicKey
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "viewer", signed.Name)
+	assert.NotNil(t, signed.LastUsedAt)
+
+	_, err = store.AuthenticateSignedAdminToken(ctx, created.ID, func(model.AdminToken) bool { return false })
+	assert.Equal(t, ErrAdminTokenInvalid, err)
+	_, err = store.AuthenticateSignedAdminToken(ctx, "none", func(model.AdminToken) bool { return true })
+	assert.Equal(t, ErrAdminTokenInvalid, err)
+
+	tokens, err := store.GetAllAdminTokens(ctx)
+	assert.Nil(t, err)
+	if assert.Len(t, tokens, 2) {
+		assert.Equal(t, "viewer", tokens[0].Name)
+	}
+
+	_, totpSecret, err := store.ResetAdminTokenTOTP(ctx, created.ID)
+	assert.Nil(t, err)
+	assert.NotEqual(t, created.TOTPSecret, totpSecret)
+
+	// The expired and revoked tokens can no longer be used.
+	_, err = store.ExpireAdminToken(ctx, record.ID, time.Now().Add(-time.Second))
+	assert.Nil(t, err)
+	_, err = store.AuthenticateAdminToken(ctx, token)
+	assert.Equal(t, ErrAdminTokenInvalid, err)
+
+	revoked, err := store.RevokeAdminToken(ctx, created.ID)
+	assert.Nil(t, err)
+	assert.NotNil(t, revoked.RevokedAt)
+	_, err = store.AuthenticateAdminToken(ctx, newToken)
+	assert.Equal(t, ErrAdminTokenInvalid, err)
+
+	_, err = store.RevokeAdminToken(ctx, "none")
+	assert.Equal(t, ErrAdminTokenNotFound, err)
+
+	// Test invalid case (The client keys are rolled back if any of them is invalid)
+	_, err = store.BootstrapClientKeys(ctx, []string{token, "short"})
+	assert.Equal(t, ErrTokenTooShort, err)
+
+	keys, err := store.GetAllClientKeys(ctx)
+	assert.Nil(t, err)
+	assert.Empty(t, keys)
+
+	added, err = store.BootstrapClientKeys(ctx, []string{token})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, added)
+
+	key, err := store.AuthenticateClientKey(ctx, token)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), key.UsageCount)
+	assert.Equal(t, []string{model.AllProducts}, key.Products)
+
+	created2, newKey, err := store.CreateClientKey(ctx, "installer", []string{"quickcerts-pro"},
+		[]string{model.ClientScopeApplyCert}, 60, "admin")
+	assert.Nil(t, err)
+	assert.Equal(t, 60, created2.RateLimit)
+
+	key, err = store.AuthenticateClientKey(ctx, newKey)
+	assert.Nil(t, err)
+	assert.False(t, key.AllowsProduct("quickcerts-lite"))
+
+	_, err = store.RevokeClientKey(ctx, key.ID)
+	assert.Nil(t, err)
+	_, err = store.AuthenticateClientKey(ctx, newKey)
+	assert.Equal(t, ErrClientKeyInvalid, err)
+
+	_, err = store.RevokeClientKey(ctx, "none")
+	assert.Equal(t, ErrClientKeyNotFound, err)
+}
+
+func TestSQLiteStoreAuditLogsAndJobs(t *testing.T) {
+	ctx := context.Background()
+
+	store := openTestSQLiteStore(t)
+
+	from := time.Now()
+
+	for _, admin := range []string{"admin0", "admin1", "admin0"} {
+		err := store.AddAuditLog(ctx, model.AuditLog{Admin: admin, IP: "127.0.0.1", Method: "POST", Status: 200})
+		assert.Nil(t, err)
+	}
+
+	logs, err := store.GetAuditLogs(ctx, model.AuditFilter{Admin: "admin0", From: from, Limit: 10})
+	assert.Nil(t, err)
+	if assert.Len(t, logs, 2) {
+		assert.Equal(t, int64(3), logs[0].ID)
+		assert.Equal(t, "{}", string(logs[0].Params))
+	}
+
+	logs, err = store.GetAuditLogs(ctx, model.AuditFilter{To: from, Limit: 10})
+	assert.Nil(t, err)
+	assert.Empty(t, logs)
+
+	checked, brokenID, err := store.VerifyAuditLogs(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), checked)
+	assert.Equal(t, int64(0), brokenID)
+
+	// The audit logs are append-only.
+	_, err = store.db.Exec("UPDATE audit_logs SET admin = 'admin2' WHERE id = 2")
+	assert.ErrorContains(t, err, "audit_logs is append-only")
+	_, err = store.db.Exec("DELETE FROM audit_logs")
+	assert.ErrorContains(t, err, "audit_logs is append-only")
+
+	err = store.AddJob(ctx, model.Job{ID: "job", Type: "test", Status: model.JobStatusQueued, Total: 2})
+	assert.Nil(t, err)
+
+	err = store.UpdateJobProgress(ctx, "job", 1)
+	assert.Nil(t, err)
+
+	failed, err := store.FailUnfinishedJobs(ctx, "interrupted")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), failed)
+
+	job, err := store.GetJob(ctx, "job")
+	assert.Nil(t, err)
+	assert.Equal(t, model.JobStatusFailed, job.Status)
+	assert.Equal(t, 1, job.Progress)
+	assert.Equal(t, "interrupted", job.Error)
+
+	err = store.UpdateJobStatus(ctx, "job", model.JobStatusSucceeded, "result", "")
+	assert.Nil(t, err)
+
+	job, err = store.GetJob(ctx, "job")
+	assert.Nil(t, err)
+	assert.Equal(t, "result", job.Result)
+	assert.Equal(t, "", job.Error)
+
+	err = store.UpdateJobStatus(ctx, "none", model.JobStatusSucceeded, "", "")
+	assert.Equal(t, ErrJobNotFound, err)
+	_, err = store.GetJob(ctx, "none")
+	assert.Equal(t, ErrJobNotFound, err)
+}
@@ -0,0 +1,306 @@
+package data
+
+import (
+	"context"
+	"time"
+
+	cfg "github.com/mmq88/quickcerts/configs"
+	"github.com/mmq88/quickcerts/model"
+)
+
+// The S/N records, their batches, archives and activation history.
+//
+// The errors are the sentinel errors in data/errors.go, e.g. ErrSNNotFound, so the callers need not know the backend.
+type CertStore interface {
+	AddNewSN(ctx context.Context, sn string, product string) error
+	AddGeneratedSNs(
+		ctx context.Context, batch model.Batch, count int, generate func() (string, error),
+		onProgress func(inserted int) error,
+	) ([]string, error)
+	BindCertificate(
+		ctx context.Context, activation model.CertActivation, allowsProduct func(product string) bool,
+	) (model.BindStatus, error)
+	GetCertActivations(ctx context.Context, sn string) ([]model.CertActivation, error)
+	GetAllCerts(ctx context.Context) ([]model.Cert, error)
+	GetAvaliableSN(ctx context.Context) ([]string, error)
+	UpdateCertNote(ctx context.Context, sn string, note string) error
+	PatchCertMetadata(ctx context.Context, sn string, patch map[string]any) (map[string]any, error)
+	FindCertsByMetadata(ctx context.Context, filter map[string]any) ([]model.Cert, error)
+	ArchiveSN(ctx context.Context, sn string, reason string, archivedBy string, force bool) error
+	GetArchivedCerts(ctx context.Context) ([]model.ArchivedCert, error)
+	GetAllBatches(ctx context.Context) ([]model.Batch, error)
+	IsBatchExist(ctx context.Context, id string) (bool, error)
+	ForEachCertInBatch(ctx context.Context, id string, fn func(cert model.Cert) error) error
+	RevokeBatch(ctx context.Context, id string) (int64, error)
+}
+
+// The temporary permits of the trial devices.
+//
+// GetTemporaryPermitExpiredTime returns ErrPermitNotFound if the key has not applied a permit yet.
+type PermitStore interface {
+	GetTemporaryPermitExpiredTime(ctx context.Context, key string) (int64, error)
+	AddTemporaryPermit(ctx context.Context, key string) (int64, error)
+}
+
+// The cache of the keys generated for the devices.
+//
+// GetDeviceKey returns ErrCacheKeyNotFound if the key of the device is not cached.
+type KeyCache interface {
+	GetDeviceKey(ctx context.Context, deviceInfoBase string) (string, error)
+	SetDeviceKey(ctx context.Context, deviceInfoBase string, key string) error
+}
+
+// The state guarding the requests, i.e. the rate limit counters, the lockouts of the admin authentication and the
+// nonces of the signed admin requests, see the functions of the same names in data/cache_layer.go.
+type GuardCache interface {
+	CountRateLimitedRequest(ctx context.Context, scope string, id string, window time.Duration) (int64, time.Duration, error)
+	GetAdminLockout(ctx context.Context, ip string) (time.Duration, error)
+	RecordAdminAuthFailure(
+		ctx context.Context, ip string, threshold int, base time.Duration, max time.Duration,
+	) (time.Duration, error)
+	ResetAdminAuthFailures(ctx context.Context, ip string) error
+	ClaimRequestNonce(ctx context.Context, tokenID string, nonce string, ttl time.Duration) (bool, error)
+}
+
+// The admin tokens, which keep the salted hashes instead of the tokens.
+//
+// AuthenticateAdminToken and AuthenticateSignedAdminToken return ErrAdminTokenInvalid if no active token matches, and
+// the other methods taking an ID return ErrAdminTokenNotFound if the token does not exist.
+type AdminTokenStore interface {
+	CreateAdminToken(
+		ctx context.Context, name string, role string, createdBy string, expiresAt *time.Time,
+	) (model.AdminToken, string, string, error)
+	BootstrapAdminTokens(ctx context.Context, permissions []cfg.Permission) (int, error)
+	AuthenticateAdminToken(ctx context.Context, token string) (model.AdminToken, error)
+	AuthenticateSignedAdminToken(
+		ctx context.Context, id string, verify func(token model.AdminToken) bool,
+	) (model.AdminToken, error)
+	GetAllAdminTokens(ctx context.Context) ([]model.AdminToken, error)
+	RevokeAdminToken(ctx context.Context, id string) (model.AdminToken, error)
+	ExpireAdminToken(ctx context.Context, id string, expiresAt time.Time) (model.AdminToken, error)
+	ResetAdminTokenTOTP(ctx context.Context, id string) (model.AdminToken, string, error)
+}
+
+// The client keys of the apply endpoints, which keep the salted hashes instead of the keys.
+//
+// AuthenticateClientKey returns ErrClientKeyInvalid if no active key matches, and RevokeClientKey returns
+// ErrClientKeyNotFound if the key does not exist.
+type ClientKeyStore interface {
+	CreateClientKey(
+		ctx context.Context, name string, products []string, scopes []string, rateLimit int, createdBy string,
+	) (model.ClientKey, string, error)
+	BootstrapClientKeys(ctx context.Context, tokens []string) (int, error)
+	AuthenticateClientKey(ctx context.Context, key string) (model.ClientKey, error)
+	GetAllClientKeys(ctx context.Context) ([]model.ClientKey, error)
+	RevokeClientKey(ctx context.Context, id string) (model.ClientKey, error)
+}
+
+// The hash chained audit logs of the admin requests.
+type AuditStore interface {
+	AddAuditLog(ctx context.Context, log model.AuditLog) error
+	GetAuditLogs(ctx context.Context, filter model.AuditFilter) ([]model.AuditLog, error)
+	VerifyAuditLogs(ctx context.Context) (int64, int64, error)
+}
+
+// The records of the admin jobs.
+//
+// GetJob and UpdateJobStatus return ErrJobNotFound if the job does not exist.
+type JobStore interface {
+	AddJob(ctx context.Context, job model.Job) error
+	GetJob(ctx context.Context, id string) (model.Job, error)
+	UpdateJobProgress(ctx context.Context, id string, progress int) error
+	UpdateJobStatus(ctx context.Context, id string, status string, result string, errMsg string) error
+	FailUnfinishedJobs(ctx context.Context, errMsg string) (int64, error)
+}
+
+// All data of the server except the key and guard caches, implemented by PostgresStore, SQLiteStore and MemoryStore.
+type Store interface {
+	CertStore
+	PermitStore
+	AdminTokenStore
+	ClientKeyStore
+	AuditStore
+	JobStore
+}
+
+// The stores backed by the Postgres database connected by ConnectDB.
+type PostgresStore struct{}
+
+var (
+	_ Store    = PostgresStore{}
+	_ KeyCache = RedisKeyCache{}
+)
+
+func (PostgresStore) AddNewSN(ctx context.Context, sn string, product string) error {
+	return AddNewSN(ctx, sn, product)
+}
+
+func (PostgresStore) AddGeneratedSNs(
+	ctx context.Context, batch model.Batch, count int, generate func() (string, error),
+	onProgress func(inserted int) error,
+) ([]string, error) {
+	return AddGeneratedSNs(ctx, batch, count, generate, onProgress)
+}
+
+func (PostgresStore) BindCertificate(
+	ctx context.Context, activation model.CertActivation, allowsProduct func(product string) bool,
+) (model.BindStatus, error) {
+	return BindCertificate(ctx, activation, allowsProduct)
+}
+
+func (PostgresStore) GetCertActivations(ctx context.Context, sn string) ([]model.CertActivation, error) {
+	return GetCertActivations(ctx, sn)
+}
+
+func (PostgresStore) GetAllCerts(ctx context.Context) ([]model.Cert, error) {
+	return GetAllCerts(ctx)
+}
+
+func (PostgresStore) GetAvaliableSN(ctx context.Context) ([]string, error) {
+	return GetAvaliableSN(ctx)
+}
+
+func (PostgresStore) UpdateCertNote(ctx context.Context, sn string, note string) error {
+	return UpdateCertNote(ctx, sn, note)
+}
+
+func (PostgresStore) PatchCertMetadata(ctx context.Context, sn string, patch map[string]any) (map[string]any, error) {
+	return PatchCertMetadata(ctx, sn, patch)
+}
+
+func (PostgresStore) FindCertsByMetadata(ctx context.Context, filter map[string]any) ([]model.Cert, error) {
+	return FindCertsByMetadata(ctx, filter)
+}
+
+func (PostgresStore) ArchiveSN(ctx context.Context, sn string, reason string, archivedBy string, force bool) error {
+	return ArchiveSN(ctx, sn, reason, archivedBy, force)
+}
+
+func (PostgresStore) GetArchivedCerts(ctx context.Context) ([]model.ArchivedCert, error) {
+	return GetArchivedCerts(ctx)
+}
+
+func (PostgresStore) GetAllBatches(ctx context.Context) ([]model.Batch, error) {
+	return GetAllBatches(ctx)
+}
+
+func (PostgresStore) IsBatchExist(ctx context.Context, id string) (bool, error) {
+	return IsBatchExist(ctx, id)
+}
+
+func (PostgresStore) ForEachCertInBatch(ctx context.Context, id string, fn func(cert model.Cert) error) error {
+	return ForEachCertInBatch(ctx, id, fn)
+}
+
+func (PostgresStore) RevokeBatch(ctx context.Context, id string) (int64, error) {
+	return RevokeBatch(ctx, id)
+}
+
+func (PostgresStore) GetTemporaryPermitExpiredTime(ctx context.Context, key string) (int64, error) {
+	return GetTemporaryPermitExpiredTime(ctx, key)
+}
+
+func (PostgresStore) AddTemporaryPermit(ctx context.Context, key string) (int64, error) {
+	return AddTemporaryPermit(ctx, key)
+}
+
+func (PostgresStore) CreateAdminToken(
+	ctx context.Context, name string, role string, createdBy string, expiresAt *time.Time,
+) (model.AdminToken, string, string, error) {
+	return CreateAdminToken(ctx, name, role, createdBy, expiresAt)
+}
+
+func (PostgresStore) BootstrapAdminTokens(ctx context.Context, permissions []cfg.Permission) (int, error) {
+	return BootstrapAdminTokens(ctx, permissions)
+}
+
+func (PostgresStore) AuthenticateAdminToken(ctx context.Context, token string) (model.AdminToken, error) {
+	return AuthenticateAdminToken(ctx, token)
+}
+
+func (PostgresStore) AuthenticateSignedAdminToken(
+	ctx context.Context, id string, verify func(token model.AdminToken) bool,
+) (model.AdminToken, error) {
+	return AuthenticateSignedAdminToken(ctx, id, verify)
+}
+
+func (PostgresStore) GetAllAdminTokens(ctx context.Context) ([]model.AdminToken, error) {
+	return GetAllAdminTokens(ctx)
+}
+
+func (PostgresStore) RevokeAdminToken(ctx context.Context, id string) (model.AdminToken, error) {
+	return RevokeAdminToken(ctx, id)
+}
+
+func (PostgresStore) ExpireAdminToken(ctx context.Context, id string, expiresAt time.Time) (model.AdminToken, error) {
+	return ExpireAdminToken(ctx, id, expiresAt)
+}
+
+func (PostgresStore) ResetAdminTokenTOTP(ctx context.Context, id string) (model.AdminToken, string, error) {
+	return ResetAdminTokenTOTP(ctx, id)
+}
+
+func (PostgresStore) CreateClientKey(
+	ctx context.Context, name string, products []string, scopes []string, rateLimit int, createdBy string,
+) (model.ClientKey, string, error) {
+	return CreateClientKey(ctx, name, products, scopes, rateLimit, createdBy)
+}
+
+func (PostgresStore) BootstrapClientKeys(ctx context.Context, tokens []string) (int, error) {
+	return BootstrapClientKeys(ctx, tokens)
+}
+
+func (PostgresStore) AuthenticateClientKey(ctx context.Context, key string) (model.ClientKey, error) {
+	return AuthenticateClientKey(ctx, key)
+}
+
+func (PostgresStore) GetAllClientKeys(ctx context.Context) ([]model.ClientKey, error) {
+	return GetAllClientKeys(ctx)
+}
+
+func (PostgresStore) RevokeClientKey(ctx context.Context, id string) (model.ClientKey, error) {
+	return RevokeClientKey(ctx, id)
+}
+
+func (PostgresStore) AddAuditLog(ctx context.Context, log model.AuditLog) error {
+	return AddAuditLog(ctx, log)
+}
+
+func (PostgresStore) GetAuditLogs(ctx context.Context, filter model.AuditFilter) ([]model.AuditLog, error) {
+	return GetAuditLogs(ctx, filter)
+}
+
+func (PostgresStore) VerifyAuditLogs(ctx context.Context) (int64, int64, error) {
+	return VerifyAuditLogs(ctx)
+}
+
+func (PostgresStore) AddJob(ctx context.Context, job model.Job) error {
+	return AddJob(ctx, job)
+}
+
+func (PostgresStore) GetJob(ctx context.Context, id string) (model.Job, error) {
+	return GetJob(ctx, id)
+}
+
+func (PostgresStore) UpdateJobProgress(ctx context.Context, id string, progress int) error {
+	return UpdateJobProgress(ctx, id, progress)
+}
+
+func (PostgresStore) UpdateJobStatus(ctx context.Context, id string, status string, result string, errMsg string) error {
+	return UpdateJobStatus(ctx, id, status, result, errMsg)
+}
+
+func (PostgresStore) FailUnfinishedJobs(ctx context.Context, errMsg string) (int64, error) {
+	return FailUnfinishedJobs(ctx, errMsg)
+}
+
+// The KeyCache backed by the redis database connected by ConnectRDB.
+type RedisKeyCache struct{}
+
+func (RedisKeyCache) GetDeviceKey(ctx context.Context, deviceInfoBase string) (string, error) {
+	return GetDeviceKeyCache(ctx, deviceInfoBase)
+}
+
+func (RedisKeyCache) SetDeviceKey(ctx context.Context, deviceInfoBase string, key string) error {
+	return SetDeviceKeyCache(ctx, deviceInfoBase, key)
+}
@@ -0,0 +1,86 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/mmq88/quickcerts/utils"
+
+	"github.com/sirupsen/logrus"
+)
+
+// The backoff of reconnecting a database, and the interval of checking it while it is reachable.
+const (
+	minReconnectBackoff = time.Second
+	maxReconnectBackoff = time.Minute
+	connCheckInterval   = 10 * time.Second
+)
+
+// Check a database by check until ctx is done, which is called again with an exponential backoff while it fails.
+//
+// The result of the latest check is stored in available, and the lost and restored messages are recorded when it
+// changes.
+func watchConnection(
+	ctx context.Context, available *atomic.Bool, check func(ctx context.Context) error, lost string, restored string,
+) {
+	backoff := minReconnectBackoff
+	degraded := !available.Load()
+
+	for {
+		wait := connCheckInterval
+
+		err := check(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		available.Store(err == nil)
+
+		if err == nil {
+			if degraded {
+				utils.Record(logrus.InfoLevel, restored)
+			}
+			backoff = minReconnectBackoff
+		} else {
+			if !degraded {
+				utils.Record(logrus.WarnLevel, lost+" Due to: "+err.Error())
+			}
+			wait = backoff
+			backoff = min(backoff*2, maxReconnectBackoff)
+		}
+
+		degraded = err != nil
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// Call connect until it succeeds or has been retried the given times, waiting with an exponential backoff between
+// the attempts.
+func connectWithRetries(ctx context.Context, name string, retries int, connect func() error) error {
+	backoff := minReconnectBackoff
+
+	for attempt := 0; ; attempt++ {
+		err := connect()
+		if err == nil || attempt >= retries {
+			return err
+		}
+
+		utils.Record(logrus.WarnLevel, fmt.Sprintf("Failed to connect the %s, retrying in %s (%d/%d). Due to: %s",
+			name, backoff, attempt+1, retries, err))
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		backoff = min(backoff*2, maxReconnectBackoff)
+	}
+}
@@ -0,0 +1,86 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConnectWithRetries(t *testing.T) {
+	ctx := context.Background()
+	errConnect := errors.New("connection refused")
+
+	// Test valid case
+	attempts := 0
+	err := connectWithRetries(ctx, "database", 3, func() error {
+		attempts++
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, attempts)
+
+	// Test invalid case (No retry)
+	attempts = 0
+	err = connectWithRetries(ctx, "database", 0, func() error {
+		attempts++
+		return errConnect
+	})
+	assert.Equal(t, errConnect, err)
+	assert.Equal(t, 1, attempts)
+
+	// Test invalid case (Cancelled while waiting for the retry)
+	cancelledCtx, cancel := context.WithCancel(ctx)
+	attempts = 0
+	err = connectWithRetries(cancelledCtx, "database", 3, func() error {
+		attempts++
+		cancel()
+		return errConnect
+	})
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, 1, attempts)
+}
+
+func TestWatchConnection(t *testing.T) {
+	var available atomic.Bool
+	var reachable atomic.Bool
+	reachable.Store(true)
+
+	check := func(context.Context) error {
+		if !reachable.Load() {
+			return errors.New("connection refused")
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		watchConnection(ctx, &available, check, "lost", "restored")
+		close(done)
+	}()
+
+	assert.Eventually(t, available.Load, time.Second, 10*time.Millisecond)
+
+	// The watcher stops once the context is done.
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("watchConnection did not stop after the context was done")
+	}
+
+	// The result of the check is stored.
+	reachable.Store(false)
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+
+	go watchConnection(ctx, &available, check, "lost", "restored")
+
+	assert.Eventually(t, func() bool { return !available.Load() }, time.Second, 10*time.Millisecond)
+}
@@ -30,7 +30,7 @@ const docTemplate = `{
     "paths": {
         "/apply/cert": {
             "post": {
-                "description": "Provide the client with a certificate(unique key and signature) for app.",
+                "description": "Provide the client with a certificate(unique key and signature) for app. The S/N is bound to the device in one transaction, the status is \"bound\" for a new binding or \"rebound\" if the device has applied before.",
                 "consumes": [
                     "application/json"
                 ],
@@ -44,7 +44,7 @@ const docTemplate = `{
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Authorized token for client access. This value is set in path_to_qcs/configs/server.toml.",
+                        "description": "Client key for client access. The keys are managed by /client-keys and bootstrapped from CLIENT_AUTH_TOKEN in path_to_qcs/configs/server.toml.",
                         "name": "X-Access-Token",
                         "in": "header"
                     },
@@ -77,6 +77,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/model.ErrorResponse"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -102,7 +114,7 @@ const docTemplate = `{
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Authorized token for client access. This value is set in path_to_qcs/configs/server.toml.",
+                        "description": "Client key for client access. The keys are managed by /client-keys and bootstrapped from CLIENT_AUTH_TOKEN in path_to_qcs/configs/server.toml.",
                         "name": "X-Access-Token",
                         "in": "header"
                     },
@@ -135,6 +147,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/model.ErrorResponse"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -144,9 +168,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/sn/create": {
-            "post": {
-                "description": "Create serial number by providing the serial number and the reason. only requests with valid tokens are allowed.",
+        "/audit": {
+            "get": {
+                "description": "Query the audit logs of admin requests with optional filters, the newest first.",
                 "consumes": [
                     "application/json"
                 ],
@@ -154,9 +178,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "SN"
+                    "Audit"
                 ],
-                "summary": "Create serial number to the database",
+                "summary": "Query the audit logs",
                 "parameters": [
                     {
                         "type": "string",
@@ -166,25 +190,70 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "Security token for admin access. This value is set in path_to_qcs/configs/allowlist.toml.",
+                        "description": "Security token for admin access. The tokens are managed by /tokens and bootstrapped from path_to_qcs/configs/allowlist.toml.",
                         "name": "X-Access-Token",
                         "in": "header"
                     },
                     {
-                        "description": "Serial number information",
-                        "name": "snInfo",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/model.SNInfo"
-                        }
+                        "type": "string",
+                        "description": "Admin name",
+                        "name": "admin",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Admin role",
+                        "name": "role",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Client IP address",
+                        "name": "ip",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Route pattern, e.g. /api/v1/batch/:id/revoke",
+                        "name": "route",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "HTTP status code",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Start time (inclusive) in RFC3339, e.g. 2024-01-01T00:00:00+08:00",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "End time (exclusive) in RFC3339, e.g. 2024-02-01T00:00:00+08:00",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Max number of records, 100 by default and 1000 at most",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of records to skip",
+                        "name": "offset",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.CreateSNResponse"
+                            "$ref": "#/definitions/model.GetAuditLogsResponse"
                         }
                     },
                     "400": {
@@ -199,6 +268,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/model.ErrorResponse"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -208,9 +289,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/sn/generate": {
-            "post": {
-                "description": "Generate serial number(s) by providing the count and the reason. only requests with valid tokens are allowed.",
+        "/audit/verify": {
+            "get": {
+                "description": "Verify the hash chain of the audit logs to detect modified, inserted or deleted records.",
                 "consumes": [
                     "application/json"
                 ],
@@ -218,9 +299,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "SN"
+                    "Audit"
                 ],
-                "summary": "Generate serial number(s) to the database",
+                "summary": "Verify the audit logs",
                 "parameters": [
                     {
                         "type": "string",
@@ -230,39 +311,168 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "Security token for admin access. This value is set in path_to_qcs/configs/allowlist.toml.",
+                        "description": "Security token for admin access. The tokens are managed by /tokens and bootstrapped from path_to_qcs/configs/allowlist.toml.",
                         "name": "X-Access-Token",
                         "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.VerifyAuditLogsResponse"
+                        }
                     },
-                    {
-                        "description": "Serial number(s) information",
-                        "name": "snInfo",
-                        "in": "body",
-                        "required": true,
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/model.SNsInfo"
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
                         }
                     }
+                }
+            }
+        },
+        "/batch/get-all": {
+            "get": {
+                "description": "Get all S/N batches with their reseller/order information and S/N statistics, the newest first.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Batch"
+                ],
+                "summary": "Get all S/N batches from the database",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Security code for admin access. Check path_to_qcs/configs/server.toml for more information.",
+                        "name": "X-RunTime-Code",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Security token for admin access. The tokens are managed by /tokens and bootstrapped from path_to_qcs/configs/allowlist.toml.",
+                        "name": "X-Access-Token",
+                        "in": "header"
+                    }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.CreateSNResponse"
+                            "$ref": "#/definitions/model.GetAllBatchesResponse"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/model.ErrorResponse"
                         }
                     },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/batch/{id}/export": {
+            "get": {
+                "description": "Export the S/N(s) of a batch as a CSV file with the columns serial_number, product, key, note, revoked_at and metadata.",
+                "produces": [
+                    "text/csv"
+                ],
+                "tags": [
+                    "Batch"
+                ],
+                "summary": "Export the S/N(s) of a batch",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Security code for admin access. Check path_to_qcs/configs/server.toml for more information.",
+                        "name": "X-RunTime-Code",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Security token for admin access. The tokens are managed by /tokens and bootstrapped from path_to_qcs/configs/allowlist.toml.",
+                        "name": "X-Access-Token",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Batch ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
                     "401": {
                         "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/model.ErrorResponse"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -272,9 +482,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/sn/get-all": {
-            "get": {
-                "description": "Get cert list from the database.",
+        "/batch/{id}/revoke": {
+            "post": {
+                "description": "Revoke all S/N(s) of a batch. Revoked S/N(s) can no longer be bound to a device.",
                 "consumes": [
                     "application/json"
                 ],
@@ -282,9 +492,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "SN"
+                    "Batch"
                 ],
-                "summary": "Get cert list from the database",
+                "summary": "Revoke all S/N(s) of a batch",
                 "parameters": [
                     {
                         "type": "string",
@@ -294,26 +504,45 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "Security token for admin access. This value is set in path_to_qcs/configs/allowlist.toml.",
+                        "description": "Security token for admin access. The tokens are managed by /tokens and bootstrapped from path_to_qcs/configs/allowlist.toml.",
                         "name": "X-Access-Token",
                         "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Batch ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.GetAllSNResponse"
+                            "$ref": "#/definitions/model.RevokeBatchResponse"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/model.ErrorResponse"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
                         "schema": {
                             "$ref": "#/definitions/model.ErrorResponse"
                         }
@@ -327,9 +556,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/sn/get-available": {
+        "/client-keys": {
             "get": {
-                "description": "Get available S/N from the database.",
+                "description": "Get all client keys with their scopes and usage, the newest first. The keys themselves are never returned.",
                 "consumes": [
                     "application/json"
                 ],
@@ -337,9 +566,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "SN"
+                    "ClientKeys"
                 ],
-                "summary": "Get available S/N from the database",
+                "summary": "Get all client keys",
                 "parameters": [
                     {
                         "type": "string",
@@ -349,7 +578,7 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "Security token for admin access. This value is set in path_to_qcs/configs/allowlist.toml.",
+                        "description": "Security token for admin access. The tokens are managed by /tokens and bootstrapped from path_to_qcs/configs/allowlist.toml.",
                         "name": "X-Access-Token",
                         "in": "header"
                     }
@@ -358,17 +587,23 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.GetAvaliableSNResponse"
+                            "$ref": "#/definitions/model.GetAllClientKeysResponse"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/model.ErrorResponse"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
                         "schema": {
                             "$ref": "#/definitions/model.ErrorResponse"
                         }
@@ -380,11 +615,9 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/sn/update": {
+            },
             "post": {
-                "description": "Update a note for a serial number by providing the serial number and the note.",
+                "description": "Create a new client key scoped to the given products and apply endpoints. The key is only shown in this response.",
                 "consumes": [
                     "application/json"
                 ],
@@ -392,9 +625,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "SN"
+                    "ClientKeys"
                 ],
-                "summary": "Update a note for a serial number",
+                "summary": "Create a new client key",
                 "parameters": [
                     {
                         "type": "string",
@@ -404,17 +637,17 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "Security token for admin access. This value is set in path_to_qcs/configs/allowlist.toml.",
+                        "description": "Security token for admin access. The tokens are managed by /tokens and bootstrapped from path_to_qcs/configs/allowlist.toml.",
                         "name": "X-Access-Token",
                         "in": "header"
                     },
                     {
-                        "description": "Serial number and note",
-                        "name": "certNote",
+                        "description": "Name, products, scopes and rate limit",
+                        "name": "clientKeyInfo",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/model.CertNote"
+                            "$ref": "#/definitions/model.ClientKeyInfo"
                         }
                     }
                 ],
@@ -422,7 +655,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.UpdateCertNoteResponse"
+                            "$ref": "#/definitions/model.CreateClientKeyResponse"
                         }
                     },
                     "400": {
@@ -437,6 +670,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/model.ErrorResponse"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -445,141 +690,1992 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "model.ApplyCertInfo": {
-            "type": "object",
-            "required": [
-                "board_name",
-                "board_producer",
-                "mac_address",
-                "serial_number"
-            ],
-            "properties": {
-                "board_name": {
-                    "type": "string",
-                    "example": "ROG CROSSHAIR X670E HERO"
-                },
-                "board_producer": {
-                    "type": "string",
-                    "example": "ASUSTEK COMPUTER INCORPORATION"
-                },
+        },
+        "/client-keys/{id}/revoke": {
+            "post": {
+                "description": "Revoke a client key, a revoked key can no longer be used.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ClientKeys"
+                ],
+                "summary": "Revoke a client key",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Security code for admin access. Check path_to_qcs/configs/server.toml for more information.",
+                        "name": "X-RunTime-Code",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Security token for admin access. The tokens are managed by /tokens and bootstrapped from path_to_qcs/configs/allowlist.toml.",
+                        "name": "X-Access-Token",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Client key ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.RevokeClientKeyResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/jobs/{id}": {
+            "get": {
+                "description": "Get the status and progress of an admin job, e.g. a background S/N generation.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Jobs"
+                ],
+                "summary": "Get the status and progress of an admin job",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Security code for admin access. Check path_to_qcs/configs/server.toml for more information.",
+                        "name": "X-RunTime-Code",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Security token for admin access. The tokens are managed by /tokens and bootstrapped from path_to_qcs/configs/allowlist.toml.",
+                        "name": "X-Access-Token",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Job ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Job"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/jobs/{id}/cancel": {
+            "post": {
+                "description": "Cancel a queued or running admin job. The changes made by a cancelled job are rolled back.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Jobs"
+                ],
+                "summary": "Cancel an admin job",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Security code for admin access. Check path_to_qcs/configs/server.toml for more information.",
+                        "name": "X-RunTime-Code",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Security token for admin access. The tokens are managed by /tokens and bootstrapped from path_to_qcs/configs/allowlist.toml.",
+                        "name": "X-Access-Token",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Job ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.CancelJobResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/jobs/{id}/result": {
+            "get": {
+                "description": "Download the output of a succeeded admin job, e.g. the S/N(s) created by a background generation.",
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "Jobs"
+                ],
+                "summary": "Download the output of an admin job",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Security code for admin access. Check path_to_qcs/configs/server.toml for more information.",
+                        "name": "X-RunTime-Code",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Security token for admin access. The tokens are managed by /tokens and bootstrapped from path_to_qcs/configs/allowlist.toml.",
+                        "name": "X-Access-Token",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Job ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sn/create": {
+            "post": {
+                "description": "Create serial number by providing the serial number and the reason. only requests with valid tokens are allowed.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "SN"
+                ],
+                "summary": "Create serial number to the database",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Security code for admin access. Check path_to_qcs/configs/server.toml for more information.",
+                        "name": "X-RunTime-Code",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Security token for admin access. The tokens are managed by /tokens and bootstrapped from path_to_qcs/configs/allowlist.toml.",
+                        "name": "X-Access-Token",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Serial number information",
+                        "name": "snInfo",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.SNInfo"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.CreateSNResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sn/delete": {
+            "post": {
+                "description": "Delete a serial number and move its record into the archive. A serial number which has been bound to a device is refused unless force is true.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "SN"
+                ],
+                "summary": "Delete a serial number",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Security code for admin access. Check path_to_qcs/configs/server.toml for more information.",
+                        "name": "X-RunTime-Code",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Security token for admin access. The tokens are managed by /tokens and bootstrapped from path_to_qcs/configs/allowlist.toml.",
+                        "name": "X-Access-Token",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Serial number, reason and whether to force the deletion",
+                        "name": "snDeleteInfo",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.SNDeleteInfo"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.DeleteSNResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sn/generate": {
+            "post": {
+                "description": "Generate serial number(s) by providing the count and the reason. only requests with valid tokens are allowed. Large counts are generated by a background job, and the job ID is returned instead (see /jobs/{id}).",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "SN"
+                ],
+                "summary": "Generate serial number(s) to the database",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Security code for admin access. Check path_to_qcs/configs/server.toml for more information.",
+                        "name": "X-RunTime-Code",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Security token for admin access. The tokens are managed by /tokens and bootstrapped from path_to_qcs/configs/allowlist.toml.",
+                        "name": "X-Access-Token",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Serial number(s) information",
+                        "name": "snInfo",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.SNsInfo"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenerateSNResponse"
+                        }
+                    },
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/model.GenerateSNJobResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sn/get-all": {
+            "get": {
+                "description": "Get cert list from the database.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "SN"
+                ],
+                "summary": "Get cert list from the database",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Security code for admin access. Check path_to_qcs/configs/server.toml for more information.",
+                        "name": "X-RunTime-Code",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Security token for admin access. The tokens are managed by /tokens and bootstrapped from path_to_qcs/configs/allowlist.toml.",
+                        "name": "X-Access-Token",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.GetAllRecordsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sn/get-archived": {
+            "get": {
+                "description": "Get the records of the deleted serial numbers, the newest first.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "SN"
+                ],
+                "summary": "Get the archived cert list from the database",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Security code for admin access. Check path_to_qcs/configs/server.toml for more information.",
+                        "name": "X-RunTime-Code",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Security token for admin access. The tokens are managed by /tokens and bootstrapped from path_to_qcs/configs/allowlist.toml.",
+                        "name": "X-Access-Token",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.GetArchivedCertsResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sn/get-available": {
+            "get": {
+                "description": "Get available S/N from the database.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "SN"
+                ],
+                "summary": "Get available S/N from the database",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Security code for admin access. Check path_to_qcs/configs/server.toml for more information.",
+                        "name": "X-RunTime-Code",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Security token for admin access. The tokens are managed by /tokens and bootstrapped from path_to_qcs/configs/allowlist.toml.",
+                        "name": "X-Access-Token",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.GetAvaliableSNResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sn/metadata": {
+            "post": {
+                "description": "Merge the given metadata into the current metadata of a serial number. The given keys overwrite the existing ones, and the keys with null values are removed.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "SN"
+                ],
+                "summary": "Update the metadata of a serial number",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Security code for admin access. Check path_to_qcs/configs/server.toml for more information.",
+                        "name": "X-RunTime-Code",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Security token for admin access. The tokens are managed by /tokens and bootstrapped from path_to_qcs/configs/allowlist.toml.",
+                        "name": "X-Access-Token",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Serial number and metadata patch",
+                        "name": "certMetadata",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.CertMetadataPatch"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.UpdateCertMetadataResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sn/search": {
+            "post": {
+                "description": "Get the certificate records whose metadata contains all of the given keys and values.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "SN"
+                ],
+                "summary": "Search the certificate records by metadata",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Security code for admin access. Check path_to_qcs/configs/server.toml for more information.",
+                        "name": "X-RunTime-Code",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Security token for admin access. The tokens are managed by /tokens and bootstrapped from path_to_qcs/configs/allowlist.toml.",
+                        "name": "X-Access-Token",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Metadata keys and values to match",
+                        "name": "metadataQuery",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.CertMetadataQuery"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.SearchCertsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/sn/update": {
+            "post": {
+                "description": "Update a note for a serial number by providing the serial number and the note.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "SN"
+                ],
+                "summary": "Update a note for a serial number",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Security code for admin access. Check path_to_qcs/configs/server.toml for more information.",
+                        "name": "X-RunTime-Code",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Security token for admin access. The tokens are managed by /tokens and bootstrapped from path_to_qcs/configs/allowlist.toml.",
+                        "name": "X-Access-Token",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Serial number and note",
+                        "name": "certNote",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.CertNote"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.UpdateCertNoteResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tokens": {
+            "get": {
+                "description": "Get all admin tokens including the revoked and expired ones, the newest first. The tokens themselves are never returned.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tokens"
+                ],
+                "summary": "Get all admin tokens",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Security code for admin access. Check path_to_qcs/configs/server.toml for more information.",
+                        "name": "X-RunTime-Code",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Security token for admin access. The tokens are managed by /tokens and bootstrapped from path_to_qcs/configs/allowlist.toml.",
+                        "name": "X-Access-Token",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.GetAllAdminTokensResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create a new admin token with the given role, a new TOTP secret and a new signing secret. The token and the secrets are only shown in this response. The signed requests carry the ID of the token in X-QCS-Token-ID instead of the token, see REQUIRE_REQUEST_SIGNATURE in server.toml.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tokens"
+                ],
+                "summary": "Create a new admin token",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Security code for admin access. Check path_to_qcs/configs/server.toml for more information.",
+                        "name": "X-RunTime-Code",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Security token for admin access. The tokens are managed by /tokens and bootstrapped from path_to_qcs/configs/allowlist.toml.",
+                        "name": "X-Access-Token",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Admin name, role and expiration time",
+                        "name": "adminTokenInfo",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.AdminTokenInfo"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.CreateAdminTokenResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tokens/{id}/expire": {
+            "post": {
+                "description": "Set the expiration time of an admin token, the token expires immediately if no time is given.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tokens"
+                ],
+                "summary": "Expire an admin token",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Security code for admin access. Check path_to_qcs/configs/server.toml for more information.",
+                        "name": "X-RunTime-Code",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Security token for admin access. The tokens are managed by /tokens and bootstrapped from path_to_qcs/configs/allowlist.toml.",
+                        "name": "X-Access-Token",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Admin token ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Expiration time",
+                        "name": "adminTokenExpiration",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/model.AdminTokenExpiration"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.UpdateAdminTokenResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tokens/{id}/revoke": {
+            "post": {
+                "description": "Revoke an admin token, a revoked token can no longer be used.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tokens"
+                ],
+                "summary": "Revoke an admin token",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Security code for admin access. Check path_to_qcs/configs/server.toml for more information.",
+                        "name": "X-RunTime-Code",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Security token for admin access. The tokens are managed by /tokens and bootstrapped from path_to_qcs/configs/allowlist.toml.",
+                        "name": "X-Access-Token",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Admin token ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.UpdateAdminTokenResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tokens/{id}/totp": {
+            "post": {
+                "description": "Replace the TOTP secret of an admin token with a new one, which is required as the runtime code when RUNTIME_CODE_TYPE is \"totp\". The secret is only shown in this response.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tokens"
+                ],
+                "summary": "Reset the TOTP secret of an admin token",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Security code for admin access. Check path_to_qcs/configs/server.toml for more information.",
+                        "name": "X-RunTime-Code",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Security token for admin access. The tokens are managed by /tokens and bootstrapped from path_to_qcs/configs/allowlist.toml.",
+                        "name": "X-Access-Token",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Admin token ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.ResetAdminTokenTOTPResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "model.AdminToken": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string",
+                    "example": "2024-01-01T00:00:00+08:00"
+                },
+                "created_by": {
+                    "type": "string",
+                    "example": "EXAMPLE ADMIN 0"
+                },
+                "expires_at": {
+                    "type": "string",
+                    "example": "2025-01-01T00:00:00+08:00"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "2c7e5a9b1d3f4e6a8b0c2d4e6f8a0b1c"
+                },
+                "last_used_at": {
+                    "type": "string",
+                    "example": "2024-03-01T00:00:00+08:00"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "EXAMPLE ADMIN 0"
+                },
+                "revoked_at": {
+                    "type": "string",
+                    "example": "2024-06-01T00:00:00+08:00"
+                },
+                "role": {
+                    "type": "string",
+                    "example": "owner"
+                }
+            }
+        },
+        "model.AdminTokenExpiration": {
+            "type": "object",
+            "properties": {
+                "expires_at": {
+                    "type": "string",
+                    "example": "2025-01-01T00:00:00+08:00"
+                }
+            }
+        },
+        "model.AdminTokenInfo": {
+            "type": "object",
+            "required": [
+                "name",
+                "role"
+            ],
+            "properties": {
+                "expires_at": {
+                    "type": "string",
+                    "example": "2025-01-01T00:00:00+08:00"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "EXAMPLE ADMIN 2"
+                },
+                "role": {
+                    "type": "string",
+                    "example": "support"
+                }
+            }
+        },
+        "model.ApplyCertInfo": {
+            "type": "object",
+            "required": [
+                "board_name",
+                "board_producer",
+                "mac_address",
+                "serial_number"
+            ],
+            "properties": {
+                "board_name": {
+                    "type": "string",
+                    "example": "ROG CROSSHAIR X670E HERO"
+                },
+                "board_producer": {
+                    "type": "string",
+                    "example": "ASUSTEK COMPUTER INCORPORATION"
+                },
+                "mac_address": {
+                    "type": "string",
+                    "example": "B42499FE0000"
+                },
+                "serial_number": {
+                    "type": "string",
+                    "example": "779f-4e90-aebd-4295-881a-f8d7"
+                }
+            }
+        },
+        "model.ApplyCertResponse": {
+            "type": "object",
+            "properties": {
+                "key": {
+                    "type": "string",
+                    "example": "3266cd6a16ca77f9c0f0ff9934eb0e29c4b6bb0729cde98811f9f0caf76d603c"
+                },
+                "signature": {
+                    "type": "string",
+                    "example": "MNj/g7W+X5PmirfgWl5jveV54t50+LZAPmByh5Py880pB2z67Ser0YvZ2G/mTNV4XcIrKmLy1ICFmQ1esjydhvBj1FOuTm3eTIixUIsFLxwlW2co/R6kCIjNRydB3N7L/kWv+ZwSjsSsdHqmMUleXV3OJruxeoXV8TLRCSGE4tHGEwhPULuBLn2aldIehDTgteJx1O1YNJGIcDM3NWVDjJnUA0Bjhq3oRvXWN4M23SnZZG2vT94wJIK0X5q6oNqFTupFjDVBCFcHeWoxQ5xZdPhfXF8rC/VTb4vkZZm5RIiIK1UC9XVaAsXVPEzlxVfYJ0gh+wULx8syE2QyB5GfyQ=="
+                },
+                "status": {
+                    "type": "string",
+                    "example": "bound"
+                }
+            }
+        },
+        "model.ApplyTempPermitInfo": {
+            "type": "object",
+            "required": [
+                "board_name",
+                "board_producer",
+                "mac_address"
+            ],
+            "properties": {
+                "board_name": {
+                    "type": "string",
+                    "example": "ROG CROSSHAIR X670E HERO"
+                },
+                "board_producer": {
+                    "type": "string",
+                    "example": "ASUSTEK COMPUTER INCORPORATION"
+                },
                 "mac_address": {
                     "type": "string",
-                    "example": "B42499FE0000"
+                    "example": "B42499FE0000"
+                }
+            }
+        },
+        "model.ApplyTempPermitResponse": {
+            "type": "object",
+            "properties": {
+                "remaining_time": {
+                    "type": "integer",
+                    "example": 604800
+                },
+                "status": {
+                    "type": "string",
+                    "example": "activated"
+                }
+            }
+        },
+        "model.ArchivedCert": {
+            "type": "object",
+            "properties": {
+                "archived_at": {
+                    "type": "string",
+                    "example": "2024-01-01T00:00:00+08:00"
+                },
+                "archived_by": {
+                    "type": "string",
+                    "example": "EXAMPLE ADMIN 0"
+                },
+                "batch_id": {
+                    "type": "string",
+                    "example": "9d3c1f7a2b6e4d8c0a5f3e1b7c9d2a4e"
+                },
+                "key": {
+                    "type": "string",
+                    "example": "3266cd6a16ca77f9c0f0ff9934eb0e29c4b6bb0729cde98811f9f0caf76d603c"
+                },
+                "metadata": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    },
+                    "example": {
+                        "customer_email": "user@example.com"
+                    }
+                },
+                "note": {
+                    "type": "string",
+                    "example": "Updated note."
+                },
+                "product": {
+                    "type": "string",
+                    "example": "quickcerts-pro"
+                },
+                "reason": {
+                    "type": "string",
+                    "example": "Refunded."
+                },
+                "revoked_at": {
+                    "type": "string",
+                    "example": "2024-01-01T00:00:00+08:00"
+                },
+                "serial_number": {
+                    "type": "string",
+                    "example": "779f-4e90-aebd-4295-881a-f8d7"
+                }
+            }
+        },
+        "model.AuditLog": {
+            "type": "object",
+            "properties": {
+                "admin": {
+                    "type": "string",
+                    "example": "EXAMPLE ADMIN 0"
+                },
+                "created_at": {
+                    "type": "string",
+                    "example": "2024-01-01T00:00:00+08:00"
+                },
+                "hash": {
+                    "type": "string",
+                    "example": "0d5f6c1c1a2b3e4f5a6b7c8d9e0f1a2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d7e8f"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "ip": {
+                    "type": "string",
+                    "example": "127.0.0.1"
+                },
+                "method": {
+                    "type": "string",
+                    "example": "POST"
+                },
+                "params": {
+                    "type": "object"
+                },
+                "prev_hash": {
+                    "type": "string",
+                    "example": ""
+                },
+                "result": {
+                    "type": "string",
+                    "example": ""
+                },
+                "role": {
+                    "type": "string",
+                    "example": "owner"
+                },
+                "route": {
+                    "type": "string",
+                    "example": "/api/v1/sn/create"
+                },
+                "status": {
+                    "type": "integer",
+                    "example": 200
+                }
+            }
+        },
+        "model.Batch": {
+            "type": "object",
+            "properties": {
+                "bound": {
+                    "type": "integer",
+                    "example": 10
+                },
+                "count": {
+                    "type": "integer",
+                    "example": 100
+                },
+                "created_at": {
+                    "type": "string",
+                    "example": "2024-01-01T00:00:00+08:00"
+                },
+                "created_by": {
+                    "type": "string",
+                    "example": "EXAMPLE ADMIN 0"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "9d3c1f7a2b6e4d8c0a5f3e1b7c9d2a4e"
+                },
+                "order_number": {
+                    "type": "string",
+                    "example": "PO-2024-0001"
+                },
+                "product": {
+                    "type": "string",
+                    "example": "quickcerts-pro"
+                },
+                "reason": {
+                    "type": "string",
+                    "example": "For testing."
+                },
+                "reseller": {
+                    "type": "string",
+                    "example": "Example Reseller Inc."
+                },
+                "revoked": {
+                    "type": "integer",
+                    "example": 0
+                }
+            }
+        },
+        "model.CancelJobResponse": {
+            "type": "object",
+            "properties": {
+                "job_id": {
+                    "type": "string",
+                    "example": "5f0c3b1e9a7d4c2b8e6f1a3d5c7b9e0f"
+                },
+                "msg": {
+                    "type": "string",
+                    "example": "Successfully cancelled the job."
+                }
+            }
+        },
+        "model.Cert": {
+            "type": "object",
+            "properties": {
+                "batch_id": {
+                    "type": "string",
+                    "example": "9d3c1f7a2b6e4d8c0a5f3e1b7c9d2a4e"
+                },
+                "key": {
+                    "type": "string",
+                    "example": "3266cd6a16ca77f9c0f0ff9934eb0e29c4b6bb0729cde98811f9f0caf76d603c"
+                },
+                "metadata": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    },
+                    "example": {
+                        "customer_email": "user@example.com"
+                    }
+                },
+                "note": {
+                    "type": "string",
+                    "example": "Updated note."
+                },
+                "product": {
+                    "type": "string",
+                    "example": "quickcerts-pro"
+                },
+                "revoked_at": {
+                    "type": "string",
+                    "example": "2024-01-01T00:00:00+08:00"
+                },
+                "serial_number": {
+                    "type": "string",
+                    "example": "779f-4e90-aebd-4295-881a-f8d7"
+                }
+            }
+        },
+        "model.CertMetadataPatch": {
+            "type": "object",
+            "required": [
+                "metadata",
+                "serial_number"
+            ],
+            "properties": {
+                "metadata": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    },
+                    "example": {
+                        "customer_email": "user@example.com"
+                    }
+                },
+                "serial_number": {
+                    "type": "string",
+                    "example": "779f-4e90-aebd-4295-881a-f8d7"
+                }
+            }
+        },
+        "model.CertMetadataQuery": {
+            "type": "object",
+            "required": [
+                "metadata"
+            ],
+            "properties": {
+                "metadata": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    },
+                    "example": {
+                        "crm_id": "C-1024"
+                    }
+                }
+            }
+        },
+        "model.CertNote": {
+            "type": "object",
+            "required": [
+                "note",
+                "serial_number"
+            ],
+            "properties": {
+                "note": {
+                    "type": "string",
+                    "example": "Additional information"
+                },
+                "serial_number": {
+                    "type": "string",
+                    "example": "779f-4e90-aebd-4295-881a-f8d7"
+                }
+            }
+        },
+        "model.ClientKey": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string",
+                    "example": "2024-01-01T00:00:00+08:00"
+                },
+                "created_by": {
+                    "type": "string",
+                    "example": "EXAMPLE ADMIN 0"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "7a1c3e5b9d2f4a6c8e0b1d3f5a7c9e2b"
+                },
+                "last_used_at": {
+                    "type": "string",
+                    "example": "2024-03-01T00:00:00+08:00"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Windows installer"
+                },
+                "products": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "quickcerts-pro"
+                    ]
+                },
+                "rate_limit": {
+                    "type": "integer",
+                    "example": 60
+                },
+                "revoked_at": {
+                    "type": "string",
+                    "example": "2024-06-01T00:00:00+08:00"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "apply_cert"
+                    ]
+                },
+                "usage_count": {
+                    "type": "integer",
+                    "example": 1024
+                }
+            }
+        },
+        "model.ClientKeyInfo": {
+            "type": "object",
+            "required": [
+                "name",
+                "products",
+                "scopes"
+            ],
+            "properties": {
+                "name": {
+                    "type": "string",
+                    "example": "Windows installer"
+                },
+                "products": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "quickcerts-pro"
+                    ]
+                },
+                "rate_limit": {
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 60
+                },
+                "scopes": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "apply_cert"
+                    ]
+                }
+            }
+        },
+        "model.CreateAdminTokenResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/model.AdminToken"
+                },
+                "msg": {
+                    "type": "string",
+                    "example": "Successfully created the admin token, it will not be shown again."
                 },
-                "serial_number": {
+                "signing_secret": {
                     "type": "string",
-                    "example": "779f-4e90-aebd-4295-881a-f8d7"
+                    "example": "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+                },
+                "token": {
+                    "type": "string",
+                    "example": "3b42f6e4040dcc0e472450c279fc8a1eade54c0e0973776635eec4d450fb622e"
+                },
+                "totp_secret": {
+                    "type": "string",
+                    "example": "JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"
+                },
+                "totp_uri": {
+                    "type": "string",
+                    "example": "otpauth://totp/QuickCertS:EXAMPLE%20ADMIN%202?issuer=QuickCertS\u0026secret=JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"
                 }
             }
         },
-        "model.ApplyCertResponse": {
+        "model.CreateClientKeyResponse": {
             "type": "object",
             "properties": {
+                "data": {
+                    "$ref": "#/definitions/model.ClientKey"
+                },
                 "key": {
                     "type": "string",
-                    "example": "3266cd6a16ca77f9c0f0ff9934eb0e29c4b6bb0729cde98811f9f0caf76d603c"
+                    "example": "5e9a1c3b7d2f4e6a8c0b2d4f6a8c0e1b3d5f7a9c1e3b5d7f9a2c4e6b8d0f1a3c"
                 },
-                "signature": {
+                "msg": {
                     "type": "string",
-                    "example": "MNj/g7W+X5PmirfgWl5jveV54t50+LZAPmByh5Py880pB2z67Ser0YvZ2G/mTNV4XcIrKmLy1ICFmQ1esjydhvBj1FOuTm3eTIixUIsFLxwlW2co/R6kCIjNRydB3N7L/kWv+ZwSjsSsdHqmMUleXV3OJruxeoXV8TLRCSGE4tHGEwhPULuBLn2aldIehDTgteJx1O1YNJGIcDM3NWVDjJnUA0Bjhq3oRvXWN4M23SnZZG2vT94wJIK0X5q6oNqFTupFjDVBCFcHeWoxQ5xZdPhfXF8rC/VTb4vkZZm5RIiIK1UC9XVaAsXVPEzlxVfYJ0gh+wULx8syE2QyB5GfyQ=="
+                    "example": "Successfully created the client key, it will not be shown again."
                 }
             }
         },
-        "model.ApplyTempPermitInfo": {
+        "model.CreateSNResponse": {
             "type": "object",
-            "required": [
-                "board_name",
-                "board_producer",
-                "mac_address"
-            ],
             "properties": {
-                "board_name": {
+                "msg": {
                     "type": "string",
-                    "example": "ROG CROSSHAIR X670E HERO"
+                    "example": "Successfully uploaded a new S/N."
                 },
-                "board_producer": {
+                "serial_number": {
                     "type": "string",
-                    "example": "ASUSTEK COMPUTER INCORPORATION"
+                    "example": "779f-4e90-aebd-4295-881a-f8d7"
+                }
+            }
+        },
+        "model.DeleteSNResponse": {
+            "type": "object",
+            "properties": {
+                "msg": {
+                    "type": "string",
+                    "example": "Successfully deleted the S/N."
                 },
-                "mac_address": {
+                "serial_number": {
                     "type": "string",
-                    "example": "B42499FE0000"
+                    "example": "779f-4e90-aebd-4295-881a-f8d7"
                 }
             }
         },
-        "model.ApplyTempPermitResponse": {
+        "model.ErrorResponse": {
             "type": "object",
             "properties": {
-                "remainingTime": {
-                    "type": "integer",
-                    "example": 604800
+                "code": {
+                    "type": "string",
+                    "example": "SN_NOT_FOUND"
                 },
-                "status": {
+                "error": {
                     "type": "string",
-                    "example": "activated"
+                    "example": "Error message."
                 }
             }
         },
-        "model.Cert": {
+        "model.GenerateSNJobResponse": {
             "type": "object",
             "properties": {
-                "key": {
+                "batch_id": {
                     "type": "string",
-                    "example": "3266cd6a16ca77f9c0f0ff9934eb0e29c4b6bb0729cde98811f9f0caf76d603c"
+                    "example": "9d3c1f7a2b6e4d8c0a5f3e1b7c9d2a4e"
                 },
-                "note": {
+                "job_id": {
                     "type": "string",
-                    "example": "Updated note."
+                    "example": "5f0c3b1e9a7d4c2b8e6f1a3d5c7b9e0f"
                 },
-                "sn": {
+                "msg": {
                     "type": "string",
-                    "example": "779f-4e90-aebd-4295-881a-f8d7"
+                    "example": "The generation is running in the background."
                 }
             }
         },
-        "model.CertNote": {
+        "model.GenerateSNResponse": {
             "type": "object",
-            "required": [
-                "note",
-                "serial_number"
-            ],
             "properties": {
-                "note": {
+                "batch_id": {
                     "type": "string",
-                    "example": "Additional information"
+                    "example": "9d3c1f7a2b6e4d8c0a5f3e1b7c9d2a4e"
                 },
-                "serial_number": {
+                "msg": {
                     "type": "string",
-                    "example": "779f-4e90-aebd-4295-881a-f8d7"
+                    "example": "Successfully generated a new S/N."
+                },
+                "serial_numbers": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "[\"779f-4e90-aebd-4295-881a-f8d7\"]"
+                    ]
                 }
             }
         },
-        "model.CreateSNResponse": {
+        "model.GetAllAdminTokensResponse": {
             "type": "object",
             "properties": {
-                "msg": {
-                    "type": "string",
-                    "example": "Successfully uploaded a new S/N."
-                },
-                "serial_number": {
-                    "type": "string",
-                    "example": "779f-4e90-aebd-4295-881a-f8d7"
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.AdminToken"
+                    }
                 }
             }
         },
-        "model.ErrorResponse": {
+        "model.GetAllBatchesResponse": {
             "type": "object",
             "properties": {
-                "error": {
-                    "type": "string",
-                    "example": "Error message."
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.Batch"
+                    }
+                }
+            }
+        },
+        "model.GetAllClientKeysResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.ClientKey"
+                    }
                 }
             }
         },
-        "model.GetAllSNResponse": {
+        "model.GetAllRecordsResponse": {
             "type": "object",
             "properties": {
                 "data": {
@@ -590,6 +2686,28 @@ const docTemplate = `{
                 }
             }
         },
+        "model.GetArchivedCertsResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.ArchivedCert"
+                    }
+                }
+            }
+        },
+        "model.GetAuditLogsResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.AuditLog"
+                    }
+                }
+            }
+        },
         "model.GetAvaliableSNResponse": {
             "type": "object",
             "properties": {
@@ -604,13 +2722,130 @@ const docTemplate = `{
                 }
             }
         },
+        "model.Job": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string",
+                    "example": "2024-01-01T00:00:00+08:00"
+                },
+                "created_by": {
+                    "type": "string",
+                    "example": "EXAMPLE ADMIN 0"
+                },
+                "error": {
+                    "type": "string",
+                    "example": ""
+                },
+                "id": {
+                    "type": "string",
+                    "example": "5f0c3b1e9a7d4c2b8e6f1a3d5c7b9e0f"
+                },
+                "progress": {
+                    "type": "integer",
+                    "example": 250000
+                },
+                "result": {
+                    "type": "string",
+                    "example": "job_results/5f0c3b1e9a7d4c2b8e6f1a3d5c7b9e0f.txt"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "running"
+                },
+                "total": {
+                    "type": "integer",
+                    "example": 1000000
+                },
+                "type": {
+                    "type": "string",
+                    "example": "generate_sn"
+                },
+                "updated_at": {
+                    "type": "string",
+                    "example": "2024-01-01T00:00:00+08:00"
+                }
+            }
+        },
+        "model.ResetAdminTokenTOTPResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/model.AdminToken"
+                },
+                "msg": {
+                    "type": "string",
+                    "example": "Successfully reset the TOTP secret of the admin token, it will not be shown again."
+                },
+                "totp_secret": {
+                    "type": "string",
+                    "example": "JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"
+                },
+                "totp_uri": {
+                    "type": "string",
+                    "example": "otpauth://totp/QuickCertS:EXAMPLE%20ADMIN%202?issuer=QuickCertS\u0026secret=JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"
+                }
+            }
+        },
+        "model.RevokeBatchResponse": {
+            "type": "object",
+            "properties": {
+                "batch_id": {
+                    "type": "string",
+                    "example": "9d3c1f7a2b6e4d8c0a5f3e1b7c9d2a4e"
+                },
+                "msg": {
+                    "type": "string",
+                    "example": "Successfully revoked the S/N(s) of the batch."
+                },
+                "revoked": {
+                    "type": "integer",
+                    "example": 100
+                }
+            }
+        },
+        "model.RevokeClientKeyResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/model.ClientKey"
+                },
+                "msg": {
+                    "type": "string",
+                    "example": "Successfully revoked the client key."
+                }
+            }
+        },
+        "model.SNDeleteInfo": {
+            "type": "object",
+            "required": [
+                "serial_number"
+            ],
+            "properties": {
+                "force": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "reason": {
+                    "type": "string",
+                    "example": "Refunded."
+                },
+                "serial_number": {
+                    "type": "string",
+                    "example": "779f-4e90-aebd-4295-881a-f8d7"
+                }
+            }
+        },
         "model.SNInfo": {
             "type": "object",
             "required": [
-                "reason",
                 "serial_number"
             ],
             "properties": {
+                "product": {
+                    "type": "string",
+                    "example": "quickcerts-pro"
+                },
                 "reason": {
                     "type": "string",
                     "example": "For testing."
@@ -624,17 +2859,73 @@ const docTemplate = `{
         "model.SNsInfo": {
             "type": "object",
             "required": [
-                "count",
-                "reason"
+                "count"
             ],
             "properties": {
                 "count": {
                     "type": "integer",
                     "example": 1
                 },
+                "order_number": {
+                    "type": "string",
+                    "example": "PO-2024-0001"
+                },
+                "product": {
+                    "type": "string",
+                    "example": "quickcerts-pro"
+                },
                 "reason": {
                     "type": "string",
                     "example": "For testing."
+                },
+                "reseller": {
+                    "type": "string",
+                    "example": "Example Reseller Inc."
+                }
+            }
+        },
+        "model.SearchCertsResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.Cert"
+                    }
+                }
+            }
+        },
+        "model.UpdateAdminTokenResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/model.AdminToken"
+                },
+                "msg": {
+                    "type": "string",
+                    "example": "Successfully revoked the admin token."
+                }
+            }
+        },
+        "model.UpdateCertMetadataResponse": {
+            "type": "object",
+            "properties": {
+                "metadata": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    },
+                    "example": {
+                        "customer_email": "user@example.com"
+                    }
+                },
+                "msg": {
+                    "type": "string",
+                    "example": "Successfully updated the metadata of specified S/N."
+                },
+                "serial_number": {
+                    "type": "string",
+                    "example": "779f-4e90-aebd-4295-881a-f8d7"
                 }
             }
         },
@@ -650,6 +2941,27 @@ const docTemplate = `{
                     "example": "Updated note."
                 }
             }
+        },
+        "model.VerifyAuditLogsResponse": {
+            "type": "object",
+            "properties": {
+                "broken_id": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "checked": {
+                    "type": "integer",
+                    "example": 1024
+                },
+                "msg": {
+                    "type": "string",
+                    "example": "The audit logs are intact."
+                },
+                "valid": {
+                    "type": "boolean",
+                    "example": true
+                }
+            }
         }
     }
 }`
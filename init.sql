@@ -1,12 +1,3 @@
 SET TIME ZONE '+8';
 
-CREATE TABLE certs (
-    sn TEXT PRIMARY KEY NOT NULL,
-    key TEXT,
-    note TEXT
-);
-
-CREATE TABLE temporary_permits (
-    key TEXT PRIMARY KEY NOT NULL,
-    expiration TIMESTAMP WITH TIME ZONE NOT NULL
-);
\ No newline at end of file
+-- The tables are created and upgraded by the server, see data/migrations and `./server migrate`.
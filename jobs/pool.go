@@ -0,0 +1,324 @@
+// Description: Worker pool for long running admin jobs, e.g. mass S/N generation.
+
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	cfg "github.com/mmq88/quickcerts/configs"
+	"github.com/mmq88/quickcerts/data"
+	"github.com/mmq88/quickcerts/model"
+	"github.com/mmq88/quickcerts/utils"
+
+	"github.com/sirupsen/logrus"
+)
+
+// The work of a job, id is the ID of the job being run.
+//
+// report updates the progress of the job. It returns an error once the job has been cancelled, which the
+// task should return to stop as soon as possible.
+//
+// The returned result is the location of the job output, or "" if the job has no output.
+type Task func(ctx context.Context, id string, report func(progress int) error) (result string, err error)
+
+// The errors returned by the job pool, which are checked with errors.Is.
+var (
+	ErrWorkersStarted    = errors.New("the job workers have already been started")
+	ErrWorkersNotRunning = errors.New("the job workers are not running")
+	ErrQueueFull         = errors.New("the job queue is full")
+	ErrJobFinished       = errors.New("the job has already finished")
+)
+
+var (
+	errJobCancelled = errors.New("the job has been cancelled")
+	errShutdown     = errors.New("the job was interrupted by the server shutdown")
+)
+
+// A submitted job waiting for or being processed by a worker.
+type queuedJob struct {
+	id      string
+	task    Task
+	ctx     context.Context
+	cancel  context.CancelCauseFunc
+	started bool
+}
+
+var (
+	mu         sync.Mutex
+	queue      chan *queuedJob
+	pending    map[string]*queuedJob // Queued and running jobs, keyed by job ID.
+	reserved   int                   // Places in the queue reserved by the jobs being added to the database.
+	workers    sync.WaitGroup
+	stopping   bool
+	baseCtx    context.Context
+	baseCancel context.CancelCauseFunc
+)
+
+// The store of the job records, Postgres by default.
+var store data.JobStore = data.PostgresStore{}
+
+// Replace the store of the job records, e.g. with data.NewMemoryStore. It should be called before Start.
+func UseStore(jobs data.JobStore) {
+	store = jobs
+}
+
+// Get the record of the job with the given ID.
+func Get(ctx context.Context, id string) (model.Job, error) {
+	return store.GetJob(ctx, id)
+}
+
+// Start the worker pool with the configured number of workers.
+//
+// The jobs which were left unfinished by the last run of the server are marked as failed.
+func Start() error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if queue != nil {
+		return ErrWorkersStarted
+	}
+
+	if err := os.MkdirAll(cfg.SERVER_CONFIG.JOB_RESULT_DIR, os.FileMode(0700)); err != nil {
+		return err
+	}
+
+	count, err := store.FailUnfinishedJobs(context.Background(), "the job was interrupted by the server restart")
+	if err != nil {
+		return err
+	}
+
+	if count > 0 {
+		utils.Record(logrus.WarnLevel, fmt.Sprintf("Marked %d unfinished job(s) as failed.", count))
+	}
+
+	queue = make(chan *queuedJob, cfg.SERVER_CONFIG.JOB_QUEUE_SIZE)
+	pending = make(map[string]*queuedJob)
+	stopping = false
+	baseCtx, baseCancel = context.WithCancelCause(context.Background())
+
+	for i := 0; i < cfg.SERVER_CONFIG.JOB_WORKER_COUNT; i++ {
+		workers.Add(1)
+		go work()
+	}
+
+	return nil
+}
+
+// Submit a job to the worker pool and return the job ID.
+//
+// total is the expected progress when the job finishes, and createdBy is the name of the admin.
+func Submit(ctx context.Context, jobType string, total int, createdBy string, task Task) (string, error) {
+	// Reserve a place in the queue, so that the job can be queued once it has been added to the database
+	// without holding the lock during the insert.
+	mu.Lock()
+	if queue == nil || stopping {
+		mu.Unlock()
+		return "", ErrWorkersNotRunning
+	}
+
+	if len(queue)+reserved >= cap(queue) {
+		mu.Unlock()
+		return "", ErrQueueFull
+	}
+
+	reserved++
+	mu.Unlock()
+
+	id, err := utils.GenerateID()
+	if err == nil {
+		err = store.AddJob(ctx, model.Job{
+			ID:        id,
+			Type:      jobType,
+			Status:    model.JobStatusQueued,
+			Total:     total,
+			CreatedBy: createdBy,
+		})
+	}
+
+	mu.Lock()
+	reserved--
+
+	if err != nil {
+		mu.Unlock()
+		return "", err
+	}
+
+	// The workers were stopped during the insert.
+	if queue == nil || stopping {
+		mu.Unlock()
+		recordStatus(id, model.JobStatusFailed, "", errShutdown.Error())
+		return "", ErrWorkersNotRunning
+	}
+
+	jobCtx, cancel := context.WithCancelCause(baseCtx)
+	job := &queuedJob{id: id, task: task, ctx: jobCtx, cancel: cancel}
+	pending[id] = job
+	queue <- job
+	mu.Unlock()
+
+	return id, nil
+}
+
+// Cancel a queued or running job.
+func Cancel(ctx context.Context, id string) error {
+	mu.Lock()
+	job, ok := pending[id]
+
+	if ok {
+		job.cancel(errJobCancelled)
+		started := job.started
+		mu.Unlock()
+
+		// A running job is marked as cancelled by its worker once the task returns.
+		if !started {
+			return store.UpdateJobStatus(ctx, id, model.JobStatusCancelled, "", errJobCancelled.Error())
+		}
+		return nil
+	}
+	mu.Unlock()
+
+	record, err := store.GetJob(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if record.IsFinished() {
+		return ErrJobFinished
+	}
+
+	// The job is not handled by this server, e.g. it was left by a previous run.
+	return store.UpdateJobStatus(ctx, id, model.JobStatusCancelled, "", errJobCancelled.Error())
+}
+
+// Stop accepting jobs and wait for the running jobs to finish.
+//
+// The running jobs are interrupted if they have not finished before ctx is done, and the queued jobs
+// which have not been started are marked as failed.
+func Shutdown(ctx context.Context) {
+	mu.Lock()
+	if queue == nil || stopping {
+		mu.Unlock()
+		return
+	}
+	stopping = true
+	close(queue)
+	mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		utils.Record(logrus.WarnLevel, "Interrupting the running jobs ...")
+		baseCancel(errShutdown)
+		<-done
+	}
+
+	mu.Lock()
+	queue = nil
+	baseCancel(errShutdown)
+	mu.Unlock()
+
+	utils.Record(logrus.InfoLevel, "The job workers have exited successfully.")
+}
+
+// Create the file for the output of the given job, and return the file with its location.
+func CreateResultFile(id string, ext string) (*os.File, string, error) {
+	location := filepath.Join(cfg.SERVER_CONFIG.JOB_RESULT_DIR, id+ext)
+
+	f, err := os.OpenFile(location, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(0600))
+	if err != nil {
+		return nil, "", err
+	}
+
+	return f, location, nil
+}
+
+// Process the queued jobs until the queue is closed.
+func work() {
+	defer workers.Done()
+
+	for job := range queue {
+		run(job)
+	}
+}
+
+// Run the given job and record its final status.
+func run(job *queuedJob) {
+	mu.Lock()
+	if stopping && job.ctx.Err() == nil {
+		job.cancel(errors.New("the server shut down before the job started"))
+	}
+	job.started = job.ctx.Err() == nil
+	mu.Unlock()
+
+	defer func() {
+		mu.Lock()
+		delete(pending, job.id)
+		mu.Unlock()
+		job.cancel(nil)
+	}()
+
+	if !job.started {
+		cause := context.Cause(job.ctx)
+
+		// Jobs cancelled by admins have been recorded by Cancel.
+		if cause != errJobCancelled {
+			recordStatus(job.id, model.JobStatusFailed, "", cause.Error())
+		}
+		return
+	}
+
+	recordStatus(job.id, model.JobStatusRunning, "", "")
+
+	report := func(progress int) error {
+		if err := job.ctx.Err(); err != nil {
+			return context.Cause(job.ctx)
+		}
+
+		if err := store.UpdateJobProgress(context.Background(), job.id, progress); err != nil {
+			utils.Record(logrus.ErrorLevel, err.Error())
+		}
+		return nil
+	}
+
+	result, err := runTask(job, report)
+
+	switch {
+	case err == nil:
+		recordStatus(job.id, model.JobStatusSucceeded, result, "")
+		utils.Record(logrus.InfoLevel, fmt.Sprintf("Job [%s] succeeded.", job.id))
+	case context.Cause(job.ctx) == errJobCancelled:
+		recordStatus(job.id, model.JobStatusCancelled, "", errJobCancelled.Error())
+		utils.Record(logrus.InfoLevel, fmt.Sprintf("Job [%s] has been cancelled.", job.id))
+	default:
+		recordStatus(job.id, model.JobStatusFailed, "", err.Error())
+		utils.Record(logrus.ErrorLevel, fmt.Sprintf("Job [%s] failed. Due to: %s", job.id, err.Error()))
+	}
+}
+
+// Run the task of the given job, a panic inside the task fails the job instead of the server.
+func runTask(job *queuedJob, report func(progress int) error) (result string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("the job panicked: %v", r)
+		}
+	}()
+
+	return job.task(job.ctx, job.id, report)
+}
+
+func recordStatus(id string, status string, result string, errMsg string) {
+	if err := store.UpdateJobStatus(context.Background(), id, status, result, errMsg); err != nil {
+		utils.Record(logrus.ErrorLevel, err.Error())
+	}
+}
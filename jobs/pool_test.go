@@ -0,0 +1,157 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	cfg "github.com/mmq88/quickcerts/configs"
+	"github.com/mmq88/quickcerts/data"
+	"github.com/mmq88/quickcerts/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Wait until the given job has finished, or fail the test after a timeout.
+func waitForJob(t *testing.T, id string) model.Job {
+	ctx := context.Background()
+
+	for i := 0; i < 100; i++ {
+		job, err := Get(ctx, id)
+		assert.Nil(t, err)
+
+		if job.IsFinished() {
+			return job
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	t.Fatalf("the job [%s] did not finish in time", id)
+	return model.Job{}
+}
+
+func TestSubmitAndCancel(t *testing.T) {
+	ctx := context.Background()
+
+	backupHost := cfg.DB_CONFIG.HOST
+	backupPort := cfg.DB_CONFIG.PORT
+	defer func() {
+		cfg.DB_CONFIG.HOST = backupHost
+		cfg.DB_CONFIG.PORT = backupPort
+	}()
+
+	// Test invalid case
+	_, err := Submit(ctx, "test", 1, "tester", nil)
+	assert.Equal(t, "the job workers are not running", err.Error())
+
+	// Test valid case
+	// Uses docker-compose config
+	cfg.DB_CONFIG.HOST = "localhost"
+	cfg.DB_CONFIG.PORT = 33332
+
+	err = data.ConnectDB()
+	assert.Nil(t, err)
+	defer func() {
+		err = data.DisconnectDB()
+		assert.Nil(t, err)
+	}()
+
+	err = Start()
+	assert.Nil(t, err)
+	defer Shutdown(ctx)
+
+	// A job reporting its progress until it succeeds.
+	id0, err := Submit(ctx, "test", 3, "tester",
+		func(ctx context.Context, id string, report func(int) error) (string, error) {
+			for i := 1; i <= 3; i++ {
+				if err := report(i); err != nil {
+					return "", err
+				}
+			}
+			return "result", nil
+		})
+	assert.Nil(t, err)
+
+	job := waitForJob(t, id0)
+	assert.Equal(t, model.JobStatusSucceeded, job.Status)
+	assert.Equal(t, 3, job.Progress)
+	assert.Equal(t, "result", job.Result)
+	assert.Equal(t, "tester", job.CreatedBy)
+
+	// A job running until it is cancelled.
+	started := make(chan struct{})
+	id1, err := Submit(ctx, "test", 1, "tester",
+		func(ctx context.Context, id string, report func(int) error) (string, error) {
+			close(started)
+			<-ctx.Done()
+			return "", report(0)
+		})
+	assert.Nil(t, err)
+
+	<-started
+	err = Cancel(ctx, id1)
+	assert.Nil(t, err)
+
+	job = waitForJob(t, id1)
+	assert.Equal(t, model.JobStatusCancelled, job.Status)
+
+	// Test invalid case
+	err = Cancel(ctx, id1)
+	assert.Equal(t, "the job has already finished", err.Error())
+
+	err = Cancel(ctx, "none")
+	assert.Equal(t, "the job does not exist", err.Error())
+
+	// A failed job
+	id2, err := Submit(ctx, "test", 1, "tester",
+		func(ctx context.Context, id string, report func(int) error) (string, error) {
+			return "", errors.New("failed")
+		})
+	assert.Nil(t, err)
+
+	job = waitForJob(t, id2)
+	assert.Equal(t, model.JobStatusFailed, job.Status)
+	assert.Equal(t, "failed", job.Error)
+
+	// Delete the added test data
+	err = data.DeleteTestingData(ctx, "DELETE FROM jobs WHERE id IN ($1, $2, $3)", id0, id1, id2)
+	assert.Nil(t, err)
+}
+
+func TestSubmitWithMemoryStore(t *testing.T) {
+	ctx := context.Background()
+
+	jobStore := data.NewMemoryStore()
+	UseStore(jobStore)
+	defer UseStore(data.PostgresStore{})
+
+	// A job left unfinished by the last run is failed on start.
+	err := jobStore.AddJob(ctx, model.Job{ID: "left", Type: "test", Status: model.JobStatusRunning, Total: 1})
+	assert.Nil(t, err)
+
+	err = Start()
+	assert.Nil(t, err)
+	defer Shutdown(ctx)
+
+	job, err := Get(ctx, "left")
+	assert.Nil(t, err)
+	assert.Equal(t, model.JobStatusFailed, job.Status)
+
+	id, err := Submit(ctx, "test", 2, "tester",
+		func(ctx context.Context, id string, report func(int) error) (string, error) {
+			return "result", report(2)
+		})
+	assert.Nil(t, err)
+
+	job = waitForJob(t, id)
+	assert.Equal(t, model.JobStatusSucceeded, job.Status)
+	assert.Equal(t, 2, job.Progress)
+	assert.Equal(t, "result", job.Result)
+
+	err = Cancel(ctx, id)
+	assert.Equal(t, "the job has already finished", err.Error())
+
+	err = Cancel(ctx, "none")
+	assert.Equal(t, "the job does not exist", err.Error())
+}
@@ -3,12 +3,20 @@
 package middleware
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"net"
+	"io"
 	"net/http"
+	"net/netip"
+	"slices"
+	"strconv"
 	"time"
 
 	cfg "github.com/mmq88/quickcerts/configs"
+	"github.com/mmq88/quickcerts/data"
 	"github.com/mmq88/quickcerts/model"
 	"github.com/mmq88/quickcerts/utils"
 
@@ -16,17 +24,39 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// Get the IP address of the client, resolved through the trusted proxies.
+func clientIP(ctx *gin.Context) string {
+	if ip := ctx.GetString("client_ip"); ip != "" {
+		return ip
+	}
+
+	ip := utils.ResolveClientIP(ctx.Request.RemoteAddr, ctx.Request.Header, cfg.IP_RANGES.TRUSTED_PROXIES)
+	ctx.Set("client_ip", ip)
+	return ip
+}
+
 // Override the default logger of Gin Framework.
-func AccessLogger() gin.HandlerFunc {
+//
+// The requests to skipPaths are not logged, e.g. the probes polling /healthz and /readyz.
+func AccessLogger(skipPaths ...string) gin.HandlerFunc {
+	skipped := make(map[string]bool, len(skipPaths))
+	for _, path := range skipPaths {
+		skipped[path] = true
+	}
+
 	return func(ctx *gin.Context) {
 		start := time.Now()
 		ctx.Next()
+
+		if skipped[ctx.FullPath()] {
+			return
+		}
 		latency := time.Since(start)
 
 		qcsOctx := &utils.QCSExtractGINCtx{
 			StatusCode: ctx.Writer.Status(),
 			Latency:    latency,
-			ClientIP:   ctx.ClientIP(),
+			ClientIP:   clientIP(ctx),
 			Method:     ctx.Request.Method,
 			FullPath:   ctx.FullPath(),
 		}
@@ -36,86 +66,639 @@ func AccessLogger() gin.HandlerFunc {
 }
 
 // Middleware for IP authentication.
+//
+// The client IP is denied if it is in DENIED_IPs, or allowed if it is in ALLOWED_IPs.
 func IPAddressAuth() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		clientIP, _, err := net.SplitHostPort(ctx.Request.RemoteAddr)
+		ip, err := netip.ParseAddr(clientIP(ctx))
 
 		if err != nil {
-			ctx.AbortWithStatusJSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, model.ErrorResponse{Code: data.ErrorCode(err), Error: err.Error()})
 			return
 		}
 
-		for _, allowedIP := range cfg.SERVER_CONFIG.ALLOWED_IPs {
-			if clientIP == allowedIP || allowedIP == "*" {
-				ctx.Next()
-				return
-			}
+		if utils.ContainsIP(cfg.IP_RANGES.DENIED_IPs, ip) || !utils.ContainsIP(cfg.IP_RANGES.ALLOWED_IPs, ip) {
+			utils.Record(
+				logrus.InfoLevel,
+				fmt.Sprintf("IP address is not allowed to access [%s], From [%s]", ctx.FullPath(), ip),
+			)
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, model.ErrorResponse{Code: model.ErrCodeUnauthorized, Error: "Unauthorized Request."})
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// Middleware for limiting the requests to the apply endpoints per client IP address.
+//
+// It runs before the client authentication, so that the client keys can not be brute-forced either.
+func ApplyRateLimitByIP() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !allowRequest(ctx, "apply_ip", clientIP(ctx), cfg.SERVER_CONFIG.APPLY_RATE_LIMIT_PER_IP) {
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// Middleware for limiting the requests to the apply endpoints per S/N, so that the S/N(s) can not be enumerated.
+func ApplyRateLimitBySN() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if cfg.SERVER_CONFIG.APPLY_RATE_LIMIT_PER_SN <= 0 || ctx.Request.Body == nil || ctx.Request.Body == http.NoBody {
+			ctx.Next()
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(ctx.Request.Body, maxAuditBodySize))
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, model.ErrorResponse{Code: model.ErrCodeInvalidRequest, Error: "Invalid data format."})
+			return
+		}
+
+		// Give the body back to the handlers.
+		ctx.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), ctx.Request.Body))
+
+		var applyInfo struct {
+			SerialNumber string `json:"serial_number"`
+		}
+
+		// The malformed requests are rejected by the handlers.
+		if json.Unmarshal(body, &applyInfo) != nil || applyInfo.SerialNumber == "" {
+			ctx.Next()
+			return
+		}
+
+		if !allowRequest(ctx, "apply_sn", applyInfo.SerialNumber, cfg.SERVER_CONFIG.APPLY_RATE_LIMIT_PER_SN) {
+			return
 		}
-		ctx.AbortWithStatusJSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Unauthorized Request."})
+
+		ctx.Next()
+	}
+}
+
+// Count the request in the one-minute window of the rate limit for the id, and abort it if the limit is exceeded.
+// A limit of 0 disables the rate limit.
+func allowRequest(ctx *gin.Context, scope string, id string, limit int) bool {
+	if limit <= 0 {
+		return true
+	}
+
+	count, retryAfter, err := guardCache.CountRateLimitedRequest(ctx.Request.Context(), scope, id, time.Minute)
+
+	if err != nil {
+		utils.Record(logrus.ErrorLevel, err.Error())
+		ctx.AbortWithStatusJSON(http.StatusInternalServerError, model.ErrorResponse{Code: model.ErrCodeInternal, Error: "Internal server error."})
+		return false
+	}
+
+	if count > int64(limit) {
+		utils.Record(
+			logrus.WarnLevel,
+			fmt.Sprintf("Rate limit of [%s] exceeded by [%s] (%d/min), From [%s]", scope, id, limit, clientIP(ctx)),
+		)
+		abortTooManyRequests(ctx, retryAfter)
+		return false
+	}
+
+	return true
+}
+
+// Reject the request with 429, telling the client when to retry.
+func abortTooManyRequests(ctx *gin.Context, retryAfter time.Duration) {
+	seconds := int64((retryAfter + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
 	}
+
+	ctx.Header("Retry-After", strconv.FormatInt(seconds, 10))
+	ctx.AbortWithStatusJSON(http.StatusTooManyRequests, model.ErrorResponse{Code: model.ErrCodeRateLimited, Error: "Too Many Requests."})
 }
 
 // Middleware for client authentication.
-func ClientAccessAuth() gin.HandlerFunc {
+//
+// Only the client keys with the given scope are allowed, and the requests beyond the rate limit of the key
+// are rejected. The key is stored as "client_key" for the handlers to check its products.
+func ClientAccessAuth(scope string) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		reqToken := ctx.GetHeader("X-Access-Token")
 
 		if reqToken == "" {
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Unauthorized Request."})
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, model.ErrorResponse{Code: model.ErrCodeUnauthorized, Error: "Unauthorized Request."})
 			return
 		}
 
-		for _, allowedToken := range cfg.SERVER_CONFIG.CLIENT_AUTH_TOKEN {
-			if reqToken == allowedToken || allowedToken == "" {
-				ctx.Next()
-				return
+		key, err := clientKeyStore.AuthenticateClientKey(ctx.Request.Context(), reqToken)
+
+		if err != nil {
+			if errors.Is(err, data.ErrClientKeyInvalid) {
+				ctx.AbortWithStatusJSON(http.StatusUnauthorized, model.ErrorResponse{Code: model.ErrCodeUnauthorized, Error: "Unauthorized Request."})
+			} else {
+				utils.Record(logrus.ErrorLevel, err.Error())
+				ctx.AbortWithStatusJSON(http.StatusInternalServerError, model.ErrorResponse{Code: model.ErrCodeInternal, Error: "Internal server error."})
 			}
+			return
+		}
+
+		if !key.HasScope(scope) {
+			utils.Record(
+				logrus.WarnLevel,
+				fmt.Sprintf("Client key [%s] is not allowed to access [%s], From [%s]", key.ID, ctx.FullPath(), clientIP(ctx)),
+			)
+			ctx.AbortWithStatusJSON(http.StatusForbidden, model.ErrorResponse{Code: model.ErrCodeForbidden, Error: "Forbidden Request."})
+			return
+		}
+
+		rateLimit := key.RateLimit
+		if rateLimit == 0 {
+			rateLimit = cfg.SERVER_CONFIG.APPLY_RATE_LIMIT_PER_KEY
+		}
+
+		if !allowRequest(ctx, "client_key", key.ID, rateLimit) {
+			return
 		}
-		ctx.AbortWithStatusJSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Unauthorized Request."})
+
+		ctx.Set("client_key", key)
+		ctx.Next()
 	}
 }
 
 // Middleware for admin authentication.
-func AdminAccessAuth(runTimeCode string) gin.HandlerFunc {
+//
+// Only the admins with one of the given roles are allowed. When USE_ADMIN_MTLS is enabled, the admins are
+// authenticated by their client certificates instead of the tokens. When RUNTIME_CODE_TYPE is "totp", the runtime
+// code is the TOTP code of the authenticated admin instead of the code generated on the console.
+func AdminAccessAuth(runTimeCode string, roles ...string) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
+		if !checkAdminLockout(ctx) {
+			return
+		}
+
 		reqRunTimeCode := ctx.GetHeader("X-Runtime-Code")
-		reqToken := ctx.GetHeader("X-Access-Token")
 
-		if cfg.SERVER_CONFIG.USE_RUNTIME_CODE {
+		if cfg.SERVER_CONFIG.USE_RUNTIME_CODE && !cfg.UseTOTPRuntimeCode() {
 			if reqRunTimeCode == "" || reqRunTimeCode != runTimeCode {
 				utils.Record(
 					logrus.InfoLevel,
-					fmt.Sprintf("Runtime Code error(%s), From [%s]", reqRunTimeCode, ctx.RemoteIP()),
+					fmt.Sprintf("Runtime Code error(%s), From [%s]", reqRunTimeCode, clientIP(ctx)),
 				)
-				ctx.AbortWithStatusJSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Unauthorized Request."})
+				ctx.AbortWithStatusJSON(http.StatusUnauthorized, model.ErrorResponse{Code: model.ErrCodeUnauthorized, Error: "Unauthorized Request."})
+				recordAdminAuthFailure(ctx)
 				return
 			}
 		}
 
-		if reqToken == "" {
+		var admin adminIdentity
+		var ok bool
+
+		if cfg.SERVER_CONFIG.USE_ADMIN_MTLS {
+			admin, ok = authenticateAdminCertificate(ctx)
+		} else {
+			admin, ok = authenticateAdminToken(ctx)
+		}
+
+		if !ok {
+			if ctx.Writer.Status() == http.StatusUnauthorized {
+				recordAdminAuthFailure(ctx)
+			}
+			return
+		}
+
+		if cfg.UseTOTPRuntimeCode() && !verifyAdminTOTPCode(ctx, admin, reqRunTimeCode) {
+			return
+		}
+
+		resetAdminAuthFailures(ctx)
+
+		name, role := admin.name, admin.role
+
+		ctx.Set("admin", name)
+		ctx.Set("role", role)
+
+		if !slices.Contains(roles, role) {
 			utils.Record(
-				logrus.InfoLevel,
-				fmt.Sprintf("Token error, From [%s]", ctx.RemoteIP()),
+				logrus.WarnLevel,
+				fmt.Sprintf("Admin [%s] with role [%s] is not allowed to access [%s], From [%s]",
+					name, role, ctx.FullPath(), clientIP(ctx)),
 			)
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Unauthorized Request."})
+			ctx.AbortWithStatusJSON(http.StatusForbidden, model.ErrorResponse{Code: model.ErrCodeForbidden, Error: "Forbidden Request."})
 			return
 		}
 
-		for _, permission := range cfg.ALLOWEDLIST.PERMISSIONS {
-			if reqToken == permission.TOKEN || permission.TOKEN == "" {
-				utils.Record(
-					logrus.InfoLevel,
-					fmt.Sprintf("Admin [%s] login, From [%s]", permission.NAME, ctx.RemoteIP()),
-				)
-				ctx.Next()
-				return
-			}
+		utils.Record(
+			logrus.InfoLevel,
+			fmt.Sprintf("Admin [%s] login, From [%s]", name, clientIP(ctx)),
+		)
+		ctx.Next()
+	}
+}
+
+// The admin authenticated by a token or a client certificate.
+//
+// id is the ID of the admin token, or the certificate subject prefixed by "cert:".
+type adminIdentity struct {
+	id         string
+	name       string
+	role       string
+	totpSecret string
+}
+
+// Verify the TOTP code of the admin.
+//
+// A code can be used for several requests within its time steps, the signed requests are protected from being
+// replayed by their nonces instead.
+//
+// Aborts the request and returns false if the code is invalid.
+func verifyAdminTOTPCode(ctx *gin.Context, admin adminIdentity, code string) bool {
+	if utils.VerifyTOTPCode(admin.totpSecret, code, time.Now()) {
+		return true
+	}
+
+	utils.Record(
+		logrus.WarnLevel,
+		fmt.Sprintf("TOTP code error of admin [%s], From [%s]", admin.name, clientIP(ctx)),
+	)
+	ctx.AbortWithStatusJSON(http.StatusUnauthorized, model.ErrorResponse{Code: model.ErrCodeUnauthorized, Error: "Unauthorized Request."})
+	recordAdminAuthFailure(ctx)
+	return false
+}
+
+// Reject the request with 429 if the client IP is locked out by the repeated failures of the admin authentication.
+func checkAdminLockout(ctx *gin.Context) bool {
+	if cfg.SERVER_CONFIG.ADMIN_LOCKOUT_THRESHOLD == 0 {
+		return true
+	}
+
+	lockout, err := guardCache.GetAdminLockout(ctx.Request.Context(), clientIP(ctx))
+
+	if err != nil {
+		utils.Record(logrus.ErrorLevel, err.Error())
+		ctx.AbortWithStatusJSON(http.StatusInternalServerError, model.ErrorResponse{Code: model.ErrCodeInternal, Error: "Internal server error."})
+		return false
+	}
+
+	if lockout > 0 {
+		abortTooManyRequests(ctx, lockout)
+		return false
+	}
+
+	return true
+}
+
+// Count a failure of the admin authentication from the client IP, which may lock the IP out.
+func recordAdminAuthFailure(ctx *gin.Context) {
+	if cfg.SERVER_CONFIG.ADMIN_LOCKOUT_THRESHOLD == 0 {
+		return
+	}
+
+	lockout, err := guardCache.RecordAdminAuthFailure(ctx.Request.Context(),
+		clientIP(ctx),
+		cfg.SERVER_CONFIG.ADMIN_LOCKOUT_THRESHOLD,
+		time.Duration(cfg.SERVER_CONFIG.ADMIN_LOCKOUT_BASE_TIME)*time.Second,
+		time.Duration(cfg.SERVER_CONFIG.ADMIN_LOCKOUT_MAX_TIME)*time.Second,
+	)
+
+	if err != nil {
+		utils.Record(logrus.ErrorLevel, err.Error())
+		return
+	}
+
+	if lockout > 0 {
+		utils.Record(
+			logrus.WarnLevel,
+			fmt.Sprintf("Admin authentication is locked out for %s due to repeated failures, From [%s]",
+				lockout, clientIP(ctx)),
+		)
+	}
+}
+
+// Forget the failures of the admin authentication from the client IP.
+func resetAdminAuthFailures(ctx *gin.Context) {
+	if cfg.SERVER_CONFIG.ADMIN_LOCKOUT_THRESHOLD == 0 {
+		return
+	}
+
+	if err := guardCache.ResetAdminAuthFailures(ctx.Request.Context(), clientIP(ctx)); err != nil {
+		utils.Record(logrus.ErrorLevel, err.Error())
+	}
+}
+
+// Authenticate the admin by the signature of the request signed with the token given in the X-QCS-Token-ID header,
+// or by the token in the X-Access-Token header if unsigned requests are allowed.
+//
+// Aborts the request and returns false if the authentication fails.
+func authenticateAdminToken(ctx *gin.Context) (adminIdentity, bool) {
+	var token model.AdminToken
+	var ok bool
+
+	if ctx.GetHeader("X-QCS-Token-ID") != "" {
+		token, ok = authenticateSignedRequest(ctx)
+	} else {
+		token, ok = authenticateBearerToken(ctx)
+	}
+
+	if !ok {
+		return adminIdentity{}, false
+	}
+
+	ctx.Set("admin_token_id", token.ID)
+	return adminIdentity{id: token.ID, name: token.Name, role: token.Role, totpSecret: token.TOTPSecret}, true
+}
+
+// Authenticate the admin by the token in the X-Access-Token header, which is only allowed if
+// REQUIRE_REQUEST_SIGNATURE is false.
+func authenticateBearerToken(ctx *gin.Context) (model.AdminToken, bool) {
+	reqToken := ctx.GetHeader("X-Access-Token")
+
+	if reqToken == "" {
+		utils.Record(
+			logrus.InfoLevel,
+			fmt.Sprintf("Token error, From [%s]", clientIP(ctx)),
+		)
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, model.ErrorResponse{Code: model.ErrCodeUnauthorized, Error: "Unauthorized Request."})
+		return model.AdminToken{}, false
+	}
+
+	// The signature headers without the token ID come from the clients signing with the keys derived from the tokens,
+	// which are no longer verified.
+	if cfg.SERVER_CONFIG.REQUIRE_REQUEST_SIGNATURE || hasSignatureHeaders(ctx) {
+		utils.Record(
+			logrus.WarnLevel,
+			fmt.Sprintf("Unsigned admin request refused (missing X-QCS-Token-ID), From [%s]", clientIP(ctx)),
+		)
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, model.ErrorResponse{Code: model.ErrCodeUnauthorized, Error: "Unauthorized Request."})
+		return model.AdminToken{}, false
+	}
+
+	token, err := adminTokenStore.AuthenticateAdminToken(ctx.Request.Context(), reqToken)
+
+	if err != nil {
+		if errors.Is(err, data.ErrAdminTokenInvalid) {
+			utils.Record(
+				logrus.WarnLevel,
+				fmt.Sprintf("Illegal access detected, From [%s]", clientIP(ctx)),
+			)
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, model.ErrorResponse{Code: model.ErrCodeUnauthorized, Error: "Unauthorized Request."})
+		} else {
+			utils.Record(logrus.ErrorLevel, err.Error())
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, model.ErrorResponse{Code: model.ErrCodeInternal, Error: "Internal server error."})
+		}
+		return model.AdminToken{}, false
+	}
+
+	return token, true
+}
+
+// Check if the request carries any of the signature headers.
+func hasSignatureHeaders(ctx *gin.Context) bool {
+	return ctx.GetHeader("X-QCS-Timestamp") != "" || ctx.GetHeader("X-QCS-Nonce") != "" ||
+		ctx.GetHeader("X-QCS-Signature") != ""
+}
+
+// Authenticate the admin by the signature of the request, signed with the signing secret of the token given in the
+// X-QCS-Token-ID header. The token itself is not sent along with the signed requests.
+func authenticateSignedRequest(ctx *gin.Context) (model.AdminToken, bool) {
+	tokenID := ctx.GetHeader("X-QCS-Token-ID")
+
+	var reason string
+	var verifyErr error
+
+	token, err := adminTokenStore.AuthenticateSignedAdminToken(ctx.Request.Context(), tokenID,
+		func(token model.AdminToken) bool {
+			reason, verifyErr = verifyRequestSignature(ctx, token)
+			return reason == "" && verifyErr == nil
+		},
+	)
+
+	if err != nil && verifyErr != nil {
+		err = verifyErr
+	}
+
+	if err != nil {
+		if !errors.Is(err, data.ErrAdminTokenInvalid) {
+			utils.Record(logrus.ErrorLevel, err.Error())
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, model.ErrorResponse{Code: data.ErrorCode(err), Error: "Internal server error."})
+			return model.AdminToken{}, false
+		}
+
+		if reason == "" {
+			reason = "unknown or inactive token"
 		}
 
 		utils.Record(
 			logrus.WarnLevel,
-			fmt.Sprintf("Illegal access detected, From [%s]", ctx.RemoteIP()),
+			fmt.Sprintf("Invalid request signature of admin token [%s] (%s), From [%s]", tokenID, reason, clientIP(ctx)),
+		)
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, model.ErrorResponse{Code: model.ErrCodeUnauthorized, Error: "Unauthorized Request."})
+		return model.AdminToken{}, false
+	}
+
+	return token, true
+}
+
+// Authenticate the admin by the client certificate verified by the admin mTLS listener, which is mapped to the
+// permission with the same CERT_SUBJECT in the allowlist.
+//
+// Aborts the request and returns false if the authentication fails.
+func authenticateAdminCertificate(ctx *gin.Context) (adminIdentity, bool) {
+	state := ctx.Request.TLS
+
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		utils.Record(
+			logrus.WarnLevel,
+			fmt.Sprintf("Admin request without a verified client certificate, From [%s]", clientIP(ctx)),
+		)
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, model.ErrorResponse{Code: model.ErrCodeUnauthorized, Error: "Unauthorized Request."})
+		return adminIdentity{}, false
+	}
+
+	subject := state.VerifiedChains[0][0].Subject.String()
+	permission, ok := cfg.FindPermissionByCertSubject(subject)
+
+	if !ok {
+		utils.Record(
+			logrus.WarnLevel,
+			fmt.Sprintf("Illegal access detected with the client certificate (%s), From [%s]", subject, clientIP(ctx)),
 		)
-		ctx.AbortWithStatusJSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Unauthorized Request."})
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, model.ErrorResponse{Code: model.ErrCodeUnauthorized, Error: "Unauthorized Request."})
+		return adminIdentity{}, false
+	}
+
+	return adminIdentity{
+		id: "cert:" + subject, name: permission.NAME, role: permission.ROLE, totpSecret: permission.TOTP_SECRET,
+	}, true
+}
+
+// The max size of the body of a signed request.
+const maxSignedBodySize = 1024 * 1024
+
+// Verify the signature of an admin request signed with the signing secret of the given token, and return the reason
+// if it is invalid.
+//
+// The returned error is not about the request itself, e.g. the nonce can not be claimed.
+func verifyRequestSignature(ctx *gin.Context, token model.AdminToken) (string, error) {
+	timestamp := ctx.GetHeader("X-QCS-Timestamp")
+	nonce := ctx.GetHeader("X-QCS-Nonce")
+	signature := ctx.GetHeader("X-QCS-Signature")
+
+	if timestamp == "" || nonce == "" || signature == "" {
+		return "incomplete signature headers", nil
+	}
+
+	if token.SigningPublicKey == "" {
+		return "the token has no signing secret", nil
+	}
+
+	signedAt, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return "invalid timestamp", nil
+	}
+
+	maxAge := time.Duration(cfg.SERVER_CONFIG.REQUEST_SIGNATURE_MAX_AGE) * time.Second
+	if age := time.Since(time.Unix(signedAt, 0)); age > maxAge || age < -maxAge {
+		return "expired timestamp", nil
+	}
+
+	var body []byte
+	if ctx.Request.Body != nil {
+		body, err = io.ReadAll(io.LimitReader(ctx.Request.Body, maxSignedBodySize+1))
+		if err != nil {
+			return "", err
+		}
+
+		if len(body) > maxSignedBodySize {
+			return "body too large", nil
+		}
+
+		ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	if !utils.VerifyRequestSignature(
+		token.SigningPublicKey, ctx.Request.Method, ctx.Request.URL.RequestURI(), timestamp, nonce, body, signature,
+	) {
+		return "signature mismatch", nil
+	}
+
+	// The nonce is kept for both sides of the allowed skew, so it can not be reused while the timestamp is valid.
+	claimed, err := guardCache.ClaimRequestNonce(ctx.Request.Context(), token.ID, nonce, 2*maxAge)
+	if err != nil {
+		return "", err
+	}
+
+	if !claimed {
+		return "replayed nonce", nil
+	}
+
+	return "", nil
+}
+
+// The max size of the request body and the error response kept in an audit log.
+const maxAuditBodySize = 64 * 1024
+
+// Capture the body of the error responses for the audit logs.
+type auditResponseWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *auditResponseWriter) Write(b []byte) (int, error) {
+	w.capture(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *auditResponseWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *auditResponseWriter) capture(b []byte) {
+	if w.Status() < http.StatusBadRequest {
+		return
+	}
+
+	if remaining := maxAuditBodySize - w.body.Len(); remaining > 0 {
+		w.body.Write(b[:min(len(b), remaining)])
+	}
+}
+
+// Middleware recording every admin request into the audit logs, including the rejected ones.
+//
+// It should be placed before the authentication middlewares.
+func AuditLog() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		params := map[string]any{}
+
+		if len(ctx.Params) > 0 {
+			pathParams := map[string]string{}
+			for _, param := range ctx.Params {
+				pathParams[param.Key] = param.Value
+			}
+			params["path"] = pathParams
+		}
+
+		if query := ctx.Request.URL.Query(); len(query) > 0 {
+			params["query"] = query
+		}
+
+		if ctx.Request.Body != nil && ctx.Request.Body != http.NoBody {
+			body, err := io.ReadAll(io.LimitReader(ctx.Request.Body, maxAuditBodySize+1))
+			if err != nil {
+				ctx.AbortWithStatusJSON(http.StatusBadRequest, model.ErrorResponse{Code: model.ErrCodeInvalidRequest, Error: "Invalid data format."})
+				return
+			}
+
+			// Give the unread part back to the handlers as well.
+			ctx.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), ctx.Request.Body))
+
+			if len(body) > maxAuditBodySize {
+				params["body_truncated"] = true
+			} else if json.Valid(body) {
+				params["body"] = json.RawMessage(body)
+			} else if len(body) > 0 {
+				params["body"] = string(body)
+			}
+		}
+
+		writer := &auditResponseWriter{ResponseWriter: ctx.Writer}
+		ctx.Writer = writer
+
+		ctx.Next()
+
+		rawParams, err := json.Marshal(params)
+		if err != nil {
+			utils.Record(logrus.ErrorLevel, "Failed to encode the audit log parameters. Due to: "+err.Error())
+			rawParams = []byte("{}")
+		}
+
+		result := ""
+		if ctx.Writer.Status() >= http.StatusBadRequest {
+			var errRes model.ErrorResponse
+			if json.Unmarshal(writer.body.Bytes(), &errRes) == nil && errRes.Error != "" {
+				result = errRes.Error
+			} else {
+				result = http.StatusText(ctx.Writer.Status())
+			}
+		}
+
+		// The action is recorded even if the client has gone away.
+		err = auditStore.AddAuditLog(context.WithoutCancel(ctx.Request.Context()), model.AuditLog{
+			Admin:  ctx.GetString("admin"),
+			Role:   ctx.GetString("role"),
+			IP:     clientIP(ctx),
+			Method: ctx.Request.Method,
+			Route:  ctx.FullPath(),
+			Params: rawParams,
+			Status: ctx.Writer.Status(),
+			Result: result,
+		})
+
+		if err != nil {
+			utils.Record(
+				logrus.ErrorLevel,
+				fmt.Sprintf("Failed to record the audit log of [%s %s]. Due to: %s",
+					ctx.Request.Method, ctx.Request.URL.Path, err.Error()),
+			)
+		}
 	}
 }
@@ -0,0 +1,190 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	cfg "github.com/mmq88/quickcerts/configs"
+	"github.com/mmq88/quickcerts/data"
+	"github.com/mmq88/quickcerts/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimitWithoutRedis(t *testing.T) {
+	backupServerConfig := cfg.SERVER_CONFIG
+	backupGuardCache := guardCache
+	defer func() {
+		cfg.SERVER_CONFIG = backupServerConfig
+		guardCache = backupGuardCache
+	}()
+
+	// The redis database is not connected, the requests are still limited by the counters kept in memory.
+	assert.False(t, data.RDBAvailable())
+	guardCache = data.NewRedisGuardCache()
+	cfg.SERVER_CONFIG.APPLY_RATE_LIMIT_PER_IP = 1
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/api/v1/apply/temp-permit", ApplyRateLimitByIP(), func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	})
+
+	for _, want := range []int{http.StatusOK, http.StatusTooManyRequests, http.StatusTooManyRequests} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/v1/apply/temp-permit", nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, want, w.Code)
+	}
+}
+
+func TestAdminLockoutWithoutRedis(t *testing.T) {
+	backupServerConfig := cfg.SERVER_CONFIG
+	backupGuardCache := guardCache
+	defer func() {
+		cfg.SERVER_CONFIG = backupServerConfig
+		guardCache = backupGuardCache
+	}()
+
+	// The redis database is not connected, the failures still lock the client out by the counters kept in memory.
+	assert.False(t, data.RDBAvailable())
+	guardCache = data.NewRedisGuardCache()
+	cfg.SERVER_CONFIG.USE_RUNTIME_CODE = false
+	cfg.SERVER_CONFIG.USE_ADMIN_MTLS = false
+	cfg.SERVER_CONFIG.ADMIN_LOCKOUT_THRESHOLD = 1
+	cfg.SERVER_CONFIG.ADMIN_LOCKOUT_BASE_TIME = 60
+	cfg.SERVER_CONFIG.ADMIN_LOCKOUT_MAX_TIME = 600
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/v1/sn/all", AdminAccessAuth("", "admin"), func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	})
+
+	for _, want := range []int{http.StatusUnauthorized, http.StatusTooManyRequests} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/v1/sn/all", nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, want, w.Code)
+	}
+}
+
+func TestSignedAdminRequest(t *testing.T) {
+	backupServerConfig := cfg.SERVER_CONFIG
+	defer func() {
+		cfg.SERVER_CONFIG = backupServerConfig
+		UseStores(data.PostgresStore{}, data.PostgresStore{}, data.PostgresStore{}, data.NewRedisGuardCache())
+	}()
+
+	cfg.SERVER_CONFIG.USE_RUNTIME_CODE = false
+	cfg.SERVER_CONFIG.USE_ADMIN_MTLS = false
+	cfg.SERVER_CONFIG.REQUEST_SIGNATURE_MAX_AGE = 300
+
+	store := data.NewMemoryStore()
+	UseStores(store, store, store, data.NewMemoryGuardCache())
+
+	record, token, signingSecret, err := store.CreateAdminToken(context.Background(), "tester", cfg.RoleOwner, "tester", nil)
+	assert.Nil(t, err)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/api/v1/sn/create", AdminAccessAuth("", cfg.RoleOwner), func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	})
+
+	send := func(setHeaders func(req *http.Request)) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/v1/sn/create", strings.NewReader(`{"serial_number":"XXXX"}`))
+		setHeaders(req)
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	signature, err := utils.SignRequest(
+		signingSecret, "POST", "/api/v1/sn/create", timestamp, "nonce", []byte(`{"serial_number":"other"}`),
+	)
+	assert.Nil(t, err)
+
+	signRequest := func(req *http.Request, signature string) {
+		req.Header.Set("X-QCS-Token-ID", record.ID)
+		req.Header.Set("X-QCS-Timestamp", timestamp)
+		req.Header.Set("X-QCS-Nonce", "nonce")
+		req.Header.Set("X-QCS-Signature", signature)
+	}
+
+	// The signature of another body is refused.
+	assert.Equal(t, http.StatusUnauthorized, send(func(req *http.Request) { signRequest(req, signature) }))
+
+	// The signature without the token ID is refused, even along with the valid token.
+	assert.Equal(t, http.StatusUnauthorized, send(func(req *http.Request) {
+		req.Header.Set("X-Access-Token", token)
+		req.Header.Set("X-QCS-Timestamp", timestamp)
+		req.Header.Set("X-QCS-Nonce", "nonce")
+		req.Header.Set("X-QCS-Signature", signature)
+	}))
+
+	assert.Equal(t, http.StatusOK, send(func(req *http.Request) {
+		req.Header.Set("X-Access-Token", token)
+	}))
+
+	// The token alone is refused once the signature is required.
+	cfg.SERVER_CONFIG.REQUIRE_REQUEST_SIGNATURE = true
+	assert.Equal(t, http.StatusUnauthorized, send(func(req *http.Request) {
+		req.Header.Set("X-Access-Token", token)
+	}))
+
+	// The signed request is accepted without the token, but only once.
+	signature, err = utils.SignRequest(
+		signingSecret, "POST", "/api/v1/sn/create", timestamp, "nonce", []byte(`{"serial_number":"XXXX"}`),
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, send(func(req *http.Request) { signRequest(req, signature) }))
+	assert.Equal(t, http.StatusUnauthorized, send(func(req *http.Request) { signRequest(req, signature) }))
+}
+
+func TestIPAddressAuth(t *testing.T) {
+	backupIPRanges := cfg.IP_RANGES
+	defer func() {
+		cfg.IP_RANGES = backupIPRanges
+	}()
+
+	// The ranges validated with the configs are used, instead of the ones parsed when the package is loaded.
+	cfg.IP_RANGES = cfg.IPRanges{
+		ALLOWED_IPs: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")},
+		DENIED_IPs:  []netip.Prefix{netip.MustParsePrefix("10.0.0.1/32")},
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/v1/sn/all", IPAddressAuth(), func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		remoteAddr string
+		want       int
+	}{
+		{"10.0.0.2:1234", http.StatusOK},
+		{"10.0.0.1:1234", http.StatusUnauthorized},
+		{"192.168.0.1:1234", http.StatusUnauthorized},
+	}
+
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/v1/sn/all", nil)
+		req.RemoteAddr = test.remoteAddr
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, test.want, w.Code, test.remoteAddr)
+	}
+}
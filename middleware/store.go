@@ -0,0 +1,22 @@
+package middleware
+
+import "github.com/mmq88/quickcerts/data"
+
+// The stores used by the middlewares, Postgres and Redis by default.
+var (
+	adminTokenStore data.AdminTokenStore = data.PostgresStore{}
+	clientKeyStore  data.ClientKeyStore  = data.PostgresStore{}
+	auditStore      data.AuditStore      = data.PostgresStore{}
+	guardCache      data.GuardCache      = data.NewRedisGuardCache()
+)
+
+// Replace the stores used by the authentication, rate limit and audit middlewares, e.g. with data.NewMemoryStore
+// and data.NewMemoryGuardCache. It should be called before the server starts, along with api.UseAdminStores.
+func UseStores(
+	tokens data.AdminTokenStore, clientKeys data.ClientKeyStore, audit data.AuditStore, guard data.GuardCache,
+) {
+	adminTokenStore = tokens
+	clientKeyStore = clientKeys
+	auditStore = audit
+	guardCache = guard
+}
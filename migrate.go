@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	cfg "github.com/mmq88/quickcerts/configs"
+	"github.com/mmq88/quickcerts/data"
+	"github.com/mmq88/quickcerts/utils"
+
+	"github.com/sirupsen/logrus"
+)
+
+const migrateUsage = "Usage: server migrate [up | down [steps] | status]"
+
+// Check the schema of the database before the server starts, see AUTO_MIGRATE in database.toml.
+//
+// The server refuses to start if the schema is newer than it supports, or outdated while AUTO_MIGRATE is disabled.
+func prepareSchema(name string, migrator data.Migrator) {
+	applied, err := migrator.Prepare(cfg.DB_CONFIG.AUTO_MIGRATE)
+
+	if errors.Is(err, data.ErrSchemaOutdated) {
+		utils.Record(logrus.FatalLevel, fmt.Sprintf(
+			"The schema of the %s is outdated, run `server migrate` or enable AUTO_MIGRATE. Due to: %s", name, err))
+	} else if err != nil {
+		utils.Record(logrus.FatalLevel, fmt.Sprintf("Failed to prepare the schema of the %s. Due to: %s", name, err))
+	}
+
+	for _, migration := range applied {
+		utils.Record(logrus.InfoLevel, fmt.Sprintf(
+			"Applied the migration %d [%s] to the %s.", migration.Version, migration.Name, name))
+	}
+}
+
+// Run the `migrate` subcommand on the SQLite database if DRIVER is sqlite, or the Postgres database otherwise.
+func runMigrate(args []string) error {
+	action := "up"
+	if len(args) > 0 {
+		action = args[0]
+	}
+
+	steps := 1
+	if action == "down" && len(args) > 1 {
+		var err error
+		if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+			return errors.New(migrateUsage)
+		}
+	}
+
+	if cfg.UseSQLite() {
+		store, err := data.OpenSQLiteStore(cfg.DB_CONFIG.SQLITE_PATH)
+		if err != nil {
+			return err
+		}
+
+		defer store.Close()
+
+		migrator, err := store.Migrator()
+		if err != nil {
+			return err
+		}
+
+		return migrate("SQLite database", migrator, action, steps)
+	}
+
+	if err := data.ConnectDBWithRetries(context.Background()); err != nil {
+		return err
+	}
+
+	defer data.DisconnectDB()
+
+	migrator, err := data.PostgresMigrator()
+	if err != nil {
+		return err
+	}
+
+	return migrate("database", migrator, action, steps)
+}
+
+func migrate(name string, migrator data.Migrator, action string, steps int) error {
+	switch action {
+	case "up":
+		applied, err := migrator.Up()
+
+		for _, migration := range applied {
+			utils.Record(logrus.InfoLevel, fmt.Sprintf(
+				"Applied the migration %d [%s] to the %s.", migration.Version, migration.Name, name))
+		}
+
+		return err
+	case "down":
+		reverted, err := migrator.Down(steps)
+
+		for _, migration := range reverted {
+			utils.Record(logrus.InfoLevel, fmt.Sprintf(
+				"Reverted the migration %d [%s] of the %s.", migration.Version, migration.Name, name))
+		}
+
+		return err
+	case "status":
+		version, err := migrator.Version()
+		if err != nil {
+			return err
+		}
+
+		utils.Record(logrus.InfoLevel, fmt.Sprintf(
+			"The schema version of the %s is %d, the latest version is %d.", name, version, migrator.Latest()))
+
+		return nil
+	default:
+		return errors.New(migrateUsage)
+	}
+}
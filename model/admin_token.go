@@ -0,0 +1,39 @@
+package model
+
+import "time"
+
+// For database table `admin_tokens`.
+//
+// Only the salted hash of the token is stored, along with its keyed hash for finding it, see utils.TokenLookupHash.
+//
+// SigningPublicKey is the hex Ed25519 public key verifying the signed requests, whose signing secret is only shown
+// when the token is created, see utils.GenerateSigningSecret. It is empty if the token can not sign requests, e.g.
+// it was created before the signing secrets were introduced.
+//
+// TOTPSecret is the base32 seed of the TOTP codes required when RUNTIME_CODE_TYPE is "totp", which is empty if the
+// admin has no seed yet.
+//
+// ExpiresAt is nil if the token never expires, and RevokedAt is nil if the token has not been revoked.
+type AdminToken struct {
+	ID               string     `json:"id" example:"2c7e5a9b1d3f4e6a8b0c2d4e6f8a0b1c"`
+	Name             string     `json:"name" example:"EXAMPLE ADMIN 0"`
+	Role             string     `json:"role" example:"owner"`
+	Salt             string     `json:"-"`
+	TokenHash        string     `json:"-"`
+	SigningPublicKey string     `json:"-"`
+	TOTPSecret       string     `json:"-"`
+	CreatedBy        string     `json:"created_by" example:"EXAMPLE ADMIN 0"`
+	CreatedAt        time.Time  `json:"created_at" example:"2024-01-01T00:00:00+08:00"`
+	ExpiresAt        *time.Time `json:"expires_at,omitempty" example:"2025-01-01T00:00:00+08:00"`
+	RevokedAt        *time.Time `json:"revoked_at,omitempty" example:"2024-06-01T00:00:00+08:00"`
+	LastUsedAt       *time.Time `json:"last_used_at,omitempty" example:"2024-03-01T00:00:00+08:00"`
+}
+
+// Check if the token can still be used at the given time.
+func (token AdminToken) IsActive(at time.Time) bool {
+	if token.RevokedAt != nil {
+		return false
+	}
+
+	return token.ExpiresAt == nil || at.Before(*token.ExpiresAt)
+}
@@ -0,0 +1,41 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// For database table `audit_logs`.
+//
+// Admin and Role are empty if the request did not pass the admin authentication.
+//
+// Result is the error message of a failed request, or empty if the request succeeded.
+//
+// Hash is computed from PrevHash and the other fields, so any modified or deleted record breaks the chain.
+type AuditLog struct {
+	ID        int64           `json:"id" example:"1"`
+	Admin     string          `json:"admin" example:"EXAMPLE ADMIN 0"`
+	Role      string          `json:"role" example:"owner"`
+	IP        string          `json:"ip" example:"127.0.0.1"`
+	Method    string          `json:"method" example:"POST"`
+	Route     string          `json:"route" example:"/api/v1/sn/create"`
+	Params    json.RawMessage `json:"params" swaggertype:"object"`
+	Status    int             `json:"status" example:"200"`
+	Result    string          `json:"result" example:""`
+	CreatedAt time.Time       `json:"created_at" example:"2024-01-01T00:00:00+08:00"`
+	PrevHash  string          `json:"prev_hash" example:""`
+	Hash      string          `json:"hash" example:"0d5f6c1c1a2b3e4f5a6b7c8d9e0f1a2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d7e8f"`
+}
+
+// The filters of querying audit logs, the zero values are ignored.
+type AuditFilter struct {
+	Admin  string    `form:"admin"`
+	Role   string    `form:"role"`
+	IP     string    `form:"ip"`
+	Route  string    `form:"route"`
+	Status int       `form:"status"`
+	From   time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To     time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Limit  int       `form:"limit"`
+	Offset int       `form:"offset"`
+}
@@ -0,0 +1,55 @@
+package model
+
+import (
+	"slices"
+	"time"
+)
+
+// The apply endpoints a client key may call.
+const (
+	ClientScopeApplyCert       = "apply_cert"
+	ClientScopeApplyTempPermit = "apply_temp_permit"
+)
+
+// The product scope of a client key allowing the S/N(s) of all products, including those without a product.
+const AllProducts = "*"
+
+// For database table `client_keys`.
+//
+// Only the salted hash of the key is stored, along with its keyed hash for finding it, see utils.TokenLookupHash.
+//
+// RateLimit is the max number of requests per minute, 0 means unlimited. UsageCount is the number of
+// authenticated requests made with the key.
+type ClientKey struct {
+	ID         string     `json:"id" example:"7a1c3e5b9d2f4a6c8e0b1d3f5a7c9e2b"`
+	Name       string     `json:"name" example:"Windows installer"`
+	Salt       string     `json:"-"`
+	TokenHash  string     `json:"-"`
+	Products   []string   `json:"products" example:"quickcerts-pro"`
+	Scopes     []string   `json:"scopes" example:"apply_cert"`
+	RateLimit  int        `json:"rate_limit" example:"60"`
+	UsageCount int64      `json:"usage_count" example:"1024"`
+	CreatedBy  string     `json:"created_by" example:"EXAMPLE ADMIN 0"`
+	CreatedAt  time.Time  `json:"created_at" example:"2024-01-01T00:00:00+08:00"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" example:"2024-06-01T00:00:00+08:00"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" example:"2024-03-01T00:00:00+08:00"`
+}
+
+// Check if the key may call the endpoint of the given scope.
+func (key ClientKey) HasScope(scope string) bool {
+	return slices.Contains(key.Scopes, scope)
+}
+
+// Check if the key may apply the S/N(s) of the given product, product is "" for S/N(s) without a product.
+func (key ClientKey) AllowsProduct(product string) bool {
+	if slices.Contains(key.Products, AllProducts) {
+		return true
+	}
+
+	return product != "" && slices.Contains(key.Products, product)
+}
+
+// Check if the given scope is one of the client scopes.
+func IsValidClientScope(scope string) bool {
+	return scope == ClientScopeApplyCert || scope == ClientScopeApplyTempPermit
+}
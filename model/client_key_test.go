@@ -0,0 +1,24 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientKeyAllowsProduct(t *testing.T) {
+	key := ClientKey{Products: []string{"quickcerts-pro"}}
+	assert.True(t, key.AllowsProduct("quickcerts-pro"))
+	assert.False(t, key.AllowsProduct("quickcerts-lite"))
+	assert.False(t, key.AllowsProduct(""))
+
+	key = ClientKey{Products: []string{AllProducts}}
+	assert.True(t, key.AllowsProduct("quickcerts-lite"))
+	assert.True(t, key.AllowsProduct(""))
+}
+
+func TestClientKeyHasScope(t *testing.T) {
+	key := ClientKey{Scopes: []string{ClientScopeApplyCert}}
+	assert.True(t, key.HasScope(ClientScopeApplyCert))
+	assert.False(t, key.HasScope(ClientScopeApplyTempPermit))
+}
@@ -0,0 +1,40 @@
+package model
+
+// The machine-readable codes of ErrorResponse. The codes are stable, while the messages may change.
+const (
+	// The request body or parameters are malformed or not valid.
+	ErrCodeInvalidRequest = "INVALID_REQUEST"
+	// The admin token, client key, runtime code, signature or client certificate is missing or not valid.
+	ErrCodeUnauthorized = "UNAUTHORIZED"
+	// The caller is authenticated, but is not allowed to do the request.
+	ErrCodeForbidden = "FORBIDDEN"
+	// The caller is rate limited or locked out, retry after the time in the Retry-After header.
+	ErrCodeRateLimited = "RATE_LIMITED"
+	// An unexpected error on the server.
+	ErrCodeInternal = "INTERNAL_ERROR"
+	// The database can not be accessed.
+	ErrCodeDatabaseUnavailable = "DATABASE_UNAVAILABLE"
+	// The redis cache can not be accessed.
+	ErrCodeCacheUnavailable = "CACHE_UNAVAILABLE"
+
+	ErrCodeSNNotFound      = "SN_NOT_FOUND"
+	ErrCodeSNAlreadyExists = "SN_ALREADY_EXISTS"
+	// The S/N does not exist or has already been bound to another device.
+	ErrCodeSNUnavailable = "SN_UNAVAILABLE"
+	// The S/N has been bound to a device, and can not be deleted without force.
+	ErrCodeSNAlreadyBound = "SN_ALREADY_BOUND"
+	// The S/N belongs to a product the client key may not apply.
+	ErrCodeSNNotAllowed = "SN_NOT_ALLOWED"
+	// The temporary permit of the device has expired.
+	ErrCodeTempPermitExpired = "TEMP_PERMIT_EXPIRED"
+
+	ErrCodeBatchNotFound = "BATCH_NOT_FOUND"
+
+	ErrCodeJobNotFound        = "JOB_NOT_FOUND"
+	ErrCodeJobAlreadyFinished = "JOB_ALREADY_FINISHED"
+	// The job queue is full or the job workers are not running.
+	ErrCodeJobQueueUnavailable = "JOB_QUEUE_UNAVAILABLE"
+
+	ErrCodeAdminTokenNotFound = "ADMIN_TOKEN_NOT_FOUND"
+	ErrCodeClientKeyNotFound  = "CLIENT_KEY_NOT_FOUND"
+)
@@ -0,0 +1,36 @@
+package model
+
+// The status of the server reported by /readyz.
+//
+// The server is degraded if only the optional components are down, e.g. the redis database of the key cache.
+const (
+	ReadinessStatusReady    = "ready"
+	ReadinessStatusDegraded = "degraded"
+	ReadinessStatusNotReady = "not_ready"
+)
+
+// The status of a component checked by /readyz.
+const (
+	ComponentStatusUp   = "up"
+	ComponentStatusDown = "down"
+)
+
+type LivenessResponse struct {
+	Status string `json:"status" example:"alive"`
+}
+
+// Components: The status of each component, keyed by "postgres", "redis", "signing_key" and "migrations", plus
+// "sqlite" and "sqlite_migrations" if DRIVER is sqlite.
+type ReadinessResponse struct {
+	Status     string                     `json:"status" example:"ready"`
+	Components map[string]ComponentHealth `json:"components"`
+}
+
+// Error: Why the component is down.
+//
+// Optional: The server keeps serving requests without the component.
+type ComponentHealth struct {
+	Status   string `json:"status" example:"up"`
+	Optional bool   `json:"optional,omitempty" example:"false"`
+	Error    string `json:"error,omitempty" example:""`
+}
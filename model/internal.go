@@ -1,8 +1,80 @@
 package model
 
+import "time"
+
 // For database table `certs`.
+//
+// RevokedAt is nil if the S/N has not been revoked.
+//
+// Metadata is the custom structured information of the S/N, e.g. customer email, CRM ID, order ID.
+//
+// Product is the product the S/N is issued for, which limits the client keys that can apply it.
 type Cert struct {
-	SerialNumber string `json:"serial_number" example:"779f-4e90-aebd-4295-881a-f8d7"`
-	Key          string `json:"key" example:"3266cd6a16ca77f9c0f0ff9934eb0e29c4b6bb0729cde98811f9f0caf76d603c"`
-	Note         string `json:"note" example:"Updated note."`
+	SerialNumber string         `json:"serial_number" example:"779f-4e90-aebd-4295-881a-f8d7"`
+	Key          string         `json:"key" example:"3266cd6a16ca77f9c0f0ff9934eb0e29c4b6bb0729cde98811f9f0caf76d603c"`
+	Note         string         `json:"note" example:"Updated note."`
+	BatchID      string         `json:"batch_id" example:"9d3c1f7a2b6e4d8c0a5f3e1b7c9d2a4e"`
+	RevokedAt    *time.Time     `json:"revoked_at,omitempty" example:"2024-01-01T00:00:00+08:00"`
+	Metadata     map[string]any `json:"metadata" swaggertype:"object,string" example:"customer_email:user@example.com"`
+	Product      string         `json:"product" example:"quickcerts-pro"`
+}
+
+// For database table `batches`.
+//
+// Count, Bound and Revoked are the numbers of S/N(s) in the batch, which are bound to a device and
+// which have been revoked respectively.
+type Batch struct {
+	ID          string    `json:"id" example:"9d3c1f7a2b6e4d8c0a5f3e1b7c9d2a4e"`
+	Reason      string    `json:"reason" example:"For testing."`
+	CreatedBy   string    `json:"created_by" example:"EXAMPLE ADMIN 0"`
+	Reseller    string    `json:"reseller" example:"Example Reseller Inc."`
+	OrderNumber string    `json:"order_number" example:"PO-2024-0001"`
+	Product     string    `json:"product" example:"quickcerts-pro"`
+	CreatedAt   time.Time `json:"created_at" example:"2024-01-01T00:00:00+08:00"`
+	Count       int       `json:"count" example:"100"`
+	Bound       int       `json:"bound" example:"10"`
+	Revoked     int       `json:"revoked" example:"0"`
+}
+
+// For database table `archived_certs`, the S/N(s) deleted by admins.
+type ArchivedCert struct {
+	Cert
+	Reason     string    `json:"reason" example:"Refunded."`
+	ArchivedBy string    `json:"archived_by" example:"EXAMPLE ADMIN 0"`
+	ArchivedAt time.Time `json:"archived_at" example:"2024-01-01T00:00:00+08:00"`
+}
+
+// The result of binding a S/N to the key of a device.
+type BindStatus string
+
+const (
+	// The S/N was not bound, and has been bound to the key now.
+	BindStatusBound BindStatus = "bound"
+	// The S/N has already been bound to the same key, e.g. the device applied again.
+	BindStatusRebound BindStatus = "rebound"
+	// The S/N has been bound to the key of another device.
+	BindStatusTaken BindStatus = "taken"
+	// The S/N has been revoked.
+	BindStatusRevoked BindStatus = "revoked"
+	// The S/N belongs to a product the caller may not apply.
+	BindStatusNotAllowed BindStatus = "not_allowed"
+	// The S/N does not exist.
+	BindStatusNotFound BindStatus = "not_found"
+)
+
+// Check if the S/N is bound to the key after binding.
+func (status BindStatus) IsBound() bool {
+	return status == BindStatusBound || status == BindStatusRebound
+}
+
+// For database table `cert_activations`, the history of the devices applying the S/N(s).
+//
+// ClientKeyID is "" if the request was authorized by the legacy CLIENT_AUTH_TOKEN or no client key.
+type CertActivation struct {
+	SerialNumber string     `json:"serial_number" example:"779f-4e90-aebd-4295-881a-f8d7"`
+	Key          string     `json:"key" example:"3266cd6a16ca77f9c0f0ff9934eb0e29c4b6bb0729cde98811f9f0caf76d603c"`
+	Status       BindStatus `json:"status" example:"bound"`
+	ClientKeyID  string     `json:"client_key_id" example:"4c1d2e3f4a5b6c7d8e9f0a1b2c3d4e5f"`
+	IP           string     `json:"ip" example:"203.0.113.7"`
+	CreatedAt    time.Time  `json:"created_at" example:"2024-01-01T00:00:00+08:00"`
 }
@@ -0,0 +1,40 @@
+package model
+
+import "time"
+
+// The status of an admin job.
+const (
+	JobStatusQueued    = "queued"
+	JobStatusRunning   = "running"
+	JobStatusSucceeded = "succeeded"
+	JobStatusFailed    = "failed"
+	JobStatusCancelled = "cancelled"
+)
+
+// For database table `jobs`.
+//
+// Progress and Total are counted in the unit of the job type, e.g. S/N(s) for "generate_sn".
+//
+// Result is the location of the job output, which can be downloaded from /jobs/{id}/result.
+type Job struct {
+	ID        string    `json:"id" example:"5f0c3b1e9a7d4c2b8e6f1a3d5c7b9e0f"`
+	Type      string    `json:"type" example:"generate_sn"`
+	Status    string    `json:"status" example:"running"`
+	Progress  int       `json:"progress" example:"250000"`
+	Total     int       `json:"total" example:"1000000"`
+	Result    string    `json:"result,omitempty" example:"job_results/5f0c3b1e9a7d4c2b8e6f1a3d5c7b9e0f.txt"`
+	Error     string    `json:"error,omitempty" example:""`
+	CreatedBy string    `json:"created_by" example:"EXAMPLE ADMIN 0"`
+	CreatedAt time.Time `json:"created_at" example:"2024-01-01T00:00:00+08:00"`
+	UpdatedAt time.Time `json:"updated_at" example:"2024-01-01T00:00:00+08:00"`
+}
+
+// Finished jobs can not be cancelled anymore.
+func (job Job) IsFinished() bool {
+	switch job.Status {
+	case JobStatusSucceeded, JobStatusFailed, JobStatusCancelled:
+		return true
+	default:
+		return false
+	}
+}
@@ -1,11 +1,15 @@
 package model
 
-// ErrorMsg: Error message for response
+// Code: Machine-readable error code, see model/error_code.go
+//
+// Error: Error message for response
 type ErrorResponse struct {
+	Code  string `json:"code" example:"SN_NOT_FOUND"`
 	Error string `json:"error" example:"Error message."`
 }
 
 type ApplyCertResponse struct {
+	Status    string `json:"status" example:"bound"`
 	Key       string `json:"key" example:"3266cd6a16ca77f9c0f0ff9934eb0e29c4b6bb0729cde98811f9f0caf76d603c"`
 	Signature string `json:"signature" example:"MNj/g7W+X5PmirfgWl5jveV54t50+LZAPmByh5Py880pB2z67Ser0YvZ2G/mTNV4XcIrKmLy1ICFmQ1esjydhvBj1FOuTm3eTIixUIsFLxwlW2co/R6kCIjNRydB3N7L/kWv+ZwSjsSsdHqmMUleXV3OJruxeoXV8TLRCSGE4tHGEwhPULuBLn2aldIehDTgteJx1O1YNJGIcDM3NWVDjJnUA0Bjhq3oRvXWN4M23SnZZG2vT94wJIK0X5q6oNqFTupFjDVBCFcHeWoxQ5xZdPhfXF8rC/VTb4vkZZm5RIiIK1UC9XVaAsXVPEzlxVfYJ0gh+wULx8syE2QyB5GfyQ=="`
 }
@@ -22,14 +26,45 @@ type CreateSNResponse struct {
 
 type GenerateSNResponse struct {
 	Msg           string   `json:"msg" example:"Successfully generated a new S/N."`
+	BatchID       string   `json:"batch_id" example:"9d3c1f7a2b6e4d8c0a5f3e1b7c9d2a4e"`
 	SerialNumbers []string `json:"serial_numbers" example:"[\"779f-4e90-aebd-4295-881a-f8d7\"]"`
 }
 
+type GenerateSNJobResponse struct {
+	Msg     string `json:"msg" example:"The generation is running in the background."`
+	BatchID string `json:"batch_id" example:"9d3c1f7a2b6e4d8c0a5f3e1b7c9d2a4e"`
+	JobID   string `json:"job_id" example:"5f0c3b1e9a7d4c2b8e6f1a3d5c7b9e0f"`
+}
+
+type CancelJobResponse struct {
+	Msg   string `json:"msg" example:"Successfully cancelled the job."`
+	JobID string `json:"job_id" example:"5f0c3b1e9a7d4c2b8e6f1a3d5c7b9e0f"`
+}
+
 type UpdateCertNoteResponse struct {
 	Msg  string `json:"msg" example:"Successfully updated the note of specified S/N."`
 	Note string `json:"note" example:"Updated note."`
 }
 
+type DeleteSNResponse struct {
+	Msg          string `json:"msg" example:"Successfully deleted the S/N."`
+	SerialNumber string `json:"serial_number" example:"779f-4e90-aebd-4295-881a-f8d7"`
+}
+
+type GetArchivedCertsResponse struct {
+	Data []ArchivedCert `json:"data"`
+}
+
+type UpdateCertMetadataResponse struct {
+	Msg          string         `json:"msg" example:"Successfully updated the metadata of specified S/N."`
+	SerialNumber string         `json:"serial_number" example:"779f-4e90-aebd-4295-881a-f8d7"`
+	Metadata     map[string]any `json:"metadata" swaggertype:"object,string" example:"customer_email:user@example.com"`
+}
+
+type SearchCertsResponse struct {
+	Data []Cert `json:"data"`
+}
+
 type GetAllRecordsResponse struct {
 	Data []Cert `json:"data"`
 }
@@ -37,3 +72,64 @@ type GetAllRecordsResponse struct {
 type GetAvaliableSNResponse struct {
 	Data []string `json:"data" example:"779f-4e90-aebd-4295-881a-f8d7"`
 }
+
+type GetAllBatchesResponse struct {
+	Data []Batch `json:"data"`
+}
+
+type RevokeBatchResponse struct {
+	Msg     string `json:"msg" example:"Successfully revoked the S/N(s) of the batch."`
+	BatchID string `json:"batch_id" example:"9d3c1f7a2b6e4d8c0a5f3e1b7c9d2a4e"`
+	Revoked int64  `json:"revoked" example:"100"`
+}
+
+type GetAuditLogsResponse struct {
+	Data []AuditLog `json:"data"`
+}
+
+type VerifyAuditLogsResponse struct {
+	Msg      string `json:"msg" example:"The audit logs are intact."`
+	Valid    bool   `json:"valid" example:"true"`
+	Checked  int64  `json:"checked" example:"1024"`
+	BrokenID int64  `json:"broken_id,omitempty" example:"0"`
+}
+
+type CreateAdminTokenResponse struct {
+	Msg           string     `json:"msg" example:"Successfully created the admin token, it will not be shown again."`
+	Token         string     `json:"token" example:"3b42f6e4040dcc0e472450c279fc8a1eade54c0e0973776635eec4d450fb622e"`
+	SigningSecret string     `json:"signing_secret" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
+	TOTPSecret    string     `json:"totp_secret" example:"JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"`
+	TOTPURI       string     `json:"totp_uri" example:"otpauth://totp/QuickCertS:EXAMPLE%20ADMIN%202?issuer=QuickCertS&secret=JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"`
+	Data          AdminToken `json:"data"`
+}
+
+type ResetAdminTokenTOTPResponse struct {
+	Msg        string     `json:"msg" example:"Successfully reset the TOTP secret of the admin token, it will not be shown again."`
+	TOTPSecret string     `json:"totp_secret" example:"JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"`
+	TOTPURI    string     `json:"totp_uri" example:"otpauth://totp/QuickCertS:EXAMPLE%20ADMIN%202?issuer=QuickCertS&secret=JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"`
+	Data       AdminToken `json:"data"`
+}
+
+type GetAllAdminTokensResponse struct {
+	Data []AdminToken `json:"data"`
+}
+
+type UpdateAdminTokenResponse struct {
+	Msg  string     `json:"msg" example:"Successfully revoked the admin token."`
+	Data AdminToken `json:"data"`
+}
+
+type CreateClientKeyResponse struct {
+	Msg  string    `json:"msg" example:"Successfully created the client key, it will not be shown again."`
+	Key  string    `json:"key" example:"5e9a1c3b7d2f4e6a8c0b2d4f6a8c0e1b3d5f7a9c1e3b5d7f9a2c4e6b8d0f1a3c"`
+	Data ClientKey `json:"data"`
+}
+
+type GetAllClientKeysResponse struct {
+	Data []ClientKey `json:"data"`
+}
+
+type RevokeClientKeyResponse struct {
+	Msg  string    `json:"msg" example:"Successfully revoked the client key."`
+	Data ClientKey `json:"data"`
+}
@@ -1,19 +1,33 @@
 package model
 
+import "time"
+
 // SerialNumber: The new serial number to be uploaded
 //
 // Reason: The reason for uploading the serial number
+//
+// Product: The product the serial number is issued for
 type SNInfo struct {
 	SerialNumber string `json:"serial_number" binding:"required" example:"779f-4e90-aebd-4295-881a-f8d7"`
 	Reason       string `json:"reason" example:"For testing."`
+	Product      string `json:"product" example:"quickcerts-pro"`
 }
 
 // Count: The new serial number to be uploaded
 //
 // Reason: The reason for uploading the serial number(s)
+//
+// Reseller: The reseller the serial number(s) are issued for
+//
+// OrderNumber: The order number the serial number(s) are issued for
+//
+// Product: The product the serial number(s) are issued for
 type SNsInfo struct {
-	Count  int    `json:"count" binding:"required" example:"1"`
-	Reason string `json:"reason" example:"For testing."`
+	Count       int    `json:"count" binding:"required" example:"1"`
+	Reason      string `json:"reason" example:"For testing."`
+	Reseller    string `json:"reseller" example:"Example Reseller Inc."`
+	OrderNumber string `json:"order_number" example:"PO-2024-0001"`
+	Product     string `json:"product" example:"quickcerts-pro"`
 }
 
 // SerialNumber: Serial number obtained from purchasing software
@@ -23,3 +37,57 @@ type CertNote struct {
 	SerialNumber string `json:"serial_number" binding:"required" example:"779f-4e90-aebd-4295-881a-f8d7"`
 	Note         string `json:"note" binding:"required" example:"Additional information"`
 }
+
+// SerialNumber: Serial number obtained from purchasing software
+//
+// Metadata: The metadata to be merged into the current metadata, keys with null values are removed
+type CertMetadataPatch struct {
+	SerialNumber string         `json:"serial_number" binding:"required" example:"779f-4e90-aebd-4295-881a-f8d7"`
+	Metadata     map[string]any `json:"metadata" binding:"required" swaggertype:"object,string" example:"customer_email:user@example.com"`
+}
+
+// Metadata: The S/N(s) whose metadata contains all of the given keys and values are matched
+type CertMetadataQuery struct {
+	Metadata map[string]any `json:"metadata" binding:"required" swaggertype:"object,string" example:"crm_id:C-1024"`
+}
+
+// SerialNumber: The serial number to be deleted
+//
+// Reason: The reason for deleting the serial number
+//
+// Force: Delete the serial number even if it has been bound to a device
+type SNDeleteInfo struct {
+	SerialNumber string `json:"serial_number" binding:"required" example:"779f-4e90-aebd-4295-881a-f8d7"`
+	Reason       string `json:"reason" example:"Refunded."`
+	Force        bool   `json:"force" example:"false"`
+}
+
+// Name: The name of the admin using the token
+//
+// Role: The role of the token, one of viewer, support, issuer and owner
+//
+// ExpiresAt: The expiration time of the token, the token never expires if omitted
+type AdminTokenInfo struct {
+	Name      string     `json:"name" binding:"required" example:"EXAMPLE ADMIN 2"`
+	Role      string     `json:"role" binding:"required" example:"support"`
+	ExpiresAt *time.Time `json:"expires_at" example:"2025-01-01T00:00:00+08:00"`
+}
+
+// ExpiresAt: The new expiration time of the token, the token expires immediately if omitted
+type AdminTokenExpiration struct {
+	ExpiresAt *time.Time `json:"expires_at" example:"2025-01-01T00:00:00+08:00"`
+}
+
+// Name: The name of the product or integration using the key
+//
+// Products: The products whose serial numbers may be applied with the key, "*" for all products
+//
+// Scopes: The apply endpoints the key may call, apply_cert and/or apply_temp_permit
+//
+// RateLimit: The max number of requests per minute, 0 means unlimited
+type ClientKeyInfo struct {
+	Name      string   `json:"name" binding:"required" example:"Windows installer"`
+	Products  []string `json:"products" binding:"required,min=1" example:"quickcerts-pro"`
+	Scopes    []string `json:"scopes" binding:"required,min=1" example:"apply_cert"`
+	RateLimit int      `json:"rate_limit" binding:"min=0" example:"60"`
+}
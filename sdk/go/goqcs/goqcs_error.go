@@ -0,0 +1,50 @@
+package goqcs
+
+// The error returned by the QCS server, check it with errors.Is against the Err* values, e.g.
+//
+//	if errors.Is(err, goqcs.ErrSNNotFound) { ... }
+type QCSError struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *QCSError) Error() string {
+	return "QCS::Error:" + e.Message
+}
+
+// Two QCS errors are the same if they have the same code.
+func (e *QCSError) Is(target error) bool {
+	t, ok := target.(*QCSError)
+	return ok && t.Code != "" && t.Code == e.Code
+}
+
+// The error codes of the QCS server, the codes are stable while the messages may change.
+var (
+	ErrInvalidRequest      = &QCSError{Code: "INVALID_REQUEST"}
+	ErrUnauthorized        = &QCSError{Code: "UNAUTHORIZED"}
+	ErrForbidden           = &QCSError{Code: "FORBIDDEN"}
+	ErrRateLimited         = &QCSError{Code: "RATE_LIMITED"}
+	ErrInternal            = &QCSError{Code: "INTERNAL_ERROR"}
+	ErrDatabaseUnavailable = &QCSError{Code: "DATABASE_UNAVAILABLE"}
+	ErrCacheUnavailable    = &QCSError{Code: "CACHE_UNAVAILABLE"}
+	ErrSNNotFound          = &QCSError{Code: "SN_NOT_FOUND"}
+	ErrSNAlreadyExists     = &QCSError{Code: "SN_ALREADY_EXISTS"}
+	ErrSNUnavailable       = &QCSError{Code: "SN_UNAVAILABLE"}
+	ErrSNAlreadyBound      = &QCSError{Code: "SN_ALREADY_BOUND"}
+	ErrSNNotAllowed        = &QCSError{Code: "SN_NOT_ALLOWED"}
+	ErrTempPermitExpired   = &QCSError{Code: "TEMP_PERMIT_EXPIRED"}
+	ErrBatchNotFound       = &QCSError{Code: "BATCH_NOT_FOUND"}
+	ErrJobNotFound         = &QCSError{Code: "JOB_NOT_FOUND"}
+	ErrJobAlreadyFinished  = &QCSError{Code: "JOB_ALREADY_FINISHED"}
+	ErrJobQueueUnavailable = &QCSError{Code: "JOB_QUEUE_UNAVAILABLE"}
+	ErrAdminTokenNotFound  = &QCSError{Code: "ADMIN_TOKEN_NOT_FOUND"}
+	ErrClientKeyNotFound   = &QCSError{Code: "CLIENT_KEY_NOT_FOUND"}
+)
+
+// Create the QCSError from the status code and the decoded error response.
+func newQCSError(statusCode int, data map[string]interface{}) error {
+	code, _ := data["code"].(string)
+	message, _ := data["error"].(string)
+	return &QCSError{StatusCode: statusCode, Code: code, Message: message}
+}
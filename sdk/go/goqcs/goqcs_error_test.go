@@ -0,0 +1,27 @@
+package goqcs
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewQCSError(t *testing.T) {
+	err := newQCSError(400, map[string]interface{}{"code": "SN_NOT_FOUND", "error": "The S/N does not exist."})
+
+	assert.EqualError(t, err, "QCS::Error:The S/N does not exist.")
+	assert.True(t, errors.Is(err, ErrSNNotFound))
+	assert.True(t, errors.Is(fmt.Errorf("wrapped: %w", err), ErrSNNotFound))
+	assert.False(t, errors.Is(err, ErrSNAlreadyBound))
+
+	var qcsErr *QCSError
+	assert.True(t, errors.As(err, &qcsErr))
+	assert.Equal(t, 400, qcsErr.StatusCode)
+
+	// The responses of the older servers have no code.
+	err = newQCSError(500, map[string]interface{}{"error": "Internal server error."})
+	assert.EqualError(t, err, "QCS::Error:Internal server error.")
+	assert.False(t, errors.Is(err, ErrInternal))
+}
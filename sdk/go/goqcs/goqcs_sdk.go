@@ -2,6 +2,7 @@ package goqcs
 
 import (
 	"bytes"
+	"crypto/ed25519"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -11,6 +12,9 @@ type QCSAdmin struct {
 	accessPrefix string
 	accessToken string
 	runtimeCode string
+	totpSecret string
+	tokenID string
+	signingKey ed25519.PrivateKey
 }
 
 // Create a QCSAdmin instance.
@@ -51,8 +55,11 @@ func (qcsA *QCSAdmin) CreateSN(sn string, reason string) (*QCSCreateSNResponse,
 		return nil, err
 	}
 	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("X-Access-Token", qcsA.accessToken)
-	req.Header.Add("X-Runtime-Code", qcsA.runtimeCode)
+	req.Header.Add("X-Runtime-Code", qcsA.currentRuntimeCode())
+
+	if err := qcsA.authorize(req, jsonfiedBody); err != nil {
+		return nil, err
+	}
 
 	res, err := http.DefaultClient.Do(req)
 
@@ -70,8 +77,7 @@ func (qcsA *QCSAdmin) CreateSN(sn string, reason string) (*QCSCreateSNResponse,
 	}
 
 	if res.StatusCode != 200 {
-		errorMsg := data["error"].(string)
-		return nil, fmt.Errorf("QCS::Error:%s", errorMsg)
+		return nil, newQCSError(res.StatusCode, data)
 	}
 
 	var response QCSCreateSNResponse
@@ -102,8 +108,11 @@ func (qcsA *QCSAdmin) GenerateSN(count uint, reason string) (*QCSGnerateSNRespon
 		return nil, err
 	}
 	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("X-Access-Token", qcsA.accessToken)
-	req.Header.Add("X-Runtime-Code", qcsA.runtimeCode)
+	req.Header.Add("X-Runtime-Code", qcsA.currentRuntimeCode())
+
+	if err := qcsA.authorize(req, jsonfiedBody); err != nil {
+		return nil, err
+	}
 
 	res, err := http.DefaultClient.Do(req)
 
@@ -120,21 +129,115 @@ func (qcsA *QCSAdmin) GenerateSN(count uint, reason string) (*QCSGnerateSNRespon
 		return nil, err
 	}
 
-	if res.StatusCode != 200 {
-		errorMsg := data["error"].(string)
-		return nil, fmt.Errorf("QCS::Error:%s", errorMsg)
+	if res.StatusCode != 200 && res.StatusCode != 202 {
+		return nil, newQCSError(res.StatusCode, data)
 	}
 
 	var response QCSGnerateSNResponse
 	response.Msg, _ = data["msg"].(string)
+	response.BatchID, _ = data["batch_id"].(string)
+	// Large generations run in the background, use GetJob to poll the progress.
+	response.JobID, _ = data["job_id"].(string)
 	
-	for _, sn := range data["serial_numbers"].([]interface{}) {
+	serialNumbers, _ := data["serial_numbers"].([]interface{})
+	for _, sn := range serialNumbers {
 		response.SerialNumbers = append(response.SerialNumbers, sn.(string))
 	}
 	
 	return &response, nil
 }
 
+// Get the status and progress of a background job, e.g. a serial number generation.
+//
+// jobID: job ID returned by QCS.
+func (qcsA *QCSAdmin) GetJob(jobID string) (*QCSJobResponse, error) {
+	url := qcsA.accessPrefix + "/jobs/" + jobID
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("X-Runtime-Code", qcsA.currentRuntimeCode())
+
+	if err := qcsA.authorize(req, nil); err != nil {
+		return nil, err
+	}
+
+	res, err := http.DefaultClient.Do(req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != 200 {
+		var data map[string]interface{}
+		err = json.NewDecoder(res.Body).Decode(&data)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, newQCSError(res.StatusCode, data)
+	}
+
+	var response QCSJobResponse
+	err = json.NewDecoder(res.Body).Decode(&response)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
+
+// Cancel a queued or running background job.
+//
+// jobID: job ID returned by QCS.
+func (qcsA *QCSAdmin) CancelJob(jobID string) (*QCSCancelJobResponse, error) {
+	url := qcsA.accessPrefix + "/jobs/" + jobID + "/cancel"
+
+	req, err := http.NewRequest(http.MethodPost, url, nil)
+	
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("X-Runtime-Code", qcsA.currentRuntimeCode())
+
+	if err := qcsA.authorize(req, nil); err != nil {
+		return nil, err
+	}
+
+	res, err := http.DefaultClient.Do(req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+
+	var data map[string]interface{}
+	err = json.NewDecoder(res.Body).Decode(&data)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != 200 {
+		return nil, newQCSError(res.StatusCode, data)
+	}
+
+	var response QCSCancelJobResponse
+	response.Msg, _ = data["msg"].(string)
+	response.JobID, _ = data["job_id"].(string)
+
+	return &response, nil
+}
+
 // Get all available serial numbers in QCS.
 func (qcsA *QCSAdmin) GetAllRecords() (*QCSAllRecordsResponse, error) {
 	url := qcsA.accessPrefix + "/sn/get-all"
@@ -145,8 +248,11 @@ func (qcsA *QCSAdmin) GetAllRecords() (*QCSAllRecordsResponse, error) {
 		return nil, err
 	}
 	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("X-Access-Token", qcsA.accessToken)
-	req.Header.Add("X-Runtime-Code", qcsA.runtimeCode)
+	req.Header.Add("X-Runtime-Code", qcsA.currentRuntimeCode())
+
+	if err := qcsA.authorize(req, nil); err != nil {
+		return nil, err
+	}
 
 	res, err := http.DefaultClient.Do(req)
 
@@ -164,8 +270,7 @@ func (qcsA *QCSAdmin) GetAllRecords() (*QCSAllRecordsResponse, error) {
 	}
 
 	if res.StatusCode != 200 {
-		errorMsg := data["error"].(string)
-		return nil, fmt.Errorf("QCS::Error:%s", errorMsg)
+		return nil, newQCSError(res.StatusCode, data)
 	}
 
 	var response QCSAllRecordsResponse
@@ -176,6 +281,8 @@ func (qcsA *QCSAdmin) GetAllRecords() (*QCSAllRecordsResponse, error) {
 		record.SerialNumber = irecord.(map[string]interface{})["serial_number"].(string)
 		record.Key = irecord.(map[string]interface{})["key"].(string)
 		record.Note = irecord.(map[string]interface{})["note"].(string)
+		record.BatchID, _ = irecord.(map[string]interface{})["batch_id"].(string)
+		record.Metadata, _ = irecord.(map[string]interface{})["metadata"].(map[string]interface{})
 		records = append(records, record)
 	}
 
@@ -194,8 +301,11 @@ func (qcsA *QCSAdmin) GetAvailableSN() (*QCSAvailableSNResponse, error) {
 		return nil, err
 	}
 	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("X-Access-Token", qcsA.accessToken)
-	req.Header.Add("X-Runtime-Code", qcsA.runtimeCode)
+	req.Header.Add("X-Runtime-Code", qcsA.currentRuntimeCode())
+
+	if err := qcsA.authorize(req, nil); err != nil {
+		return nil, err
+	}
 
 	res, err := http.DefaultClient.Do(req)
 
@@ -213,8 +323,7 @@ func (qcsA *QCSAdmin) GetAvailableSN() (*QCSAvailableSNResponse, error) {
 	}
 
 	if res.StatusCode != 200 {
-		errorMsg := data["error"].(string)
-		return nil, fmt.Errorf("QCS::Error:%s", errorMsg)
+		return nil, newQCSError(res.StatusCode, data)
 	}
 
 	var response QCSAvailableSNResponse
@@ -247,8 +356,11 @@ func (qcsA *QCSAdmin) UpdateSNNote(sn string, note string) (*QCSUpdateSNNoteResp
 		return nil, err
 	}
 	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("X-Access-Token", qcsA.accessToken)
-	req.Header.Add("X-Runtime-Code", qcsA.runtimeCode)
+	req.Header.Add("X-Runtime-Code", qcsA.currentRuntimeCode())
+
+	if err := qcsA.authorize(req, jsonfiedBody); err != nil {
+		return nil, err
+	}
 
 	res, err := http.DefaultClient.Do(req)
 
@@ -266,8 +378,7 @@ func (qcsA *QCSAdmin) UpdateSNNote(sn string, note string) (*QCSUpdateSNNoteResp
 	}
 
 	if res.StatusCode != 200 {
-		errorMsg := data["error"].(string)
-		return nil, fmt.Errorf("QCS::Error:%s", errorMsg)
+		return nil, newQCSError(res.StatusCode, data)
 	}
 
 	var response QCSUpdateSNNoteResponse
@@ -277,6 +388,163 @@ func (qcsA *QCSAdmin) UpdateSNNote(sn string, note string) (*QCSUpdateSNNoteResp
 	return &response, nil
 }
 
+// Delete a serial number, the record is moved into the archive of the server.
+//
+// sn: serial number to delete.
+//
+// reason: the reason for deleting the serial number.
+//
+// force: delete the serial number even if it has been bound to a device.
+func (qcsA *QCSAdmin) DeleteSN(sn string, reason string, force bool) (*QCSDeleteSNResponse, error) {
+	url := qcsA.accessPrefix + "/sn/delete"
+
+	body := map[string]interface{}{
+		"serial_number": sn,
+		"reason": reason,
+		"force": force,
+	}
+
+	jsonfiedBody, _ := json.Marshal(body)
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader([]byte(jsonfiedBody)))
+
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("X-Runtime-Code", qcsA.currentRuntimeCode())
+
+	if err := qcsA.authorize(req, jsonfiedBody); err != nil {
+		return nil, err
+	}
+
+	res, err := http.DefaultClient.Do(req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+
+	var data map[string]interface{}
+	err = json.NewDecoder(res.Body).Decode(&data)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != 200 {
+		return nil, newQCSError(res.StatusCode, data)
+	}
+
+	var response QCSDeleteSNResponse
+	response.Msg, _ = data["msg"].(string)
+	response.SerialNumber, _ = data["serial_number"].(string)
+
+	return &response, nil
+}
+
+// Merge the metadata into the metadata of a serial number.
+//
+// sn: serial number to update.
+//
+// metadata: keys to add or overwrite, keys with nil values are removed.
+func (qcsA *QCSAdmin) UpdateSNMetadata(sn string, metadata map[string]interface{}) (*QCSUpdateSNMetadataResponse, error) {
+	url := qcsA.accessPrefix + "/sn/metadata"
+
+	body := map[string]interface{}{
+		"serial_number": sn,
+		"metadata": metadata,
+	}
+
+	jsonfiedBody, _ := json.Marshal(body)
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader([]byte(jsonfiedBody)))
+
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("X-Runtime-Code", qcsA.currentRuntimeCode())
+
+	if err := qcsA.authorize(req, jsonfiedBody); err != nil {
+		return nil, err
+	}
+
+	res, err := http.DefaultClient.Do(req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+
+	var response QCSUpdateSNMetadataResponse
+
+	if res.StatusCode != 200 {
+		var data map[string]interface{}
+		if err := json.NewDecoder(res.Body).Decode(&data); err != nil {
+			return nil, err
+		}
+
+		return nil, newQCSError(res.StatusCode, data)
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
+
+// Search the records whose metadata contains all of the given keys and values.
+func (qcsA *QCSAdmin) SearchRecords(metadata map[string]interface{}) (*QCSAllRecordsResponse, error) {
+	url := qcsA.accessPrefix + "/sn/search"
+
+	body := map[string]interface{}{
+		"metadata": metadata,
+	}
+
+	jsonfiedBody, _ := json.Marshal(body)
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader([]byte(jsonfiedBody)))
+
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("X-Runtime-Code", qcsA.currentRuntimeCode())
+
+	if err := qcsA.authorize(req, jsonfiedBody); err != nil {
+		return nil, err
+	}
+
+	res, err := http.DefaultClient.Do(req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+
+	var response QCSAllRecordsResponse
+
+	if res.StatusCode != 200 {
+		var data map[string]interface{}
+		if err := json.NewDecoder(res.Body).Decode(&data); err != nil {
+			return nil, err
+		}
+
+		return nil, newQCSError(res.StatusCode, data)
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
+
 type QCSClient struct {	
 	accessPrefix string
 	accessToken string
@@ -351,8 +619,7 @@ func (qcsC *QCSClient) ApplyCert(
 	}
 
 	if res.StatusCode != 200 {
-		errorMsg := data["error"].(string)
-		return nil, fmt.Errorf("QCS::Error:%s", errorMsg)
+		return nil, newQCSError(res.StatusCode, data)
 	}
 
 	var response QCSApplyCertResponse
@@ -411,8 +678,7 @@ func (qcsC *QCSClient) ApplyTempPermit(
 	}
 
 	if res.StatusCode != 200 {
-		errorMsg := data["error"].(string)
-		return nil, fmt.Errorf("QCS::Error:%s", errorMsg)
+		return nil, newQCSError(res.StatusCode, data)
 	}
 
 	var response QCSApplyTempPermitResponse
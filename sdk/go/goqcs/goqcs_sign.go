@@ -0,0 +1,87 @@
+package goqcs
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sign the admin requests with the signing secret of the token instead of sending the access token, see
+// REQUIRE_REQUEST_SIGNATURE in server.toml.
+//
+// The server only keeps the public key of the secret, so neither the captured requests nor the server's database can
+// be used to sign other requests, and the nonce keeps a captured request from being replayed.
+//
+// tokenID: the ID of the admin token.
+//
+// secret: the signing secret returned once when the token is created, or the one generated by GenerateSigningSecret
+// whose public key is set as SIGNING_PUBLIC_KEY in allowlist.toml.
+func (qcsA *QCSAdmin) UseSigningSecret(tokenID string, secret string) error {
+	signingKey, err := signingPrivateKey(secret)
+	if err != nil {
+		return err
+	}
+
+	qcsA.tokenID = tokenID
+	qcsA.signingKey = signingKey
+	return nil
+}
+
+// Generate a signing secret and its public key for the tokens set in allowlist.toml.
+func GenerateSigningSecret() (secret string, publicKey string, err error) {
+	seed := make([]byte, ed25519.SeedSize)
+	if _, err := rand.Read(seed); err != nil {
+		return "", "", err
+	}
+
+	signingKey := ed25519.NewKeyFromSeed(seed)
+	return hex.EncodeToString(seed), hex.EncodeToString(signingKey.Public().(ed25519.PublicKey)), nil
+}
+
+// Authorize the admin request, by signing it if the signing secret is set, or by the access token otherwise.
+func (qcsA *QCSAdmin) authorize(req *http.Request, body []byte) error {
+	if qcsA.signingKey == nil {
+		req.Header.Add("X-Access-Token", qcsA.accessToken)
+		return nil
+	}
+
+	nonceBytes := make([]byte, 16)
+	if _, err := rand.Read(nonceBytes); err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	nonce := hex.EncodeToString(nonceBytes)
+	signature := computeSignature(qcsA.signingKey, req.Method, req.URL.RequestURI(), timestamp, nonce, body)
+
+	req.Header.Add("X-QCS-Token-ID", qcsA.tokenID)
+	req.Header.Add("X-QCS-Timestamp", timestamp)
+	req.Header.Add("X-QCS-Nonce", nonce)
+	req.Header.Add("X-QCS-Signature", signature)
+
+	return nil
+}
+
+// Get the Ed25519 private key of the hex encoded signing secret.
+func signingPrivateKey(secret string) (ed25519.PrivateKey, error) {
+	seed, err := hex.DecodeString(secret)
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("the signing secret should be 64 hex characters")
+	}
+
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// Compute the Ed25519 signature over the method, the request URI, the timestamp, the nonce and the body hash.
+func computeSignature(signingKey ed25519.PrivateKey, method string, uri string, timestamp string, nonce string, body []byte) string {
+	bodyHash := sha256.Sum256(body)
+	content := strings.Join([]string{method, uri, timestamp, nonce, hex.EncodeToString(bodyHash[:])}, "\n")
+
+	return hex.EncodeToString(ed25519.Sign(signingKey, []byte(content)))
+}
@@ -0,0 +1,55 @@
+package goqcs
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComputeSignature(t *testing.T) {
+	signingKey, err := signingPrivateKey("9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08")
+	assert.Nil(t, err)
+
+	signature := computeSignature(
+		signingKey, "POST", "/api/v1/sn/create?x=1", "1700000000", "nonce", []byte(`{"serial_number":"XXXX"}`),
+	)
+
+	// Must match the signature verified by the server (utils.VerifyRequestSignature).
+	assert.Equal(t, "accb93d6381350f0123b8456d4d36aaf4e7b53d3b134d5e51f31bdc2fcf5a98a6745f22c24f8fd889d7631b78a2f07aa46b58b61b2fd71d02f9621ede89d5d0c", signature)
+}
+
+func TestGenerateSigningSecret(t *testing.T) {
+	secret, publicKey, err := GenerateSigningSecret()
+	assert.Nil(t, err)
+	assert.Len(t, secret, 64)
+	assert.Len(t, publicKey, 64)
+
+	_, err = signingPrivateKey("short")
+	assert.NotNil(t, err)
+}
+
+func TestAuthorize(t *testing.T) {
+	qcsA := NewQCSAdmin("localhost", 33333, "/api/v1", false, "token", "")
+	req, _ := http.NewRequest(http.MethodGet, "http://localhost:33333/api/v1/sn/get-all", nil)
+
+	// Without the signing secret, the access token is sent.
+	err := qcsA.authorize(req, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "token", req.Header.Get("X-Access-Token"))
+	assert.Empty(t, req.Header.Get("X-QCS-Signature"))
+
+	secret, _, err := GenerateSigningSecret()
+	assert.Nil(t, err)
+	assert.Nil(t, qcsA.UseSigningSecret("token-id", secret))
+
+	// With the signing secret, the access token is no longer sent.
+	req, _ = http.NewRequest(http.MethodGet, "http://localhost:33333/api/v1/sn/get-all", nil)
+	err = qcsA.authorize(req, nil)
+	assert.Nil(t, err)
+	assert.Empty(t, req.Header.Get("X-Access-Token"))
+	assert.Equal(t, "token-id", req.Header.Get("X-QCS-Token-ID"))
+	assert.NotEmpty(t, req.Header.Get("X-QCS-Timestamp"))
+	assert.Len(t, req.Header.Get("X-QCS-Nonce"), 32)
+	assert.Len(t, req.Header.Get("X-QCS-Signature"), 128)
+}
@@ -0,0 +1,59 @@
+package goqcs
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Use the TOTP codes generated from the secret as the runtime code, for the servers with RUNTIME_CODE_TYPE "totp".
+//
+// secret: the base32 TOTP secret of the admin token, returned when the token is created or its TOTP is reset.
+func (qcsA *QCSAdmin) UseTOTP(secret string) {
+	qcsA.totpSecret = secret
+}
+
+// The time step of the TOTP codes.
+const totpPeriod = 30
+
+// Get the runtime code for the next request.
+//
+// The TOTP code of the current time step can be used for any number of requests.
+func (qcsA *QCSAdmin) currentRuntimeCode() string {
+	if qcsA.totpSecret == "" {
+		return qcsA.runtimeCode
+	}
+
+	code, err := generateTOTPCode(qcsA.totpSecret, time.Now())
+	if err != nil {
+		return ""
+	}
+
+	return code
+}
+
+// Get the TOTP code of the base32 secret at the given time (RFC 6238, HMAC-SHA1, 6 digits, 30 seconds).
+func generateTOTPCode(secret string, at time.Time) (string, error) {
+	secret = strings.ToUpper(strings.ReplaceAll(strings.TrimRight(secret, "="), " ", ""))
+
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil || len(key) == 0 {
+		return "", fmt.Errorf("the TOTP secret is invalid")
+	}
+
+	var message [8]byte
+	binary.BigEndian.PutUint64(message[:], uint64(at.Unix()/totpPeriod))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(message[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%06d", value%1000000), nil
+}
@@ -0,0 +1,44 @@
+package goqcs
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateTOTPCode(t *testing.T) {
+	// The SHA1 test vector of RFC 6238, truncated to 6 digits.
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	code, err := generateTOTPCode(secret, time.Unix(1111111109, 0))
+	assert.Nil(t, err)
+	assert.Equal(t, "081804", code)
+
+	_, err = generateTOTPCode("not base32!", time.Now())
+	assert.EqualError(t, err, "the TOTP secret is invalid")
+}
+
+func TestCurrentRuntimeCode(t *testing.T) {
+	qcsA := NewQCSAdmin("localhost", 33333, "/api/v1", false, "token", "123456")
+	assert.Equal(t, "123456", qcsA.currentRuntimeCode())
+
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	qcsA.UseTOTP(secret)
+
+	before, err := generateTOTPCode(secret, time.Now())
+	assert.Nil(t, err)
+
+	// The code of the current time step is used again for the next request, instead of waiting for the next step.
+	first := qcsA.currentRuntimeCode()
+	second := qcsA.currentRuntimeCode()
+
+	after, err := generateTOTPCode(secret, time.Now())
+	assert.Nil(t, err)
+	assert.Contains(t, []string{before, after}, first)
+	assert.Contains(t, []string{before, after}, second)
+	if before == after {
+		assert.Equal(t, first, second)
+	}
+}
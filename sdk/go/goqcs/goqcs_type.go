@@ -7,13 +7,35 @@ type QCSCreateSNResponse struct {
 
 type QCSGnerateSNResponse struct {
 	Msg           string   `json:"msg"`
+	BatchID       string   `json:"batch_id"`
+	JobID         string   `json:"job_id"`
 	SerialNumbers []string `json:"serial_numbers"`
 }
 
+type QCSJobResponse struct {
+	ID        string `json:"id"`
+	Type      string `json:"type"`
+	Status    string `json:"status"`
+	Progress  int    `json:"progress"`
+	Total     int    `json:"total"`
+	Result    string `json:"result"`
+	Error     string `json:"error"`
+	CreatedBy string `json:"created_by"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+type QCSCancelJobResponse struct {
+	Msg   string `json:"msg"`
+	JobID string `json:"job_id"`
+}
+
 type QCSRecord struct {
-	SerialNumber string `json:"serial_number"`
-	Key          string `json:"key"`
-	Note         string `json:"note"`
+	SerialNumber string                 `json:"serial_number"`
+	Key          string                 `json:"key"`
+	Note         string                 `json:"note"`
+	BatchID      string                 `json:"batch_id"`
+	Metadata     map[string]interface{} `json:"metadata"`
 }
 
 type QCSAllRecordsResponse struct {
@@ -29,7 +51,19 @@ type QCSUpdateSNNoteResponse struct {
 	Note string `json:"note"`
 }
 
+type QCSDeleteSNResponse struct {
+	Msg          string `json:"msg"`
+	SerialNumber string `json:"serial_number"`
+}
+
+type QCSUpdateSNMetadataResponse struct {
+	Msg          string                 `json:"msg"`
+	SerialNumber string                 `json:"serial_number"`
+	Metadata     map[string]interface{} `json:"metadata"`
+}
+
 type QCSApplyCertResponse struct {
+	Status    string `json:"status"`
 	Key       string `json:"key"`
 	Signature string `json:"signature"`
 }
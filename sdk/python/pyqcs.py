@@ -35,7 +35,7 @@ class QCSAdmin:
         res = requests.post(url, headers=headers, json=body)
 
         if res.status_code != 200:
-            raise Exception("QCS::Error:" + res.json()["error"])
+            raise pyqcs_type.QCSError.from_response(res)
         else:
             data = res.json()
             return pyqcs_type.QCSCreateResponse(data["msg"], data["serial_number"])
@@ -53,7 +53,7 @@ class QCSAdmin:
         res = requests.post(url, headers=headers, json=body)
 
         if res.status_code != 200:
-            raise Exception("QCS::Error:" + res.json()["error"])
+            raise pyqcs_type.QCSError.from_response(res)
         else:
             data = res.json()
             return pyqcs_type.QCSGenerateSNResponse(data["msg"], data["serial_numbers"])
@@ -68,7 +68,7 @@ class QCSAdmin:
         res = requests.get(url, headers=headers)
 
         if res.status_code != 200:
-            raise Exception("QCS::Error:" + res.json()["error"])
+            raise pyqcs_type.QCSError.from_response(res)
         else:
             data = res.json()
             records = []
@@ -86,7 +86,7 @@ class QCSAdmin:
         res = requests.get(url, headers=headers)
 
         if res.status_code != 200:
-            raise Exception("QCS::Error:" + res.json()["error"])
+            raise pyqcs_type.QCSError.from_response(res)
         else:
             data = res.json()
             return pyqcs_type.QCSAvailableSNResponse(data["data"])
@@ -104,7 +104,7 @@ class QCSAdmin:
         res = requests.post(url, headers=headers, json=body)
 
         if res.status_code != 200:
-            raise Exception("QCS::Error:" + res.json()["error"])
+            raise pyqcs_type.QCSError.from_response(res)
         else:
             data = res.json()
             return pyqcs_type.QCSUpdateSNNoteResponse(data["msg"], data["note"])
@@ -148,7 +148,7 @@ class QCSClient:
         res = requests.post(url, headers=headers, json=body)
 
         if res.status_code != 200:
-            raise Exception("QCS::Error:" + res.json()["error"])
+            raise pyqcs_type.QCSError.from_response(res)
         else:
             data = res.json()   
             return pyqcs_type.QCSApplyCertResponse(data["key"], data["signature"])
@@ -173,7 +173,7 @@ class QCSClient:
         res = requests.post(url, headers=headers, json=body)
 
         if res.status_code != 200:
-            raise Exception("QCS::Error:" + res.json()["error"])
+            raise pyqcs_type.QCSError.from_response(res)
         else:
             data = res.json()   
             return pyqcs_type.QCSApplyTempPermitResponse(data["remaining_time"], data["status"])
\ No newline at end of file
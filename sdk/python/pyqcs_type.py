@@ -3,6 +3,38 @@
 
 from typing import List
 
+class QCSError(Exception):
+    '''
+    The error returned by the QCS server, check `code` against the QCSError constants, e.g.
+    `except QCSError as e: if e.code == QCSError.SN_NOT_FOUND: ...`.
+    The codes are stable while the messages may change.
+    '''
+
+    INVALID_REQUEST = "INVALID_REQUEST"
+    UNAUTHORIZED = "UNAUTHORIZED"
+    FORBIDDEN = "FORBIDDEN"
+    RATE_LIMITED = "RATE_LIMITED"
+    INTERNAL_ERROR = "INTERNAL_ERROR"
+    DATABASE_UNAVAILABLE = "DATABASE_UNAVAILABLE"
+    CACHE_UNAVAILABLE = "CACHE_UNAVAILABLE"
+    SN_NOT_FOUND = "SN_NOT_FOUND"
+    SN_ALREADY_EXISTS = "SN_ALREADY_EXISTS"
+    SN_UNAVAILABLE = "SN_UNAVAILABLE"
+    SN_ALREADY_BOUND = "SN_ALREADY_BOUND"
+    SN_NOT_ALLOWED = "SN_NOT_ALLOWED"
+    TEMP_PERMIT_EXPIRED = "TEMP_PERMIT_EXPIRED"
+
+    def __init__(self, status_code: int, code: str, message: str) -> None:
+        super().__init__("QCS::Error:" + message)
+        self.status_code = status_code
+        self.code = code
+        self.message = message
+
+    @classmethod
+    def from_response(cls, res) -> "QCSError":
+        data = res.json()
+        return cls(res.status_code, data.get("code", ""), data.get("error", ""))
+
 class QCSCreateSNResponse:
     def __init__(self, msg: str, serial_number: str) -> None:
         self.msg = msg
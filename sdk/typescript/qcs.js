@@ -9,7 +9,36 @@ var __awaiter = (this && this.__awaiter) || function (thisArg, _arguments, P, ge
     });
 };
 Object.defineProperty(exports, "__esModule", { value: true });
-exports.QCSClient = exports.QCSAdmin = void 0;
+exports.QCSClient = exports.QCSAdmin = exports.QCSErrorCode = exports.QCSError = void 0;
+/**
+ * The error returned by the QCS server, check `code` against QCSErrorCode, e.g.
+ * `if (err instanceof QCSError && err.code === QCSErrorCode.SN_NOT_FOUND) { ... }`.
+ */
+class QCSError extends Error {
+    constructor(statusCode, code, message) {
+        super("QCS::Error:" + message);
+        this.name = "QCSError";
+        this.statusCode = statusCode;
+        this.code = code ? code : "";
+    }
+}
+exports.QCSError = QCSError;
+/** The error codes of the QCS server, the codes are stable while the messages may change. */
+exports.QCSErrorCode = {
+    INVALID_REQUEST: "INVALID_REQUEST",
+    UNAUTHORIZED: "UNAUTHORIZED",
+    FORBIDDEN: "FORBIDDEN",
+    RATE_LIMITED: "RATE_LIMITED",
+    INTERNAL_ERROR: "INTERNAL_ERROR",
+    DATABASE_UNAVAILABLE: "DATABASE_UNAVAILABLE",
+    CACHE_UNAVAILABLE: "CACHE_UNAVAILABLE",
+    SN_NOT_FOUND: "SN_NOT_FOUND",
+    SN_ALREADY_EXISTS: "SN_ALREADY_EXISTS",
+    SN_UNAVAILABLE: "SN_UNAVAILABLE",
+    SN_ALREADY_BOUND: "SN_ALREADY_BOUND",
+    SN_NOT_ALLOWED: "SN_NOT_ALLOWED",
+    TEMP_PERMIT_EXPIRED: "TEMP_PERMIT_EXPIRED",
+};
 class QCSAdmin {
     constructor(qcsAdminConfig) {
         const c = qcsAdminConfig;
@@ -50,7 +79,7 @@ class QCSAdmin {
             });
             if (res.status != 200) {
                 const errorObj = (yield res.json());
-                throw new Error("QCS::Error:" + errorObj["error"]);
+                throw new QCSError(res.status, errorObj["code"], errorObj["error"]);
             }
             else {
                 const data = (yield res.json());
@@ -88,7 +117,7 @@ class QCSAdmin {
             });
             if (res.status != 200) {
                 const errorObj = (yield res.json());
-                throw new Error("QCS::Error:" + errorObj["error"]);
+                throw new QCSError(res.status, errorObj["code"], errorObj["error"]);
             }
             else {
                 const data = (yield res.json());
@@ -118,7 +147,7 @@ class QCSAdmin {
             });
             if (res.status != 200) {
                 const errorObj = (yield res.json());
-                throw new Error("QCS::Error:" + errorObj["error"]);
+                throw new QCSError(res.status, errorObj["code"], errorObj["error"]);
             }
             else {
                 const data = (yield res.json());
@@ -147,7 +176,7 @@ class QCSAdmin {
             });
             if (res.status != 200) {
                 const errorObj = (yield res.json());
-                throw new Error("QCS::Error:" + errorObj["error"]);
+                throw new QCSError(res.status, errorObj["code"], errorObj["error"]);
             }
             else {
                 const data = (yield res.json());
@@ -183,7 +212,7 @@ class QCSAdmin {
             });
             if (res.status != 200) {
                 const errorObj = (yield res.json());
-                throw new Error("QCS::Error:" + errorObj["error"]);
+                throw new QCSError(res.status, errorObj["code"], errorObj["error"]);
             }
             else {
                 const data = (yield res.json());
@@ -238,7 +267,7 @@ class QCSClient {
             });
             if (res.status != 200) {
                 const errorObj = (yield res.json());
-                throw new Error("QCS::Error:" + errorObj["error"]);
+                throw new QCSError(res.status, errorObj["code"], errorObj["error"]);
             }
             else {
                 const data = (yield res.json());
@@ -277,7 +306,7 @@ class QCSClient {
             });
             if (res.status != 200) {
                 const errorObj = (yield res.json());
-                throw new Error("QCS::Error:" + errorObj["error"]);
+                throw new QCSError(res.status, errorObj["code"], errorObj["error"]);
             }
             else {
                 const data = (yield res.json());
@@ -1,5 +1,38 @@
 import { QCSType } from "./qcs_type";
 
+/**
+ * The error returned by the QCS server, check `code` against QCSErrorCode, e.g.
+ * `if (err instanceof QCSError && err.code === QCSErrorCode.SN_NOT_FOUND) { ... }`.
+ */
+export class QCSError extends Error {
+  statusCode: number;
+  code: string;
+
+  constructor(statusCode: number, code: string, message: string) {
+    super("QCS::Error:" + message);
+    this.name = "QCSError";
+    this.statusCode = statusCode;
+    this.code = code ? code : "";
+  }
+}
+
+/** The error codes of the QCS server, the codes are stable while the messages may change. */
+export const QCSErrorCode = {
+  INVALID_REQUEST: "INVALID_REQUEST",
+  UNAUTHORIZED: "UNAUTHORIZED",
+  FORBIDDEN: "FORBIDDEN",
+  RATE_LIMITED: "RATE_LIMITED",
+  INTERNAL_ERROR: "INTERNAL_ERROR",
+  DATABASE_UNAVAILABLE: "DATABASE_UNAVAILABLE",
+  CACHE_UNAVAILABLE: "CACHE_UNAVAILABLE",
+  SN_NOT_FOUND: "SN_NOT_FOUND",
+  SN_ALREADY_EXISTS: "SN_ALREADY_EXISTS",
+  SN_UNAVAILABLE: "SN_UNAVAILABLE",
+  SN_ALREADY_BOUND: "SN_ALREADY_BOUND",
+  SN_NOT_ALLOWED: "SN_NOT_ALLOWED",
+  TEMP_PERMIT_EXPIRED: "TEMP_PERMIT_EXPIRED",
+} as const;
+
 export class QCSAdmin {
   accessPrefix: string;
   accessToken: string;
@@ -49,8 +82,8 @@ export class QCSAdmin {
     });
 
     if (res.status != 200) {
-      const errorObj = (await res.json()) as { error: string };
-      throw new Error("QCS::Error:" + errorObj["error"]);
+      const errorObj = (await res.json()) as { code: string; error: string };
+      throw new QCSError(res.status, errorObj["code"], errorObj["error"]);
     } else {
       const data = (await res.json()) as { msg: string; serial_number: string };
       const result = {
@@ -92,8 +125,8 @@ export class QCSAdmin {
     });
 
     if (res.status != 200) {
-      const errorObj = (await res.json()) as { error: string };
-      throw new Error("QCS::Error:" + errorObj["error"]);
+      const errorObj = (await res.json()) as { code: string; error: string };
+      throw new QCSError(res.status, errorObj["code"], errorObj["error"]);
     } else {
       const data = (await res.json()) as {
         msg: string;
@@ -126,8 +159,8 @@ export class QCSAdmin {
     });
 
     if (res.status != 200) {
-      const errorObj = (await res.json()) as { error: string };
-      throw new Error("QCS::Error:" + errorObj["error"]);
+      const errorObj = (await res.json()) as { code: string; error: string };
+      throw new QCSError(res.status, errorObj["code"], errorObj["error"]);
     } else {
       const data = (await res.json()) as {
         data: Array<QCSType.QCSRecord>;
@@ -159,8 +192,8 @@ export class QCSAdmin {
     });
 
     if (res.status != 200) {
-      const errorObj = (await res.json()) as { error: string };
-      throw new Error("QCS::Error:" + errorObj["error"]);
+      const errorObj = (await res.json()) as { code: string; error: string };
+      throw new QCSError(res.status, errorObj["code"], errorObj["error"]);
     } else {
       const data = (await res.json()) as {
         data: Array<string>;
@@ -203,8 +236,8 @@ export class QCSAdmin {
     });
 
     if (res.status != 200) {
-      const errorObj = (await res.json()) as { error: string };
-      throw new Error("QCS::Error:" + errorObj["error"]);
+      const errorObj = (await res.json()) as { code: string; error: string };
+      throw new QCSError(res.status, errorObj["code"], errorObj["error"]);
     } else {
       const data = (await res.json()) as {
         msg: string;
@@ -272,8 +305,8 @@ export class QCSClient {
     });
 
     if (res.status != 200) {
-      const errorObj = (await res.json()) as { error: string };
-      throw new Error("QCS::Error:" + errorObj["error"]);
+      const errorObj = (await res.json()) as { code: string; error: string };
+      throw new QCSError(res.status, errorObj["code"], errorObj["error"]);
     } else {
       const data = (await res.json()) as {
         key: string;
@@ -321,8 +354,8 @@ export class QCSClient {
     });
 
     if (res.status != 200) {
-      const errorObj = (await res.json()) as { error: string };
-      throw new Error("QCS::Error:" + errorObj["error"]);
+      const errorObj = (await res.json()) as { code: string; error: string };
+      throw new QCSError(res.status, errorObj["code"], errorObj["error"]);
     } else {
       const data = (await res.json()) as {
         remaining_time: number;
@@ -1,13 +1,20 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
 	"net/http"
+	"os"
 
 	"github.com/mmq88/quickcerts/api"
 	cfg "github.com/mmq88/quickcerts/configs"
 	"github.com/mmq88/quickcerts/data"
+	"github.com/mmq88/quickcerts/jobs"
 	"github.com/mmq88/quickcerts/middleware"
+	"github.com/mmq88/quickcerts/model"
 	"github.com/mmq88/quickcerts/utils"
 
 	_ "github.com/mmq88/quickcerts/docs"
@@ -19,9 +26,17 @@ import (
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
+// The paths polled by the liveness and readiness probes, which are not access logged.
+const (
+	livenessPath  = "/healthz"
+	readinessPath = "/readyz"
+)
+
 var (
 	runtimeCode string
 	router      *gin.Engine
+	// Serves the admin routes on the separate admin listener when ADMIN_ADDRESS is set.
+	adminRouter *gin.Engine
 )
 
 func init() {
@@ -31,9 +46,17 @@ func init() {
 	gin.SetMode(gin.ReleaseMode)
 	router = gin.New()
 	router.Use(gin.Recovery())
-	router.Use(middleware.AccessLogger())
+	router.Use(middleware.AccessLogger(livenessPath, readinessPath))
+
+	if cfg.SERVER_CONFIG.ADMIN_ADDRESS != "" {
+		adminRouter = gin.New()
+		adminRouter.Use(gin.Recovery())
+		adminRouter.Use(middleware.AccessLogger())
+	}
 
-	if cfg.SERVER_CONFIG.USE_RUNTIME_CODE {
+	if cfg.UseTOTPRuntimeCode() {
+		utils.Record(logrus.InfoLevel, color.HiCyanString("[USE_RUNTIME_CODE] is enabled, admins use their TOTP codes."))
+	} else if cfg.SERVER_CONFIG.USE_RUNTIME_CODE {
 		var err error
 		runtimeCode, err = utils.GenerateRunTimeCode()
 
@@ -60,108 +83,380 @@ func init() {
 // @accept json
 // @produce json
 func main() {
-	err := data.ConnectDB()
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			utils.Record(logrus.FatalLevel, "Failed to migrate the schema. Due to: "+err.Error())
+		}
+		return
+	}
+
+	// Keep checking the databases in the background, until the server stops accepting requests.
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	utils.AddShutdownHook(func(context.Context) { stopWatching() })
+
+	// The SQLite database keeps the key and guard caches as well, so redis is not used at all.
+	if cfg.UseSQLite() {
+		store := openSQLiteStore()
+		defer closeSQLiteStore(store)
+
+		useStores(store, store, store)
+	} else {
+		connectPostgres(watchCtx)
+		defer disconnectPostgres()
+
+		connectRedis(watchCtx)
+		defer disconnectRedis()
 
+		useStores(data.PostgresStore{}, data.RedisKeyCache{}, data.NewRedisGuardCache())
+	}
+
+	err := jobs.Start()
 	if err != nil {
-		utils.Record(logrus.FatalLevel, err.Error())
+		utils.Record(logrus.FatalLevel, "Failed to start the job workers. Due to: "+err.Error())
 	}
-	utils.Record(logrus.InfoLevel, "Successfully connected the database.")
+	utils.AddShutdownHook(jobs.Shutdown)
 
-	defer func() {
-		err := data.DisconnectDB()
-		if err != nil {
-			if err.Error() == "currently not connecting the database" {
-				utils.Record(logrus.WarnLevel, "Currently not connecting the database.")
-				return
-			}
-			utils.Record(logrus.FatalLevel, err.Error())
+	registerRoutes()
+
+	var servers []*http.Server
+
+	if !cfg.SERVER_CONFIG.USE_TLS {
+		servers = append(servers, run(router))
+
+	} else {
+		if cfg.SERVER_CONFIG.TLS_CERT_PATH == "" || cfg.SERVER_CONFIG.TLS_KEY_PATH == "" {
+			utils.Record(logrus.FatalLevel, "TLS_CERT_PATH or TLS_KEY_PATH is empty. Please fill in the configs file.")
 		}
+		servers = append(servers, runTLS(router))
+	}
 
-		utils.Record(logrus.InfoLevel, "Successfully disconnected the database.")
-	}()
+	if adminRouter != nil {
+		servers = append(servers, runAdmin(adminRouter))
+	}
+
+	utils.WaitForShutdown(servers...)
+}
+
+// Connect the Postgres database and prepare its schema, it is kept checked until watchCtx is done.
+func connectPostgres(watchCtx context.Context) {
+	err := data.ConnectDBWithRetries(context.Background())
 
-	err = data.ConnectRDB()
 	if err != nil {
 		utils.Record(logrus.FatalLevel, err.Error())
 	}
-	utils.Record(logrus.InfoLevel, "Successfully connected the redis database.")
+	utils.Record(logrus.InfoLevel, "Successfully connected the database.")
 
-	defer func() {
-		err := data.DisconnectRDB()
-		if err != nil {
-			if err.Error() == "currently not connecting the redis database" {
-				utils.Record(logrus.WarnLevel, "Currently not connecting the redis database.")
-				return
-			}
-			utils.Record(logrus.FatalLevel, err.Error())
-		}
+	go data.WatchDB(watchCtx)
 
-		utils.Record(logrus.InfoLevel, "Successfully disconnected the redis database.")
-	}()
+	migrator, err := data.PostgresMigrator()
+	if err != nil {
+		utils.Record(logrus.FatalLevel, err.Error())
+	}
+	prepareSchema("database", migrator)
+	api.AddReadinessCheck("postgres", false, api.AvailabilityCheck(data.DBAvailable, data.ErrDBNotConnected))
+	api.AddReadinessCheck("migrations", false, migrator.Check)
+}
 
-	registerRoutes()
+func disconnectPostgres() {
+	err := data.DisconnectDB()
+	if err != nil {
+		if errors.Is(err, data.ErrDBNotConnected) {
+			utils.Record(logrus.WarnLevel, "Currently not connecting the database.")
+			return
+		}
+		utils.Record(logrus.FatalLevel, err.Error())
+	}
 
-	if !cfg.SERVER_CONFIG.USE_TLS {
-		run(router)
+	utils.Record(logrus.InfoLevel, "Successfully disconnected the database.")
+}
 
+// Connect the redis database, it is kept checked until watchCtx is done.
+//
+// The server starts without the redis database, the key cache is skipped and the rate limits, admin lockouts and
+// nonces are kept in memory until it is reconnected.
+func connectRedis(watchCtx context.Context) {
+	err := data.ConnectRDB()
+	if err != nil {
+		utils.Record(logrus.WarnLevel,
+			"Failed to connect the redis database, running in the degraded mode. Due to: "+err.Error())
 	} else {
-		if cfg.SERVER_CONFIG.TLS_CERT_PATH == "" || cfg.SERVER_CONFIG.TLS_KEY_PATH == "" {
-			utils.Record(logrus.FatalLevel, "TLS_CERT_PATH or TLS_KEY_PATH is empty. Please fill in the configs file.")
+		utils.Record(logrus.InfoLevel, "Successfully connected the redis database.")
+	}
+
+	go data.WatchRDB(watchCtx)
+
+	api.AddReadinessCheck("redis", true, api.AvailabilityCheck(data.RDBAvailable, data.ErrRDBNotConnected))
+}
+
+func disconnectRedis() {
+	err := data.DisconnectRDB()
+	if err != nil {
+		if errors.Is(err, data.ErrRDBNotConnected) {
+			utils.Record(logrus.WarnLevel, "Currently not connecting the redis database.")
+			return
 		}
-		runTLS(router)
+		utils.Record(logrus.FatalLevel, err.Error())
+	}
+
+	utils.Record(logrus.InfoLevel, "Successfully disconnected the redis database.")
+}
+
+// Open the SQLite database at SQLITE_PATH and prepare its schema, Postgres is not used at all.
+func openSQLiteStore() *data.SQLiteStore {
+	store, err := data.OpenSQLiteStore(cfg.DB_CONFIG.SQLITE_PATH)
+	if err != nil {
+		utils.Record(logrus.FatalLevel, "Failed to open the SQLite database. Due to: "+err.Error())
+	}
+	utils.Record(logrus.InfoLevel, fmt.Sprintf("Successfully opened the SQLite database [%s].",
+		cfg.DB_CONFIG.SQLITE_PATH))
+
+	migrator, err := store.Migrator()
+	if err != nil {
+		utils.Record(logrus.FatalLevel, err.Error())
+	}
+	prepareSchema("SQLite database", migrator)
+	api.AddReadinessCheck("sqlite", false, store.Ping)
+	api.AddReadinessCheck("sqlite_migrations", false, migrator.Check)
+
+	return store
+}
+
+func closeSQLiteStore(store *data.SQLiteStore) {
+	if err := store.Close(); err != nil {
+		utils.Record(logrus.ErrorLevel, "Failed to close the SQLite database. Due to: "+err.Error())
+		return
+	}
+
+	utils.Record(logrus.InfoLevel, "Successfully closed the SQLite database.")
+}
+
+// Use the given stores for the handlers, middlewares and job workers, and bootstrap the credentials into them.
+func useStores(store data.Store, cache data.KeyCache, guard data.GuardCache) {
+	api.UseStores(store, store, cache)
+	api.UseAdminStores(store, store, store)
+	middleware.UseStores(store, store, store, guard)
+	jobs.UseStore(store)
+
+	bootstrapCredentials(store, store)
+}
+
+// Add the admin tokens of allowlist.toml and the client keys of server.toml into the given stores if they are empty.
+func bootstrapCredentials(tokens data.AdminTokenStore, clientKeys data.ClientKeyStore) {
+	added, err := tokens.BootstrapAdminTokens(context.Background(), cfg.ALLOWEDLIST.PERMISSIONS)
+	if err != nil {
+		utils.Record(logrus.FatalLevel, "Failed to bootstrap the admin tokens. Due to: "+err.Error())
+	}
+
+	if added > 0 {
+		utils.Record(logrus.InfoLevel, fmt.Sprintf("Added %d admin token(s) from allowlist.toml.", added))
+	}
+
+	added, err = clientKeys.BootstrapClientKeys(context.Background(), cfg.SERVER_CONFIG.CLIENT_AUTH_TOKEN)
+	if err != nil {
+		utils.Record(logrus.FatalLevel, "Failed to bootstrap the client keys. Due to: "+err.Error())
+	}
+
+	if added > 0 {
+		utils.Record(logrus.InfoLevel, fmt.Sprintf("Added %d client key(s) from server.toml.", added))
 	}
 }
 
 func registerRoutes() {
-	registerRoutesForDocs()
+	registerRoutesForDocs(router)
+	registerRoutesForHealth(router)
 
 	rootGroup := router.Group("/api/v1")
-	registerRoutesForAdmin(rootGroup)
 	registerRoutesForClient(rootGroup)
+
+	// The admin routes are only served on the admin listener when it is configured, so that they are not
+	// exposed wherever the client routes are.
+	if adminRouter != nil {
+		registerRoutesForDocs(adminRouter)
+		registerRoutesForAdmin(adminRouter.Group("/api/v1"))
+	} else {
+		registerRoutesForAdmin(rootGroup)
+	}
 }
 
-func registerRoutesForDocs() {
+func registerRoutesForDocs(router *gin.Engine) {
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 }
 
+// The probes are served on the client listener without authentication.
+func registerRoutesForHealth(router *gin.Engine) {
+	router.GET(livenessPath, api.Liveness)
+	router.GET(readinessPath, api.Readiness)
+}
+
+// The roles allowed to access each kind of admin routes.
+var (
+	viewRoles    = []string{cfg.RoleViewer, cfg.RoleSupport, cfg.RoleIssuer, cfg.RoleOwner}
+	supportRoles = []string{cfg.RoleSupport, cfg.RoleOwner}
+	issueRoles   = []string{cfg.RoleIssuer, cfg.RoleOwner}
+	ownerRoles   = []string{cfg.RoleOwner}
+)
+
 func registerRoutesForAdmin(rootGroup *gin.RouterGroup) {
-	snGroup := rootGroup.Group("/sn")
+	snGroup := rootGroup.Group("/sn", middleware.AuditLog())
 
 	snGroup.POST("/create",
 		middleware.IPAddressAuth(),
-		middleware.AdminAccessAuth(runtimeCode),
+		middleware.AdminAccessAuth(runtimeCode, issueRoles...),
 		api.CreateSN,
 	)
 	snGroup.POST("/generate",
 		middleware.IPAddressAuth(),
-		middleware.AdminAccessAuth(runtimeCode),
+		middleware.AdminAccessAuth(runtimeCode, issueRoles...),
 		api.GenerateSN,
 	)
 	snGroup.POST("/update",
 		middleware.IPAddressAuth(),
-		middleware.AdminAccessAuth(runtimeCode),
+		middleware.AdminAccessAuth(runtimeCode, supportRoles...),
 		api.UpdateCertNote,
 	)
+	snGroup.POST("/metadata",
+		middleware.IPAddressAuth(),
+		middleware.AdminAccessAuth(runtimeCode, supportRoles...),
+		api.UpdateCertMetadata,
+	)
+	snGroup.POST("/search",
+		middleware.IPAddressAuth(),
+		middleware.AdminAccessAuth(runtimeCode, viewRoles...),
+		api.SearchCerts,
+	)
+	snGroup.POST("/delete",
+		middleware.IPAddressAuth(),
+		middleware.AdminAccessAuth(runtimeCode, ownerRoles...),
+		api.DeleteSN,
+	)
+	snGroup.GET("/get-archived",
+		middleware.IPAddressAuth(),
+		middleware.AdminAccessAuth(runtimeCode, viewRoles...),
+		api.GetArchivedRecords,
+	)
 	snGroup.GET("/get-available",
 		middleware.IPAddressAuth(),
-		middleware.AdminAccessAuth(runtimeCode),
+		middleware.AdminAccessAuth(runtimeCode, viewRoles...),
 		api.GetAvaliableSN,
 	)
 	snGroup.GET("/get-all",
 		middleware.IPAddressAuth(),
-		middleware.AdminAccessAuth(runtimeCode),
+		middleware.AdminAccessAuth(runtimeCode, viewRoles...),
 		api.GetAllRecords,
 	)
+
+	batchGroup := rootGroup.Group("/batch", middleware.AuditLog())
+
+	batchGroup.GET("/get-all",
+		middleware.IPAddressAuth(),
+		middleware.AdminAccessAuth(runtimeCode, viewRoles...),
+		api.GetAllBatches,
+	)
+	batchGroup.GET("/:id/export",
+		middleware.IPAddressAuth(),
+		middleware.AdminAccessAuth(runtimeCode, viewRoles...),
+		api.ExportBatch,
+	)
+	batchGroup.POST("/:id/revoke",
+		middleware.IPAddressAuth(),
+		middleware.AdminAccessAuth(runtimeCode, ownerRoles...),
+		api.RevokeBatch,
+	)
+
+	jobsGroup := rootGroup.Group("/jobs", middleware.AuditLog())
+
+	jobsGroup.GET("/:id",
+		middleware.IPAddressAuth(),
+		middleware.AdminAccessAuth(runtimeCode, viewRoles...),
+		api.GetJob,
+	)
+	jobsGroup.GET("/:id/result",
+		middleware.IPAddressAuth(),
+		middleware.AdminAccessAuth(runtimeCode, viewRoles...),
+		api.GetJobResult,
+	)
+	jobsGroup.POST("/:id/cancel",
+		middleware.IPAddressAuth(),
+		middleware.AdminAccessAuth(runtimeCode, issueRoles...),
+		api.CancelJob,
+	)
+
+	tokensGroup := rootGroup.Group("/tokens", middleware.AuditLog())
+
+	tokensGroup.GET("",
+		middleware.IPAddressAuth(),
+		middleware.AdminAccessAuth(runtimeCode, ownerRoles...),
+		api.GetAllAdminTokens,
+	)
+	tokensGroup.POST("",
+		middleware.IPAddressAuth(),
+		middleware.AdminAccessAuth(runtimeCode, ownerRoles...),
+		api.CreateAdminToken,
+	)
+	tokensGroup.POST("/:id/revoke",
+		middleware.IPAddressAuth(),
+		middleware.AdminAccessAuth(runtimeCode, ownerRoles...),
+		api.RevokeAdminToken,
+	)
+	tokensGroup.POST("/:id/expire",
+		middleware.IPAddressAuth(),
+		middleware.AdminAccessAuth(runtimeCode, ownerRoles...),
+		api.ExpireAdminToken,
+	)
+	tokensGroup.POST("/:id/totp",
+		middleware.IPAddressAuth(),
+		middleware.AdminAccessAuth(runtimeCode, ownerRoles...),
+		api.ResetAdminTokenTOTP,
+	)
+
+	clientKeysGroup := rootGroup.Group("/client-keys", middleware.AuditLog())
+
+	clientKeysGroup.GET("",
+		middleware.IPAddressAuth(),
+		middleware.AdminAccessAuth(runtimeCode, ownerRoles...),
+		api.GetAllClientKeys,
+	)
+	clientKeysGroup.POST("",
+		middleware.IPAddressAuth(),
+		middleware.AdminAccessAuth(runtimeCode, ownerRoles...),
+		api.CreateClientKey,
+	)
+	clientKeysGroup.POST("/:id/revoke",
+		middleware.IPAddressAuth(),
+		middleware.AdminAccessAuth(runtimeCode, ownerRoles...),
+		api.RevokeClientKey,
+	)
+
+	auditGroup := rootGroup.Group("/audit", middleware.AuditLog())
+
+	auditGroup.GET("",
+		middleware.IPAddressAuth(),
+		middleware.AdminAccessAuth(runtimeCode, ownerRoles...),
+		api.GetAuditLogs,
+	)
+	auditGroup.GET("/verify",
+		middleware.IPAddressAuth(),
+		middleware.AdminAccessAuth(runtimeCode, ownerRoles...),
+		api.VerifyAuditLogs,
+	)
 }
 
 func registerRoutesForClient(rootGroup *gin.RouterGroup) {
-	applyGroup := rootGroup.Group("/apply")
+	applyGroup := rootGroup.Group("/apply", middleware.ApplyRateLimitByIP())
 
-	applyGroup.POST("/cert", middleware.ClientAccessAuth(), api.ApplyCertificate)
-	applyGroup.POST("/temp-permit", middleware.ClientAccessAuth(), api.ApplyTemporaryPermit)
+	applyGroup.POST("/cert",
+		middleware.ClientAccessAuth(model.ClientScopeApplyCert),
+		middleware.ApplyRateLimitBySN(),
+		api.ApplyCertificate,
+	)
+	applyGroup.POST("/temp-permit",
+		middleware.ClientAccessAuth(model.ClientScopeApplyTempPermit),
+		middleware.ApplyRateLimitBySN(),
+		api.ApplyTemporaryPermit,
+	)
 }
 
-func run(router *gin.Engine) {
+func run(router *gin.Engine) *http.Server {
 	httpServer := &http.Server{
 		Addr:        cfg.SERVER_CONFIG.PORT,
 		Handler:     router,
@@ -174,14 +469,14 @@ func run(router *gin.Engine) {
 		}
 	}()
 
-	runningMsg := fmt.Sprintf("Server is running in %s mode. listening on port: %s",
-		color.HiCyanString("http"), color.HiCyanString("%s", cfg.SERVER_CONFIG.PORT[1:]))
+	runningMsg := fmt.Sprintf("Server is running in %s mode. listening on: %s",
+		color.HiCyanString("http"), color.HiCyanString("%s", cfg.SERVER_CONFIG.PORT))
 	utils.Record(logrus.InfoLevel, runningMsg)
 
-	utils.WaitForShutdown(httpServer)
+	return httpServer
 }
 
-func runTLS(router *gin.Engine) {
+func runTLS(router *gin.Engine) *http.Server {
 	httpsServer := &http.Server{
 		Addr:        cfg.SERVER_CONFIG.TLS_PORT,
 		Handler:     router,
@@ -199,9 +494,68 @@ func runTLS(router *gin.Engine) {
 		}
 	}()
 
-	runningMsg := fmt.Sprintf("Server is running in %s mode. listening on port: %s",
-		color.HiMagentaString("https"), color.HiMagentaString("%s", cfg.SERVER_CONFIG.TLS_PORT[1:]))
+	runningMsg := fmt.Sprintf("Server is running in %s mode. listening on: %s",
+		color.HiMagentaString("https"), color.HiMagentaString("%s", cfg.SERVER_CONFIG.TLS_PORT))
+	utils.Record(logrus.InfoLevel, runningMsg)
+
+	return httpsServer
+}
+
+// Run the separate listener for the admin routes on ADMIN_ADDRESS. If USE_ADMIN_MTLS is enabled, the clients
+// are required to present a certificate signed by one of the CAs in ADMIN_MTLS_CLIENT_CA_PATH.
+func runAdmin(router *gin.Engine) *http.Server {
+	adminServer := &http.Server{
+		Addr:        cfg.SERVER_CONFIG.ADMIN_ADDRESS,
+		Handler:     router,
+		IdleTimeout: cfg.SERVER_CONFIG.KEEP_ALIVE_TIMEOUT,
+	}
+
+	mode := color.HiCyanString("http")
+
+	if cfg.SERVER_CONFIG.ADMIN_USE_TLS {
+		adminServer.SetKeepAlivesEnabled(false)
+		mode = color.HiMagentaString("https")
+	}
+
+	if cfg.SERVER_CONFIG.USE_ADMIN_MTLS {
+		caBundle, err := os.ReadFile(cfg.SERVER_CONFIG.ADMIN_MTLS_CLIENT_CA_PATH)
+		if err != nil {
+			utils.Record(logrus.FatalLevel, "Failed to read the admin client CA bundle. Due to: "+err.Error())
+		}
+
+		clientCAs := x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(caBundle) {
+			utils.Record(logrus.FatalLevel, "No valid certificate is found in ADMIN_MTLS_CLIENT_CA_PATH.")
+		}
+
+		adminServer.TLSConfig = &tls.Config{
+			ClientAuth: tls.RequireAndVerifyClientCert,
+			ClientCAs:  clientCAs,
+			MinVersion: tls.VersionTLS12,
+		}
+		mode = color.HiMagentaString("mtls")
+	}
+
+	go func() {
+		var err error
+
+		if cfg.SERVER_CONFIG.ADMIN_USE_TLS {
+			err = adminServer.ListenAndServeTLS(
+				cfg.SERVER_CONFIG.ADMIN_TLS_CERT_PATH,
+				cfg.SERVER_CONFIG.ADMIN_TLS_KEY_PATH,
+			)
+		} else {
+			err = adminServer.ListenAndServe()
+		}
+
+		if err != nil && err != http.ErrServerClosed {
+			utils.Record(logrus.FatalLevel, "Failed to start the admin server. Due to: "+err.Error())
+		}
+	}()
+
+	runningMsg := fmt.Sprintf("Admin server is running in %s mode. listening on: %s",
+		mode, color.HiCyanString("%s", cfg.SERVER_CONFIG.ADMIN_ADDRESS))
 	utils.Record(logrus.InfoLevel, runningMsg)
 
-	utils.WaitForShutdown(httpsServer)
+	return adminServer
 }
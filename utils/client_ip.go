@@ -0,0 +1,106 @@
+package utils
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// Check if the IP address is in one of the ranges.
+func ContainsIP(ranges []netip.Prefix, ip netip.Addr) bool {
+	ip = ip.Unmap()
+
+	for _, prefix := range ranges {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Resolve the IP address of the client who sent the request.
+//
+// The forwarding headers are only read if the request comes from one of the trusted proxies. The "Forwarded"
+// header takes precedence over "X-Forwarded-For", and the hops are walked from the right (the closest proxy)
+// to the first address which is not a trusted proxy, so that the addresses added by the client are ignored.
+func ResolveClientIP(remoteAddr string, header http.Header, trustedProxies []netip.Prefix) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+
+	client, err := netip.ParseAddr(host)
+	if err != nil {
+		return host
+	}
+	client = client.Unmap()
+
+	if !ContainsIP(trustedProxies, client) {
+		return client.String()
+	}
+
+	hops := forwardedHops(header)
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, ok := parseForwardedHop(hops[i])
+		if !ok {
+			// The address is obfuscated or malformed, the closest trusted proxy is the best we know.
+			break
+		}
+
+		client = hop
+		if !ContainsIP(trustedProxies, client) {
+			break
+		}
+	}
+
+	return client.String()
+}
+
+// Get the forwarded-for addresses in the order the proxies appended them.
+func forwardedHops(header http.Header) []string {
+	var hops []string
+
+	if values := header.Values("Forwarded"); len(values) > 0 {
+		for _, value := range values {
+			for _, element := range strings.Split(value, ",") {
+				forwardedFor := ""
+
+				for _, pair := range strings.Split(element, ";") {
+					name, value, found := strings.Cut(strings.TrimSpace(pair), "=")
+					if found && strings.EqualFold(name, "for") {
+						forwardedFor = value
+					}
+				}
+				hops = append(hops, forwardedFor)
+			}
+		}
+
+		return hops
+	}
+
+	for _, value := range header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(value, ",")...)
+	}
+
+	return hops
+}
+
+// Parse an address of the forwarding headers, which may be quoted, bracketed or have a port,
+// e.g. "192.0.2.1", "192.0.2.1:8080", "\"[2001:db8::1]:8080\"".
+func parseForwardedHop(hop string) (netip.Addr, bool) {
+	hop = strings.Trim(strings.TrimSpace(hop), "\"")
+
+	if addrPort, err := netip.ParseAddrPort(hop); err == nil {
+		return addrPort.Addr().Unmap(), true
+	}
+
+	addr, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(hop, "["), "]"))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+
+	return addr.Unmap(), true
+}
@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"net/http"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContainsIP(t *testing.T) {
+	ranges := []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("2001:db8::/32"),
+	}
+
+	assert.True(t, ContainsIP(ranges, netip.MustParseAddr("10.1.2.3")))
+	assert.True(t, ContainsIP(ranges, netip.MustParseAddr("::ffff:10.1.2.3")))
+	assert.True(t, ContainsIP(ranges, netip.MustParseAddr("2001:db8::1")))
+	assert.False(t, ContainsIP(ranges, netip.MustParseAddr("192.168.0.1")))
+	assert.False(t, ContainsIP(ranges, netip.MustParseAddr("2001:db9::1")))
+	assert.False(t, ContainsIP(nil, netip.MustParseAddr("10.1.2.3")))
+}
+
+func TestResolveClientIP(t *testing.T) {
+	trustedProxies := []netip.Prefix{
+		netip.MustParsePrefix("127.0.0.1/32"),
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("::1/128"),
+	}
+
+	newHeader := func(pairs ...string) http.Header {
+		header := http.Header{}
+		for i := 0; i < len(pairs); i += 2 {
+			header.Add(pairs[i], pairs[i+1])
+		}
+		return header
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		header     http.Header
+		want       string
+	}{
+		{"direct", "203.0.113.5:5000", newHeader(), "203.0.113.5"},
+		{"direct ipv6", "[2001:db8::5]:5000", newHeader(), "2001:db8::5"},
+		{"untrusted proxy is ignored", "203.0.113.5:5000",
+			newHeader("X-Forwarded-For", "198.51.100.1"), "203.0.113.5"},
+		{"trusted proxy", "127.0.0.1:5000",
+			newHeader("X-Forwarded-For", "198.51.100.1"), "198.51.100.1"},
+		{"spoofed hops are ignored", "127.0.0.1:5000",
+			newHeader("X-Forwarded-For", "1.2.3.4, 198.51.100.1, 10.0.0.2"), "198.51.100.1"},
+		{"multiple header lines", "127.0.0.1:5000",
+			newHeader("X-Forwarded-For", "1.2.3.4", "X-Forwarded-For", "198.51.100.1"), "198.51.100.1"},
+		{"all hops trusted", "127.0.0.1:5000",
+			newHeader("X-Forwarded-For", "10.0.0.3, 10.0.0.2"), "10.0.0.3"},
+		{"no header from trusted proxy", "[::1]:5000", newHeader(), "::1"},
+		{"malformed hop", "127.0.0.1:5000",
+			newHeader("X-Forwarded-For", "198.51.100.1, garbage"), "127.0.0.1"},
+		{"forwarded", "127.0.0.1:5000",
+			newHeader("Forwarded", `for=1.2.3.4, for="198.51.100.1:4711";proto=https`), "198.51.100.1"},
+		{"forwarded ipv6", "127.0.0.1:5000",
+			newHeader("Forwarded", `for="[2001:db8::7]:4711"`), "2001:db8::7"},
+		{"forwarded takes precedence", "127.0.0.1:5000",
+			newHeader("Forwarded", "for=198.51.100.1", "X-Forwarded-For", "1.2.3.4"), "198.51.100.1"},
+		{"forwarded obfuscated", "127.0.0.1:5000",
+			newHeader("Forwarded", "for=_hidden"), "127.0.0.1"},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.want, ResolveClientIP(test.remoteAddr, test.header, trustedProxies), test.name)
+	}
+}
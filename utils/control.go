@@ -5,13 +5,25 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
 )
 
-// Gracefully shutdown the server.
-func WaitForShutdown(server *http.Server) {
+var shutdownHooks []func(ctx context.Context)
+
+// Register a function to be called after the server has stopped accepting requests during the graceful
+// shutdown, e.g. for stopping background workers. The hooks share the shutdown timeout with the server.
+func AddShutdownHook(hook func(ctx context.Context)) {
+	shutdownHooks = append(shutdownHooks, hook)
+}
+
+// Gracefully shutdown the servers.
+//
+// The servers stop accepting requests together and share the shutdown timeout, and the shutdown hooks are called
+// after all of them have stopped.
+func WaitForShutdown(servers ...*http.Server) {
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt)
 	<-quit
@@ -19,8 +31,21 @@ func WaitForShutdown(server *http.Server) {
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
-	if err := server.Shutdown(ctx); err != nil {
-		Record(logrus.FatalLevel, "Something wrong happened when shutting down the server: "+err.Error())
+
+	var wg sync.WaitGroup
+	for _, server := range servers {
+		wg.Add(1)
+		go func(server *http.Server) {
+			defer wg.Done()
+			if err := server.Shutdown(ctx); err != nil {
+				Record(logrus.ErrorLevel, "Something wrong happened when shutting down the server: "+err.Error())
+			}
+		}(server)
+	}
+	wg.Wait()
+
+	for _, hook := range shutdownHooks {
+		hook(ctx)
 	}
 
 	Record(logrus.InfoLevel, "The Server has exited successfully.")
@@ -2,11 +2,15 @@ package utils
 
 import (
 	"crypto"
+	"crypto/ed25519"
+	"crypto/hmac"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha256"
 	"crypto/sha512"
+	"crypto/subtle"
 	"crypto/x509"
+	"encoding/hex"
 	"encoding/pem"
 	"errors"
 	"fmt"
@@ -41,6 +45,123 @@ func GenerateSN() (string, error) {
 		plainUUID[0:4], plainUUID[4:8], plainUUID[8:12], plainUUID[12:16], plainUUID[16:20], plainUUID[20:24]), nil
 }
 
+// Generate a random ID (32 hex characters) for identifying server side resources such as jobs.
+func GenerateID() (string, error) {
+	id := make([]byte, 16)
+	_, err := rand.Read(id)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", id), nil
+}
+
+// Generate a random admin API token with 256 bits of entropy.
+func GenerateToken() (string, error) {
+	token := make([]byte, 32)
+	_, err := rand.Read(token)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", token), nil
+}
+
+// Hash the token with the given salt by SHA-256, the hashes are stored instead of the tokens.
+func HashToken(token string, salt string) string {
+	hash := sha256.Sum256([]byte(salt + token))
+	return fmt.Sprintf("%x", hash)
+}
+
+// Compare the hash of the token with the given hash in constant time.
+func VerifyToken(token string, salt string, tokenHash string) bool {
+	return subtle.ConstantTimeCompare([]byte(HashToken(token, salt)), []byte(tokenHash)) == 1
+}
+
+// Compute the keyed hash for finding a stored token without keeping any part of it in plaintext.
+//
+// The key is derived from the private key, so the hashes can not be brute-forced without it.
+func TokenLookupHash(token string) string {
+	key := sha256.Sum256(append([]byte("qcs-token-lookup:"), privateKeyBytes...))
+
+	mac := hmac.New(sha256.New, key[:])
+	mac.Write([]byte(token))
+	return fmt.Sprintf("%x", mac.Sum(nil))
+}
+
+// Generate a random secret for signing admin requests, and return it along with its public key.
+//
+// The secret is the hex Ed25519 seed, which is only given to the admin. The server keeps the public key for
+// verifying the signatures, which can not sign requests by itself.
+func GenerateSigningSecret() (string, string, error) {
+	seed := make([]byte, ed25519.SeedSize)
+	if _, err := rand.Read(seed); err != nil {
+		return "", "", err
+	}
+
+	secret := hex.EncodeToString(seed)
+	publicKey, err := SigningPublicKey(secret)
+	if err != nil {
+		return "", "", err
+	}
+
+	return secret, publicKey, nil
+}
+
+// Get the hex Ed25519 public key of the signing secret.
+func SigningPublicKey(secret string) (string, error) {
+	privateKey, err := signingPrivateKey(secret)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(privateKey.Public().(ed25519.PublicKey)), nil
+}
+
+func signingPrivateKey(secret string) (ed25519.PrivateKey, error) {
+	seed, err := hex.DecodeString(secret)
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, errors.New("the signing secret should be 64 hex characters")
+	}
+
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// Get the content of an admin request covered by its signature.
+//
+// The signed content is the method, the request URI (path and query), the timestamp, the nonce and the SHA-256
+// hash of the body, joined by line breaks.
+func requestSigningContent(method string, uri string, timestamp string, nonce string, body []byte) []byte {
+	bodyHash := sha256.Sum256(body)
+	return []byte(strings.Join([]string{method, uri, timestamp, nonce, fmt.Sprintf("%x", bodyHash)}, "\n"))
+}
+
+// Compute the hex Ed25519 signature of an admin request with the signing secret.
+func SignRequest(secret string, method string, uri string, timestamp string, nonce string, body []byte) (string, error) {
+	privateKey, err := signingPrivateKey(secret)
+	if err != nil {
+		return "", err
+	}
+
+	signature := ed25519.Sign(privateKey, requestSigningContent(method, uri, timestamp, nonce, body))
+	return hex.EncodeToString(signature), nil
+}
+
+// Verify the hex Ed25519 signature of an admin request with the hex public key of the signing secret.
+func VerifyRequestSignature(
+	publicKey string, method string, uri string, timestamp string, nonce string, body []byte, signature string,
+) bool {
+	key, err := hex.DecodeString(publicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return false
+	}
+
+	sig, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+
+	return ed25519.Verify(key, requestSigningContent(method, uri, timestamp, nonce, body), sig)
+}
+
 // Generate an APP key by SHA3-256 for the device.
 func GenerateKey(base string) (string, error) {
 	hash := sha3.New256()
@@ -72,6 +193,12 @@ func SignMessage(message []byte) ([]byte, error) {
 	return sinature, err
 }
 
+// Check if the private key signing the certificates is loaded and valid.
+func CheckSigningKey() error {
+	_, err := keyBytesToPrivateKey(privateKeyBytes)
+	return err
+}
+
 // Convert the private key bytes to a *rsa.PrivateKey.
 func keyBytesToPrivateKey(keyBytes []byte) (*rsa.PrivateKey, error) {
 	block, _ := pem.Decode(keyBytes)
@@ -3,6 +3,7 @@ package utils
 import (
 	"crypto"
 	"crypto/rsa"
+	"crypto/sha256"
 	"crypto/x509"
 	"encoding/hex"
 	"encoding/pem"
@@ -17,6 +18,92 @@ func TestGenerateSN(t *testing.T) {
 	assert.Equal(t, len(sn), 29)
 }
 
+func TestGenerateID(t *testing.T) {
+	id0, _ := GenerateID()
+	id1, _ := GenerateID()
+	assert.Equal(t, 32, len(id0))
+	assert.NotEqual(t, id0, id1)
+}
+
+func TestGenerateToken(t *testing.T) {
+	token0, _ := GenerateToken()
+	token1, _ := GenerateToken()
+	assert.Equal(t, 64, len(token0))
+	assert.NotEqual(t, token0, token1)
+}
+
+func TestVerifyToken(t *testing.T) {
+	salt0, _ := GenerateID()
+	salt1, _ := GenerateID()
+	hash := HashToken("token", salt0)
+
+	assert.NotEqual(t, hash, HashToken("token", salt1))
+	assert.True(t, VerifyToken("token", salt0, hash))
+	assert.False(t, VerifyToken("token", salt1, hash))
+	assert.False(t, VerifyToken("invalid token", salt0, hash))
+}
+
+func TestTokenLookupHash(t *testing.T) {
+	hash := TokenLookupHash("token")
+
+	assert.Len(t, hash, 64)
+	assert.Equal(t, hash, TokenLookupHash("token"))
+	assert.NotEqual(t, hash, TokenLookupHash("another token"))
+	plainHash := sha256.Sum256([]byte("token"))
+	assert.NotEqual(t, hex.EncodeToString(plainHash[:]), hash)
+}
+
+func TestGenerateSigningSecret(t *testing.T) {
+	secret, publicKey, err := GenerateSigningSecret()
+	assert.Nil(t, err)
+	assert.Len(t, secret, 64)
+	assert.Len(t, publicKey, 64)
+	assert.NotEqual(t, secret, publicKey)
+
+	derived, err := SigningPublicKey(secret)
+	assert.Nil(t, err)
+	assert.Equal(t, publicKey, derived)
+
+	another, _, err := GenerateSigningSecret()
+	assert.Nil(t, err)
+	assert.NotEqual(t, secret, another)
+
+	_, err = SigningPublicKey("invalid")
+	assert.NotNil(t, err)
+}
+
+func TestSignRequest(t *testing.T) {
+	secret := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	publicKey, err := SigningPublicKey(secret)
+	assert.Nil(t, err)
+	assert.Equal(t, "67d3b5eaf0c0bf6b5a602d359daecc86a7a74053490ec37ae08e71360587c870", publicKey)
+
+	// Must match the signature computed by the Go SDK.
+	signature, err := SignRequest(secret, "POST", "/api/v1/sn/create?x=1", "1700000000", "nonce", []byte(`{"serial_number":"XXXX"}`))
+	assert.Nil(t, err)
+	assert.Equal(t, "accb93d6381350f0123b8456d4d36aaf4e7b53d3b134d5e51f31bdc2fcf5a98a"+
+		"6745f22c24f8fd889d7631b78a2f07aa46b58b61b2fd71d02f9621ede89d5d0c", signature)
+
+	body := []byte(`{"count":1}`)
+	signature, err = SignRequest(secret, "POST", "/api/v1/sn/generate", "1700000000", "nonce", body)
+	assert.Nil(t, err)
+	assert.True(t, VerifyRequestSignature(publicKey, "POST", "/api/v1/sn/generate", "1700000000", "nonce", body, signature))
+
+	// Any change of the request invalidates the signature.
+	assert.False(t, VerifyRequestSignature(publicKey, "GET", "/api/v1/sn/generate", "1700000000", "nonce", body, signature))
+	assert.False(t, VerifyRequestSignature(publicKey, "POST", "/api/v1/sn/create", "1700000000", "nonce", body, signature))
+	assert.False(t, VerifyRequestSignature(publicKey, "POST", "/api/v1/sn/generate", "1700000001", "nonce", body, signature))
+	assert.False(t, VerifyRequestSignature(publicKey, "POST", "/api/v1/sn/generate", "1700000000", "nonce2", body, signature))
+	assert.False(t, VerifyRequestSignature(publicKey, "POST", "/api/v1/sn/generate", "1700000000", "nonce", []byte(`{"count":2}`), signature))
+
+	// The signatures of the other secrets and the malformed keys are refused.
+	_, anotherPublicKey, err := GenerateSigningSecret()
+	assert.Nil(t, err)
+	assert.False(t, VerifyRequestSignature(anotherPublicKey, "POST", "/api/v1/sn/generate", "1700000000", "nonce", body, signature))
+	assert.False(t, VerifyRequestSignature("", "POST", "/api/v1/sn/generate", "1700000000", "nonce", body, signature))
+	assert.False(t, VerifyRequestSignature(publicKey, "POST", "/api/v1/sn/generate", "1700000000", "nonce", body, "invalid"))
+}
+
 func TestGenerateKey(t *testing.T) {
 	// Using SHA3-256
 	testMsg := "test"
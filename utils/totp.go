@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// The time step of the TOTP codes (RFC 6238).
+	totpPeriod = 30 * time.Second
+
+	// The number of digits of the TOTP codes.
+	totpDigits = 6
+
+	// The number of time steps before and after the current one whose codes are still accepted,
+	// to tolerate the clock drift between the server and the authenticator.
+	totpSkew = 1
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// Generate a random TOTP secret encoded in base32, which can be added to authenticator apps.
+func GenerateTOTPSecret() (string, error) {
+	secret := make([]byte, 20)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(secret), nil
+}
+
+// Get the TOTP code of the base32 secret at the given time (RFC 6238, HMAC-SHA1, 6 digits, 30 seconds).
+func GenerateTOTPCode(secret string, at time.Time) (string, error) {
+	key, err := decodeTOTPSecret(secret)
+	if err != nil {
+		return "", err
+	}
+
+	return totpCode(key, uint64(at.Unix())/uint64(totpPeriod/time.Second)), nil
+}
+
+// Check if the code is the TOTP code of the base32 secret around the given time.
+func VerifyTOTPCode(secret string, code string, at time.Time) bool {
+	key, err := decodeTOTPSecret(secret)
+	if err != nil || len(code) != totpDigits {
+		return false
+	}
+
+	counter := int64(at.Unix()) / int64(totpPeriod/time.Second)
+	matched := 0
+
+	// Every step is compared, so the time taken does not reveal which one matched.
+	for step := counter - totpSkew; step <= counter+totpSkew; step++ {
+		if step < 0 {
+			continue
+		}
+		matched |= subtle.ConstantTimeCompare([]byte(totpCode(key, uint64(step))), []byte(code))
+	}
+
+	return matched == 1
+}
+
+// Get the otpauth URI of the secret, which can be shown as a QR code for authenticator apps.
+func TOTPURI(issuer string, account string, secret string) string {
+	label := url.PathEscape(issuer + ":" + account)
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
+
+func decodeTOTPSecret(secret string) ([]byte, error) {
+	secret = strings.ToUpper(strings.ReplaceAll(strings.TrimRight(secret, "="), " ", ""))
+
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil || len(key) == 0 {
+		return nil, fmt.Errorf("the TOTP secret is invalid")
+	}
+
+	return key, nil
+}
+
+func totpCode(key []byte, counter uint64) string {
+	var message [8]byte
+	binary.BigEndian.PutUint64(message[:], counter)
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(message[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000)
+}
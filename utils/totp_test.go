@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The SHA1 test vectors of RFC 6238, truncated to 6 digits.
+var rfc6238Secret = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestGenerateTOTPSecret(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	assert.Nil(t, err)
+	assert.Len(t, secret, 32)
+
+	_, err = GenerateTOTPCode(secret, time.Now())
+	assert.Nil(t, err)
+}
+
+func TestGenerateTOTPCode(t *testing.T) {
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, test := range tests {
+		code, err := GenerateTOTPCode(rfc6238Secret, time.Unix(test.unix, 0))
+		assert.Nil(t, err)
+		assert.Equal(t, test.code, code)
+	}
+
+	_, err := GenerateTOTPCode("not base32!", time.Now())
+	assert.EqualError(t, err, "the TOTP secret is invalid")
+
+	_, err = GenerateTOTPCode("", time.Now())
+	assert.EqualError(t, err, "the TOTP secret is invalid")
+}
+
+func TestVerifyTOTPCode(t *testing.T) {
+	at := time.Unix(1111111109, 0)
+
+	assert.True(t, VerifyTOTPCode(rfc6238Secret, "081804", at))
+
+	// The codes of the adjacent time steps are accepted for the clock drift.
+	assert.True(t, VerifyTOTPCode(rfc6238Secret, "081804", at.Add(30*time.Second)))
+	assert.True(t, VerifyTOTPCode(rfc6238Secret, "081804", at.Add(-30*time.Second)))
+	assert.False(t, VerifyTOTPCode(rfc6238Secret, "081804", at.Add(90*time.Second)))
+
+	assert.False(t, VerifyTOTPCode(rfc6238Secret, "000000", at))
+	assert.False(t, VerifyTOTPCode(rfc6238Secret, "81804", at))
+	assert.False(t, VerifyTOTPCode("", "081804", at))
+}
+
+func TestTOTPURI(t *testing.T) {
+	assert.Equal(t,
+		"otpauth://totp/QuickCertS:admin%200?issuer=QuickCertS&secret=GEZDGNBV",
+		TOTPURI("QuickCertS", "admin 0", "GEZDGNBV"),
+	)
+}